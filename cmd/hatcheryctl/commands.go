@@ -0,0 +1,381 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/summerplaygames/hatchery/pkg/client"
+)
+
+// tailPollInterval is how often ledger tail -f checks for new transactions.
+const tailPollInterval = time.Second
+
+func createContract(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: hatcheryctl contract create <manifest.json>")
+	}
+	b, err := readInput(args[0])
+	if err != nil {
+		return err
+	}
+	var m client.ContractManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("invalid manifest: %s", err)
+	}
+	return c.PostContract(context.Background(), &m)
+}
+
+func validateContract(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: hatcheryctl contract validate <manifest.json>")
+	}
+	b, err := readInput(args[0])
+	if err != nil {
+		return err
+	}
+	var m client.ContractManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("invalid manifest: %s", err)
+	}
+	report, err := c.ValidateContract(context.Background(), &m)
+	if err != nil {
+		return err
+	}
+	for _, v := range report.Errors {
+		fmt.Printf("error: %s: %s\n", v.Field, v.Message)
+	}
+	for _, v := range report.Warnings {
+		fmt.Printf("warning: %s: %s\n", v.Field, v.Message)
+	}
+	if !report.Valid {
+		return fmt.Errorf("manifest has %d errors", len(report.Errors))
+	}
+	return nil
+}
+
+func bundleContracts(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: hatcheryctl contract bundle <directory|archive>")
+	}
+	bundle, err := readBundle(args[0])
+	if err != nil {
+		return err
+	}
+	results, err := c.PostContractBundle(context.Background(), bundle)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tCONTRACT\tVERSION\tERROR")
+	failed := 0
+	for _, r := range results {
+		version := "-"
+		if r.Error == "" {
+			version = fmt.Sprint(r.Version)
+		} else {
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.File, r.Contract, version, r.Error)
+		for _, v := range r.Violations {
+			fmt.Fprintf(w, "\t\t\t  %s: %s\n", v.Field, v.Message)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d contracts were not registered", failed, len(results))
+	}
+	return nil
+}
+
+// readBundle reads the archive at name, or if name is a directory, archives the
+// .json files in it and its subdirectories as a tar file.
+func readBundle(name string) ([]byte, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return ioutil.ReadFile(name)
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err = filepath.Walk(name, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() || !strings.EqualFold(filepath.Ext(path), ".json") {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(name, path)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{Name: filepath.ToSlash(rel), Mode: 0644, Size: int64(len(b)), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = tw.Write(b)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func listContracts(c *client.Client, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: hatcheryctl contract list")
+	}
+	manifests, err := c.ListContracts(context.Background())
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tRUNTIME\tIMAGE\tCRON")
+	for _, m := range manifests {
+		runtime := m.Runtime
+		if runtime == "" {
+			runtime = "docker"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Type, runtime, m.Image, m.Cron)
+	}
+	return w.Flush()
+}
+
+func deleteContract(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: hatcheryctl contract delete <name>")
+	}
+	return c.DeleteContract(context.Background(), args[0])
+}
+
+func postTransaction(c *client.Client, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: hatcheryctl txn post <txn_type> [payload]")
+	}
+	payload := []byte("{}")
+	if len(args) == 2 {
+		b, err := readArg(args[1])
+		if err != nil {
+			return err
+		}
+		if !json.Valid(b) {
+			return errors.New("payload must be valid JSON")
+		}
+		payload = b
+	}
+	t, err := c.PostTransaction(context.Background(), args[0], json.RawMessage(payload))
+	if err != nil {
+		return err
+	}
+	return printTransaction(t)
+}
+
+func getHeap(c *client.Client, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: hatcheryctl heap get <contract> <key>")
+	}
+	v, err := c.GetHeap(context.Background(), args[0], args[1])
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(v))
+	return err
+}
+
+func listHeap(c *client.Client, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: hatcheryctl heap list <contract> [prefix]")
+	}
+	prefix := ""
+	if len(args) == 2 {
+		prefix = args[1]
+	}
+	keys, err := c.ListHeap(context.Background(), args[0], prefix)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		fmt.Println(k)
+	}
+	return nil
+}
+
+func exportHeap(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: hatcheryctl heap export <contract>")
+	}
+	entries, err := c.ExportHeap(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	return printJSON(entries)
+}
+
+func importHeap(c *client.Client, args []string) error {
+	flags := flag.NewFlagSet("heap import", flag.ContinueOnError)
+	replace := flags.Bool("replace", false, "empty the heap before importing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: hatcheryctl heap import [-replace] <contract> <export.json>")
+	}
+	b, err := readInput(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	var entries []client.HeapEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return fmt.Errorf("invalid export: %s", err)
+	}
+	n, err := c.ImportHeap(context.Background(), flags.Arg(0), entries, *replace)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Printf("imported %d entries\n", n)
+	return err
+}
+
+func exportLedger(c *client.Client, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: hatcheryctl ledger export")
+	}
+	b, err := c.ExportLedger(context.Background())
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+func importLedger(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: hatcheryctl ledger import <export.jsonl>")
+	}
+	b, err := readInput(args[0])
+	if err != nil {
+		return err
+	}
+	imported, skipped, err := c.ImportLedger(context.Background(), b)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Printf("imported %d transactions, skipped %d already in the ledger\n", imported, skipped)
+	return err
+}
+
+func tailLedger(c *client.Client, args []string) error {
+	flags := flag.NewFlagSet("ledger tail", flag.ContinueOnError)
+	n := flags.Int("n", 10, "number of transactions to print")
+	follow := flags.Bool("f", false, "keep printing transactions as they are appended")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *n < 0 || flags.NArg() != 0 {
+		return errors.New("usage: hatcheryctl ledger tail [-n count] [-f]")
+	}
+	ctx := context.Background()
+	page, err := c.ListTransactions(ctx, 0, 1)
+	if err != nil {
+		return err
+	}
+	offset := page.Total - *n
+	if offset < 0 {
+		offset = 0
+	}
+	for {
+		page, err := c.ListTransactions(ctx, offset, 0)
+		if err != nil {
+			return err
+		}
+		for i := range page.Transactions {
+			if err := printTransaction(&page.Transactions[i]); err != nil {
+				return err
+			}
+		}
+		offset += len(page.Transactions)
+		if offset < page.Total {
+			continue
+		}
+		if !*follow {
+			return nil
+		}
+		time.Sleep(tailPollInterval)
+	}
+}
+
+// readArg returns arg, or the contents of stdin if arg is "-".
+func readArg(arg string) ([]byte, error) {
+	if arg == "-" {
+		return readInput(arg)
+	}
+	return []byte(arg), nil
+}
+
+// readInput returns the contents of the named file, or of stdin if name is "-".
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// printTransaction prints t as JSON. Content that is itself JSON is printed as is,
+// rather than base64 encoded.
+func printTransaction(t *client.Transaction) error {
+	v := struct {
+		*client.Transaction
+		Content interface{} `json:",omitempty"`
+	}{Transaction: t}
+	switch {
+	case json.Valid(t.Content):
+		v.Content = json.RawMessage(t.Content)
+	case len(t.Content) > 0:
+		v.Content = t.Content
+	}
+	return printJSON(v)
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,208 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Command hatcheryctl manages a Hatchery instance through its HTTP API.
+//
+// Usage:
+//
+//	hatcheryctl [-config path] <command> <subcommand> [arguments]
+//
+// The commands are:
+//
+//	contract create <manifest.json>   post a contract, or a new version of it
+//	contract bundle <dir|archive>     post every manifest in a directory or a tar or zip file
+//	contract validate <manifest.json> check a manifest without posting it
+//	contract repl [-commit] <name|manifest.json>
+//	                                  execute payloads typed interactively
+//	contract list                     list contracts
+//	contract delete <name>            delete a contract
+//	txn post <txn_type> [payload]     post a transaction; "-" reads the payload from stdin
+//	heap get <contract> <key>         print a heap value
+//	heap list <contract> [prefix]     list heap keys
+//	heap export <contract>            print every heap entry as JSON
+//	heap import [-replace] <contract> <export.json>
+//	                                  write exported heap entries
+//	ledger tail [-n count] [-f]       print the latest transactions
+//	ledger export                     print every transaction, with its hashes, as JSON Lines
+//	ledger import <export.jsonl>      append exported transactions to the ledger
+//
+// The Hatchery URL and API key are read from a YAML or JSON config file, which
+// defaults to ~/.hatcheryctl.yaml:
+//
+//	url: http://localhost:8080
+//	auth_key_id: ABCDEFGHIJKL
+//	auth_key: secret
+//	dragonchain_id: my-chain-id
+//
+// The environment variables HATCHERY_URL, AUTH_KEY_ID, AUTH_KEY and
+// DRAGONCHAIN_ID override the file.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/summerplaygames/hatchery/pkg/client"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	defaultURL        = "http://localhost:8080"
+	defaultConfigName = ".hatcheryctl.yaml"
+)
+
+// config holds the settings used to reach Hatchery.
+type config struct {
+	URL           string `json:"url" yaml:"url"`
+	AuthKeyID     string `json:"auth_key_id" yaml:"auth_key_id"`
+	AuthKey       string `json:"auth_key" yaml:"auth_key"`
+	DragonChainID string `json:"dragonchain_id" yaml:"dragonchain_id"`
+}
+
+// command runs a subcommand with the remaining command line arguments.
+type command func(c *client.Client, args []string) error
+
+var commands = map[string]map[string]command{
+	"contract": {
+		"create":   createContract,
+		"bundle":   bundleContracts,
+		"validate": validateContract,
+		"repl":     replContract,
+		"list":     listContracts,
+		"delete":   deleteContract,
+	},
+	"txn": {
+		"post": postTransaction,
+	},
+	"heap": {
+		"get":    getHeap,
+		"list":   listHeap,
+		"export": exportHeap,
+		"import": importHeap,
+	},
+	"ledger": {
+		"tail":   tailLedger,
+		"export": exportLedger,
+		"import": importLedger,
+	},
+}
+
+func main() {
+	configPath := flag.String("config", "", "path to a YAML or JSON config file (default ~/"+defaultConfigName+")")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[args[0]][args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", strings.Join(args[:2], " "))
+		usage()
+		os.Exit(2)
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	c := &client.Client{
+		BaseURL:       cfg.URL,
+		AuthKeyID:     cfg.AuthKeyID,
+		AuthKey:       cfg.AuthKey,
+		DragonChainID: cfg.DragonChainID,
+	}
+	if err := cmd(c, args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: hatcheryctl [-config path] <command> <subcommand> [arguments]
+
+commands:
+  contract create <manifest.json>   post a contract, or a new version of it
+  contract validate <manifest.json> check a manifest without posting it
+  contract repl [-commit] <name|manifest.json>
+                                    execute payloads typed interactively
+  contract list                     list contracts
+  contract delete <name>            delete a contract
+  txn post <txn_type> [payload]     post a transaction; "-" reads the payload from stdin
+  heap get <contract> <key>         print a heap value
+  heap list <contract> [prefix]     list heap keys
+  heap export <contract>            print every heap entry as JSON
+  heap import [-replace] <contract> <export.json>
+                                    write exported heap entries
+  ledger tail [-n count] [-f]       print the latest transactions
+  ledger export                     print every transaction, with its hashes, as JSON Lines
+  ledger import <export.jsonl>      append exported transactions to the ledger
+
+flags:
+`)
+	flag.PrintDefaults()
+}
+
+// loadConfig reads the config file at path, overlaid with environment variables. If
+// path is empty, the default config file is read if it exists.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{URL: defaultURL}
+	explicit := path != ""
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			path = filepath.Join(home, defaultConfigName)
+		}
+	}
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		switch {
+		case os.IsNotExist(err) && !explicit:
+		case err != nil:
+			return nil, fmt.Errorf("failed to read config: %s", err)
+		default:
+			if strings.EqualFold(filepath.Ext(path), ".json") {
+				err = json.Unmarshal(b, cfg)
+			} else {
+				err = yaml.UnmarshalStrict(b, cfg)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse config %s: %s", path, err)
+			}
+		}
+	}
+	for env, field := range map[string]*string{
+		"HATCHERY_URL":   &cfg.URL,
+		"AUTH_KEY_ID":    &cfg.AuthKeyID,
+		"AUTH_KEY":       &cfg.AuthKey,
+		"DRAGONCHAIN_ID": &cfg.DragonChainID,
+	} {
+		if v, ok := os.LookupEnv(env); ok {
+			*field = v
+		}
+	}
+	return cfg, nil
+}
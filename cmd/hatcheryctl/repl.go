@@ -0,0 +1,238 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/summerplaygames/hatchery/pkg/client"
+)
+
+// maxREPLPayload is the size of the largest payload contract repl reads.
+const maxREPLPayload = 16 << 20
+
+const replHelp = `Type a JSON payload, over several lines if need be, to execute the contract with
+it. Nothing is written to the heap or the ledger unless you commit.
+
+  :commit        post the last payload as a transaction of the posted contract
+  :auto          toggle committing every payload that exits successfully
+  :use <target>  switch to another contract name or manifest file
+  :reload        read the manifest again
+  :help          print this help
+  :quit          exit (or press Ctrl-D)
+`
+
+// repl is the state of an interactive contract repl session.
+type repl struct {
+	c *client.Client
+	// target is the contract name or manifest file the manifest was read from.
+	target   string
+	manifest *client.ContractManifest
+	// last is the last payload that was executed, which :commit posts.
+	last json.RawMessage
+	auto bool
+}
+
+func replContract(c *client.Client, args []string) error {
+	flags := flag.NewFlagSet("contract repl", flag.ContinueOnError)
+	auto := flags.Bool("commit", false, "commit every payload that exits successfully")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: hatcheryctl contract repl [-commit] <name|manifest.json>")
+	}
+	r := &repl{c: c, auto: *auto}
+	if err := r.use(flags.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Printf("executing %s; type :help for help\n", r.manifest.Type)
+	return r.run(os.Stdin)
+}
+
+// use reads the manifest of target, which is either a manifest file or the name of a
+// posted contract.
+func (r *repl) use(target string) error {
+	var m client.ContractManifest
+	if _, err := os.Stat(target); err == nil {
+		b, err := readInput(target)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return fmt.Errorf("invalid manifest: %s", err)
+		}
+	} else {
+		manifests, err := r.c.ListContracts(context.Background())
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, posted := range manifests {
+			if posted.Type == target {
+				m, found = posted, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s is neither a manifest file nor a posted contract", target)
+		}
+	}
+	r.target, r.manifest = target, &m
+	return nil
+}
+
+// run reads payloads and commands from in until it ends or :quit is typed.
+func (r *repl) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64<<10), maxREPLPayload)
+	var payload []byte
+	for {
+		if len(payload) == 0 {
+			fmt.Printf("%s> ", r.manifest.Type)
+		} else {
+			fmt.Print("... ")
+		}
+		if !scanner.Scan() {
+			fmt.Println()
+			return scanner.Err()
+		}
+		line := scanner.Text()
+		if len(payload) == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			if strings.HasPrefix(trimmed, ":") {
+				if quit := r.command(strings.Fields(trimmed)); quit {
+					return nil
+				}
+				continue
+			}
+		}
+		payload = append(payload, line...)
+		payload = append(payload, '\n')
+		var v interface{}
+		switch err := json.Unmarshal(payload, &v); {
+		case err == nil:
+			r.execute(json.RawMessage(strings.TrimSpace(string(payload))))
+		case err.Error() == "unexpected end of JSON input":
+			// The payload continues on the next line.
+			continue
+		default:
+			fmt.Fprintf(os.Stderr, "invalid payload: %s\n", err)
+		}
+		payload = nil
+	}
+}
+
+// command runs a repl command, such as ":commit", and reports whether the session
+// should end.
+func (r *repl) command(fields []string) bool {
+	switch fields[0] {
+	case ":quit", ":q", ":exit":
+		return true
+	case ":help", ":h":
+		fmt.Print(replHelp)
+	case ":commit":
+		if r.last == nil {
+			fmt.Fprintln(os.Stderr, "nothing to commit yet")
+			break
+		}
+		r.commit(r.last)
+	case ":auto":
+		r.auto = !r.auto
+		fmt.Printf("auto commit is %s\n", onOff(r.auto))
+	case ":use", ":reload":
+		target := r.target
+		if fields[0] == ":use" {
+			if len(fields) != 2 {
+				fmt.Fprintln(os.Stderr, "usage: :use <name|manifest.json>")
+				break
+			}
+			target = fields[1]
+		}
+		if err := r.use(target); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			break
+		}
+		r.last = nil
+		fmt.Printf("executing %s\n", r.manifest.Type)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %s; type :help for help\n", fields[0])
+	}
+	return false
+}
+
+// execute tests the contract with payload and prints its stdout, stderr, exit code
+// and duration.
+func (r *repl) execute(payload json.RawMessage) {
+	r.last = payload
+	res, err := r.c.TestContract(context.Background(), r.manifest, payload)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if res.Stdout != "" {
+		fmt.Print(withNewline(res.Stdout))
+	}
+	if res.Stderr != "" {
+		fmt.Fprint(os.Stderr, withNewline(res.Stderr))
+	}
+	fmt.Printf("exit %d in %s\n", res.ExitCode, res.Duration)
+	if r.auto && res.ExitCode == 0 {
+		r.commit(payload)
+	}
+}
+
+// commit posts payload as a transaction of the posted contract, executing it again,
+// and prints the transaction.
+func (r *repl) commit(payload json.RawMessage) {
+	t, err := r.c.PostTransaction(context.Background(), r.manifest.Type, payload)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if err := printTransaction(t); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func withNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
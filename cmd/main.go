@@ -19,6 +19,13 @@
 
 package main
 
-func main() {
+import (
+	// The postgres backends and elector connect through database/sql, so the
+	// binary links the driver they use by default.
+	_ "github.com/lib/pq"
+	"github.com/summerplaygames/hatchery/pkg/server"
+)
 
+func main() {
+	server.Main()
 }
@@ -0,0 +1,254 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// DefaultDockerfile is the Dockerfile built when a build doesn't name one.
+const DefaultDockerfile = "Dockerfile"
+
+// scpLikeSource matches git's scp-like syntax for SSH repositories, such as
+// "git@github.com:org/repo.git".
+var scpLikeSource = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/].*$`)
+
+// invalidTagChars matches the characters that may not appear in the repository name
+// of a built image.
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// BuildTag returns the reference an image built for version of the named contract is
+// tagged with, such as "hatchery/mycontract:v3". Characters that can't appear in a
+// repository name are replaced with dashes. Version 0, which hasn't been stored,
+// is tagged "dev".
+func BuildTag(name string, version int) string {
+	repo := strings.Trim(invalidTagChars.ReplaceAllString(strings.ToLower(name), "-"), "._-")
+	if repo == "" {
+		repo = "contract"
+	}
+	tag := "dev"
+	if version > 0 {
+		tag = fmt.Sprintf("v%d", version)
+	}
+	return "hatchery/" + repo + ":" + tag
+}
+
+// ParseSource splits a git source of the form <repository>[#<ref>] into the
+// repository URL and the ref, which may be a branch, a tag or a commit. An empty
+// ref selects the repository's default branch. An error is returned if the
+// repository is not an http, https, ssh or git URL, or an scp-like SSH address.
+func ParseSource(source string) (repo, ref string, err error) {
+	repo = source
+	if i := strings.LastIndex(source, "#"); i >= 0 {
+		repo, ref = source[:i], source[i+1:]
+	}
+	if strings.HasPrefix(ref, "-") {
+		return "", "", fmt.Errorf("invalid source %q: ref must not begin with a dash", source)
+	}
+	switch {
+	case strings.HasPrefix(repo, "https://"), strings.HasPrefix(repo, "http://"),
+		strings.HasPrefix(repo, "ssh://"), strings.HasPrefix(repo, "git://"):
+	case scpLikeSource.MatchString(repo):
+	default:
+		return "", "", fmt.Errorf("invalid source %q: must be an http, https, ssh or git URL of a git repository", source)
+	}
+	return repo, ref, nil
+}
+
+// ValidateDockerfile returns an error if dockerfile is not a path inside the
+// repository being built.
+func ValidateDockerfile(dockerfile string) error {
+	clean := path.Clean(dockerfile)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("invalid Dockerfile %q: must be a relative path inside the repository", dockerfile)
+	}
+	return nil
+}
+
+// BuildImage clones the git repository described by source, which has the form
+// accepted by ParseSource, builds the image described by dockerfile, a path in the
+// repository, with the repository as the build context, and tags the image as tag.
+// If dockerfile is empty, DefaultDockerfile is built. The git command must be
+// installed. An error is returned, including the end of git's or the build's output,
+// if the repository could not be cloned or the image could not be built.
+func BuildImage(source, dockerfile, tag string) error {
+	repo, ref, err := ParseSource(source)
+	if err != nil {
+		return err
+	}
+	if dockerfile == "" {
+		dockerfile = DefaultDockerfile
+	}
+	if err := ValidateDockerfile(dockerfile); err != nil {
+		return err
+	}
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "hatchery-build-")
+	if err != nil {
+		return fmt.Errorf("failed to create build directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := clone(dir, repo, ref); err != nil {
+		return err
+	}
+	buildContext, err := tarDirectory(dir)
+	if err != nil {
+		return fmt.Errorf("failed to archive build context: %s", err)
+	}
+	resp, err := c.ImageBuild(context.Background(), buildContext, types.ImageBuildOptions{
+		Tags:        []string{tag},
+		Dockerfile:  path.Clean(dockerfile),
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return daemonError(err)
+	}
+	defer resp.Body.Close()
+	// The build is only complete once its output has been fully consumed, and
+	// failed build steps are only reported in the output.
+	var out tailBuffer
+	if err := jsonmessage.DisplayJSONMessagesStream(resp.Body, &out, 0, false, nil); err != nil {
+		return fmt.Errorf("failed to build image: %s", buildError(err, out.String()))
+	}
+	return nil
+}
+
+// clone checks out ref of the git repository repo into dir. Only the ref's commit
+// is fetched.
+func clone(dir, repo, ref string) error {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	steps := [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", "--", repo, ref},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	}
+	for _, args := range steps {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		// Never wait for credentials on a terminal nobody is watching.
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to clone %s: %s", repo, buildError(err, string(out)))
+		}
+	}
+	return nil
+}
+
+// tarDirectory archives the files in dir, except git's metadata, as a build context.
+func tarDirectory(dir string) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil || rel == "." {
+			return err
+		}
+		if rel == ".git" {
+			return filepath.SkipDir
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(name); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// maxBuildOutput is how much of the end of git's or a build's output is kept to
+// explain a failure.
+const maxBuildOutput = 2 << 10
+
+// tailBuffer keeps the end of what is written to it.
+type tailBuffer struct {
+	b []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.b = append(t.b, p...)
+	if len(t.b) > maxBuildOutput {
+		t.b = t.b[len(t.b)-maxBuildOutput:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	return string(t.b)
+}
+
+// buildError combines err with the end of the output of the command that failed.
+func buildError(err error, output string) error {
+	output = strings.TrimSpace(output)
+	if len(output) > maxBuildOutput {
+		output = "..." + output[len(output)-maxBuildOutput:]
+	}
+	if output == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %s", err, output)
+}
@@ -20,8 +20,14 @@
 package docker
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/output"
 )
 
 // Contract is a Contract implementation that executes Smart
@@ -32,30 +38,169 @@ type Contract struct {
 	Image   string
 	Command string
 	Args    []string
+	// Timeout limits how long a single execution may run. If zero,
+	// executions are not limited.
+	Timeout time.Duration
+	// Logger receives the contract's logs. If nil, logging.Default() is used.
+	Logger logging.Logger
+	// Runner runs the contract's container. If nil, DefaultRunner is used.
+	Runner *Runner
+	// Network is the container's network policy, such as NetworkNone. If empty,
+	// the container is attached to Docker's default network.
+	Network string
+	// AllowHosts are the hosts the container may reach if Network is
+	// NetworkAllowlist.
+	AllowHosts []string
+	// Warm is how many idle containers of the contract Pool keeps running between
+	// executions. Warm containers serve executions with the protocol described by
+	// WarmEnv. If zero, every execution runs in a new container.
+	Warm int
+	// MaxUses is how many executions a warm container serves before it is
+	// replaced. If zero, warm containers are reused indefinitely.
+	MaxUses int
+	// Pool keeps the contract's warm containers. If nil, DefaultPool is used.
+	Pool *Pool
+	// Stdout and Stderr optionally receive a copy of the container's output as it
+	// is written. See Spec.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Output limits the output captured from an execution. See Spec.
+	Output output.Limit
+}
+
+// ExitError is returned by Execute when the contract's container exits with a
+// non-zero status.
+type ExitError struct {
+	Code int
+	// Stderr is everything the contract wrote to stderr.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("contract exited with status %d", e.Code)
+	if s := stderrTail(e.Stderr); s != "" {
+		msg += ": " + s
+	}
+	return msg
+}
+
+// stderrTail returns the end of stderr, trimmed of surrounding whitespace, for
+// inclusion in error messages.
+func stderrTail(stderr []byte) string {
+	const max = 512
+	s := strings.TrimSpace(string(stderr))
+	if len(s) > max {
+		s = "..." + s[len(s)-max:]
+	}
+	return s
+}
+
+// ExitCode returns the exit status of the container.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// SetEnv sets the environment variable key to value for subsequent executions.
+func (c *Contract) SetEnv(key, value string) {
+	if c.Env == nil {
+		c.Env = make(map[string]string)
+	}
+	c.Env[key] = value
+}
+
+// SetOutput sets the writers that receive a copy of the container's stdout and stderr
+// in subsequent executions.
+func (c *Contract) SetOutput(stdout, stderr io.Writer) {
+	c.Stdout, c.Stderr = stdout, stderr
 }
 
-// Execute runs the containerized smart contract by shelling out
-// to `docker run`. The container's stdout is returned along with
-// any errors that occur during execution.
-func (c *Contract) Execute(payload []byte) ([]byte, error) {
+// SetOutputLimit sets the limit of the output captured from subsequent executions.
+func (c *Contract) SetOutputLimit(limit output.Limit) {
+	c.Output = limit
+}
+
+// Execute runs the containerized smart contract using the Docker
+// Engine API. The payload is written to the container's stdin and
+// the container's stdout is returned. An error is returned if the
+// container could not be run or it exits with a non-zero status. The
+// container is killed if ctx is cancelled or Timeout is exceeded. If ctx
+// is cancelled, context.Canceled is returned.
+func (c *Contract) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	res, err := c.Run(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Code: res.ExitCode, Stderr: res.Stderr}
+	}
+	return res.Stdout, nil
+}
+
+// Run runs the containerized smart contract like Execute, but returns the complete
+// Result of the run. A non-zero exit code is not considered an error. If Warm is set,
+// the contract is run in one of its warm containers.
+func (c *Contract) Run(ctx context.Context, payload []byte) (*Result, error) {
 	if payload == nil {
 		payload = []byte("")
 	}
-	cmd, err := Run(c.Image, c.Command, c.Env, c.Args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute command: %s", err)
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
 	}
-	w, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initiate pipe to stdin: %s", err)
+	logger := c.Logger
+	if logger == nil {
+		logger = logging.Default()
 	}
-	r, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initiate pipe from stdout: %s", err)
+	logger = logger.With(logging.Contract(c.Name))
+	runner := c.Runner
+	if runner == nil {
+		runner = DefaultRunner
+	}
+	spec := &Spec{
+		Image:      c.Image,
+		Command:    c.Command,
+		Args:       c.Args,
+		Env:        c.Env,
+		Stdin:      payload,
+		Network:    c.Network,
+		AllowHosts: c.AllowHosts,
+		Stdout:     c.Stdout,
+		Stderr:     c.Stderr,
+		Output:     c.Output,
+	}
+	var res *Result
+	var err error
+	if c.Warm > 0 {
+		pool := c.Pool
+		if pool == nil {
+			pool = DefaultPool
+		}
+		logger.Debug("invoking warm container", logging.F("image", c.Image))
+		res, err = pool.Run(ctx, c.Name, spec, c.Warm, c.MaxUses)
+	} else {
+		logger.Debug("starting container", logging.F("image", c.Image))
+		res, err = runner.Run(ctx, spec)
 	}
-	defer w.Close()
-	if _, err := w.Write(payload); err != nil {
-		return nil, fmt.Errorf("failed to pipe to stdin: %s", err)
+	if err != nil && ctx.Err() == context.Canceled {
+		logger.Info("container killed because its execution was cancelled")
+		return nil, context.Canceled
+	}
+	if err == context.DeadlineExceeded && c.Timeout > 0 {
+		logger.Error("container timed out", logging.F("timeout", c.Timeout.String()))
+		return nil, fmt.Errorf("contract timed out after %s", c.Timeout)
+	}
+	if e, ok := err.(*output.TooLargeError); ok {
+		logger.Error("container output too large", logging.F("max_output_bytes", e.Max))
+		return nil, e
+	}
+	if err != nil {
+		logger.Error("container failed", logging.Err(err))
+		return nil, fmt.Errorf("failed to execute contract: %s", err)
 	}
-	return ioutil.ReadAll(r)
+	logger.Debug("container exited",
+		logging.F("exit_code", res.ExitCode),
+		logging.F("duration", res.Duration.String()),
+	)
+	return res, nil
 }
@@ -19,25 +19,272 @@
 
 package docker
 
-import "os/exec"
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
-// PullImage pulls down a docker image using `docker pull`.
-// An error is returned if the `docker pull` command fails.
-func PullImage(image string) error {
-	return exec.Command("docker", "pull", image).Run()
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+)
+
+var (
+	clientOnce sync.Once
+	cli        *client.Client
+	endpoint   *Endpoint
+	clientErr  error
+)
+
+// Client returns the Docker Engine API client shared by the package. The client
+// connects to the daemon returned by ResolveEndpoint, is otherwise configured from
+// the environment (DOCKER_API_VERSION, DOCKER_CERT_PATH and DOCKER_TLS_VERIFY) and
+// negotiates the API version with the daemon. An error is returned if the client
+// could not be created.
+func Client() (*client.Client, error) {
+	clientOnce.Do(func() {
+		ep, err := ResolveEndpoint()
+		if err != nil {
+			clientErr = err
+			return
+		}
+		cli, clientErr = client.NewClientWithOpts(clientOpts(ep)...)
+		if clientErr != nil {
+			clientErr = fmt.Errorf("failed to create docker client for %s (from %s): %s", ep.Host, ep.Source, clientErr)
+		}
+		endpoint = ep
+	})
+	return cli, clientErr
+}
+
+// Ping checks that the Docker daemon is reachable and responding. A *DaemonError
+// is returned if it is not, or another error if ctx is done first.
+func Ping(ctx context.Context) error {
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	_, err = c.Ping(ctx)
+	return daemonError(err)
+}
+
+// dockerHubServer is the address DockerHub credentials are sent to.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// Auth contains the credentials used to authenticate with an image registry.
+type Auth struct {
+	Username string
+	Password string
+	// ServerAddress is the address of the registry. If empty, DockerHub is used.
+	ServerAddress string
+}
+
+// PullImage pulls down a docker image from its registry. If auth is non-nil, it is
+// used to authenticate with the registry. The progress of each layer is reported to
+// the functions watching pulls of img. See WatchPulls. A *PullError is returned if
+// the registry refuses the pull or the pull fails part way.
+//
+// Only one pull of img happens at a time. Callers that pull img with the same
+// credentials while it is being pulled share that pull and its outcome; callers with
+// other credentials wait for it to finish and then pull img themselves, so that the
+// registry checks their credentials.
+func PullImage(img string, auth *Auth) error {
+	registryAuth, err := encodeAuth(auth)
+	if err != nil {
+		return err
+	}
+	for {
+		pullsMu.Lock()
+		p, ok := pulls[img]
+		if !ok {
+			p = &pull{auth: registryAuth, done: make(chan struct{})}
+			pulls[img] = p
+			pullsMu.Unlock()
+			p.err = pullImage(img, registryAuth)
+			pullsMu.Lock()
+			delete(pulls, img)
+			pullsMu.Unlock()
+			close(p.done)
+			return p.err
+		}
+		pullsMu.Unlock()
+		<-p.done
+		if p.auth == registryAuth {
+			return p.err
+		}
+	}
+}
+
+// pullImage pulls img, authenticating with the encoded registryAuth, and reports
+// its progress.
+func pullImage(img, registryAuth string) error {
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	opts := image.PullOptions{RegistryAuth: registryAuth}
+	r, err := c.ImagePull(context.Background(), img, opts)
+	if err != nil {
+		if derr, ok := daemonError(err).(*DaemonError); ok {
+			return derr
+		}
+		return newPullError(img, err)
+	}
+	defer r.Close()
+	// The pull is only complete once the progress stream has been fully consumed.
+	return readPull(img, r)
+}
+
+// CheckImage returns an error if img can't be pulled, without pulling it: it must
+// either be available locally or its registry must have a manifest for it. If auth
+// is non-nil, it is used to authenticate with the registry.
+func CheckImage(ctx context.Context, img string, auth *Auth) error {
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	if _, err := c.ImageInspect(ctx, img); err == nil {
+		return nil
+	}
+	encoded, err := encodeAuth(auth)
+	if err != nil {
+		return err
+	}
+	if _, err := c.DistributionInspect(ctx, img, encoded); err != nil {
+		return daemonError(err)
+	}
+	return nil
+}
+
+// encodeAuth encodes auth for the Docker Engine API. An empty string is returned if
+// auth is nil.
+func encodeAuth(auth *Auth) (string, error) {
+	if auth == nil {
+		return "", nil
+	}
+	encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ServerAddress: auth.ServerAddress,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode registry auth: %s", err)
+	}
+	return encoded, nil
+}
+
+// ParseAuth parses registry credentials for pulling img. The credentials have the
+// form <username>:<password or access token>, optionally base64 encoded as DragonChain
+// expects. The registry server is derived from img, so that credentials for images
+// on DockerHub and on other registries are both sent to the right place. An error
+// is returned if creds or img is malformed.
+func ParseAuth(creds, img string) (*Auth, error) {
+	if !strings.Contains(creds, ":") {
+		b, err := base64.StdEncoding.DecodeString(creds)
+		if err != nil {
+			return nil, errors.New("registry credentials must have the form username:password")
+		}
+		creds = string(b)
+	}
+	parts := strings.SplitN(creds, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return nil, errors.New("registry credentials must have the form username:password")
+	}
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference: %s", err)
+	}
+	server := reference.Domain(named)
+	if server == "docker.io" {
+		server = dockerHubServer
+	}
+	return &Auth{
+		Username:      parts[0],
+		Password:      parts[1],
+		ServerAddress: server,
+	}, nil
+}
+
+// ValidateImage returns an error if img is not a valid image reference.
+func ValidateImage(img string) error {
+	if _, err := reference.ParseNormalizedNamed(img); err != nil {
+		return fmt.Errorf("invalid image reference: %s", err)
+	}
+	return nil
+}
+
+// FloatingTag reports whether img refers to its repository's latest tag, explicitly or
+// by omitting the tag, rather than to a specific tag or digest. It returns false if img
+// is not a valid image reference.
+func FloatingTag(img string) bool {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return false
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return false
+	}
+	tagged, ok := named.(reference.Tagged)
+	return !ok || tagged.Tag() == "latest"
+}
+
+// ImageDigest returns the content-addressable digest of a locally available image,
+// in the form <repository>@sha256:<hex>. Images that were built locally rather than
+// pulled have no repository digest, in which case the image ID is returned instead.
+// An error is returned if the image could not be inspected.
+func ImageDigest(img string) (string, error) {
+	c, err := Client()
+	if err != nil {
+		return "", err
+	}
+	info, err := c.ImageInspect(context.Background(), img)
+	if err != nil {
+		return "", err
+	}
+	if len(info.RepoDigests) > 0 {
+		return info.RepoDigests[0], nil
+	}
+	return info.ID, nil
+}
+
+// TagImage points the target reference, such as a repository and tag, at the
+// locally available image src. An error is returned if the image could not be
+// tagged.
+func TagImage(src, target string) error {
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	return c.ImageTag(context.Background(), src, target)
+}
+
+// RemoveImage removes a docker image from the local image store. Removing an image
+// that doesn't exist is not an error. An error is returned if the image could not
+// be removed.
+func RemoveImage(img string) error {
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	_, err = c.ImageRemove(context.Background(), img, image.RemoveOptions{PruneChildren: true})
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	return err
 }
 
-// Run executes a docker image with the provided command and arguments.
-// The exec.Cmd for the shell process is returned. An error is returned
-// if the shell processes execution fails.
-func Run(image, cmd string, env map[string]string, args ...string) (*exec.Cmd, error) {
-	envArr := make([]string, 0, len(env))
+// envList converts env into the KEY=VALUE form expected by the Docker API. The
+// result is sorted so that container configuration is deterministic.
+func envList(env map[string]string) []string {
+	arr := make([]string, 0, len(env))
 	for k, v := range env {
-		envArr = append(envArr, "-e", k+"="+v)
+		arr = append(arr, k+"="+v)
 	}
-	arr := append(envArr, "-d", image, cmd)
-	arr = append(arr, args...)
-	c := exec.Command("docker", arr...)
-	err := c.Run()
-	return c, err
+	sort.Strings(arr)
+	return arr
 }
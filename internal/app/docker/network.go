@@ -0,0 +1,220 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// Network policies for contract containers.
+const (
+	// NetworkNone gives the container no network access at all.
+	NetworkNone = "none"
+	// NetworkBridge attaches the container to Docker's default bridge network.
+	NetworkBridge = "bridge"
+	// NetworkHost shares the host's network with the container.
+	NetworkHost = "host"
+	// NetworkAllowlist attaches the container to an internal network with no route
+	// out, and gives it HTTP and HTTPS access to a list of hosts through a proxy.
+	NetworkAllowlist = "allowlist"
+)
+
+// allowlistNetwork is the internal Docker network that containers with the
+// NetworkAllowlist policy are attached to. Their proxy listens on its gateway.
+const allowlistNetwork = "hatchery-allowlist"
+
+var (
+	gatewayMu sync.Mutex
+	gateway   string
+)
+
+// allowlistGateway returns the address of the host on allowlistNetwork, creating the
+// network if it doesn't exist.
+func allowlistGateway(ctx context.Context, c *client.Client) (string, error) {
+	gatewayMu.Lock()
+	defer gatewayMu.Unlock()
+	if gateway != "" {
+		return gateway, nil
+	}
+	n, err := c.NetworkInspect(ctx, allowlistNetwork, network.InspectOptions{})
+	if client.IsErrNotFound(err) {
+		_, err = c.NetworkCreate(ctx, allowlistNetwork, network.CreateOptions{Driver: "bridge", Internal: true})
+		// Another Hatchery may have created the network in the meantime.
+		if err == nil || strings.Contains(err.Error(), "already exists") {
+			n, err = c.NetworkInspect(ctx, allowlistNetwork, network.InspectOptions{})
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to set up %s network: %s", allowlistNetwork, err)
+	}
+	for _, cfg := range n.IPAM.Config {
+		if ip := net.ParseIP(cfg.Gateway); ip != nil && ip.To4() != nil {
+			gateway = cfg.Gateway
+			return gateway, nil
+		}
+	}
+	return "", fmt.Errorf("network %s has no gateway", allowlistNetwork)
+}
+
+// proxy is an HTTP proxy that only forwards requests, and tunnels CONNECT requests,
+// to the hosts in its allowlist. Entries that begin with "*." match every subdomain.
+type proxy struct {
+	allow    []string
+	listener net.Listener
+	server   *http.Server
+}
+
+// startProxy starts a proxy for the given allowlist on an ephemeral port of host.
+func startProxy(host string, allow []string) (*proxy, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start network proxy: %s", err)
+	}
+	p := &proxy{allow: allow, listener: l}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: 10 * time.Second}
+	go p.server.Serve(l)
+	return p, nil
+}
+
+// URL returns the URL that containers use to reach the proxy.
+func (p *proxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// Close stops the proxy and closes the connections it is serving.
+func (p *proxy) Close() error {
+	return p.server.Close()
+}
+
+func (p *proxy) allowed(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, a := range p.allow {
+		a = strings.ToLower(a)
+		if host == a || (strings.HasPrefix(a, "*.") && strings.HasSuffix(host, a[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.allowed(r.Host) {
+		http.Error(w, "host "+r.Host+" is not in the contract's network allowlist", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "only proxy requests are accepted", http.StatusBadRequest)
+		return
+	}
+	r.RequestURI = ""
+	r.Header.Del("Proxy-Connection")
+	r.Header.Del("Proxy-Authorization")
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// tunnel connects the client to the host of a CONNECT request and copies data
+// between them until either side closes its connection.
+func (p *proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, 10*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		conn.Close()
+		upstream.Close()
+		return
+	}
+	// Bytes the client sent after the CONNECT request are already buffered.
+	if n := buf.Reader.Buffered(); n > 0 {
+		b, _ := buf.Reader.Peek(n)
+		upstream.Write(b)
+	}
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go pipe(upstream, conn)
+	go pipe(conn, upstream)
+	<-done
+	conn.Close()
+	upstream.Close()
+}
+
+// ValidateNetwork returns an error if policy is not a known network policy, or if
+// allow is set for a policy other than NetworkAllowlist or holds an invalid host.
+func ValidateNetwork(policy string, allow []string) error {
+	switch policy {
+	case NetworkNone, NetworkBridge, NetworkHost:
+		if len(allow) > 0 {
+			return fmt.Errorf("an allowlist requires the %q network policy", NetworkAllowlist)
+		}
+	case NetworkAllowlist:
+		for _, a := range allow {
+			host := strings.TrimPrefix(a, "*.")
+			if host == "" || strings.ContainsAny(host, "/:*@ ") {
+				return fmt.Errorf("invalid allowlist host %q", a)
+			}
+		}
+	default:
+		return fmt.Errorf("must be %q, %q, %q or %q", NetworkNone, NetworkBridge, NetworkHost, NetworkAllowlist)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/client"
+)
+
+// Settings configure which Docker daemon the package connects to. They must be
+// set with Configure before the client is first used.
+type Settings struct {
+	// Host is the address of the daemon, such as "unix:///var/run/docker.sock",
+	// "npipe:////./pipe/docker_engine" or "tcp://10.0.0.2:2376". It overrides
+	// DOCKER_HOST and Docker contexts.
+	Host string
+	// Context is the name of the Docker context to connect to, as listed by
+	// "docker context ls". If empty, DOCKER_CONTEXT or the docker CLI's current
+	// context is used.
+	Context string
+}
+
+var (
+	settingsMu sync.Mutex
+	settings   Settings
+)
+
+// Configure sets the Settings the package's client is created with. It has no
+// effect once the client has been created.
+func Configure(s Settings) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings = s
+}
+
+// Endpoint is a Docker daemon address along with where it was found.
+type Endpoint struct {
+	Host string
+	// Source describes where Host came from, such as "DOCKER_HOST" or
+	// `docker context "desktop-linux"`.
+	Source string
+	// TLSPath is the directory holding the ca.pem, cert.pem and key.pem files
+	// used to connect to Host, if it requires TLS.
+	TLSPath string
+}
+
+// ResolveEndpoint returns the daemon the package connects to. The host set with
+// Configure comes first, followed by DOCKER_HOST, the Docker context named with
+// Configure or DOCKER_CONTEXT, the docker CLI's current context, and finally the
+// platform's default socket, which is a named pipe on Windows. On macOS and Linux,
+// the sockets of Docker Desktop and of rootless Docker are used if the system
+// socket doesn't exist. An error is returned if a named context can't be read.
+func ResolveEndpoint() (*Endpoint, error) {
+	settingsMu.Lock()
+	s := settings
+	settingsMu.Unlock()
+	if s.Host != "" {
+		return &Endpoint{Host: s.Host, Source: "configuration"}, nil
+	}
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return &Endpoint{Host: host, Source: "DOCKER_HOST", TLSPath: os.Getenv("DOCKER_CERT_PATH")}, nil
+	}
+	name := s.Context
+	if name == "" {
+		name = os.Getenv("DOCKER_CONTEXT")
+	}
+	if name == "" {
+		name = currentContext()
+	}
+	if name != "" && name != "default" {
+		return contextEndpoint(name)
+	}
+	return &Endpoint{Host: defaultHost(), Source: "default"}, nil
+}
+
+// dockerConfigDir returns the docker CLI's configuration directory.
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// currentContext returns the docker CLI's current context, or an empty string if
+// it has none or its configuration can't be read.
+func currentContext() string {
+	dir := dockerConfigDir()
+	if dir == "" {
+		return ""
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return ""
+	}
+	return cfg.CurrentContext
+}
+
+// contextEndpoint reads the endpoint of the named Docker context from the docker
+// CLI's context store, where each context is kept in a directory named after the
+// SHA-256 hash of its name.
+func contextEndpoint(name string) (*Endpoint, error) {
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])
+	dir := dockerConfigDir()
+	b, err := ioutil.ReadFile(filepath.Join(dir, "contexts", "meta", hash, "meta.json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("docker context %q does not exist; list the available contexts with \"docker context ls\"", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker context %q: %s", name, err)
+	}
+	var meta struct {
+		Endpoints map[string]struct {
+			Host string
+		}
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode docker context %q: %s", name, err)
+	}
+	host := meta.Endpoints["docker"].Host
+	if host == "" {
+		return nil, fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+	ep := &Endpoint{Host: host, Source: fmt.Sprintf("docker context %q", name)}
+	if tls := filepath.Join(dir, "contexts", "tls", hash, "docker"); fileExists(filepath.Join(tls, "cert.pem")) {
+		ep.TLSPath = tls
+	}
+	return ep, nil
+}
+
+// defaultHost returns the address of the platform's default daemon.
+func defaultHost() string {
+	if runtime.GOOS == "windows" {
+		return "npipe:////./pipe/docker_engine"
+	}
+	const system = "/var/run/docker.sock"
+	if fileExists(system) {
+		return "unix://" + system
+	}
+	var candidates []string
+	if home, err := os.UserHomeDir(); err == nil {
+		// Docker Desktop only links the system socket when it is allowed to.
+		candidates = append(candidates, filepath.Join(home, ".docker", "run", "docker.sock"), filepath.Join(home, ".docker", "desktop", "docker.sock"))
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "docker.sock"))
+	}
+	for _, path := range candidates {
+		if fileExists(path) {
+			return "unix://" + path
+		}
+	}
+	return "unix://" + system
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// clientOpts returns the options the package's client is created with for ep.
+func clientOpts(ep *Endpoint) []client.Opt {
+	opts := []client.Opt{client.FromEnv, client.WithHost(ep.Host), client.WithAPIVersionNegotiation()}
+	if ep.TLSPath != "" && ep.Source != "DOCKER_HOST" {
+		opts = append(opts, client.WithTLSClientConfig(
+			filepath.Join(ep.TLSPath, "ca.pem"),
+			filepath.Join(ep.TLSPath, "cert.pem"),
+			filepath.Join(ep.TLSPath, "key.pem"),
+		))
+	}
+	return opts
+}
+
+// DaemonError is returned when the Docker daemon can't be reached. Its message
+// suggests how to fix the problem on the current platform.
+type DaemonError struct {
+	Endpoint *Endpoint
+	Err      error
+}
+
+func (e *DaemonError) Error() string {
+	return fmt.Sprintf("docker daemon at %s (from %s) is not reachable: %s; %s", e.Endpoint.Host, e.Endpoint.Source, e.Err, e.hint())
+}
+
+func (e *DaemonError) hint() string {
+	switch {
+	case strings.Contains(e.Err.Error(), "permission denied"):
+		if runtime.GOOS == "linux" {
+			return "add the user running hatchery to the docker group, or run hatchery as root"
+		}
+		return "check that the user running hatchery may access the docker socket"
+	case !strings.HasPrefix(e.Endpoint.Host, "unix://") && !strings.HasPrefix(e.Endpoint.Host, "npipe://"):
+		return "check that the daemon is listening at that address and that DOCKER_TLS_VERIFY and DOCKER_CERT_PATH match its TLS settings"
+	case runtime.GOOS == "windows" || runtime.GOOS == "darwin":
+		return "start Docker Desktop, or set DOCKER_HOST or docker.host to the daemon's address"
+	default:
+		return "start the docker service, for example with \"sudo systemctl start docker\", or set DOCKER_HOST or docker.host to the daemon's address"
+	}
+}
+
+// daemonError returns a *DaemonError if err means that the daemon could not be
+// reached, and err otherwise.
+func daemonError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*DaemonError); ok || endpoint == nil {
+		return err
+	}
+	msg := err.Error()
+	denied := strings.Contains(msg, "permission denied") && strings.Contains(msg, "dial")
+	if !client.IsErrConnectionFailed(err) && !denied {
+		return err
+	}
+	return &DaemonError{Endpoint: endpoint, Err: err}
+}
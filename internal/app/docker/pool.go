@@ -0,0 +1,458 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/output"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// WarmEnv is set to "1" in the environment of warm containers, so that a contract
+// can tell that it must serve invocations with the warm protocol instead of reading
+// a single payload from stdin.
+//
+// In the warm protocol, Hatchery writes each payload to the container's stdin as a
+// line holding the payload's length in bytes, in decimal, followed by the payload
+// itself. The contract responds on stdout with a line holding its exit status and
+// the length of its output, separated by a space, followed by the output, and then
+// waits for the next payload. A non-zero status fails the execution, like a non-zero
+// exit code of a cold container. Anything the contract writes to stderr while
+// serving a payload is captured as that execution's stderr.
+//
+// A warm container's environment is fixed when it starts, so it isn't given the
+// TXN_ID, TXN_TYPE, TXN_TIMESTAMP and INVOKER variables of each execution.
+const WarmEnv = "HATCHERY_WARM"
+
+// maxWarmOutput limits the size of a single output read from a warm container, when
+// the spec doesn't limit its Output.
+const maxWarmOutput = 64 << 20
+
+// Pool keeps warm containers of contracts running between executions, so that small
+// contracts don't pay for a container's start on every execution. Containers are
+// pooled by contract, and only reused for executions of the same image, command,
+// arguments, environment and network; executions with a different spec replace the
+// contract's idle containers. A Pool is safe for concurrent use.
+type Pool struct {
+	// Client is the Docker Engine API client used to run containers. If nil, the
+	// client returned by Client is used.
+	Client *client.Client
+	// Logger receives the pool's logs. If nil, logging.Default() is used.
+	Logger logging.Logger
+	// NoPrewarm stops the pool from starting containers in the background, so
+	// that containers are only started for invocations that find none idle.
+	NoPrewarm bool
+
+	mu     sync.Mutex
+	sets   map[string]*warmSet
+	closed bool
+}
+
+// DefaultPool is the Pool used when none is specified.
+var DefaultPool = &Pool{}
+
+// warmSet is the pooled containers of a single contract.
+type warmSet struct {
+	fingerprint string
+	idle        []*warmContainer
+	// starting is how many containers are being started to refill idle.
+	starting int
+}
+
+// warmContainer is a running container that serves invocations with the warm
+// protocol.
+type warmContainer struct {
+	c      *client.Client
+	id     string
+	hijack types.HijackedResponse
+	stdout *bufio.Reader
+	stderr *syncBuffer
+	uses   int
+}
+
+// Run runs spec in an idle container of the contract identified by key, or in a new
+// warm container if there is none, and returns the Result of the invocation. Once the
+// invocation finishes, the container is returned to the pool, unless it has served
+// maxUses invocations or size containers are already idle, and unless NoPrewarm is
+// set, the pool is refilled in the background so that size containers are idle. If
+// maxUses is zero, containers are reused indefinitely.
+//
+// If ctx is done before the invocation finishes, the container is removed and ctx.Err()
+// is returned. The TRACEPARENT and TRACESTATE environment variables of warm containers
+// are those of the execution that started them.
+func (p *Pool) Run(ctx context.Context, key string, spec *Spec, size, maxUses int) (res *Result, err error) {
+	ctx, span := tracing.Start(ctx, "docker.warm_run", attribute.String("container.image.name", spec.Image))
+	defer func() { tracing.End(span, err) }()
+	start := time.Now()
+	fp := fingerprint(spec)
+	w, cold, err := p.acquire(ctx, key, fp, spec)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("container.id", w.id), attribute.Bool("hatchery.cold_start", cold))
+	res, err = w.invoke(ctx, spec)
+	if err != nil {
+		w.close()
+	} else {
+		w.uses++
+		p.release(key, fp, w, size, maxUses)
+	}
+	p.fill(key, fp, spec, size)
+	if err != nil {
+		return nil, err
+	}
+	res.Duration = time.Since(start)
+	return res, nil
+}
+
+// Drain removes the idle containers of the contract identified by key. Containers
+// that are serving an invocation are removed once it finishes.
+func (p *Pool) Drain(key string) {
+	p.mu.Lock()
+	set := p.sets[key]
+	delete(p.sets, key)
+	p.mu.Unlock()
+	if set != nil {
+		closeAll(set.idle)
+	}
+}
+
+// Close removes every idle container and stops the pool from keeping containers.
+// Containers that are serving an invocation are removed once it finishes. Later calls
+// to Run start a new container for each invocation.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	sets := p.sets
+	p.sets = nil
+	p.closed = true
+	p.mu.Unlock()
+	for _, set := range sets {
+		closeAll(set.idle)
+	}
+}
+
+// acquire takes an idle container of key's set, replacing the set if its fingerprint
+// differs from fp, or starts a new container if none is idle. cold reports whether
+// the container was started for this invocation.
+func (p *Pool) acquire(ctx context.Context, key, fp string, spec *Spec) (w *warmContainer, cold bool, err error) {
+	p.mu.Lock()
+	if p.sets == nil {
+		p.sets = make(map[string]*warmSet)
+	}
+	set := p.sets[key]
+	var stale []*warmContainer
+	if set == nil || set.fingerprint != fp {
+		if set != nil {
+			stale = set.idle
+		}
+		set = &warmSet{fingerprint: fp}
+		p.sets[key] = set
+	}
+	if n := len(set.idle); n > 0 {
+		w = set.idle[n-1]
+		set.idle = set.idle[:n-1]
+	}
+	p.mu.Unlock()
+	closeAll(stale)
+	if w != nil {
+		return w, false, nil
+	}
+	w, err = p.start(ctx, spec)
+	return w, true, err
+}
+
+// release returns w to key's set, or removes it if it may not be reused.
+func (p *Pool) release(key, fp string, w *warmContainer, size, maxUses int) {
+	p.mu.Lock()
+	set := p.sets[key]
+	keep := !p.closed && set != nil && set.fingerprint == fp && len(set.idle) < size && (maxUses <= 0 || w.uses < maxUses)
+	if keep {
+		set.idle = append(set.idle, w)
+	}
+	p.mu.Unlock()
+	if !keep {
+		w.close()
+	}
+}
+
+// fill starts containers in the background until size of key's containers are idle.
+func (p *Pool) fill(key, fp string, spec *Spec, size int) {
+	if p.NoPrewarm {
+		return
+	}
+	p.mu.Lock()
+	set := p.sets[key]
+	if p.closed || set == nil || set.fingerprint != fp {
+		p.mu.Unlock()
+		return
+	}
+	need := size - len(set.idle) - set.starting
+	if need <= 0 {
+		p.mu.Unlock()
+		return
+	}
+	set.starting += need
+	p.mu.Unlock()
+
+	// The spec's payload isn't needed to start a container, and is not retained.
+	s := *spec
+	s.Stdin = nil
+	for i := 0; i < need; i++ {
+		go func() {
+			w, err := p.start(context.Background(), &s)
+			p.mu.Lock()
+			set.starting--
+			keep := err == nil && !p.closed && p.sets[key] == set && len(set.idle) < size
+			if keep {
+				set.idle = append(set.idle, w)
+			}
+			p.mu.Unlock()
+			if err != nil {
+				p.log().Error("failed to start warm container", logging.Contract(key), logging.Err(err))
+				return
+			}
+			if !keep {
+				w.close()
+			}
+		}()
+	}
+}
+
+// start creates and starts a warm container of spec.
+func (p *Pool) start(ctx context.Context, spec *Spec) (*warmContainer, error) {
+	if spec.Network == NetworkAllowlist {
+		return nil, errors.New("warm containers can't use the allowlist network policy")
+	}
+	c := p.Client
+	if c == nil {
+		var err error
+		if c, err = Client(); err != nil {
+			return nil, err
+		}
+	}
+	s := *spec
+	s.Env = make(map[string]string, len(spec.Env)+1)
+	for k, v := range tracing.Env(ctx, spec.Env) {
+		s.Env[k] = v
+	}
+	s.Env[WarmEnv] = "1"
+	id, err := create(ctx, c, &s, false)
+	if err != nil {
+		return nil, err
+	}
+	hijack, err := c.ContainerAttach(ctx, id, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		c.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
+		return nil, fmt.Errorf("failed to attach to container: %s", err)
+	}
+	if err := c.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		hijack.Close()
+		c.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
+		return nil, fmt.Errorf("failed to start container: %s", err)
+	}
+	pr, pw := io.Pipe()
+	w := &warmContainer{c: c, id: id, hijack: hijack, stdout: bufio.NewReader(pr), stderr: &syncBuffer{}}
+	go func() {
+		_, err := stdcopy.StdCopy(pw, w.stderr, hijack.Reader)
+		if err == nil {
+			err = errors.New("container exited")
+		}
+		pw.CloseWithError(err)
+	}()
+	return w, nil
+}
+
+func (p *Pool) log() logging.Logger {
+	if p.Logger == nil {
+		return logging.Default()
+	}
+	return p.Logger
+}
+
+// invoke writes spec.Stdin to the container and reads its response, with the warm
+// protocol. See WarmEnv. What the container writes to stderr during the invocation is
+// copied to spec.Stderr as it is written, and its output to spec.Stdout once it has
+// been read. The output is captured within spec.Output, and stderr truncated at the same
+// size.
+func (w *warmContainer) invoke(ctx context.Context, spec *Spec) (*Result, error) {
+	w.stderr.Reset(spec.Output.Max)
+	w.stderr.Tee(spec.Stderr)
+	defer w.stderr.Tee(nil)
+	type reply struct {
+		res *Result
+		err error
+	}
+	replies := make(chan reply, 1)
+	go func() {
+		res, err := w.exchange(ctx, spec.Stdin, spec.Output)
+		replies <- reply{res, err}
+	}()
+	select {
+	case r := <-replies:
+		if r.err != nil {
+			return nil, r.err
+		}
+		r.res.Stderr = w.stderr.Bytes()
+		if spec.Stdout != nil {
+			spec.Stdout.Write(r.res.Stdout)
+		}
+		return r.res, nil
+	case <-ctx.Done():
+		// The caller removes the container, which unblocks the exchange.
+		return nil, ctx.Err()
+	}
+}
+
+func (w *warmContainer) exchange(ctx context.Context, payload []byte, limit output.Limit) (*Result, error) {
+	if _, err := io.WriteString(w.hijack.Conn, strconv.Itoa(len(payload))+"\n"); err != nil {
+		return nil, fmt.Errorf("failed to write to warm container: %s", err)
+	}
+	if _, err := w.hijack.Conn.Write(payload); err != nil {
+		return nil, fmt.Errorf("failed to write to warm container: %s", err)
+	}
+	line, err := w.stdout.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from warm container: %s", err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid response from warm container: %q", strings.TrimSpace(line))
+	}
+	status, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid status from warm container: %q", fields[0])
+	}
+	n, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || n < 0 || (limit.Max <= 0 && n > maxWarmOutput) {
+		return nil, fmt.Errorf("invalid output length from warm container: %q", fields[1])
+	}
+	// The whole output is read even if it exceeds the limit, so that the container
+	// can serve the next payload.
+	buf := output.NewBuffer(limit)
+	defer buf.Close()
+	if _, err := io.CopyN(buf, w.stdout, n); err != nil {
+		return nil, fmt.Errorf("failed to read from warm container: %s", err)
+	}
+	out, err := buf.Result(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Stdout: out, ExitCode: status}, nil
+}
+
+func (w *warmContainer) close() {
+	w.hijack.Close()
+	w.c.ContainerRemove(context.Background(), w.id, container.RemoveOptions{Force: true})
+}
+
+func closeAll(containers []*warmContainer) {
+	for _, w := range containers {
+		w.close()
+	}
+}
+
+// fingerprint identifies the containers spec can be run in.
+func fingerprint(spec *Spec) string {
+	b, _ := json.Marshal(struct {
+		Image   string
+		Command string
+		Args    []string
+		Env     []string
+		Network string
+	}{spec.Image, spec.Command, spec.Args, envList(spec.Env), spec.Network})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use. Writes are also
+// copied to its tee, if it has one. Writes beyond its max are discarded.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	tee io.Writer
+	max int64
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tee != nil {
+		b.tee.Write(p)
+	}
+	keep := p
+	if b.max > 0 {
+		if room := b.max - int64(b.buf.Len()); room < int64(len(keep)) {
+			if room < 0 {
+				room = 0
+			}
+			keep = keep[:room]
+		}
+	}
+	b.buf.Write(keep)
+	return len(p), nil
+}
+
+// Tee sets the writer that receives a copy of subsequent writes. A nil w stops
+// copying.
+func (b *syncBuffer) Tee(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tee = w
+}
+
+// Reset empties the buffer and sets the size beyond which writes are discarded. If
+// max is zero, writes are not limited.
+func (b *syncBuffer) Reset(max int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+	b.max = max
+}
+
+// Bytes returns a copy of the buffer's contents.
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
@@ -0,0 +1,174 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// Reasons an image pull fails, as reported by PullError.
+const (
+	// PullUnauthorized means the registry refused the credentials, or required some
+	// and none were given. Registries also report repositories that don't exist this
+	// way, so that private ones can't be discovered.
+	PullUnauthorized = "unauthorized"
+	// PullNotFound means the registry has no such repository or tag.
+	PullNotFound = "not_found"
+	// PullFailed is any other failure, such as the registry being unreachable.
+	PullFailed = "failed"
+)
+
+// PullError is returned when an image can't be pulled from its registry.
+type PullError struct {
+	Image string
+	// Reason is PullUnauthorized, PullNotFound or PullFailed.
+	Reason string
+	Err    error
+}
+
+func (e *PullError) Error() string {
+	return fmt.Sprintf("failed to pull image %s: %s", e.Image, e.Err)
+}
+
+// newPullError classifies err, which the daemon returned for a pull of img.
+func newPullError(img string, err error) *PullError {
+	msg := strings.ToLower(err.Error())
+	reason := PullFailed
+	switch {
+	case errdefs.IsUnauthorized(err) || errdefs.IsForbidden(err) ||
+		strings.Contains(msg, "unauthorized") || strings.Contains(msg, "denied"):
+		reason = PullUnauthorized
+	case client.IsErrNotFound(err) || strings.Contains(msg, "not found") || strings.Contains(msg, "manifest unknown"):
+		reason = PullNotFound
+	}
+	return &PullError{Image: img, Reason: reason, Err: err}
+}
+
+// LayerProgress is the progress of a single layer of an image being pulled.
+type LayerProgress struct {
+	ID string `json:"id"`
+	// Status is the daemon's description of the layer's progress, such as
+	// "Downloading", "Extracting" or "Pull complete".
+	Status string `json:"status"`
+	// Current and Total are the bytes of the layer transferred so far and in all,
+	// while it is being downloaded or extracted.
+	Current int64 `json:"current,omitempty"`
+	Total   int64 `json:"total,omitempty"`
+}
+
+var (
+	watchMu  sync.Mutex
+	watchers = make(map[string]map[*pullWatcher]struct{})
+)
+
+// pull is a pull of an image that is underway. See PullImage.
+type pull struct {
+	// auth is the encoded credentials the image is pulled with.
+	auth string
+	// done is closed once the pull has finished, with err as its outcome.
+	done chan struct{}
+	err  error
+}
+
+var (
+	pullsMu sync.Mutex
+	pulls   = make(map[string]*pull)
+)
+
+type pullWatcher struct {
+	fn func(LayerProgress)
+}
+
+// WatchPulls calls fn with the progress of each layer of every pull of img, as it is
+// reported by the daemon, until the returned function is called. Since PullImage
+// pulls an image once at a time, fn only ever follows a single pull, which callers
+// that pull img concurrently share. Pulls report their layers from the goroutine
+// that pulls, so fn must be safe for concurrent use with the goroutine that called
+// WatchPulls.
+func WatchPulls(img string, fn func(LayerProgress)) (stop func()) {
+	w := &pullWatcher{fn: fn}
+	watchMu.Lock()
+	if watchers[img] == nil {
+		watchers[img] = make(map[*pullWatcher]struct{})
+	}
+	watchers[img][w] = struct{}{}
+	watchMu.Unlock()
+	return func() {
+		watchMu.Lock()
+		defer watchMu.Unlock()
+		delete(watchers[img], w)
+		if len(watchers[img]) == 0 {
+			delete(watchers, img)
+		}
+	}
+}
+
+// reportPull sends p to the functions watching pulls of img.
+func reportPull(img string, p LayerProgress) {
+	watchMu.Lock()
+	fns := make([]func(LayerProgress), 0, len(watchers[img]))
+	for w := range watchers[img] {
+		fns = append(fns, w.fn)
+	}
+	watchMu.Unlock()
+	for _, fn := range fns {
+		fn(p)
+	}
+}
+
+// readPull consumes the progress stream of a pull of img, reporting the progress of
+// its layers. The daemon reports failures that happen once the stream has started,
+// such as a layer that can't be downloaded, in the stream itself, and they are
+// returned as a *PullError.
+func readPull(img string, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read pull progress of %s: %s", img, err)
+		}
+		if msg.Error != nil {
+			return newPullError(img, errors.New(msg.Error.Message))
+		}
+		if msg.ErrorMessage != "" {
+			return newPullError(img, errors.New(msg.ErrorMessage))
+		}
+		// The first message names the tag being pulled, with the tag as its ID.
+		if msg.ID == "" || msg.Status == "" || strings.HasPrefix(msg.Status, "Pulling from") {
+			continue
+		}
+		p := LayerProgress{ID: msg.ID, Status: msg.Status}
+		if msg.Progress != nil {
+			p.Current, p.Total = msg.Progress.Current, msg.Progress.Total
+		}
+		reportPull(img, p)
+	}
+}
@@ -0,0 +1,274 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/summerplaygames/hatchery/internal/app/output"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Spec describes a single container run.
+type Spec struct {
+	// Image is the image to create the container from.
+	Image string
+	// Command is the command to run in the container. If empty, the image's
+	// default command is used.
+	Command string
+	// Args are passed to Command.
+	Args []string
+	// Env is set as the container's environment.
+	Env map[string]string
+	// Stdin is written to the container's stdin, which is then closed.
+	Stdin []byte
+	// Network is the container's network policy, such as NetworkNone. If empty,
+	// the container is attached to Docker's default network.
+	Network string
+	// AllowHosts are the hosts a container with the NetworkAllowlist policy may
+	// reach through its proxy.
+	AllowHosts []string
+	// Stdout and Stderr optionally receive a copy of the container's stdout and
+	// stderr as it is written, in addition to it being captured in the Result.
+	// Writes to them must not fail.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Output limits the stdout captured in the Result. Stderr is truncated at the
+	// same size. If its Max is zero, output is not limited.
+	Output output.Limit
+}
+
+// Result is the outcome of running a container to completion.
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+}
+
+// Runner runs containers to completion, capturing their output.
+type Runner struct {
+	// Client is the Docker Engine API client used to run containers. If nil,
+	// the client returned by Client is used.
+	Client *client.Client
+	// Timeout bounds every run, in addition to any deadline of the context
+	// passed to Run. If zero, runs are only bounded by their context.
+	Timeout time.Duration
+}
+
+// DefaultRunner is the Runner used when none is specified.
+var DefaultRunner = &Runner{}
+
+// Run creates a container described by spec, attaches to its stdin, stdout and stderr,
+// starts it, writes spec.Stdin and waits for it to exit. The container is removed
+// afterwards. The captured stdout, stderr and exit code are returned. An error is
+// returned if the container could not be created, started or waited on; a non-zero
+// exit code alone is not considered an error.
+//
+// If ctx is cancelled or the Runner's Timeout is exceeded before the container exits,
+// the container is killed and ctx.Err() is returned.
+//
+// The run is traced as a span of the trace in ctx, whose context is passed to the
+// container in the TRACEPARENT and TRACESTATE environment variables. The transaction
+// ctx carries, if any, is passed in TXN_ID, TXN_TYPE, TXN_TIMESTAMP and INVOKER. See
+// backend.InvocationEnv.
+func (r *Runner) Run(ctx context.Context, spec *Spec) (res *Result, err error) {
+	ctx, span := tracing.Start(ctx, "docker.run", attribute.String("container.image.name", spec.Image))
+	defer func() { tracing.End(span, err) }()
+	c := r.Client
+	if c == nil {
+		if c, err = Client(); err != nil {
+			return nil, err
+		}
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	traced := *spec
+	traced.Env = backend.InvocationEnv(ctx, tracing.Env(ctx, spec.Env))
+	spec = &traced
+	start := time.Now()
+	if spec.Network == NetworkAllowlist {
+		// The proxy only lives as long as the container, and is only reachable from
+		// the internal network the container is attached to.
+		gateway, err := allowlistGateway(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		p, err := startProxy(gateway, spec.AllowHosts)
+		if err != nil {
+			return nil, err
+		}
+		defer p.Close()
+		s := *spec
+		s.Env = make(map[string]string, len(spec.Env)+4)
+		for k, v := range spec.Env {
+			s.Env[k] = v
+		}
+		for _, k := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+			s.Env[k] = p.URL()
+		}
+		spec = &s
+	}
+	id, err := create(ctx, c, spec, true)
+	if err != nil {
+		return nil, err
+	}
+	defer c.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
+	span.SetAttributes(attribute.String("container.id", id))
+
+	hijack, err := c.ContainerAttach(ctx, id, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to container: %s", err)
+	}
+	defer hijack.Close()
+
+	// Waiting must begin before the container is started, otherwise a short-lived
+	// container may exit before we are listening for it.
+	waitCh, waitErrCh := c.ContainerWait(ctx, id, container.WaitConditionNextExit)
+	if err := c.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to start container: %s", err)
+	}
+
+	// Reading the attached streams does not observe ctx, so the container is
+	// killed on cancellation, which closes the streams.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.ContainerKill(context.Background(), id, "KILL")
+		case <-done:
+		}
+	}()
+
+	res = &Result{}
+	if res.Stdout, res.Stderr, err = stream(ctx, &hijack, spec); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case status := <-waitCh:
+		if status.Error != nil {
+			return nil, fmt.Errorf("failed to wait for container: %s", status.Error.Message)
+		}
+		res.ExitCode = int(status.StatusCode)
+	case err := <-waitErrCh:
+		return nil, fmt.Errorf("failed to wait for container: %s", err)
+	}
+	res.Duration = time.Since(start)
+	return res, nil
+}
+
+// create creates the container described by spec and returns its ID. If stdinOnce is
+// true, the container's stdin is closed once the first attached client detaches.
+func create(ctx context.Context, c *client.Client, spec *Spec, stdinOnce bool) (string, error) {
+	config := &container.Config{
+		Image:        spec.Image,
+		Env:          envList(spec.Env),
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		OpenStdin:    true,
+		StdinOnce:    stdinOnce,
+	}
+	if spec.Command != "" {
+		config.Cmd = append([]string{spec.Command}, spec.Args...)
+	}
+	var hostConfig *container.HostConfig
+	switch spec.Network {
+	case "":
+	case NetworkAllowlist:
+		hostConfig = &container.HostConfig{NetworkMode: container.NetworkMode(allowlistNetwork)}
+	default:
+		hostConfig = &container.HostConfig{NetworkMode: container.NetworkMode(spec.Network)}
+	}
+	created, err := c.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+	if err != nil {
+		// Creating the container is the first request of a run, so it is where an
+		// unreachable daemon shows.
+		if err, ok := daemonError(err).(*DaemonError); ok {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to create container: %s", err)
+	}
+	return created.ID, nil
+}
+
+// stream writes spec.Stdin to the attached container and closes it, while reading the
+// container's stdout and stderr until they are closed, copying them to spec.Stdout and
+// spec.Stderr. The captured stdout is subject to spec.Output, so a *output.TooLargeError
+// is returned for too much output under output.PolicyFail, and output spilled under
+// output.PolicySpill is stored in the Store of ctx.
+func stream(ctx context.Context, hijack *types.HijackedResponse, spec *Spec) ([]byte, []byte, error) {
+	writeErrCh := make(chan error, 1)
+	go func() {
+		_, err := hijack.Conn.Write(spec.Stdin)
+		if err == nil {
+			err = hijack.CloseWrite()
+		}
+		writeErrCh <- err
+	}()
+
+	stdout := output.NewBuffer(spec.Output)
+	defer stdout.Close()
+	stderr := output.NewBuffer(output.Limit{Max: spec.Output.Max})
+	_, err := stdcopy.StdCopy(tee(stdout, spec.Stdout), tee(stderr, spec.Stderr), hijack.Reader)
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read container output: %s", err)
+	}
+	if err := <-writeErrCh; err != nil {
+		return nil, nil, fmt.Errorf("failed to write to container stdin: %s", err)
+	}
+	out, err := stdout.Result(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	errOut, _ := stderr.Result(ctx)
+	return out, errOut, nil
+}
+
+// tee returns a writer that writes to buf and, if it is not nil, to w.
+func tee(buf io.Writer, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
@@ -20,137 +20,74 @@
 package hatchery
 
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/output"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/google/uuid"
 )
 
+// ExecutionOrderParallel and ExecutionOrderSerial are the values of
+// ContractManifest.ExecutionOrder.
 const (
-	// ExecutionOrderParallel signifies parrellel execution of smart contracts.
-	ExecutionOrderParallel = "parallel"
-	// ExecutionOrderSerial signifies serial execution of smart contracts.
-	ExecutionOrderSerial = "serial"
+	ExecutionOrderParallel = backend.ExecutionOrderParallel
+	ExecutionOrderSerial   = backend.ExecutionOrderSerial
 )
 
+// Errors returned by Heap, Ledger and Library implementations. They are defined in
+// package backend so that implementations outside of Hatchery can return them.
 var (
-	// ErrContractNotExist is returned when a request contract does not exist.
-	ErrContractNotExist = errors.New("contract does not exist")
-	// ErrHeapNotExist is returned when a requested heap key does not exist.
-	ErrHeapNotExist = errors.New("heap value doesn't exist for key")
+	ErrContractNotExist    = backend.ErrContractNotExist
+	ErrHeapNotExist        = backend.ErrHeapNotExist
+	ErrTransactionNotExist = backend.ErrTransactionNotExist
+	ErrVersionNotExist     = backend.ErrVersionNotExist
+	ErrBlobNotExist        = backend.ErrBlobNotExist
+	// ErrRuntimeNotExist is returned when a requested contract runtime is not registered.
+	ErrRuntimeNotExist = errors.New("runtime does not exist")
 )
 
-// ExecutionOrder determines how multiple instances of the same contract are executed.
-type ExecutionOrder string
+// The Heap, Ledger and Library interfaces, and the types they exchange, are
+// defined in package backend so that they can be implemented outside of Hatchery.
+type (
+	ExecutionOrder    = backend.ExecutionOrder
+	TransactionStatus = backend.TransactionStatus
+	Transaction       = backend.Transaction
+	Contract          = backend.Contract
+	ContractManifest  = backend.ContractManifest
+	ContractVersion   = backend.ContractVersion
+	Library           = backend.Library
+	Heap              = backend.Heap
+	Ledger            = backend.Ledger
+	BrokenLinkError   = backend.BrokenLinkError
+	HeapPut           = backend.HeapPut
+	HeapIterator      = backend.HeapIterator
+)
 
-// Transaction is a single, atomic operation on the ledger.
-type Transaction struct {
-	// The transaction's unique ID.
-	ID string
-	// The content that is stored along with the transaction. This could
-	// be the output of a smart contract or simply the payload of a
-	// posted transaction.
-	Content []byte `json:"-"`
-}
+// Transaction statuses.
+const (
+	TransactionStatusPending = backend.TransactionStatusPending
+	TransactionStatusSuccess = backend.TransactionStatusSuccess
+	TransactionStatusFailure = backend.TransactionStatusFailure
+)
 
 // NewTransaction returns a new Transaction instance with the provided
 // content. A unique ID is generated for the transaction.
 func NewTransaction(content []byte) *Transaction {
-	id := uuid.New()
-	return &Transaction{
-		ID:      id.String(),
-		Content: content,
-	}
-}
-
-// Contract is a smart contract that can be executed.
-type Contract interface {
-	// Execute executes the smart contract. The provided payload
-	// is passed into the contract's stdin and the contract's stdout
-	// is returned. An error is returned if the contract could not be
-	// executed.
-	Execute(payload []byte) ([]byte, error)
-}
-
-// ContractManifest contains information about a smart contract. It is used
-// by a Library to track posted contracts for later execution.
-type ContractManifest struct {
-	// Type is the transaction type. For smart contracts, this
-	// will be the name of the contract.
-	Type string `json:"txn_type"`
-	// Image is the Docker image that contains the contract code to be
-	// executed. It should be in the format <dockerhub id>/<image name>:<image version>.
-	// The docker container will be pulled down from DockerHub and the container will be
-	// executed via `docker run`.
-	Image string
-	// Cmd is the command to execute in the smart contract's docker container.
-	Cmd string
-	// Args are optional additional application arguments that are passed in to the docker
-	// container after the command.
-	Args []string
-	// ExecutionOrder stipulates how multiple instances of the same smart contract are
-	// executed. Valid values are ExecutionOrderParallel and ExecutionOrderSerial.
-	ExecutionOrder ExecutionOrder `json:"execution_order"`
-	// Env is an optional set of environment variables to pass into the contract at runtime.
-	Env map[string]string
-	// Cron is an optional rate of scheduled execution specified as a cron.
-	Cron string
-	// Auth is an optional DockerHub access key that is used when pulling the container image.
-	// This is used when your container image is private in DockerHub.
-	Auth string
-}
-
-// Library is a collection of smart contracts.
-type Library interface {
-	// Get returns the smart contract with the provided name.
-	// If the contract doesn't exist in the library, ErrContractNotExist
-	// is returned. Otherwise, an error is returned if something went wrong
-	// when retrieving the contract.
-	Get(name string) (Contract, error)
-	// Put stores a new contract in the library, described by the provided
-	// ContractManifest. An error is returned if the contract could not be
-	// stored.
-	Put(req *ContractManifest) error
-}
-
-// Heap is a generic key-value store that can contracts can write to to persist
-// data across multiple contract executions.
-type Heap interface {
-	// Put inserts a key value pair in the heap. The bucket parameter is used
-	// to segregate kvps into logical groups. This is useful when running multiple
-	// instances of Hatchery using the same backing datastore.
-	//
-	// An error is returned if the kvp could not be stored.
-	Put(bucket, key string, value []byte) error
-	// Get retrieves a value with the provided key from the Heap. An error is
-	// returned if the value for the key cannot be retrieved.
-	Get(bucket string, key string) ([]byte, error)
-	// GetAll returns all kvps for a bucket. An error is returned if the kvps
-	// could not be retrieved.
-	GetAll(bucket string) (map[string][]byte, error)
-}
-
-// Ledger is a transaction log that mimics the "blockchain."
-type Ledger interface {
-	// Head returns the first transaction in the ledger. This is
-	// known as the "genesis" transcation. If the ledger is empty,
-	// nil is returned instead.
-	Head() *Transaction
-	// Find searches the ledger for a transaction with the given ID and returns it.
-	// I no transaction with the provided ID exists in the log, nil is returned
-	// instead.
-	Find(id string) *Transaction
-	// Append adds a Transaction to the end of the ledger.
-	Append(t *Transaction)
+	return backend.NewTransaction(content)
 }
 
 type getSCHeapRequest struct {
@@ -160,28 +97,394 @@ type getSCHeapRequest struct {
 type postTransactionRequest struct {
 	Type    string `json:"txn_type"`
 	Payload json.RawMessage
+	// Signer and Signature are the ID of the signing key a transaction is signed
+	// with and its signature. See verifyTransaction.
+	Signer    string `json:"signer,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Encodings of transaction content in API responses. See contentEncoding.
+const (
+	ContentEncodingBase64 = "base64"
+	ContentEncodingJSON   = "json"
+)
+
+// transactionResponse is the JSON representation of a Transaction that
+// includes its content.
+type transactionResponse struct {
+	*Transaction
+	// Content is the content of the transaction, either as a base64 string or,
+	// if ContentEncoding is ContentEncodingJSON, as inline JSON.
+	Content         interface{}
+	ContentEncoding string
+}
+
+// newTransactionResponse returns the JSON representation of t. If encoding is
+// ContentEncodingJSON and the content of t is valid JSON, the content is inlined.
+// Otherwise, it is base64 encoded.
+func newTransactionResponse(t *Transaction, encoding string) transactionResponse {
+	if encoding == ContentEncodingJSON && json.Valid(t.Content) {
+		return transactionResponse{Transaction: t, Content: json.RawMessage(t.Content), ContentEncoding: ContentEncodingJSON}
+	}
+	return transactionResponse{Transaction: t, Content: t.Content, ContentEncoding: ContentEncodingBase64}
+}
+
+type listTransactionsResponse struct {
+	Transactions []transactionResponse
+	Offset       int
+	Limit        int
+	Total        int
 }
 
 // Application contains of all of the application state and its dependencies.
 type Application struct {
-	Bucket  string
-	Heap    Heap
-	Ledger  Ledger
-	Lib     Library
+	Heap   Heap
+	Ledger Ledger
+	Lib    Library
+	// Bucket is the heap bucket that contract output was stored in before each
+	// contract's output was stored in its own bucket, and still is if SharedBucket
+	// is set. Output left in it is moved to the bucket of the contract that wrote
+	// it when the application starts. See migrateSharedBucket.
+	Bucket string
+	// SharedBucket stores the output of every contract in Bucket rather than in the
+	// contract's own bucket, for deployments that read output from a single bucket.
+	// Contracts writing the same key overwrite each other's output.
+	SharedBucket bool
+	// BaseURL is the URL at which contracts can reach the Hatchery API. It is
+	// passed to contracts in the HATCHERY_URL environment variable.
+	BaseURL string
+	// MaxConcurrency limits the number of concurrent executions of a single
+	// contract whose ExecutionOrder is ExecutionOrderParallel. Zero means
+	// executions are not limited. Serial contracts always execute one at a time.
+	MaxConcurrency int
+	// MaxExecutions limits the number of concurrent executions of every contract on
+	// the node combined. Waiting executions are admitted in weighted round-robin
+	// order across contracts, according to the Priority of their manifests. Zero
+	// means executions are not limited. See fairQueue.
+	MaxExecutions int
+	// OutputLimit limits the output captured from each execution, so that a
+	// contract writing without end can't exhaust the node's memory. Its Policy
+	// decides what happens to output beyond the limit. See output.Limit.
+	OutputLimit output.Limit
+	// EnvPolicy restricts the environment variables that posted manifests may set.
+	// Manifests that set a variable it doesn't permit are rejected.
+	EnvPolicy EnvPolicy
+	// RequireAuth determines whether requests must be signed with an API key.
+	RequireAuth bool
+	// RequireSignatures determines whether posted transactions must be signed with
+	// a registered signing key. Signed transactions are verified either way.
+	RequireSignatures bool
+	// Audit records every POST, PUT and DELETE request in an append-only audit
+	// log, which is read through GET /audit. See audited.
+	Audit bool
+	// DragonChainID is the chain ID that signed requests must be addressed to.
+	// If empty, any chain ID is accepted.
+	DragonChainID string
+	// Environment names the environment the node serves, such as "dev" or
+	// "staging". Posted manifests reference it as {{ .Environment }}. See
+	// renderManifest.
+	Environment string
+	// ManifestVars are variables that posted manifests reference as
+	// {{ .Vars.name }}.
+	ManifestVars map[string]string
+	// Logger receives the application's logs, including HTTP access logs. If nil,
+	// logging.Default() is used.
+	Logger logging.Logger
+	// ShutdownTimeout is how long Run waits for in-flight requests to complete
+	// when shutting down. If zero, DefaultShutdownTimeout is used.
+	ShutdownTimeout time.Duration
+	// BlockInterval is how often the transactions appended to the ledger are bundled
+	// into a Block while Run is serving. If zero, blocks are not produced.
+	BlockInterval time.Duration
+	// IdempotencyWindow is how long the idempotency keys of posted transactions are
+	// remembered. If zero, DefaultIdempotencyWindow is used.
+	IdempotencyWindow time.Duration
+	// MaxTransactionSize limits the size, in bytes, of the body of requests that post
+	// a transaction. If zero, DefaultMaxTransactionSize is used.
+	MaxTransactionSize int64
+	// MaxContractSize limits the size, in bytes, of the body of requests that post a
+	// contract manifest. If zero, DefaultMaxContractSize is used.
+	MaxContractSize int64
+	// RateLimit is how many requests per second each client may make. Clients are
+	// identified by API key when RequireAuth is set, and by IP address otherwise. If
+	// zero, requests are not limited.
+	RateLimit float64
+	// RateBurst is how many requests a client may make at once before RateLimit
+	// applies. If zero, it is RateLimit rounded up.
+	RateBurst int
+	// Executions records the execution history of contracts. If nil, the history
+	// is kept in the Heap.
+	Executions ExecutionLog
+	// Chains hosts virtual chains alongside this one, managed through the /chains
+	// routes. If nil, there is only this chain.
+	Chains *Chains
+	// BreakerThreshold is how many consecutive failed executions of a contract trip
+	// its circuit breaker, after which executions fail fast until BreakerCooldown
+	// has passed. If zero, DefaultBreakerThreshold is used. If negative, breakers
+	// never trip.
+	BreakerThreshold int
+	// BreakerCooldown is how long a tripped circuit breaker fails executions before
+	// admitting a trial execution. If zero, DefaultBreakerCooldown is used.
+	BreakerCooldown time.Duration
+	// Secrets stores the secrets managed through the /secret routes. It should be the
+	// SecretStore used by Lib to resolve the secrets that manifests reference. If nil,
+	// secrets cannot be managed.
+	Secrets SecretStore
+	// Forwarder, if set, receives every transaction appended to the ledger, so that
+	// it is also posted to a DragonChain L1. Transactions wait in an outbox in the
+	// Heap until they are forwarded, so none are lost if DragonChain is unreachable
+	// or Hatchery restarts.
+	Forwarder *Forwarder
+	// GCInterval is how often garbage is collected in the background: the images of
+	// deleted contracts are removed, contract versions beyond GCKeepVersions are
+	// pruned and the heap is compacted. If zero, garbage is only collected through
+	// the API.
+	GCInterval time.Duration
+	// GCKeepVersions is how many of the latest versions of each contract garbage
+	// collection keeps. If zero, every version is kept.
+	GCKeepVersions int
+	// Retention, if set, prunes the oldest transactions from the ledger in the
+	// background. The Ledger must implement backend.Pruner.
+	Retention *RetentionPolicy
+	// HeapMaxBytes and HeapMaxKeys are the default quota of every contract heap
+	// bucket: the total size, in bytes, of its keys and values, and the number of
+	// its keys. Contracts may override them for their own bucket in their manifest.
+	// Zero means unlimited.
+	HeapMaxBytes int64
+	HeapMaxKeys  int
+	// Follow, if set, makes the application a read-only follower of another
+	// Hatchery, whose ledger and contract heaps it replicates instead of executing
+	// transactions, until it is promoted through POST /replication/promote.
+	Follow *Follower
+	// ReplicationAddr, if set, is the address Run serves the gRPC replication
+	// service on, for followers to connect to. See ReplicationServer.
+	ReplicationAddr string
+	// Elector, if set, makes the application a node of a cluster of applications
+	// that share the same Heap, Ledger and Library, such as those backed by
+	// PostgreSQL. Every node serves the API and executes the transactions posted
+	// to it, but only the elected leader fires cron jobs, dispatches one-shot
+	// schedules, forwards transactions and bundles blocks. See lead.
+	Elector Elector
+	// NodeID identifies the application among the nodes of its cluster. Each node
+	// only executes the work queue items it queued, so it must keep its NodeID
+	// across restarts to resume them.
+	NodeID string
+	// ClusterSyncInterval is how often the leader of a cluster syncs its cron jobs
+	// with the Library. If zero, DefaultClusterSyncInterval is used.
+	ClusterSyncInterval time.Duration
+
 	cronMu  sync.Mutex
 	cronTab map[string]*CronJob
 	once    sync.Once
+	queueMu sync.Mutex
+	queues  map[string]*execQueue
+	tokenMu sync.Mutex
+
+	// slots admits executions within MaxExecutions.
+	slots fairQueue
+
+	// journals hold the heap journals of executions in progress, by contract.
+	journalMu sync.Mutex
+	journals  map[string][]*heapJournal
+
+	registrationMu sync.Mutex
+	registrations  map[string]*registration
+
+	// warm keeps the warm containers of contracts with a WarmPool.
+	warm docker.Pool
+
+	circuitMu sync.Mutex
+	circuits  map[string]*circuit
+
+	workOnce    sync.Once
+	workMu      sync.Mutex
+	workWake    chan struct{}
+	workDone    chan struct{}
+	workWaiters map[string]chan workResult
+	// workCancels cancels the running attempts of queued transactions, and
+	// workCancelled holds those that were cancelled while waiting for one.
+	workCancels   map[string]context.CancelFunc
+	workCancelled map[string]bool
+
+	eventsOnce sync.Once
+	events     EventBus
+
+	outputMu sync.Mutex
+	outputs  map[string]*outputLog
+
+	streamMu sync.Mutex
+	streams  map[*streamClient]struct{}
+	replicas map[*replica]struct{}
+
+	clusterMu      sync.Mutex
+	leader         bool
+	campaignCancel context.CancelFunc
+	campaignDone   chan struct{}
+	cronVersions   map[string]int
+
+	replMu        sync.Mutex
+	promoted      bool
+	replCancel    context.CancelFunc
+	replStopped   chan struct{}
+	replConnected bool
+	replLastTxn   string
+	replErr       string
+
+	limiter rateLimiter
+
+	idemMu     sync.Mutex
+	idemPruned time.Time
+
+	oneShotOnce sync.Once
+	oneShotMu   sync.Mutex
+	oneShotWake chan struct{}
+	oneShotDone chan struct{}
+
+	forwardOnce sync.Once
+	forwardMu   sync.Mutex
+	forwardWake chan struct{}
+	forwardDone chan struct{}
+
+	gcMu     sync.Mutex
+	gcOnce   sync.Once
+	gcLoopMu sync.Mutex
+	gcDone   chan struct{}
+
+	pruneMu       sync.Mutex
+	pruneRunsMu   sync.Mutex
+	pruneRuns     []PruneRun
+	retentionOnce sync.Once
+	retentionMu   sync.Mutex
+	retentionDone chan struct{}
+
+	blockOnce   sync.Once
+	blockMu     sync.Mutex
+	blockDone   chan struct{}
+	lastBlock   *Block
+	pendingTxns []*Transaction
 }
 
-// SetupRoutes initializes the HTTP routes with the provided muxer.
+// SetupRoutes initializes the HTTP routes with the provided muxer. If RequireAuth is set,
+// every route except the health checks, the OpenAPI document and the contract-facing heap
+// API requires a signed request. See authenticated for details. The same routes are rate
+// limited per client if RateLimit is set. See rateLimited. The routes are described for
+// the OpenAPI document by apiRoutes. While the application is a follower, only reads are
+// accepted. See readOnly. Every request is traced, and mutating requests are audited if
+// Audit is set. See traced and audited.
 func (a *Application) SetupRoutes(muxer *mux.Router) {
-	muxer.HandleFunc("/get/{sc_name}/{key}", a.GetSCHeap()).Methods(http.MethodGet)
-	muxer.HandleFunc("/transaction", a.PostTransaction()).Methods(http.MethodPost)
-	muxer.HandleFunc("/contract", a.PostContract()).Methods(http.MethodPost)
+	muxer.Use(a.traced, a.accessLog, a.readOnly, a.audited)
+	muxer.NotFoundHandler = http.HandlerFunc(notFound)
+	muxer.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowed)
+	muxer.HandleFunc("/healthz", a.Healthz()).Methods(http.MethodGet)
+	muxer.HandleFunc("/readyz", a.Readyz()).Methods(http.MethodGet)
+	muxer.HandleFunc("/openapi.json", a.OpenAPI()).Methods(http.MethodGet)
+	muxer.HandleFunc("/get/{sc_name}/{key}", a.heapReadable(a.GetSCHeap())).Methods(http.MethodGet)
+	muxer.HandleFunc("/list/{sc_name}", a.heapReadable(a.ListSCHeap())).Methods(http.MethodGet)
+	muxer.HandleFunc("/list/{sc_name}/{prefix:.*}", a.heapReadable(a.ListSCHeap())).Methods(http.MethodGet)
+	muxer.HandleFunc("/heap", a.protected(a.ListHeaps())).Methods(http.MethodGet)
+	muxer.HandleFunc("/heap/{sc_name}", a.PostSCHeap()).Methods(http.MethodPost)
+	muxer.HandleFunc("/heap/{sc_name}", a.protected(a.DeleteSCHeap())).Methods(http.MethodDelete)
+	muxer.HandleFunc("/heap/{sc_name}/export", a.protected(a.ExportSCHeap())).Methods(http.MethodGet)
+	muxer.HandleFunc("/heap/{sc_name}/import", a.protected(a.ImportSCHeap())).Methods(http.MethodPost)
+	muxer.HandleFunc("/heap/{sc_name}/usage", a.protected(a.GetHeapUsage())).Methods(http.MethodGet)
+	muxer.HandleFunc("/heap/{sc_name}/{key}", a.DeleteSCHeapKey()).Methods(http.MethodDelete)
+	muxer.HandleFunc("/heap/{sc_name}/{key}/cas", a.CompareAndSwapSCHeap()).Methods(http.MethodPost)
+	muxer.HandleFunc("/transaction", a.protected(a.PostTransaction())).Methods(http.MethodPost)
+	muxer.HandleFunc("/transaction/bulk", a.protected(a.PostTransactionBulk())).Methods(http.MethodPost)
+	muxer.HandleFunc("/transaction/fanout", a.protected(a.PostTransactionFanout())).Methods(http.MethodPost)
+	muxer.HandleFunc("/transaction/{id}", a.protected(a.GetTransaction())).Methods(http.MethodGet)
+	muxer.HandleFunc("/transaction/{id}/status", a.protected(a.GetTransactionStatus())).Methods(http.MethodGet)
+	muxer.HandleFunc("/transaction/{id}/output", a.protected(a.GetTransactionOutput())).Methods(http.MethodGet)
+	muxer.HandleFunc("/transactions", a.protected(a.ListTransactions())).Methods(http.MethodGet)
+	muxer.HandleFunc("/ledger/export", a.protected(a.ExportLedger())).Methods(http.MethodGet)
+	muxer.HandleFunc("/ledger/import", a.protected(a.ImportLedger())).Methods(http.MethodPost)
+	muxer.HandleFunc("/ledger/prune", a.protected(a.PruneLedger())).Methods(http.MethodPost)
+	muxer.HandleFunc("/ledger/prune", a.protected(a.GetPruneStatus())).Methods(http.MethodGet)
+	muxer.HandleFunc("/replay", a.protected(a.Replay())).Methods(http.MethodPost)
+	muxer.HandleFunc("/block/{id}", a.protected(a.GetBlock())).Methods(http.MethodGet)
+	muxer.HandleFunc("/queue", a.protected(a.ListQueue())).Methods(http.MethodGet)
+	muxer.HandleFunc("/outbox", a.protected(a.ListOutbox())).Methods(http.MethodGet)
+	muxer.HandleFunc("/stream", a.protected(a.Stream())).Methods(http.MethodGet)
+	muxer.HandleFunc("/contract", a.protected(a.ListContracts())).Methods(http.MethodGet)
+	muxer.HandleFunc("/contract", a.protected(a.PostContract())).Methods(http.MethodPost)
+	muxer.HandleFunc("/contract/test", a.protected(a.TestContract())).Methods(http.MethodPost)
+	muxer.HandleFunc("/contract/validate", a.protected(a.ValidateContract())).Methods(http.MethodPost)
+	muxer.HandleFunc("/contract/bundle", a.protected(a.PostContractBundle())).Methods(http.MethodPost)
+	muxer.HandleFunc("/contract/{name}", a.protected(a.PutContract())).Methods(http.MethodPut)
+	muxer.HandleFunc("/contract/{name}/versions", a.protected(a.ListContractVersions())).Methods(http.MethodGet)
+	muxer.HandleFunc("/contract/{name}/executions", a.protected(a.ListExecutions())).Methods(http.MethodGet)
+	muxer.HandleFunc("/contract/{name}/status", a.protected(a.GetContractStatus())).Methods(http.MethodGet)
+	muxer.HandleFunc("/contract/{name}/logs", a.protected(a.ContractLogs())).Methods(http.MethodGet)
+	muxer.HandleFunc("/contract/{name}/logs/stream", a.protected(a.ContractLogStream())).Methods(http.MethodGet)
+	muxer.HandleFunc("/contract/{name}", a.protected(a.DeleteContract())).Methods(http.MethodDelete)
+	muxer.HandleFunc("/schedule", a.protected(a.PostSchedule())).Methods(http.MethodPost)
+	muxer.HandleFunc("/schedule", a.protected(a.GetSchedule())).Methods(http.MethodGet)
+	if a.Chains != nil {
+		muxer.HandleFunc("/chains", a.protected(a.PostChain())).Methods(http.MethodPost)
+		muxer.HandleFunc("/chains", a.protected(a.ListChains())).Methods(http.MethodGet)
+		muxer.HandleFunc("/chains/{chain_id}", a.protected(a.DeleteChain())).Methods(http.MethodDelete)
+		muxer.PathPrefix("/chains/{chain_id}/").Handler(a.Chains)
+	}
+	muxer.HandleFunc("/subscription", a.protected(a.PostSubscription())).Methods(http.MethodPost)
+	muxer.HandleFunc("/subscription", a.protected(a.ListSubscriptions())).Methods(http.MethodGet)
+	muxer.HandleFunc("/subscription/{id}", a.protected(a.DeleteSubscription())).Methods(http.MethodDelete)
+	muxer.HandleFunc("/subscription/{id}/deliveries", a.protected(a.ListDeliveries())).Methods(http.MethodGet)
+	muxer.HandleFunc("/api-key", a.protected(a.PostAPIKey())).Methods(http.MethodPost)
+	muxer.HandleFunc("/api-key/{id}", a.protected(a.DeleteAPIKey())).Methods(http.MethodDelete)
+	muxer.HandleFunc("/keys", a.protected(a.PostSigningKey())).Methods(http.MethodPost)
+	muxer.HandleFunc("/keys", a.protected(a.ListSigningKeys())).Methods(http.MethodGet)
+	muxer.HandleFunc("/keys/{id}", a.protected(a.GetSigningKey())).Methods(http.MethodGet)
+	muxer.HandleFunc("/keys/{id}", a.protected(a.DeleteSigningKey())).Methods(http.MethodDelete)
+	muxer.HandleFunc("/secret", a.protected(a.PostSecret())).Methods(http.MethodPost)
+	muxer.HandleFunc("/secret", a.protected(a.ListSecrets())).Methods(http.MethodGet)
+	muxer.HandleFunc("/secret/{name}", a.protected(a.DeleteSecret())).Methods(http.MethodDelete)
+	muxer.HandleFunc("/gc", a.protected(a.CollectGarbage())).Methods(http.MethodPost)
+	muxer.HandleFunc("/audit", a.protected(a.ListAudit())).Methods(http.MethodGet)
+	muxer.HandleFunc("/replication", a.protected(a.GetReplication())).Methods(http.MethodGet)
+	muxer.HandleFunc("/cluster", a.protected(a.GetCluster())).Methods(http.MethodGet)
+	muxer.HandleFunc("/replication/promote", a.protected(a.Promote())).Methods(http.MethodPost)
+	muxer.HandleFunc("/v1/status", a.dragonchain(a.DragonChainStatus())).Methods(http.MethodGet)
+	muxer.HandleFunc("/v1/transaction", a.dragonchain(a.DragonChainPostTransaction())).Methods(http.MethodPost)
+	muxer.HandleFunc("/v1/transaction_bulk", a.dragonchain(a.DragonChainPostTransactionBulk())).Methods(http.MethodPost)
+	muxer.HandleFunc("/v1/transaction/{id}", a.dragonchain(a.DragonChainGetTransaction())).Methods(http.MethodGet)
+	muxer.HandleFunc("/v1/contract", a.dragonchain(a.DragonChainListContracts())).Methods(http.MethodGet)
+	muxer.HandleFunc("/v1/contract", a.dragonchain(a.DragonChainPostContract())).Methods(http.MethodPost)
+	muxer.HandleFunc("/v1/contract/txn_type/{name}", a.dragonchain(a.DragonChainGetContract())).Methods(http.MethodGet)
+	muxer.HandleFunc("/v1/contract/{name}", a.dragonchain(a.DragonChainGetContract())).Methods(http.MethodGet)
+	muxer.HandleFunc("/v1/contract/{name}", a.dragonchain(a.DragonChainDeleteContract())).Methods(http.MethodDelete)
+	muxer.HandleFunc("/v1/get/{sc_name}/{key}", dragonchainErrors(a.heapReadable(dragonchainRaw(a.GetSCHeap())))).Methods(http.MethodGet)
+	muxer.HandleFunc("/v1/list/{sc_name}", dragonchainErrors(a.heapReadable(a.ListSCHeap()))).Methods(http.MethodGet)
+	muxer.HandleFunc("/v1/list/{sc_name}/{prefix:.*}", dragonchainErrors(a.heapReadable(a.ListSCHeap()))).Methods(http.MethodGet)
+}
+
+// protected wraps next with the rate limiting and authentication applied to every route
+// except the contract-facing heap API.
+func (a *Application) protected(next http.HandlerFunc) http.HandlerFunc {
+	return a.rateLimited(a.authenticated(next))
 }
 
-// Shutdown shuts down the application. All currently running cron jobs will be stopped.
+// Shutdown shuts down the application, after shutting down its chains. All currently
+// running cron jobs will be stopped, the work queue stops dispatching transactions,
+// pending transactions are bundled into a final block, forwarding to DragonChain stops,
+// background pruning of the ledger stops, replication stops, stream clients, log stream
+// clients and followers are disconnected and warm containers are removed. Transactions
+// still queued, or waiting in the outbox, are resumed the next time the application
+// starts.
 func (a *Application) Shutdown() {
+	if a.Chains != nil {
+		a.Chains.shutdown()
+	}
+	a.stopFollowing()
+	a.stopCampaign()
+	a.stopGC()
+	a.stopRetention()
+	a.stopOneShots()
+	a.stopWorkQueue()
+	a.stopBlocks()
+	a.stopForwarding()
+	a.closeStreams()
+	a.closeOutputStreams()
+	a.warm.Close()
 	a.cronMu.Lock()
 	defer a.cronMu.Unlock()
 	for _, cron := range a.cronTab {
@@ -190,18 +493,68 @@ func (a *Application) Shutdown() {
 }
 
 // GetSCHeap returns an HTTP handler function that responds with the heap data for the requested
-// contract and key.
+// contract and key. Values are returned with the content type the contract's HeapSchemas declare
+// for the key. Otherwise, values that are valid JSON are returned as-is with a JSON content type,
+// and other values as raw bytes. The optional format query parameter overrides this: "raw" always
+// returns the raw bytes, and "json" always returns JSON, encoding non-JSON values as base64
+// strings.
 func (a *Application) GetSCHeap() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		name := vars["sc_name"]
 		key := vars["key"]
+		if isReservedBucket(name) {
+			writeError(w, http.StatusNotFound, ErrCodeHeapMiss, ErrHeapNotExist.Error())
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "" && format != heapFormatRaw && format != heapFormatJSON {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "format must be raw or json")
+			return
+		}
+		_, span := tracing.Start(r.Context(), "heap.get", attribute.String("hatchery.heap.bucket", name))
 		h, err := a.Heap.Get(name, key)
+		tracing.End(span, err)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			writeErrorFrom(w, err)
 			return
 		}
-		writeJSONResponse(w, h)
+		a.touchHeap(name, key)
+		writeHeapValue(w, h, format, a.heapContentType(name, key))
+	}
+}
+
+// ListSCHeap returns an HTTP handler function that responds with the keys in the smart
+// contract's heap that begin with the requested prefix, in ascending order. If no prefix
+// is given, every key is listed. The optional offset and limit query parameters select
+// a page of the keys.
+func (a *Application) ListSCHeap() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		name := vars["sc_name"]
+		if isReservedBucket(name) {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "heap does not exist")
+			return
+		}
+		offset, limit, ok := pageParams(w, r)
+		if !ok {
+			return
+		}
+		_, span := tracing.Start(r.Context(), "heap.keys", attribute.String("hatchery.heap.bucket", name))
+		keys, err := a.Heap.Keys(name, vars["prefix"])
+		tracing.End(span, err)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if offset > len(keys) {
+			offset = len(keys)
+		}
+		keys = keys[offset:]
+		if limit > 0 && limit < len(keys) {
+			keys = keys[:limit]
+		}
+		writeJSONResponse(w, keys)
 	}
 }
 
@@ -209,100 +562,616 @@ func (a *Application) GetSCHeap() func(http.ResponseWriter, *http.Request) {
 // the transaction is a smart contract, the smart contract will be executed and the output will
 // be stored in the heap. Regardless, the "content" (The output in the case of a smart contract
 // or the payload itself in the case of a regular transaction) is stored in a new transaction on
-// the ledger.
+// the ledger. The transaction is processed through the durable work queue, so it survives a
+// restart, and the response is sent once it has been appended or has exhausted its retries.
+//
+// Requests may carry an Idempotency-Key header so that they can be retried safely. A
+// request whose key was used within the IdempotencyWindow responds with the transaction
+// appended for the first request, with the Idempotent-Replayed header set, instead of
+// executing the contract again. If that transaction is still being processed, or was
+// posted with a different txn_type, the request fails with a conflict.
+//
+// Long-running contracts can be posted asynchronously, with the async=true query
+// parameter or a "Prefer: respond-async" header. The transaction is queued as usual,
+// but the request is answered immediately with a 202 response holding its ID and a
+// status of pending, and a Location header pointing at GetTransactionStatus.
+//
+// If the client of a synchronous request disconnects before the transaction has been
+// appended, the transaction is cancelled: its container is killed, its execution is
+// recorded as cancelled and it is not retried. See cancelWork.
+//
+// The response includes the transaction's content, encoded as requested. See
+// contentEncoding.
 func (a *Application) PostTransaction() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		encoding, ok := contentEncoding(w, r)
+		if !ok {
+			return
+		}
 		var req postTransactionRequest
+		limitBody(w, r, a.maxTransactionSize())
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			// TODO: return proper response
-			w.WriteHeader(http.StatusBadRequest)
+			writeDecodeError(w, ErrCodeBadRequest, "invalid transaction", err)
 			return
 		}
-		contract, err := a.Lib.Get(req.Type)
-		if err == ErrContractNotExist {
-			http.NotFound(w, r)
+		auditNote(r, "txn_type", req.Type)
+		signer, err := a.verifyTransaction(&req)
+		if err != nil {
+			writeErrorFrom(w, err)
 			return
 		}
+		id := ""
+		if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+			if len(key) > maxIdempotencyKeyLength {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("%s may not exceed %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+				return
+			}
+			t, claimed, err := a.claimIdempotencyKey(key, req.Type)
+			if err != nil {
+				writeErrorFrom(w, err)
+				return
+			}
+			if t != nil {
+				w.Header().Set(ReplayedHeader, "true")
+				writeJSONResponse(w, newTransactionResponse(t, encoding))
+				return
+			}
+			id = claimed
+		}
+		if id == "" {
+			id = uuid.New().String()
+		}
+		auditNote(r, "txn_id", id)
+		done, err := a.enqueue(r.Context(), id, req.Type, req.Payload, signer)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			writeErrorFrom(w, err)
 			return
 		}
-		content, err := contract.Execute(req.Payload)
+		if asyncRequested(r) {
+			// done is buffered, so the outcome is dropped once nobody waits for it.
+			writeAccepted(w, id, req.Type)
+			return
+		}
+		select {
+		case res := <-done:
+			if res.err != nil {
+				writeErrorFrom(w, res.err)
+				return
+			}
+			writeJSONResponse(w, newTransactionResponse(res.t, encoding))
+		case <-r.Context().Done():
+			// Nobody is left to receive the outcome, so the execution is cancelled
+			// rather than left running.
+			a.cancelWork(id)
+		}
+	}
+}
+
+// transact executes the contract for the queued item's transaction type, if there is
+// one, and appends the resulting transaction to the ledger with the item's ID,
+// invocation chain and signer, and the number of attempts its contract took along with
+// the error of the last one that failed. Transactions whose type has no contract are
+// appended with the payload as their content. Any contracts the output invokes are
+// then queued.
+func (a *Application) transact(ctx context.Context, item *QueueItem) (*Transaction, error) {
+	txnType := item.TxnType
+	ctx = backend.WithInvocation(ctx, backend.Invocation{Invoker: a.invoker(item.InvocationChain)})
+	t, puts, err := a.execute(ctx, item.ID, txnType, item.Payload)
+	if err != nil {
+		return nil, err
+	}
+	t.InvocationChain = item.InvocationChain
+	t.Signer = item.Signer
+	if t.InvokerContract != "" {
+		t.Attempts = item.Attempts
+		t.LastError = item.LastError
+	}
+	if err := a.commit(ctx, t, puts); err != nil {
+		a.log().Error("failed to append transaction", logging.Contract(txnType), logging.TxnID(t.ID), logging.Err(err))
+		return nil, err
+	}
+	a.log().Info("transaction appended", logging.Contract(txnType), logging.TxnID(t.ID))
+	a.invokeDownstream(ctx, t)
+	return t, nil
+}
+
+// execute executes the contract for txnType, if there is one, and returns the resulting
+// transaction without appending it to the ledger, along with the heap writes of the
+// contract's output, which are not made yet. The transaction is given the provided ID,
+// or a new one if id is empty. The contract is passed the transaction's ID, type and
+// timestamp, along with the invoker of any backend.Invocation ctx carries. See
+// backend.InvocationEnv. If the ledger is a backend.BlobStore, output spilled under
+// output.PolicySpill is stored in it and referenced by the transaction's OutputRef,
+// and isn't written to the heap.
+func (a *Application) execute(ctx context.Context, id, txnType string, payload []byte) (*Transaction, []HeapPut, error) {
+	logger := a.log().With(logging.Contract(txnType))
+	content := payload
+	invoker := ""
+	var (
+		puts    []HeapPut
+		spilled *output.Spilled
+	)
+	if id == "" {
+		id = uuid.New().String()
+	}
+	inv, _ := backend.InvocationFrom(ctx)
+	inv.TxnID, inv.TxnType, inv.Timestamp = id, txnType, time.Now().UTC()
+	ctx = backend.WithInvocation(ctx, inv)
+	contract, err := a.contract(txnType)
+	switch {
+	case err == ErrContractNotExist:
+	case err != nil:
+		return nil, nil, err
+	default:
+		if store, ok := a.Ledger.(backend.BlobStore); ok {
+			ctx, spilled = output.WithSpill(ctx, store)
+		}
+		content, err = a.run(ctx, txnType, TriggerTransaction, id, contract, payload)
+		if e, ok := err.(*CircuitOpenError); ok {
+			return nil, nil, e
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			logger.Error("execution failed", logging.Err(err))
+			return nil, nil, &ExecutionError{Contract: txnType, Err: err}
+		}
+		invoker = txnType
+		if spilled == nil || spilled.Address == "" {
+			puts = a.contractOutputPuts(txnType, a.outputBucket(txnType), content)
+		}
+	}
+	t := NewTransaction(content)
+	t.ID = id
+	t.Type = txnType
+	t.Timestamp = inv.Timestamp
+	t.InvokerContract = invoker
+	if invoker != "" {
+		t.Payload = payload
+	}
+	if spilled != nil {
+		t.OutputRef, t.OutputSize = spilled.Address, spilled.Size
+	}
+	t.Status = TransactionStatusSuccess
+	return t, puts, nil
+}
+
+// commit writes the heap output of t's contract and appends t to the ledger. If the
+// ledger implements backend.HeapAppender, both are committed atomically. Otherwise,
+// the heap is written first, and failed heap writes are only logged. Neither happens
+// if the output doesn't fit within the quota of its bucket or satisfy its schemas.
+func (a *Application) commit(ctx context.Context, t *Transaction, puts []HeapPut) (err error) {
+	if len(puts) > 0 {
+		if err := a.admitHeap(puts[0].Bucket, puts, false); err != nil {
+			return err
+		}
+	}
+	l, ok := a.Ledger.(backend.HeapAppender)
+	if !ok {
+		a.putOutput(ctx, t.Type, puts)
+	}
+	_, span := tracing.Start(ctx, "ledger.append", attribute.String("hatchery.txn_id", t.ID))
+	defer func() { tracing.End(span, err) }()
+	if !ok {
+		return a.append(t)
+	}
+	if err := l.AppendWithHeap(puts, t); err != nil {
+		return err
+	}
+	a.heapWritten(t.Type, puts...)
+	a.touchPuts(puts)
+	a.appended(t)
+	return nil
+}
+
+// putOutput makes the heap writes of a contract's output, logging any failures.
+func (a *Application) putOutput(ctx context.Context, txnType string, puts []HeapPut) {
+	if len(puts) == 0 {
+		return
+	}
+	_, span := tracing.Start(ctx, "heap.put", attribute.Int("hatchery.heap.keys", len(puts)))
+	defer span.End()
+	for _, p := range puts {
+		if err := a.Heap.Put(p.Bucket, p.Key, p.Value); err != nil {
+			a.log().Error("failed to write heap", logging.Contract(txnType), logging.F("key", p.Key), logging.Err(err))
+			continue
+		}
+		a.heapWritten(txnType, p)
+	}
+	a.touchPuts(puts)
+}
+
+// append adds ts to the ledger and notifies any subscribers.
+func (a *Application) append(ts ...*Transaction) error {
+	if err := a.Ledger.Append(ts...); err != nil {
+		return err
+	}
+	a.appended(ts...)
+	return nil
+}
+
+// appended bundles ts into the next block, adds them to the outbox if they are
+// forwarded to DragonChain and publishes them on the event bus, once ts have been
+// appended to the ledger.
+func (a *Application) appended(ts ...*Transaction) {
+	a.addToBlock(ts...)
+	a.addToOutbox(ts...)
+	for _, t := range ts {
+		a.bus().Publish(&Event{Type: EventTransaction, Contract: t.Type, Transaction: t})
+	}
+}
+
+// GetTransaction returns an HTTP handler function that responds with the transaction
+// with the requested ID, including its content, encoded as requested. See
+// contentEncoding.
+func (a *Application) GetTransaction() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding, ok := contentEncoding(w, r)
+		if !ok {
 			return
 		}
-		var output map[string]interface{}
-		if err := json.Unmarshal(content, &output); err == nil {
-			for k, v := range output {
-				var buf bytes.Buffer
-				if err := binary.Write(&buf, binary.BigEndian, v); err == nil {
-					a.Heap.Put(a.Bucket, k, buf.Bytes())
-				}
+		_, span := tracing.Start(r.Context(), "ledger.find")
+		t, err := a.Ledger.Find(mux.Vars(r)["id"])
+		tracing.End(span, err)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, newTransactionResponse(t, encoding))
+	}
+}
+
+// GetTransactionOutput returns an HTTP handler function that responds with the raw
+// output of the contract that produced the transaction with the requested ID. Output
+// that was spilled to the ledger's blob store is streamed from it; any other
+// transaction responds with its content.
+func (a *Application) GetTransactionOutput() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "ledger.find")
+		t, err := a.Ledger.Find(mux.Vars(r)["id"])
+		tracing.End(span, err)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if t.OutputRef == "" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(t.Content)
+			return
+		}
+		store, ok := a.Ledger.(backend.BlobStore)
+		if !ok {
+			writeErrorFrom(w, ErrBlobNotExist)
+			return
+		}
+		blob, err := store.OpenBlob(t.OutputRef)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		defer blob.Close()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(t.OutputSize, 10))
+		if _, err := io.Copy(w, blob); err != nil {
+			a.log().Error("failed to stream transaction output", logging.TxnID(t.ID), logging.Err(err))
+		}
+	}
+}
+
+// ListTransactions returns an HTTP handler function that responds with a page of
+// transactions from the ledger in the order they were appended. The page is selected
+// with the optional offset and limit query parameters. The limit defaults to
+// defaultPageLimit and may not exceed maxPageLimit. Content is encoded as requested.
+// See contentEncoding.
+func (a *Application) ListTransactions() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding, ok := contentEncoding(w, r)
+		if !ok {
+			return
+		}
+		offset, err := queryInt(r, "offset", 0)
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		limit, err := queryInt(r, "limit", defaultPageLimit)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", maxPageLimit))
+			return
+		}
+		resp := listTransactionsResponse{
+			Transactions: make([]transactionResponse, 0, limit),
+			Offset:       offset,
+			Limit:        limit,
+		}
+		err = a.Ledger.Iterate(func(t *Transaction) bool {
+			if resp.Total >= offset && len(resp.Transactions) < limit {
+				resp.Transactions = append(resp.Transactions, newTransactionResponse(t, encoding))
 			}
+			resp.Total++
+			return true
+		})
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
 		}
-		t := NewTransaction(content)
-		a.Ledger.Append(t)
-		writeJSONResponse(w, t)
+		writeJSONResponse(w, resp)
 	}
 }
 
-// PostContract returns an HTTP handler function that creates a new Contract in the Library.
-// If the request specifies a cron interval, a new cron job is started in the background.
+// ListContracts returns an HTTP handler function that responds with the manifests of all
+// contracts in the Library. Secrets are redacted from the manifests.
+func (a *Application) ListContracts() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		manifests, err := a.Lib.List()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		for i := range manifests {
+			manifests[i] = manifests[i].Redacted()
+		}
+		writeJSONResponse(w, manifests)
+	}
+}
+
+// ListContractVersions returns an HTTP handler function that responds with the version
+// history of a Contract in the Library, oldest first.
+func (a *Application) ListContractVersions() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		versions, err := a.Lib.Versions(mux.Vars(r)["name"])
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, versions)
+	}
+}
+
+// PostContract returns an HTTP handler function that creates a new Contract in the Library,
+// or a new version of it if it already exists. If the request specifies a cron schedule, the
+// contract's cron job is rescheduled, or started in the background if there is none. See
+// rescheduleCronJob. Otherwise, any existing cron job is stopped. The contract's circuit
+// breaker is reset, since the new version may fix whatever tripped it. Preparing the
+// contract can take a while, since its image is pulled, so if the request asks to be
+// handled asynchronously, like PostTransaction, the manifest is only validated before
+// the response, and GetContractStatus reports the pull's progress and outcome.
 func (a *Application) PostContract() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req ContractManifest
-		err := json.NewDecoder(r.Body).Decode(&req)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
+		limitBody(w, r, a.maxContractSize())
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, ErrCodeInvalidManifest, "invalid manifest", err)
 			return
 		}
-		var interval time.Duration
-		if req.Cron != "" {
-			interval, err = time.ParseDuration(req.Cron)
-			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				return
-			}
+		schedule, ok := a.validateManifest(w, &req)
+		if !ok {
+			return
+		}
+		reg := a.beginRegistration(&req)
+		auditNote(r, "contract", req.Type)
+		if asyncRequested(r) {
+			go a.storeContractAsync(reg, &req, schedule, a.Lib.Put)
+			writeRegistrationAccepted(w, req.Type, reg)
+			return
+		}
+		if err := a.storeContract(reg, &req, schedule, a.Lib.Put); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		auditNote(r, "version", strconv.Itoa(req.Version))
+	}
+}
+
+// registered announces that m has been stored in the Library, resets the contract's
+// circuit breaker, removes the warm containers of its previous version and switches its cron job to schedule, or stops it if schedule is
+// nil. Only the leader of a cluster runs cron jobs. An error is returned if the cron
+// job could not be scheduled.
+func (a *Application) registered(m *ContractManifest, schedule Schedule) error {
+	a.bus().Publish(&Event{Type: EventContractRegistered, Contract: m.Type, Manifest: m})
+	a.resetCircuit(m.Type)
+	a.warm.Drain(m.Type)
+	if schedule == nil {
+		a.stopCronJob(m.Type)
+		return nil
+	}
+	return a.whileLeading(func() error {
+		return a.rescheduleCronJob(m.Type, schedule)
+	})
+}
+
+// PutContract returns an HTTP handler function that updates an existing Contract in the
+// Library. If the updated manifest specifies a cron schedule, the contract's cron job is
+// switched to it without being recreated, so a changed Cron value takes effect from the
+// job's next activation. Otherwise, any cron job for the contract is stopped. Like
+// PostContract, the contract's circuit breaker is reset, and the update may be
+// handled asynchronously.
+func (a *Application) PutContract() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		var req ContractManifest
+		limitBody(w, r, a.maxContractSize())
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, ErrCodeInvalidManifest, "invalid manifest", err)
+			return
+		}
+		if req.Type == "" {
+			req.Type = name
+		}
+		if req.Type != name {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidManifest, "txn_type does not match the contract name")
+			return
+		}
+		schedule, ok := a.validateManifest(w, &req)
+		if !ok {
+			return
+		}
+		if _, err := a.Lib.Manifest(name); err != nil {
+			writeErrorFrom(w, err)
+			return
 		}
-		if err := a.Lib.Put(&req); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+		reg := a.beginRegistration(&req)
+		if asyncRequested(r) {
+			go a.storeContractAsync(reg, &req, schedule, a.Lib.Update)
+			writeRegistrationAccepted(w, req.Type, reg)
 			return
 		}
-		if interval > 0 {
-			a.startCronJob(w, req.Type, interval)
+		if err := a.storeContract(reg, &req, schedule, a.Lib.Update); err != nil {
+			writeErrorFrom(w, err)
+			return
 		}
+		auditNote(r, "version", strconv.Itoa(req.Version))
 	}
 }
 
-func (a *Application) startCronJob(w http.ResponseWriter, name string, interval time.Duration) {
+// DeleteContract returns an HTTP handler function that removes a Contract from the Library.
+// Any cron job for the contract is stopped.
+func (a *Application) DeleteContract() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := a.deleteContract(mux.Vars(r)["name"]); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// deleteContract removes the named contract from the Library, stops its cron job and
+// discards everything the node keeps about it.
+func (a *Application) deleteContract(name string) error {
+	a.stopCronJob(name)
+	if err := a.Lib.Delete(name); err != nil {
+		return err
+	}
+	a.resetCircuit(name)
+	a.warm.Drain(name)
+	a.forgetOutput(name)
+	a.slots.forget(name)
+	a.forgetRegistration(name)
+	if err := a.executionLog().Clear(name); err != nil {
+		a.log().Error("failed to clear execution history", logging.Contract(name), logging.Err(err))
+	}
+	return nil
+}
+
+// rescheduleCronJob switches the named contract's cron job to schedule. A running job
+// is rescheduled in place, so executions that are underway are not interrupted. The
+// job is replaced instead if the manifest's overlap policy or jitter has changed,
+// since those can't be changed while it runs. If the contract has no cron job, one is
+// started.
+func (a *Application) rescheduleCronJob(name string, schedule Schedule) error {
 	a.ensureCronTab()
-	contract, err := a.Lib.Get(name)
+	m, err := a.Lib.Manifest(name)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return err
+	}
+	jitter, err := m.Jitter()
+	if err != nil {
+		return err
+	}
+	a.cronMu.Lock()
+	cron, ok := a.cronTab[name]
+	a.cronMu.Unlock()
+	if ok && cron.Overlap == OverlapPolicy(m.CronOverlap) && cron.Jitter == jitter {
+		if err := cron.Reschedule(schedule); err == nil {
+			a.log().Info("cron job rescheduled", logging.Contract(name), logging.F("cron", m.Cron))
+			return nil
+		}
+	}
+	a.stopCronJob(name)
+	return a.scheduleCronJob(name, schedule)
+}
+
+// scheduleCronJob starts a cron job that executes the named contract on schedule, in
+// the background, with the overlap policy, jitter and payload of the contract's manifest.
+// The payload is read from the manifest at each execution, so that it follows updates
+// to the manifest made while the job is rescheduled in place.
+func (a *Application) scheduleCronJob(name string, schedule Schedule) error {
+	a.ensureCronTab()
+	m, err := a.Lib.Manifest(name)
+	if err != nil {
+		return err
+	}
+	jitter, err := m.Jitter()
+	if err != nil {
+		return err
+	}
+	logger := a.log().With(logging.Contract(name))
+	cron := NewCronJob(schedule, &cronExecutable{app: a, name: name})
+	cron.Logger = logger
+	cron.Overlap = OverlapPolicy(m.CronOverlap)
+	cron.Jitter = jitter
+	cron.Payload = func() ([]byte, error) {
+		m, err := a.Lib.Manifest(name)
+		if err != nil {
+			return nil, err
+		}
+		return a.cronPayload(m)
 	}
-	cron := NewCronJob(interval, contract)
 	// In order to properly start the cron job, we need to aggressively consume the errros,
 	// aggressively consume the output, and finally, start the cron job itself.
 	go func() {
 		for err := range cron.Errors() {
-			fmt.Fprintln(os.Stderr, err)
+			logger.Error("scheduled execution failed", logging.Err(err))
 		}
 	}()
 	go func() {
 		for result := range cron.Output() {
-			fmt.Println(result)
+			logger.Info("scheduled execution finished", logging.F("output", string(result)))
 		}
 	}()
 	go func() {
 		if err := cron.Run(); err != nil {
-			fmt.Fprintln(os.Stderr, err)
+			logger.Error("cron job failed", logging.Err(err))
 		}
 	}()
 	a.cronMu.Lock()
 	a.cronTab[name] = cron
 	a.cronMu.Unlock()
+	return nil
+}
+
+// recoverCronJobs starts a cron job for every contract in the Library whose manifest
+// has a cron schedule, since cron jobs don't outlive the process that started them.
+// Contracts whose job can't be started are logged and skipped.
+func (a *Application) recoverCronJobs() {
+	manifests, err := a.Lib.List()
+	if err != nil {
+		a.log().Error("failed to recover cron jobs", logging.Err(err))
+		return
+	}
+	for _, m := range manifests {
+		if m.Cron == "" {
+			continue
+		}
+		logger := a.log().With(logging.Contract(m.Type), logging.F("cron", m.Cron))
+		schedule, err := ParseSchedule(m.Cron)
+		if err != nil {
+			logger.Error("failed to recover cron job", logging.Err(err))
+			continue
+		}
+		a.stopCronJob(m.Type)
+		if err := a.scheduleCronJob(m.Type, schedule); err != nil {
+			logger.Error("failed to recover cron job", logging.Err(err))
+			continue
+		}
+		logger.Info("recovered cron job")
+	}
+}
+
+func (a *Application) stopCronJob(name string) {
+	a.ensureCronTab()
+	a.cronMu.Lock()
+	defer a.cronMu.Unlock()
+	if cron, ok := a.cronTab[name]; ok {
+		cron.Stop()
+		delete(a.cronTab, name)
+	}
+}
+
+func (a *Application) log() logging.Logger {
+	if a.Logger == nil {
+		return logging.Default()
+	}
+	return a.Logger
 }
 
 func (a *Application) ensureCronTab() {
@@ -0,0 +1,216 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveTimeout bounds how long an S3Archiver waits for an archive to be uploaded.
+const archiveTimeout = 5 * time.Minute
+
+// Archiver stores the transactions pruned from the ledger before they are removed.
+// See RetentionPolicy.
+type Archiver interface {
+	// Archive stores the archive read from r under name, and returns where it
+	// was stored. The archive is a gzipped JSON Lines file of LedgerEntries, as
+	// exported by ExportLedger.
+	Archive(ctx context.Context, name string, r io.ReadSeeker) (string, error)
+}
+
+// FileArchiver stores archives as files in a directory.
+type FileArchiver struct {
+	// Dir is the directory archives are stored in. It is created if it doesn't
+	// exist.
+	Dir string
+}
+
+// Archive writes the archive to a file named name in Dir. The file only appears
+// once it has been written completely.
+func (f *FileArchiver) Archive(ctx context.Context, name string, r io.ReadSeeker) (string, error) {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(f.Dir, "."+name+".")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(f.Dir, name)
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// S3Archiver uploads archives to an S3 bucket, or to a bucket of a service with an
+// S3 compatible API. Requests are signed with AWS Signature Version 4.
+type S3Archiver struct {
+	// Bucket is the name of the bucket archives are uploaded to.
+	Bucket string
+	// Prefix is prepended to the key of each archive, such as "hatchery/".
+	Prefix string
+	// Region is the region of the bucket, such as "us-east-1".
+	Region string
+	// Endpoint is the URL of the S3 API. If empty, AWS's endpoint for Region is
+	// used. Buckets are addressed by path, so other services can be used as well.
+	Endpoint string
+	// AccessKeyID, SecretAccessKey and SessionToken are the credentials requests
+	// are signed with. If AccessKeyID is empty, they are read from the
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+	// variables.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Client is the HTTP client used to upload archives. If nil, a client with a
+	// five minute timeout is used.
+	Client *http.Client
+}
+
+// Archive uploads the archive to Prefix+name in Bucket, and returns its s3:// URL.
+func (s *S3Archiver) Archive(ctx context.Context, name string, r io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	key := s.Prefix + name
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	uri := "/" + s.Bucket + "/" + awsEscapePath(key)
+	req, err := http.NewRequest(http.MethodPut, strings.TrimRight(endpoint, "/")+uri, ioutil.NopCloser(r))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	s.sign(req, uri, hex.EncodeToString(h.Sum(nil)), time.Now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: archiveTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("s3 responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return "s3://" + path.Join(s.Bucket, key), nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req, whose escaped
+// path is uri and whose body has the hex encoded SHA-256 hash payloadHash.
+func (s *S3Archiver) sign(req *http.Request, uri, payloadHash string, now time.Time) {
+	keyID, secret, token := s.AccessKeyID, s.SecretAccessKey, s.SessionToken
+	if keyID == "" {
+		keyID, secret, token = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN")
+	}
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonical := strings.Join([]string{req.Method, uri, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	for _, part := range []string{s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		keyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscapePath escapes every byte of p that AWS doesn't leave unescaped in paths,
+// which is every byte other than letters, digits, '-', '.', '_', '~' and '/'.
+func awsEscapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,112 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+)
+
+// transactionStatus is the state of a posted transaction. Transaction is set once it
+// has been appended to the ledger, and Error once it has failed for good.
+type transactionStatus struct {
+	ID      string            `json:"id"`
+	Status  TransactionStatus `json:"status"`
+	TxnType string            `json:"txn_type,omitempty"`
+	// Attempts is how many times the transaction has been attempted so far.
+	Attempts    int                  `json:"attempts,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	Transaction *transactionResponse `json:"transaction,omitempty"`
+}
+
+// asyncRequested reports whether r asks for a transaction to be posted asynchronously,
+// with either the async query parameter or a Prefer header of respond-async.
+func asyncRequested(r *http.Request) bool {
+	if r.URL.Query().Get("async") == "true" {
+		return true
+	}
+	for _, pref := range strings.Split(r.Header.Get("Prefer"), ",") {
+		if strings.EqualFold(strings.TrimSpace(pref), "respond-async") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAccepted responds to an asynchronously posted transaction with the given ID,
+// pointing the client at its status.
+func writeAccepted(w http.ResponseWriter, id, txnType string) {
+	w.Header().Set("Location", "/transaction/"+id+"/status")
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSONResponse(w, transactionStatus{ID: id, Status: TransactionStatusPending, TxnType: txnType})
+}
+
+// GetTransactionStatus returns an HTTP handler function that responds with the status
+// of a posted transaction, typically one posted asynchronously. A transaction is
+// pending while it is in the work queue, has the status recorded on it once it has been
+// appended to the ledger, in which case the response includes it with its content
+// encoded as requested, and is a failure once it has exhausted its attempts or been
+// cancelled, in which case the response includes the last error. Transactions that
+// were never posted respond with a 404 error.
+func (a *Application) GetTransactionStatus() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding, ok := contentEncoding(w, r)
+		if !ok {
+			return
+		}
+		id := mux.Vars(r)["id"]
+		_, span := tracing.Start(r.Context(), "ledger.find")
+		t, err := a.Ledger.Find(id)
+		tracing.End(span, err)
+		if err == nil {
+			resp := newTransactionResponse(t, encoding)
+			status := t.Status
+			if status == "" {
+				// Transactions appended before statuses were recorded were all successes.
+				status = TransactionStatusSuccess
+			}
+			writeJSONResponse(w, transactionStatus{ID: id, Status: status, TxnType: t.Type, Transaction: &resp})
+			return
+		}
+		if err != ErrTransactionNotExist {
+			writeErrorFrom(w, err)
+			return
+		}
+		item, err := a.queuedItem(id)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if item == nil {
+			writeErrorFrom(w, ErrTransactionNotExist)
+			return
+		}
+		status := transactionStatus{ID: id, Status: TransactionStatusPending, TxnType: item.TxnType, Attempts: item.Attempts}
+		if item.Status == QueueStatusFailed || item.Status == QueueStatusCancelled {
+			status.Status = TransactionStatusFailure
+			status.Error = item.LastError
+		}
+		writeJSONResponse(w, status)
+	}
+}
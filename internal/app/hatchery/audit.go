@@ -0,0 +1,309 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+const (
+	auditBucket = reservedBucketPrefix + "audit"
+
+	// maxAuditErrorBody is how much of an error response is kept to record its
+	// error code and message.
+	maxAuditErrorBody = 4 << 10
+)
+
+// AuditEntry records a single mutating API call: every POST, PUT and DELETE request,
+// such as creating or deleting a contract, posting a transaction or writing to a heap.
+type AuditEntry struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Caller identifies who made the request: the ID of the API key it was signed
+	// with, "contract:<name>" for heap writes authorized with a contract's heap
+	// token, or "anonymous". Requests that failed authentication are recorded with
+	// the identity they claimed and a 401 status.
+	Caller string `json:"caller"`
+	Remote string `json:"remote"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Route is the path template of the route that handled the request, such as
+	// "/contract/{name}".
+	Route string `json:"route"`
+	// Params summarizes the request: the variables of its route, such as the
+	// contract's name, and details noted by its handler, such as the txn_type of a
+	// posted transaction.
+	Params   map[string]string `json:"params,omitempty"`
+	Status   int               `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	Duration string            `json:"duration"`
+}
+
+// auditContextKey is the context key of the auditRecord of a request.
+type auditContextKey struct{}
+
+// auditRecord collects the details handlers note about an audited request.
+type auditRecord struct {
+	mu     sync.Mutex
+	params map[string]string
+	caller string
+}
+
+// auditNote adds a detail to the audit entry of r, if it is audited.
+func auditNote(r *http.Request, key, value string) {
+	rec, ok := r.Context().Value(auditContextKey{}).(*auditRecord)
+	if !ok {
+		return
+	}
+	rec.mu.Lock()
+	rec.params[key] = value
+	rec.mu.Unlock()
+}
+
+// auditCallerAs records caller as the identity of r in its audit entry, if it is
+// audited, for handlers that authenticate requests themselves.
+func auditCallerAs(r *http.Request, caller string) {
+	rec, ok := r.Context().Value(auditContextKey{}).(*auditRecord)
+	if !ok {
+		return
+	}
+	rec.mu.Lock()
+	rec.caller = caller
+	rec.mu.Unlock()
+}
+
+// auditRecorder captures the status of a response, and the start of its body if it
+// is an error.
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *auditRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *auditRecorder) Write(b []byte) (int, error) {
+	if r.status >= http.StatusBadRequest && r.body.Len() < maxAuditErrorBody {
+		n := maxAuditErrorBody - r.body.Len()
+		if n > len(b) {
+			n = len(b)
+		}
+		r.body.Write(b[:n])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, so that streaming handlers can flush through the
+// recorder.
+func (r *auditRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// audited is middleware that records an AuditEntry for every POST, PUT and DELETE
+// request handled by next, if Audit is set. Entries are appended to a reserved heap
+// bucket, in the order the requests finished, and are never modified. Failing to
+// record an entry is logged but doesn't fail the request. Requests a follower rejects
+// as read-only are not recorded.
+func (a *Application) audited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Audit || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		vars := mux.Vars(r)
+		audit := &auditRecord{params: make(map[string]string, len(vars))}
+		for k, v := range vars {
+			audit.params[k] = v
+		}
+		rec := &auditRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, audit)))
+
+		route := r.URL.Path
+		if cur := mux.CurrentRoute(r); cur != nil {
+			if tmpl, err := cur.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		e := &AuditEntry{
+			ID:       uuid.New().String(),
+			Time:     start.UTC(),
+			Caller:   auditCaller(r, vars),
+			Remote:   r.RemoteAddr,
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Route:    route,
+			Status:   rec.status,
+			Duration: time.Since(start).String(),
+		}
+		audit.mu.Lock()
+		if len(audit.params) > 0 {
+			e.Params = audit.params
+		}
+		if audit.caller != "" {
+			e.Caller = audit.caller
+		}
+		audit.mu.Unlock()
+		if rec.status >= http.StatusBadRequest {
+			var resp errorResponse
+			if json.Unmarshal(rec.body.Bytes(), &resp) == nil && resp.Error.Code != "" {
+				e.Error = resp.Error.Code + ": " + resp.Error.Message
+			} else {
+				e.Error = http.StatusText(rec.status)
+			}
+		}
+		if err := a.recordAudit(e); err != nil {
+			a.log().Error("failed to record audit entry", logging.F("method", e.Method), logging.F("path", e.Path), logging.Err(err))
+		}
+	})
+}
+
+// auditCaller returns the identity r was made with. See AuditEntry.Caller.
+func auditCaller(r *http.Request, vars map[string]string) string {
+	auth := r.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(auth, hmacScheme+" "):
+		creds := strings.SplitN(strings.TrimPrefix(auth, hmacScheme+" "), ":", 2)
+		if creds[0] != "" {
+			return creds[0]
+		}
+	case strings.HasPrefix(auth, "Bearer ") && vars["sc_name"] != "":
+		return "contract:" + vars["sc_name"]
+	}
+	return "anonymous"
+}
+
+// recordAudit appends e to the audit log. Entries are keyed by their Time, so that
+// the bucket iterates in the order the requests were made.
+func (a *Application) recordAudit(e *AuditEntry) error {
+	key := fmt.Sprintf("%020d-%s", e.Time.UnixNano(), e.ID)
+	return a.putJSON(auditBucket, key, e)
+}
+
+// auditFilter selects audit entries. Zero fields match every entry. since and until
+// are applied by ListAudit, which reads entries in order of their Time.
+type auditFilter struct {
+	caller   string
+	method   string
+	contract string
+	failed   *bool
+	since    time.Time
+	until    time.Time
+}
+
+func (f *auditFilter) match(e *AuditEntry) bool {
+	switch {
+	case f.caller != "" && e.Caller != f.caller:
+		return false
+	case f.method != "" && !strings.EqualFold(e.Method, f.method):
+		return false
+	case f.contract != "" && e.Params["name"] != f.contract && e.Params["sc_name"] != f.contract &&
+		e.Params["contract"] != f.contract && e.Params["txn_type"] != f.contract:
+		return false
+	case f.failed != nil && *f.failed != (e.Status >= http.StatusBadRequest):
+		return false
+	}
+	return true
+}
+
+// ListAudit returns an HTTP handler function that responds with entries of the audit
+// log, oldest first. Entries are filtered with the optional caller, method and
+// contract query parameters, which match the entry's Caller, its Method and the
+// contract it concerns, the status parameter, which is "success" or "failure", and
+// the since and until parameters, which bound the entry's Time as RFC 3339
+// timestamps. The limit parameter defaults to defaultPageLimit and may not exceed
+// maxPageLimit; to read further, request entries since the Time of the last one.
+func (a *Application) ListAudit() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := auditFilter{caller: q.Get("caller"), method: q.Get("method"), contract: q.Get("contract")}
+		switch q.Get("status") {
+		case "":
+		case "success":
+			f.failed = new(bool)
+		case "failure":
+			failed := true
+			f.failed = &failed
+		default:
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, `status must be "success" or "failure"`)
+			return
+		}
+		for name, dst := range map[string]*time.Time{"since": &f.since, "until": &f.until} {
+			if v := q.Get(name); v != "" {
+				t, err := time.Parse(time.RFC3339Nano, v)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, ErrCodeBadRequest, name+" must be an RFC 3339 timestamp")
+					return
+				}
+				*dst = t
+			}
+		}
+		limit, err := queryInt(r, "limit", defaultPageLimit)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageLimit))
+			return
+		}
+		start := ""
+		if !f.since.IsZero() {
+			start = fmt.Sprintf("%020d", f.since.UnixNano())
+		}
+		entries := []*AuditEntry{}
+		var decodeErr error
+		err = backend.IterateHeap(a.Heap, auditBucket, start, func(key string, value []byte) bool {
+			var e AuditEntry
+			if decodeErr = json.Unmarshal(value, &e); decodeErr != nil {
+				return false
+			}
+			if !f.until.IsZero() && !e.Time.Before(f.until) {
+				return false
+			}
+			if f.match(&e) {
+				entries = append(entries, &e)
+			}
+			return len(entries) < limit
+		})
+		if err == nil {
+			err = decodeErr
+		}
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, entries)
+	}
+}
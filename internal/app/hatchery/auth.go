@@ -0,0 +1,230 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/pkg/client"
+)
+
+const (
+	apiKeyBucket = reservedBucketPrefix + "api_keys"
+	// hmacScheme is the Authorization scheme used by DragonChain SDKs.
+	// Only the SHA256 variant is supported.
+	hmacScheme = "DC1-HMAC-SHA256"
+	// maxClockSkew is how far a request's timestamp may be from the current time.
+	maxClockSkew = 5 * time.Minute
+)
+
+var (
+	// ErrAPIKeyNotExist is returned when a requested API key does not exist.
+	ErrAPIKeyNotExist = errors.New("api key does not exist")
+
+	idAlphabet = []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+)
+
+// APIKey is a credential used to sign requests to the Hatchery API.
+type APIKey struct {
+	// ID identifies the key and is sent in the clear with each request.
+	ID string `json:"id"`
+	// Key is the shared secret used to compute request signatures.
+	Key string `json:"key"`
+	// Created is when the key was generated.
+	Created time.Time `json:"created"`
+}
+
+// GenerateAPIKey creates a new random API key and stores it in the heap. An
+// error is returned if the key could not be generated or stored.
+func (a *Application) GenerateAPIKey() (*APIKey, error) {
+	id, err := randomKeyID()
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	key := &APIKey{
+		ID:      id,
+		Key:     base64.RawURLEncoding.EncodeToString(secret),
+		Created: time.Now().UTC(),
+	}
+	if err := a.putJSON(apiKeyBucket, key.ID, key); err != nil {
+		return nil, fmt.Errorf("failed to store api key: %s", err)
+	}
+	return key, nil
+}
+
+// randomKeyID returns a random 12 letter key ID, in the style of DragonChain's
+// API key IDs.
+func randomKeyID() (string, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	for i, b := range id {
+		id[i] = idAlphabet[int(b)%len(idAlphabet)]
+	}
+	return string(id), nil
+}
+
+// APIKey returns the API key with the provided ID. ErrAPIKeyNotExist is
+// returned if no such key exists.
+func (a *Application) APIKey(id string) (*APIKey, error) {
+	b, err := a.Heap.Get(apiKeyBucket, id)
+	if err == ErrHeapNotExist || (err == nil && len(b) == 0) {
+		return nil, ErrAPIKeyNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	var key APIKey
+	if err := json.Unmarshal(b, &key); err != nil {
+		return nil, fmt.Errorf("failed to decode api key: %s", err)
+	}
+	return &key, nil
+}
+
+// APIKeys returns all stored API keys, excluding revoked ones.
+func (a *Application) APIKeys() ([]*APIKey, error) {
+	all, err := a.Heap.GetAll(apiKeyBucket)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*APIKey, 0, len(all))
+	for _, b := range all {
+		if len(b) == 0 {
+			continue
+		}
+		var key APIKey
+		if err := json.Unmarshal(b, &key); err != nil {
+			return nil, fmt.Errorf("failed to decode api key: %s", err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, nil
+}
+
+// PostAPIKey returns an HTTP handler function that generates a new API key and
+// responds with it, including its secret.
+func (a *Application) PostAPIKey() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := a.GenerateAPIKey()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSONResponse(w, key)
+	}
+}
+
+// DeleteAPIKey returns an HTTP handler function that revokes the requested API key.
+func (a *Application) DeleteAPIKey() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		if _, err := a.APIKey(id); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if err := a.Heap.Delete(apiKeyBucket, id); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// authenticated wraps next so that it is only called for requests signed with a
+// valid API key when RequireAuth is set. Requests are signed using DragonChain's
+// HMAC scheme, so DragonChain SDKs can be used against Hatchery unmodified. The
+// Authorization header has the form "DC1-HMAC-SHA256 <key id>:<signature>", where the
+// signature is the base64 encoded HMAC-SHA256, keyed with the API key's secret, of
+// the newline separated request method, request URI, "dragonchain" header, "timestamp"
+// header, Content-Type header, and base64 encoded SHA256 hash of the request body.
+func (a *Application) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.RequireAuth {
+			next(w, r)
+			return
+		}
+		if err := a.verifySignature(r); err != nil {
+			w.Header().Set("WWW-Authenticate", hmacScheme)
+			writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (a *Application) verifySignature(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, hmacScheme+" ") {
+		return errors.New("missing or unsupported authorization scheme")
+	}
+	creds := strings.SplitN(strings.TrimPrefix(auth, hmacScheme+" "), ":", 2)
+	if len(creds) != 2 {
+		return errors.New("malformed authorization header")
+	}
+	dcID := r.Header.Get("dragonchain")
+	if a.DragonChainID != "" && dcID != a.DragonChainID {
+		return errors.New("dragonchain id does not match")
+	}
+	timestamp := r.Header.Get("timestamp")
+	ts, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+	if skew := time.Since(ts); skew > maxClockSkew || skew < -maxClockSkew {
+		return errors.New("timestamp is too far from the current time")
+	}
+	key, err := a.APIKey(creds[0])
+	if err != nil {
+		return errors.New("invalid api key")
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return errors.New("failed to read request body")
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	expected := SignRequest(key.Key, r.Method, r.URL.RequestURI(), dcID, timestamp, r.Header.Get("Content-Type"), body)
+	if !hmac.Equal([]byte(creds[1]), []byte(expected)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// SignRequest returns the base64 encoded HMAC-SHA256 signature of a request, as
+// expected in the Authorization header by DragonChain and Hatchery.
+func SignRequest(secret, method, uri, dragonchainID, timestamp, contentType string, body []byte) string {
+	return client.SignRequest(secret, method, uri, dragonchainID, timestamp, contentType, body)
+}
@@ -0,0 +1,419 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// Defaults for the value log garbage collection of a BadgerHeap.
+const (
+	DefaultBadgerGCInterval     = 10 * time.Minute
+	DefaultBadgerGCDiscardRatio = 0.5
+)
+
+// Prefixes of the two key spaces of a BadgerHeap. A bucket's marker key records
+// that the bucket exists, and its entries are stored under the bucket's name and a
+// separator, so that a bucket is a contiguous range of keys.
+const (
+	badgerBucketPrefix = "b"
+	badgerEntryPrefix  = "e"
+	badgerSeparator    = "\x00"
+)
+
+// maxBadgerConflictRetries is how many times a read-write transaction that
+// conflicts with a concurrent one is retried.
+const maxBadgerConflictRetries = 5
+
+// BadgerHeap is a Heap implementation backed by BadgerDB. Unlike BoltDB, which
+// serializes every write, BadgerDB commits concurrent writes in parallel, so it
+// suits workloads with heavy write throughput. Buckets are ranges of keys sharing
+// the bucket's name as a prefix, with the same semantics as the buckets of a
+// BoltDBHeap. Bucket names may not contain NUL bytes.
+//
+// BadgerDB keeps values in a log that is only shrunk by garbage collection, which
+// the heap runs every GCInterval, and when it is compacted.
+type BadgerHeap struct {
+	// Path is the directory the database lives in. It is created if it doesn't
+	// exist.
+	Path string
+	// ReadOnly opens the database in read-only mode. It must already exist.
+	// Every write fails.
+	ReadOnly bool
+	// GCInterval is how often value log garbage collection runs. If zero,
+	// DefaultBadgerGCInterval is used. If negative, it only runs when the heap
+	// is compacted.
+	GCInterval time.Duration
+	// GCDiscardRatio is the fraction of a value log file that must be garbage
+	// before the file is rewritten, between 0 and 1. If zero,
+	// DefaultBadgerGCDiscardRatio is used.
+	GCDiscardRatio float64
+
+	once   sync.Once
+	err    error
+	db     *badger.DB
+	gcDone chan struct{}
+	gcWG   sync.WaitGroup
+}
+
+// Put stores the kvp in the given bucket, creating the bucket if it doesn't
+// already exist. If the key already exists in the bucket, it is overwritten.
+func (h *BadgerHeap) Put(bucket, key string, value []byte) error {
+	if strings.Contains(bucket, badgerSeparator) {
+		return fmt.Errorf("put failed: bucket name %q contains a NUL byte", bucket)
+	}
+	if err := h.initOnce(); err != nil {
+		return err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	err := h.update(func(txn *badger.Txn) error {
+		if err := txn.Set(badgerBucketKey(bucket), nil); err != nil {
+			return err
+		}
+		return txn.Set(badgerEntryKey(bucket, key), value)
+	})
+	if err != nil {
+		return fmt.Errorf("put failed: %s", err)
+	}
+	return nil
+}
+
+// CompareAndSwap stores new under key in the given bucket if the key holds old, or if
+// old is nil, if it doesn't exist. The value is read in the transaction that writes
+// it, so a concurrent write to the key makes the transaction conflict and compare
+// again.
+func (h *BadgerHeap) CompareAndSwap(bucket, key string, old, new []byte) (bool, error) {
+	if strings.Contains(bucket, badgerSeparator) {
+		return false, fmt.Errorf("compare and swap failed: bucket name %q contains a NUL byte", bucket)
+	}
+	if err := h.initOnce(); err != nil {
+		return false, err
+	}
+	if new == nil {
+		new = []byte{}
+	}
+	var swapped bool
+	err := h.update(func(txn *badger.Txn) error {
+		swapped = false
+		var current []byte
+		item, err := txn.Get(badgerEntryKey(bucket, key))
+		exists := err == nil
+		switch {
+		case exists:
+			if current, err = item.ValueCopy(nil); err != nil {
+				return err
+			}
+		case err != badger.ErrKeyNotFound:
+			return err
+		}
+		if !casMatches(current, exists, old) {
+			return nil
+		}
+		if err := txn.Set(badgerBucketKey(bucket), nil); err != nil {
+			return err
+		}
+		swapped = true
+		return txn.Set(badgerEntryKey(bucket, key), new)
+	})
+	if err != nil {
+		return false, fmt.Errorf("compare and swap failed: %s", err)
+	}
+	return swapped, nil
+}
+
+// Get returns the value for the provided key and bucket. ErrHeapNotExist is returned
+// if the bucket doesn't exist or has no entry for the requested key.
+func (h *BadgerHeap) Get(bucket, key string) ([]byte, error) {
+	if err := h.initOnce(); err != nil {
+		return nil, err
+	}
+	var b []byte
+	err := h.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(badgerEntryKey(bucket, key))
+		if err == badger.ErrKeyNotFound {
+			return ErrHeapNotExist
+		}
+		if err != nil {
+			return err
+		}
+		b, err = item.ValueCopy(nil)
+		return err
+	})
+	return b, err
+}
+
+// GetAll returns all heap entries in the given bucket. If the bucket doesn't
+// exist, an empty map is returned.
+func (h *BadgerHeap) GetAll(bucket string) (map[string][]byte, error) {
+	return h.GetRange(bucket, "", "")
+}
+
+// Iterate calls fn with each heap entry in the given bucket whose key is at least
+// start, in ascending byte order of keys, until fn returns false. The entries are
+// read from a single read-only transaction, so fn sees a consistent snapshot of the
+// bucket, but must not write to the heap. value is only valid until fn returns. If
+// the bucket doesn't exist, fn is never called.
+func (h *BadgerHeap) Iterate(bucket, start string, fn func(key string, value []byte) bool) error {
+	if err := h.initOnce(); err != nil {
+		return err
+	}
+	prefix := badgerEntryKey(bucket, "")
+	return h.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 100})
+		defer it.Close()
+		for it.Seek(badgerEntryKey(bucket, start)); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			key := string(item.Key()[len(prefix):])
+			more := true
+			if err := item.Value(func(v []byte) error {
+				more = fn(key, v)
+				return nil
+			}); err != nil {
+				return err
+			}
+			if !more {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Keys returns the keys in the given bucket that begin with prefix, in ascending
+// order. If the bucket doesn't exist, an empty slice is returned.
+func (h *BadgerHeap) Keys(bucket, prefix string) ([]string, error) {
+	if err := h.initOnce(); err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	bucketPrefix := badgerEntryKey(bucket, "")
+	err := h.db.View(func(txn *badger.Txn) error {
+		p := badgerEntryKey(bucket, prefix)
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: p})
+		defer it.Close()
+		for it.Rewind(); it.ValidForPrefix(p); it.Next() {
+			keys = append(keys, string(it.Item().Key()[len(bucketPrefix):]))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// GetRange returns the heap entries in the given bucket whose keys are at least start
+// and, if end is not empty, less than end. If the bucket doesn't exist, an empty map
+// is returned.
+func (h *BadgerHeap) GetRange(bucket, start, end string) (map[string][]byte, error) {
+	heap := make(map[string][]byte)
+	err := h.Iterate(bucket, start, func(key string, value []byte) bool {
+		if end != "" && key >= end {
+			return false
+		}
+		v := make([]byte, len(value))
+		copy(v, value)
+		heap[key] = v
+		return true
+	})
+	return heap, err
+}
+
+// Delete removes key from the given bucket. ErrHeapNotExist is returned if the
+// bucket doesn't exist or has no entry for key.
+func (h *BadgerHeap) Delete(bucket, key string) error {
+	if err := h.initOnce(); err != nil {
+		return err
+	}
+	return h.update(func(txn *badger.Txn) error {
+		k := badgerEntryKey(bucket, key)
+		if _, err := txn.Get(k); err == badger.ErrKeyNotFound {
+			return ErrHeapNotExist
+		} else if err != nil {
+			return err
+		}
+		if err := txn.Delete(k); err != nil {
+			return fmt.Errorf("delete failed: %s", err)
+		}
+		return nil
+	})
+}
+
+// DeleteBucket removes the given bucket and its contents. ErrHeapNotExist is
+// returned if the bucket doesn't exist. Writes to the heap wait while the bucket's
+// entries are dropped.
+func (h *BadgerHeap) DeleteBucket(bucket string) error {
+	if err := h.initOnce(); err != nil {
+		return err
+	}
+	err := h.update(func(txn *badger.Txn) error {
+		k := badgerBucketKey(bucket)
+		if _, err := txn.Get(k); err == badger.ErrKeyNotFound {
+			return ErrHeapNotExist
+		} else if err != nil {
+			return err
+		}
+		return txn.Delete(k)
+	})
+	if err != nil {
+		if err == ErrHeapNotExist {
+			return err
+		}
+		return fmt.Errorf("delete bucket failed: %s", err)
+	}
+	// The bucket may be too large to delete in a single transaction, so its entries
+	// are dropped instead.
+	if err := h.db.DropPrefix(badgerEntryKey(bucket, "")); err != nil {
+		return fmt.Errorf("delete bucket failed: %s", err)
+	}
+	return nil
+}
+
+// Buckets returns the names of every bucket in the heap, in ascending order.
+func (h *BadgerHeap) Buckets() ([]string, error) {
+	if err := h.initOnce(); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	err := h.db.View(func(txn *badger.Txn) error {
+		p := []byte(badgerBucketPrefix)
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: p})
+		defer it.Close()
+		for it.Rewind(); it.ValidForPrefix(p); it.Next() {
+			names = append(names, string(it.Item().Key()[len(p):]))
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Compact runs value log garbage collection until no more value log files can be
+// rewritten, and returns the number of bytes reclaimed, as estimated by BadgerDB. An
+// error is returned if the heap is read-only.
+func (h *BadgerHeap) Compact() (int64, error) {
+	if h.ReadOnly {
+		return 0, errors.New("a read-only heap can't be compacted")
+	}
+	if err := h.initOnce(); err != nil {
+		return 0, err
+	}
+	lsm, vlog := h.db.Size()
+	before := lsm + vlog
+	if err := h.collectValueLog(); err != nil {
+		return 0, fmt.Errorf("compaction failed: %s", err)
+	}
+	lsm, vlog = h.db.Size()
+	if n := before - lsm - vlog; n > 0 {
+		return n, nil
+	}
+	return 0, nil
+}
+
+// Close stops value log garbage collection and closes the database.
+func (h *BadgerHeap) Close() error {
+	if h.gcDone != nil {
+		close(h.gcDone)
+		h.gcWG.Wait()
+		h.gcDone = nil
+	}
+	if h.db != nil {
+		return h.db.Close()
+	}
+	return nil
+}
+
+// collectValueLog rewrites value log files until none has enough garbage.
+func (h *BadgerHeap) collectValueLog() error {
+	ratio := h.GCDiscardRatio
+	if ratio <= 0 {
+		ratio = DefaultBadgerGCDiscardRatio
+	}
+	for {
+		err := h.db.RunValueLogGC(ratio)
+		if err == badger.ErrNoRewrite || err == badger.ErrRejected {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// collectGarbage runs value log garbage collection every interval until the heap is
+// closed.
+func (h *BadgerHeap) collectGarbage(interval time.Duration, done <-chan struct{}) {
+	defer h.gcWG.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.collectValueLog()
+		case <-done:
+			return
+		}
+	}
+}
+
+// update runs fn in a read-write transaction, retrying it if it conflicts with a
+// concurrent transaction.
+func (h *BadgerHeap) update(fn func(txn *badger.Txn) error) error {
+	for attempt := 0; ; attempt++ {
+		err := h.db.Update(fn)
+		if err != badger.ErrConflict || attempt >= maxBadgerConflictRetries {
+			return err
+		}
+	}
+}
+
+func (h *BadgerHeap) initOnce() error {
+	h.once.Do(func() {
+		opts := badger.DefaultOptions(h.Path).WithReadOnly(h.ReadOnly).WithLogger(nil)
+		if h.db, h.err = badger.Open(opts); h.err != nil {
+			return
+		}
+		interval := h.GCInterval
+		if interval == 0 {
+			interval = DefaultBadgerGCInterval
+		}
+		if !h.ReadOnly && interval > 0 {
+			h.gcDone = make(chan struct{})
+			h.gcWG.Add(1)
+			go h.collectGarbage(interval, h.gcDone)
+		}
+	})
+	if h.err != nil {
+		return fmt.Errorf("failed to open db at path %s: %s", h.Path, h.err)
+	}
+	return nil
+}
+
+func badgerBucketKey(bucket string) []byte {
+	return []byte(badgerBucketPrefix + bucket)
+}
+
+func badgerEntryKey(bucket, key string) []byte {
+	return []byte(badgerEntryPrefix + bucket + badgerSeparator + key)
+}
@@ -0,0 +1,290 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+const blockBucket = reservedBucketPrefix + "blocks"
+
+// ErrBlockNotExist is returned when a requested block does not exist.
+var ErrBlockNotExist = errors.New("block does not exist")
+
+// Block groups the transactions appended to the ledger during one block interval,
+// in the manner of a DragonChain block. Blocks are chained by hash like transactions,
+// and a block's hash also covers the hashes of its transactions.
+type Block struct {
+	// Index is the position of the block in the chain, starting at 1.
+	Index     uint64    `json:"index"`
+	Timestamp time.Time `json:"timestamp"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+	// Transactions are the IDs of the block's transactions, in ledger order.
+	Transactions []string `json:"transactions"`
+}
+
+type blockResponse struct {
+	*Block
+	Transactions []transactionResponse `json:"transactions"`
+}
+
+// blockHash returns the hex encoded SHA-256 hash of b's PrevHash, Index, Timestamp
+// and the hashes of its transactions, in order.
+func blockHash(b *Block, txnHashes []string) string {
+	h := sha256.New()
+	h.Write([]byte(b.PrevHash))
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], b.Index)
+	h.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(b.Timestamp.UnixNano()))
+	h.Write(buf[:])
+	for _, th := range txnHashes {
+		h.Write([]byte(th))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func blockKey(index uint64) string {
+	return fmt.Sprintf("%020d", index)
+}
+
+// startBlocks loads the latest block and begins bundling transactions into a new
+// block every BlockInterval. Transactions appended after the latest block, such as
+// those appended just before the previous run stopped, go into the next block. It
+// does nothing if BlockInterval is not positive.
+func (a *Application) startBlocks() {
+	if a.BlockInterval <= 0 {
+		return
+	}
+	a.blockOnce.Do(func() {
+		a.blockMu.Lock()
+		defer a.blockMu.Unlock()
+		latest, err := a.latestBlock()
+		if err != nil && err != ErrBlockNotExist {
+			a.log().Error("failed to load latest block", logging.Err(err))
+			return
+		}
+		a.lastBlock = latest
+		if err := a.recoverPendingTxns(); err != nil {
+			a.log().Error("failed to recover unbundled transactions", logging.Err(err))
+		}
+		a.blockDone = make(chan struct{})
+		go a.bundleBlocks(a.blockDone)
+	})
+}
+
+// stopBlocks stops bundling transactions into blocks, after bundling any that are
+// pending into a final block.
+func (a *Application) stopBlocks() {
+	a.blockMu.Lock()
+	if a.blockDone == nil {
+		a.blockMu.Unlock()
+		return
+	}
+	close(a.blockDone)
+	a.blockDone = nil
+	a.blockMu.Unlock()
+	if err := a.bundleBlock(); err != nil {
+		a.log().Error("failed to store block", logging.Err(err))
+	}
+}
+
+// recoverPendingTxns queues every transaction that follows the last transaction
+// of the latest block. a.blockMu must be held.
+func (a *Application) recoverPendingTxns() error {
+	after := ""
+	if a.lastBlock != nil && len(a.lastBlock.Transactions) > 0 {
+		after = a.lastBlock.Transactions[len(a.lastBlock.Transactions)-1]
+	}
+	found := after == ""
+	return a.Ledger.Iterate(func(t *Transaction) bool {
+		if found {
+			a.pendingTxns = append(a.pendingTxns, t)
+		}
+		found = found || t.ID == after
+		return true
+	})
+}
+
+func (a *Application) bundleBlocks(done chan struct{}) {
+	ticker := time.NewTicker(a.BlockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.bundleBlock(); err != nil {
+				a.log().Error("failed to store block", logging.Err(err))
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// addToBlock queues ts for the next block, if blocks are being bundled.
+func (a *Application) addToBlock(ts ...*Transaction) {
+	a.blockMu.Lock()
+	defer a.blockMu.Unlock()
+	if a.blockDone != nil {
+		a.pendingTxns = append(a.pendingTxns, ts...)
+	}
+}
+
+// bundleBlock stores the transactions appended since the last block in a new block.
+// No block is stored if there are none. In a cluster, only the leader bundles blocks,
+// and since every node appends to the ledger, it reads the transactions that follow
+// the latest block from the ledger rather than bundling those it appended itself.
+func (a *Application) bundleBlock() error {
+	a.blockMu.Lock()
+	defer a.blockMu.Unlock()
+	if a.Elector != nil {
+		a.pendingTxns = nil
+		if !a.leading() {
+			return nil
+		}
+		latest, err := a.latestBlock()
+		if err != nil && err != ErrBlockNotExist {
+			return err
+		}
+		a.lastBlock = latest
+		if err := a.recoverPendingTxns(); err != nil {
+			return err
+		}
+	}
+	if len(a.pendingTxns) == 0 {
+		return nil
+	}
+	b := &Block{
+		Index:        1,
+		Timestamp:    time.Now().UTC(),
+		Transactions: make([]string, len(a.pendingTxns)),
+	}
+	if a.lastBlock != nil {
+		b.Index = a.lastBlock.Index + 1
+		b.PrevHash = a.lastBlock.Hash
+	}
+	hashes := make([]string, len(a.pendingTxns))
+	for i, t := range a.pendingTxns {
+		b.Transactions[i] = t.ID
+		hashes[i] = t.Hash
+	}
+	b.Hash = blockHash(b, hashes)
+	if err := a.putJSON(blockBucket, blockKey(b.Index), b); err != nil {
+		return err
+	}
+	a.lastBlock = b
+	a.pendingTxns = nil
+	return nil
+}
+
+// Block returns the block with the given index. ErrBlockNotExist is returned if
+// there is no such block.
+func (a *Application) Block(index uint64) (*Block, error) {
+	v, err := a.Heap.Get(blockBucket, blockKey(index))
+	if err == ErrHeapNotExist || (err == nil && len(v) == 0) {
+		return nil, ErrBlockNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	var b Block
+	if err := json.Unmarshal(v, &b); err != nil {
+		return nil, fmt.Errorf("failed to decode block: %s", err)
+	}
+	return &b, nil
+}
+
+// latestBlock returns the block with the highest index. ErrBlockNotExist is
+// returned if no blocks have been stored.
+func (a *Application) latestBlock() (*Block, error) {
+	keys, err := a.Heap.Keys(blockBucket, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrBlockNotExist
+	}
+	index, err := strconv.ParseUint(keys[len(keys)-1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block key %q", keys[len(keys)-1])
+	}
+	return a.Block(index)
+}
+
+// GetBlock returns an HTTP handler function that responds with the requested block,
+// including the content of its transactions, encoded as requested. See contentEncoding.
+// The block is identified by its index, or by "latest" for the most recent block.
+// Transactions that have been pruned from the ledger are left out.
+func (a *Application) GetBlock() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding, ok := contentEncoding(w, r)
+		if !ok {
+			return
+		}
+		id := mux.Vars(r)["id"]
+		var (
+			b   *Block
+			err error
+		)
+		if id == "latest" {
+			b, err = a.latestBlock()
+		} else {
+			index, perr := strconv.ParseUint(id, 10, 64)
+			if perr != nil {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "block id must be an index or \"latest\"")
+				return
+			}
+			b, err = a.Block(index)
+		}
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		resp := blockResponse{
+			Block:        b,
+			Transactions: make([]transactionResponse, 0, len(b.Transactions)),
+		}
+		for _, id := range b.Transactions {
+			t, err := a.Ledger.Find(id)
+			if err == ErrTransactionNotExist {
+				// The transaction has been pruned from the ledger.
+				continue
+			}
+			if err != nil {
+				writeErrorFrom(w, err)
+				return
+			}
+			resp.Transactions = append(resp.Transactions, newTransactionResponse(t, encoding))
+		}
+		writeJSONResponse(w, resp)
+	}
+}
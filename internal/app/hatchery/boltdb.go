@@ -20,10 +20,41 @@
 package hatchery
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/boltdb/bolt"
+	"github.com/google/uuid"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// Buckets reserved for Hatchery's internal use. These share the BoltDB file with
+// the heap buckets of smart contracts.
+const (
+	ledgerBucket       = reservedBucketPrefix + "ledger"
+	ledgerIndexBucket  = reservedBucketPrefix + "ledger_index"
+	ledgerBlobBucket   = reservedBucketPrefix + "ledger_blobs"
+	ledgerPrunedBucket = reservedBucketPrefix + "ledger_pruned"
+	ledgerChunkBucket  = reservedBucketPrefix + "ledger_blob_chunks"
+)
+
+// pendingChunksPrefix prefixes the keys of the chunk lists of blobs that are still
+// being stored by PutBlob, whose address isn't known yet.
+const pendingChunksPrefix = "pending/"
+
+// Keys of the ledger's pruned bucket.
+var (
+	prunedHashKey  = []byte("hash")
+	prunedCountKey = []byte("count")
 )
 
 // BoltDBHeap is a Heap implementation backed by BoltDB.
@@ -33,9 +64,17 @@ type BoltDBHeap struct {
 	// created automatically. Otherwise, it will just be used
 	// as-is.
 	Path string
+	// ReadOnly opens the BoltDB file in read-only mode. The file must already
+	// exist. Only a shared lock is taken on the file, so several read-only
+	// handles may be open at once, but not alongside a read-write handle.
+	// Every write fails.
+	ReadOnly bool
 
 	once sync.Once
-	db   *bolt.DB
+	// mu is held for reading while db is in use, and for writing while Compact
+	// replaces it.
+	mu sync.RWMutex
+	db *bolt.DB
 }
 
 // Put stores the kvp in the given BoltDB bucket. If the bucket doesn't
@@ -46,7 +85,7 @@ func (c *BoltDBHeap) Put(bucket, key string, value []byte) error {
 	if err := c.initOnce(); err != nil {
 		return err
 	}
-	err := c.db.Update(func(tx *bolt.Tx) error {
+	err := c.update(func(tx *bolt.Tx) error {
 		buck, e := tx.CreateBucketIfNotExists([]byte(bucket))
 		if e != nil {
 			return e
@@ -59,19 +98,50 @@ func (c *BoltDBHeap) Put(bucket, key string, value []byte) error {
 	return nil
 }
 
-// Get returns the value for the provided key and bucket. If the bucket doesn't
-// already exist, it will be created automatically. ErrHeapNotExist is returned if
-// No entry in the heap bucket for the requested key. Otherwise, an error is returned
-// only if the bucket could not be created.
+// CompareAndSwap stores new under key in the given BoltDB bucket if the key holds old,
+// or if old is nil, if it doesn't exist. The comparison and the write happen in a
+// single transaction. The bucket is only created if the swap succeeds. An error is
+// returned if the transaction fails.
+func (c *BoltDBHeap) CompareAndSwap(bucket, key string, old, new []byte) (bool, error) {
+	if err := c.initOnce(); err != nil {
+		return false, err
+	}
+	swapped := false
+	err := c.update(func(tx *bolt.Tx) error {
+		var current []byte
+		buck := tx.Bucket([]byte(bucket))
+		if buck != nil {
+			current = buck.Get([]byte(key))
+		}
+		if !casMatches(current, current != nil, old) {
+			return nil
+		}
+		if buck == nil {
+			var e error
+			if buck, e = tx.CreateBucket([]byte(bucket)); e != nil {
+				return e
+			}
+		}
+		swapped = true
+		return buck.Put([]byte(key), new)
+	})
+	if err != nil {
+		return false, fmt.Errorf("compare and swap failed: %s", err)
+	}
+	return swapped, nil
+}
+
+// Get returns the value for the provided key and bucket. ErrHeapNotExist is returned
+// if the bucket doesn't exist or has no entry for the requested key.
 func (c *BoltDBHeap) Get(bucket, key string) ([]byte, error) {
 	if err := c.initOnce(); err != nil {
 		return nil, err
 	}
 	var b []byte
-	err := c.db.View(func(tx *bolt.Tx) error {
-		buck, e := tx.CreateBucketIfNotExists([]byte(bucket))
-		if e != nil {
-			return e
+	err := c.view(func(tx *bolt.Tx) error {
+		buck := tx.Bucket([]byte(bucket))
+		if buck == nil {
+			return ErrHeapNotExist
 		}
 		vb := buck.Get([]byte(key))
 		if vb == nil {
@@ -85,48 +155,260 @@ func (c *BoltDBHeap) Get(bucket, key string) ([]byte, error) {
 }
 
 // GetAll returns all heap entries in the given bucket. If the bucket doesn't
-// already exist, it will be created automatically. An error is only returned if
-// the bucket cannot be created.
+// exist, an empty map is returned.
 func (c *BoltDBHeap) GetAll(bucket string) (map[string][]byte, error) {
+	heap := make(map[string][]byte)
+	err := c.Iterate(bucket, "", func(key string, value []byte) bool {
+		v := make([]byte, len(value))
+		copy(v, value)
+		heap[key] = v
+		return true
+	})
+	return heap, err
+}
+
+// Iterate calls fn with each heap entry in the given bucket whose key is at least
+// start, in ascending byte order of keys, until fn returns false. The entries are
+// read from a single read-only transaction, so fn sees a consistent snapshot of
+// the bucket, but must not write to the heap. value is only valid until fn returns.
+// If the bucket doesn't exist, fn is never called.
+func (c *BoltDBHeap) Iterate(bucket, start string, fn func(key string, value []byte) bool) error {
+	if err := c.initOnce(); err != nil {
+		return err
+	}
+	return c.view(func(tx *bolt.Tx) error {
+		buck := tx.Bucket([]byte(bucket))
+		if buck == nil {
+			return nil
+		}
+		iterateCursor(buck.Cursor(), []byte(start), func(k, v []byte) bool {
+			return fn(string(k), v)
+		})
+		return nil
+	})
+}
+
+// iterateCursor calls fn with each key value pair of the cursor's bucket whose key
+// is at least start, in the cursor's ascending key order, until fn returns false.
+// Nested buckets, whose values are nil, are skipped rather than ending the walk.
+func iterateCursor(curr *bolt.Cursor, start []byte, fn func(k, v []byte) bool) {
+	for k, v := curr.Seek(start); k != nil; k, v = curr.Next() {
+		if v == nil {
+			continue
+		}
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Keys returns the keys in the given bucket that begin with prefix, in ascending
+// order. If the bucket doesn't exist, an empty slice is returned.
+func (c *BoltDBHeap) Keys(bucket, prefix string) ([]string, error) {
 	if err := c.initOnce(); err != nil {
 		return nil, err
 	}
-	heap := make(map[string][]byte)
-	err := c.db.View(func(tx *bolt.Tx) error {
-		buck, e := tx.CreateBucketIfNotExists([]byte(bucket))
-		if e != nil {
-			return e
+	keys := []string{}
+	err := c.view(func(tx *bolt.Tx) error {
+		buck := tx.Bucket([]byte(bucket))
+		if buck == nil {
+			return nil
+		}
+		p := []byte(prefix)
+		curr := buck.Cursor()
+		for k, _ := curr.Seek(p); k != nil && bytes.HasPrefix(k, p); k, _ = curr.Next() {
+			keys = append(keys, string(k))
 		}
+		return nil
+	})
+	return keys, err
+}
 
+// GetRange returns the heap entries in the given bucket whose keys are at least start
+// and, if end is not empty, less than end. If the bucket doesn't exist, an empty map
+// is returned.
+func (c *BoltDBHeap) GetRange(bucket, start, end string) (map[string][]byte, error) {
+	if err := c.initOnce(); err != nil {
+		return nil, err
+	}
+	heap := make(map[string][]byte)
+	err := c.view(func(tx *bolt.Tx) error {
+		buck := tx.Bucket([]byte(bucket))
+		if buck == nil {
+			return nil
+		}
 		curr := buck.Cursor()
-		for {
-			k, v := curr.Next()
-			if k == nil || v == nil {
+		for k, v := curr.Seek([]byte(start)); k != nil; k, v = curr.Next() {
+			if end != "" && string(k) >= end {
 				break
 			}
-			kc := make([]byte, len(k))
-			copy(kc, k)
 			vc := make([]byte, len(v))
 			copy(vc, v)
-			heap[string(kc)] = vc
+			heap[string(k)] = vc
 		}
 		return nil
 	})
 	return heap, err
 }
 
+// Delete removes key from the given bucket. ErrHeapNotExist is returned if the
+// bucket doesn't exist or has no entry for key.
+func (c *BoltDBHeap) Delete(bucket, key string) error {
+	if err := c.initOnce(); err != nil {
+		return err
+	}
+	return c.update(func(tx *bolt.Tx) error {
+		buck := tx.Bucket([]byte(bucket))
+		if buck == nil || buck.Get([]byte(key)) == nil {
+			return ErrHeapNotExist
+		}
+		if err := buck.Delete([]byte(key)); err != nil {
+			return fmt.Errorf("delete failed: %s", err)
+		}
+		return nil
+	})
+}
+
+// DeleteBucket removes the given bucket and its contents. ErrHeapNotExist is
+// returned if the bucket doesn't exist.
+func (c *BoltDBHeap) DeleteBucket(bucket string) error {
+	if err := c.initOnce(); err != nil {
+		return err
+	}
+	return c.update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(bucket))
+		if err == bolt.ErrBucketNotFound {
+			return ErrHeapNotExist
+		}
+		if err != nil {
+			return fmt.Errorf("delete bucket failed: %s", err)
+		}
+		return nil
+	})
+}
+
+// Buckets returns the names of every bucket in the BoltDB file, in ascending order.
+func (c *BoltDBHeap) Buckets() ([]string, error) {
+	if err := c.initOnce(); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	err := c.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, string(name))
+			return nil
+		})
+	})
+	return names, err
+}
+
 // Close closes the BoltDB handle.
 func (c *BoltDBHeap) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.db != nil {
 		return c.db.Close()
 	}
 	return nil
 }
 
+// Compact rewrites the BoltDB file without the free pages left behind by deleted
+// and overwritten values, which BoltDB never returns to the filesystem, and returns
+// the number of bytes reclaimed. Reads and writes wait until it finishes. An error
+// is returned if the heap is read-only or the file could not be rewritten.
+func (c *BoltDBHeap) Compact() (int64, error) {
+	if c.ReadOnly {
+		return 0, errors.New("a read-only heap can't be compacted")
+	}
+	if err := c.initOnce(); err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	before, err := os.Stat(c.Path)
+	if err != nil {
+		return 0, fmt.Errorf("compaction failed: %s", err)
+	}
+	tmp := c.Path + ".compact"
+	os.Remove(tmp)
+	dst, err := bolt.Open(tmp, 0600, nil)
+	if err != nil {
+		return 0, fmt.Errorf("compaction failed: %s", err)
+	}
+	err = c.db.View(func(tx *bolt.Tx) error {
+		return dst.Update(func(dtx *bolt.Tx) error {
+			return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				db, err := dtx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+				return copyBucket(db, b)
+			})
+		})
+	})
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("compaction failed: %s", err)
+	}
+	if err := c.db.Close(); err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("compaction failed: %s", err)
+	}
+	renameErr := os.Rename(tmp, c.Path)
+	// The database is reopened even if the rename failed, so that the heap
+	// remains usable with the uncompacted file.
+	if c.db, err = bolt.Open(c.Path, 0600, nil); err != nil {
+		return 0, fmt.Errorf("failed to reopen db at path %s: %s", c.Path, err)
+	}
+	if renameErr != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("compaction failed: %s", renameErr)
+	}
+	after, err := os.Stat(c.Path)
+	if err != nil {
+		return 0, fmt.Errorf("compaction failed: %s", err)
+	}
+	return before.Size() - after.Size(), nil
+}
+
+// copyBucket copies the keys, nested buckets and sequence of src into dst.
+func copyBucket(dst, src *bolt.Bucket) error {
+	if err := dst.SetSequence(src.Sequence()); err != nil {
+		return err
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(nested, src.Bucket(k))
+	})
+}
+
+// view runs fn in a read-only transaction. Compact waits until it returns.
+func (c *BoltDBHeap) view(fn func(tx *bolt.Tx) error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.db.View(fn)
+}
+
+// update runs fn in a read-write transaction. Compact waits until it returns.
+func (c *BoltDBHeap) update(fn func(tx *bolt.Tx) error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.db.Update(fn)
+}
+
 func (c *BoltDBHeap) initOnce() error {
 	var err error
 	c.once.Do(func() {
-		c.db, err = bolt.Open(c.Path, 0600, nil)
+		c.db, err = bolt.Open(c.Path, 0600, &bolt.Options{ReadOnly: c.ReadOnly})
 		if err != nil {
 			return
 		}
@@ -136,3 +418,557 @@ func (c *BoltDBHeap) initOnce() error {
 	}
 	return nil
 }
+
+// BoltDBLedger is a Ledger implementation backed by BoltDB. Transactions are
+// stored in a dedicated bucket of the same BoltDB file used by a BoltDBHeap,
+// keyed by their position in the ledger so that iteration follows append order.
+// A secondary bucket indexes transactions by ID. Large content and payloads are
+// stored once in a third bucket, keyed by their content address, and referenced
+// from the transactions. See backend.SplitBlobs. A fourth bucket remembers what
+// Prune removed, and a fifth lists the chunks of the blobs stored with PutBlob.
+type BoltDBLedger struct {
+	// Heap is the BoltDBHeap whose database file the ledger is stored in.
+	// BoltDB only permits a single open handle per file, so the ledger must
+	// share it with the heap.
+	Heap *BoltDBHeap
+	// Namespace is prepended to the names of the ledger's buckets, so that several
+	// ledgers can share a BoltDB file.
+	Namespace string
+
+	once sync.Once
+	err  error
+}
+
+// Head returns the first transaction in the ledger. If the ledger is empty, nil
+// is returned instead. An error is returned if the database could not be read.
+func (l *BoltDBLedger) Head() (*Transaction, error) {
+	if err := l.initOnce(); err != nil {
+		return nil, err
+	}
+	var t *Transaction
+	err := l.Heap.view(func(tx *bolt.Tx) error {
+		buck := tx.Bucket(l.bucket())
+		if buck == nil {
+			return nil
+		}
+		_, v := buck.Cursor().First()
+		if v == nil {
+			return nil
+		}
+		var e error
+		t, e = l.decode(tx, v)
+		return e
+	})
+	return t, err
+}
+
+// Find looks up the transaction with the provided ID using the ID index.
+// ErrTransactionNotExist is returned if no such transaction exists.
+func (l *BoltDBLedger) Find(id string) (*Transaction, error) {
+	if err := l.initOnce(); err != nil {
+		return nil, err
+	}
+	var t *Transaction
+	err := l.Heap.view(func(tx *bolt.Tx) error {
+		buck, idx := tx.Bucket(l.bucket()), tx.Bucket(l.indexBucket())
+		if buck == nil || idx == nil {
+			return ErrTransactionNotExist
+		}
+		seq := idx.Get([]byte(id))
+		if seq == nil {
+			return ErrTransactionNotExist
+		}
+		v := buck.Get(seq)
+		if v == nil {
+			return ErrTransactionNotExist
+		}
+		var e error
+		t, e = l.decode(tx, v)
+		return e
+	})
+	return t, err
+}
+
+// Append links each transaction to the current tail, stores it at the end of the
+// ledger and indexes it by ID, all within a single BoltDB transaction.
+// An error is returned, and nothing is appended, if a transaction with the same ID
+// already exists or a transaction could not be written.
+func (l *BoltDBLedger) Append(ts ...*Transaction) error {
+	if err := l.initOnce(); err != nil {
+		return err
+	}
+	err := l.Heap.update(func(tx *bolt.Tx) error {
+		buck := tx.Bucket(l.bucket())
+		idx := tx.Bucket(l.indexBucket())
+		blobs := tx.Bucket(l.blobBucket())
+		// Only the hash of the tail is needed, so its blobs aren't read.
+		var prev *Transaction
+		if _, pv := buck.Cursor().Last(); pv != nil {
+			var e error
+			if prev, e = decodeTransaction(pv); e != nil {
+				return e
+			}
+		}
+		for _, t := range ts {
+			if idx.Get([]byte(t.ID)) != nil {
+				return fmt.Errorf("transaction %s already exists", t.ID)
+			}
+			t.Link(prev)
+			stored, split := backend.SplitBlobs(t)
+			for addr, blob := range split {
+				if blobs.Get([]byte(addr)) != nil {
+					continue
+				}
+				if e := blobs.Put([]byte(addr), blob); e != nil {
+					return e
+				}
+			}
+			v, e := encodeTransaction(stored)
+			if e != nil {
+				return e
+			}
+			n, e := buck.NextSequence()
+			if e != nil {
+				return e
+			}
+			seq := seqKey(n)
+			if e := buck.Put(seq, v); e != nil {
+				return e
+			}
+			if e := idx.Put([]byte(t.ID), seq); e != nil {
+				return e
+			}
+			prev = t
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("append failed: %s", err)
+	}
+	return nil
+}
+
+// Iterate calls fn for each transaction in append order until fn returns false.
+// Iteration happens inside a read-only BoltDB transaction, so fn must not modify
+// the ledger.
+func (l *BoltDBLedger) Iterate(fn func(t *Transaction) bool) error {
+	if err := l.initOnce(); err != nil {
+		return err
+	}
+	return l.Heap.view(func(tx *bolt.Tx) error {
+		return l.iterate(tx, fn)
+	})
+}
+
+// Verify walks the ledger and reports the first broken link in the chain of
+// transaction hashes, if any.
+func (l *BoltDBLedger) Verify() error {
+	if err := l.initOnce(); err != nil {
+		return err
+	}
+	return l.Heap.view(func(tx *bolt.Tx) error {
+		var anchor string
+		if pruned := tx.Bucket(l.prunedBucket()); pruned != nil {
+			anchor = string(pruned.Get(prunedHashKey))
+		}
+		return backend.VerifyChainFrom(anchor, func(fn func(t *Transaction) bool) error {
+			return l.iterate(tx, fn)
+		})
+	})
+}
+
+// Prune removes every transaction up to and including the one with the given ID,
+// along with the blobs no remaining transaction references, in a single BoltDB
+// transaction. ErrTransactionNotExist is returned if there is no such transaction,
+// and ErrPruneLatest if it is the latest.
+func (l *BoltDBLedger) Prune(throughID string) (int, error) {
+	if err := l.initOnce(); err != nil {
+		return 0, err
+	}
+	var n int
+	err := l.Heap.update(func(tx *bolt.Tx) error {
+		buck, idx := tx.Bucket(l.bucket()), tx.Bucket(l.indexBucket())
+		blobs, pruned := tx.Bucket(l.blobBucket()), tx.Bucket(l.prunedBucket())
+		through := idx.Get([]byte(throughID))
+		if through == nil {
+			return ErrTransactionNotExist
+		}
+		through = append([]byte(nil), through...)
+		if last, _ := buck.Cursor().Last(); bytes.Equal(last, through) {
+			return backend.ErrPruneLatest
+		}
+		var (
+			keys   [][]byte
+			anchor string
+		)
+		curr := buck.Cursor()
+		for k, v := curr.First(); k != nil && bytes.Compare(k, through) <= 0; k, v = curr.Next() {
+			t, e := decodeTransaction(v)
+			if e != nil {
+				return e
+			}
+			if e := idx.Delete([]byte(t.ID)); e != nil {
+				return e
+			}
+			keys = append(keys, append([]byte(nil), k...))
+			anchor = t.Hash
+		}
+		for _, k := range keys {
+			if e := buck.Delete(k); e != nil {
+				return e
+			}
+		}
+		n = len(keys)
+		count := n
+		if v := pruned.Get(prunedCountKey); v != nil {
+			count += int(binary.BigEndian.Uint64(v))
+		}
+		if e := pruned.Put(prunedHashKey, []byte(anchor)); e != nil {
+			return e
+		}
+		if e := pruned.Put(prunedCountKey, seqKey(uint64(count))); e != nil {
+			return e
+		}
+		return l.sweepBlobs(buck, blobs, tx.Bucket(l.chunkBucket()))
+	})
+	if err == ErrTransactionNotExist || err == backend.ErrPruneLatest {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("prune failed: %s", err)
+	}
+	return n, nil
+}
+
+// Pruned returns the hash of the last transaction pruned and how many have been
+// pruned.
+func (l *BoltDBLedger) Pruned() (string, int, error) {
+	if err := l.initOnce(); err != nil {
+		return "", 0, err
+	}
+	var (
+		hash  string
+		count int
+	)
+	err := l.Heap.view(func(tx *bolt.Tx) error {
+		pruned := tx.Bucket(l.prunedBucket())
+		if pruned == nil {
+			return nil
+		}
+		hash = string(pruned.Get(prunedHashKey))
+		if v := pruned.Get(prunedCountKey); v != nil {
+			count = int(binary.BigEndian.Uint64(v))
+		}
+		return nil
+	})
+	return hash, count, err
+}
+
+// sweepBlobs deletes the blobs that no transaction in buck references, along with
+// the chunk lists in chunks of the blobs stored with PutBlob that no transaction has
+// referenced within blobGracePeriod, and their chunks.
+func (l *BoltDBLedger) sweepBlobs(buck, blobs, chunks *bolt.Bucket) error {
+	referenced := map[string]bool{}
+	err := buck.ForEach(func(k, v []byte) error {
+		t, e := decodeTransaction(v)
+		if e != nil {
+			return e
+		}
+		referenced[t.ContentRef], referenced[t.PayloadRef], referenced[t.OutputRef] = true, true, true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var expired [][]byte
+	cutoff := time.Now().Add(-blobGracePeriod)
+	err = chunks.ForEach(func(k, v []byte) error {
+		stored, list := decodeChunkList(v)
+		if !referenced[string(k)] && stored.Before(cutoff) {
+			expired = append(expired, append([]byte(nil), k...))
+			return nil
+		}
+		for _, c := range list {
+			referenced[c] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range expired {
+		if err := chunks.Delete(k); err != nil {
+			return err
+		}
+	}
+	var unreferenced [][]byte
+	err = blobs.ForEach(func(k, v []byte) error {
+		if !referenced[string(k)] {
+			unreferenced = append(unreferenced, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range unreferenced {
+		if err := blobs.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterate calls fn for each transaction in append order, within tx, until fn
+// returns false.
+func (l *BoltDBLedger) iterate(tx *bolt.Tx, fn func(t *Transaction) bool) error {
+	buck := tx.Bucket(l.bucket())
+	if buck == nil {
+		return nil
+	}
+	curr := buck.Cursor()
+	for k, v := curr.First(); k != nil; k, v = curr.Next() {
+		t, e := l.decode(tx, v)
+		if e != nil {
+			return e
+		}
+		if !fn(t) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *BoltDBLedger) bucket() []byte {
+	return []byte(l.Namespace + ledgerBucket)
+}
+
+func (l *BoltDBLedger) indexBucket() []byte {
+	return []byte(l.Namespace + ledgerIndexBucket)
+}
+
+func (l *BoltDBLedger) blobBucket() []byte {
+	return []byte(l.Namespace + ledgerBlobBucket)
+}
+
+func (l *BoltDBLedger) prunedBucket() []byte {
+	return []byte(l.Namespace + ledgerPrunedBucket)
+}
+
+func (l *BoltDBLedger) chunkBucket() []byte {
+	return []byte(l.Namespace + ledgerChunkBucket)
+}
+
+// PutBlob implements backend.BlobStore. The blob is split into chunks with
+// backend.WriteChunks, which are stored in the blob bucket like the blobs of
+// transactions, each in its own BoltDB transaction so that no more than a chunk is
+// held in memory. The list of its chunks is then stored under its address.
+func (l *BoltDBLedger) PutBlob(r io.Reader) (string, int64, error) {
+	if err := l.initOnce(); err != nil {
+		return "", 0, err
+	}
+	// The chunks stored so far are listed under a pending key, so that Prune
+	// keeps them while the rest are stored.
+	pending := []byte(pendingChunksPrefix + uuid.New().String())
+	var stored []string
+	addr, list, size, err := backend.WriteChunks(r, func(addr string, chunk []byte) error {
+		stored = append(stored, addr)
+		return l.Heap.update(func(tx *bolt.Tx) error {
+			if blobs := tx.Bucket(l.blobBucket()); blobs.Get([]byte(addr)) == nil {
+				if e := blobs.Put([]byte(addr), chunk); e != nil {
+					return e
+				}
+			}
+			return tx.Bucket(l.chunkBucket()).Put(pending, encodeChunkList(time.Now(), stored))
+		})
+	})
+	if err == nil {
+		err = l.Heap.update(func(tx *bolt.Tx) error {
+			chunks := tx.Bucket(l.chunkBucket())
+			if e := chunks.Put([]byte(addr), encodeChunkList(time.Now(), list)); e != nil {
+				return e
+			}
+			return chunks.Delete(pending)
+		})
+	}
+	if err != nil {
+		if len(stored) > 0 {
+			// Prune removes the chunks once their pending list is gone.
+			l.Heap.update(func(tx *bolt.Tx) error {
+				return tx.Bucket(l.chunkBucket()).Delete(pending)
+			})
+		}
+		return "", 0, fmt.Errorf("failed to store blob: %s", err)
+	}
+	return addr, size, nil
+}
+
+// OpenBlob implements backend.BlobStore. Each chunk of the blob is read in its own
+// BoltDB transaction as the blob is read.
+func (l *BoltDBLedger) OpenBlob(address string) (io.ReadCloser, error) {
+	if err := l.initOnce(); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(address, pendingChunksPrefix) {
+		return nil, backend.ErrBlobNotExist
+	}
+	var list []string
+	err := l.Heap.view(func(tx *bolt.Tx) error {
+		var v []byte
+		if chunks := tx.Bucket(l.chunkBucket()); chunks != nil {
+			v = chunks.Get([]byte(address))
+		}
+		if v == nil {
+			return backend.ErrBlobNotExist
+		}
+		_, list = decodeChunkList(v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(backend.NewChunkReader(address, list, func(addr string) ([]byte, error) {
+		var chunk []byte
+		err := l.Heap.view(func(tx *bolt.Tx) error {
+			if blobs := tx.Bucket(l.blobBucket()); blobs != nil {
+				// Values are only valid for the life of the BoltDB transaction.
+				chunk = append([]byte(nil), blobs.Get([]byte(addr))...)
+			}
+			return nil
+		})
+		return chunk, err
+	})), nil
+}
+
+// encodeChunkList encodes the addresses of a blob's chunks, preceded by the time
+// they were stored.
+func encodeChunkList(stored time.Time, chunks []string) []byte {
+	v := seqKey(uint64(stored.UnixNano()))
+	for _, c := range chunks {
+		v = append(v, c...)
+	}
+	return v
+}
+
+func decodeChunkList(v []byte) (time.Time, []string) {
+	if len(v) < 8 {
+		return time.Time{}, nil
+	}
+	stored := time.Unix(0, int64(binary.BigEndian.Uint64(v[:8])))
+	var chunks []string
+	for rest := v[8:]; len(rest) >= blobAddressLen; rest = rest[blobAddressLen:] {
+		chunks = append(chunks, string(rest[:blobAddressLen]))
+	}
+	return stored, chunks
+}
+
+// blobAddressLen is the length of a backend.BlobAddress.
+const blobAddressLen = 64
+
+// decode decodes a stored transaction and restores the blobs it references from
+// the blob bucket of tx.
+func (l *BoltDBLedger) decode(tx *bolt.Tx, v []byte) (*Transaction, error) {
+	t, err := decodeTransaction(v)
+	if err != nil {
+		return nil, err
+	}
+	blobs := tx.Bucket(l.blobBucket())
+	err = backend.JoinBlobs(t, func(addr string) ([]byte, error) {
+		if blobs == nil {
+			return nil, nil
+		}
+		// Values are only valid for the life of the BoltDB transaction.
+		return append([]byte(nil), blobs.Get([]byte(addr))...), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (l *BoltDBLedger) initOnce() error {
+	l.once.Do(func() {
+		if l.Heap == nil {
+			l.err = errors.New("ledger has no backing heap")
+			return
+		}
+		if l.err = l.Heap.initOnce(); l.err != nil {
+			return
+		}
+		if l.Heap.ReadOnly {
+			// The ledger can't be repaired without writing, so it is read as-is.
+			return
+		}
+		l.err = l.recover()
+	})
+	return l.err
+}
+
+// recover makes sure the ledger buckets exist and that the ID index agrees with
+// the ledger itself. If they disagree, which may happen if the file was modified
+// externally, the index is rebuilt from the ledger.
+func (l *BoltDBLedger) recover() error {
+	err := l.Heap.update(func(tx *bolt.Tx) error {
+		buck, e := tx.CreateBucketIfNotExists(l.bucket())
+		if e != nil {
+			return e
+		}
+		idx, e := tx.CreateBucketIfNotExists(l.indexBucket())
+		if e != nil {
+			return e
+		}
+		if _, e := tx.CreateBucketIfNotExists(l.blobBucket()); e != nil {
+			return e
+		}
+		if _, e := tx.CreateBucketIfNotExists(l.prunedBucket()); e != nil {
+			return e
+		}
+		if _, e := tx.CreateBucketIfNotExists(l.chunkBucket()); e != nil {
+			return e
+		}
+		if idx.Stats().KeyN == buck.Stats().KeyN {
+			return nil
+		}
+		if e := tx.DeleteBucket(l.indexBucket()); e != nil {
+			return e
+		}
+		if idx, e = tx.CreateBucket(l.indexBucket()); e != nil {
+			return e
+		}
+		return buck.ForEach(func(k, v []byte) error {
+			t, e := decodeTransaction(v)
+			if e != nil {
+				return e
+			}
+			return idx.Put([]byte(t.ID), k)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to recover ledger: %s", err)
+	}
+	return nil
+}
+
+func seqKey(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
+
+// Transactions are gob encoded, rather than JSON encoded, because the JSON
+// representation of a Transaction intentionally omits its content.
+func encodeTransaction(t *Transaction) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(t); err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeTransaction(b []byte) (*Transaction, error) {
+	var t Transaction
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %s", err)
+	}
+	return &t, nil
+}
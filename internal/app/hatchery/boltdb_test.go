@@ -0,0 +1,187 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+func newTestBoltDBHeap(t *testing.T, entries map[string]string) *BoltDBHeap {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "hatchery-bolt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	heap := &BoltDBHeap{Path: filepath.Join(dir, "heap.db")}
+	t.Cleanup(func() {
+		heap.Close()
+		os.RemoveAll(dir)
+	})
+	for k, v := range entries {
+		if err := heap.Put("bucket", k, []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return heap
+}
+
+var testBoltDBEntries = map[string]string{"b": "2", "a": "1", "d": "4", "c": "3"}
+
+func TestBoltDBHeapGetAll(t *testing.T) {
+	heap := newTestBoltDBHeap(t, testBoltDBEntries)
+	got, err := heap.GetAll("bucket")
+	if err != nil {
+		t.Fatalf("GetAll() failed: %s", err)
+	}
+	want := map[string][]byte{"a": []byte("1"), "b": []byte("2"), "c": []byte("3"), "d": []byte("4")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAll() = %q, want %q", got, want)
+	}
+	got, err = heap.GetAll("missing")
+	if err != nil {
+		t.Fatalf("GetAll() of a missing bucket failed: %s", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("GetAll() of a missing bucket = %q, want no entries", got)
+	}
+}
+
+func TestBoltDBHeapIterate(t *testing.T) {
+	heap := newTestBoltDBHeap(t, testBoltDBEntries)
+	tests := []struct {
+		name  string
+		start string
+		stop  int
+		want  []string
+	}{
+		{"all", "", 0, []string{"a", "b", "c", "d"}},
+		{"start at key", "b", 0, []string{"b", "c", "d"}},
+		{"start between keys", "bb", 0, []string{"c", "d"}},
+		{"start after last key", "e", 0, nil},
+		{"stopped", "", 2, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := heap.Iterate("bucket", tt.start, func(key string, value []byte) bool {
+				if string(value) != testBoltDBEntries[key] {
+					t.Errorf("value of %s = %q, want %q", key, value, testBoltDBEntries[key])
+				}
+				got = append(got, key)
+				return len(got) != tt.stop
+			})
+			if err != nil {
+				t.Fatalf("Iterate() failed: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Iterate() visited %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSCHeapPaging(t *testing.T) {
+	a := &Application{Heap: newTestBoltDBHeap(t, testBoltDBEntries)}
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       []string
+	}{
+		{"all", "", http.StatusOK, []string{"a", "b", "c", "d"}},
+		{"offset", "?offset=1", http.StatusOK, []string{"b", "c", "d"}},
+		{"limit", "?limit=2", http.StatusOK, []string{"a", "b"}},
+		{"offset and limit", "?offset=1&limit=2", http.StatusOK, []string{"b", "c"}},
+		{"limit past end", "?offset=3&limit=5", http.StatusOK, []string{"d"}},
+		{"offset past end", "?offset=10", http.StatusOK, []string{}},
+		{"negative offset", "?offset=-1", http.StatusBadRequest, nil},
+		{"invalid limit", "?limit=two", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/list/bucket"+tt.query, nil), map[string]string{"sc_name": "bucket"})
+			w := httptest.NewRecorder()
+			a.ListSCHeap()(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []string
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid response %q: %s", w.Body, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoltDBLedgerBlobs(t *testing.T) {
+	ledger := &BoltDBLedger{Heap: newTestBoltDBHeap(t, nil)}
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"one chunk", 100},
+		{"several chunks", 2*backend.BlobChunkSize + 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := bytes.Repeat([]byte("0123456789abcdef"), tt.size/16+1)[:tt.size]
+			addr, size, err := ledger.PutBlob(bytes.NewReader(want))
+			if err != nil {
+				t.Fatalf("PutBlob() failed: %s", err)
+			}
+			if size != int64(tt.size) {
+				t.Errorf("size = %d, want %d", size, tt.size)
+			}
+			blob, err := ledger.OpenBlob(addr)
+			if err != nil {
+				t.Fatalf("OpenBlob() failed: %s", err)
+			}
+			defer blob.Close()
+			got, err := ioutil.ReadAll(blob)
+			if err != nil {
+				t.Fatalf("reading blob failed: %s", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("blob has %d bytes, want the %d that were put", len(got), len(want))
+			}
+		})
+	}
+	if _, err := ledger.OpenBlob(backend.BlobAddress([]byte("missing"))); err != backend.ErrBlobNotExist {
+		t.Errorf("OpenBlob() of a missing blob = %v, want %v", err, backend.ErrBlobNotExist)
+	}
+}
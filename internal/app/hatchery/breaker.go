@@ -0,0 +1,227 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// Defaults for the circuit breaker of each contract.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// Circuit breaker states.
+const (
+	// CircuitClosed admits every execution. It is the initial state.
+	CircuitClosed = "closed"
+	// CircuitOpen fails executions without running the contract, until the
+	// cooldown has passed.
+	CircuitOpen = "open"
+	// CircuitHalfOpen admits a single trial execution, which closes the circuit
+	// if it succeeds and opens it again if it fails.
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitOpenError is returned instead of executing a contract whose circuit
+// breaker is open.
+type CircuitOpenError struct {
+	Contract string
+	// RetryAt is when the breaker next admits an execution.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for contract %s is open after repeated failures", e.Contract)
+}
+
+// circuit is the circuit breaker and execution counters of a single contract.
+type circuit struct {
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	trial               bool
+	inFlight            int
+	executions          uint64
+	failures            uint64
+	lastError           string
+}
+
+// contractStatus is the response of GetContractStatus.
+type contractStatus struct {
+	Contract            string     `json:"contract"`
+	Circuit             string     `json:"circuit"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+	// InFlight counts executions in progress, including those waiting for an
+	// execution slot, which are also counted by Queued.
+	InFlight   int    `json:"in_flight"`
+	Queued     int    `json:"queued"`
+	Executions uint64 `json:"executions"`
+	Failures   uint64 `json:"failures"`
+	LastError  string `json:"last_error,omitempty"`
+	// Registration is the progress of the contract's latest registration since
+	// Hatchery started, if any.
+	Registration *registrationStatus `json:"registration,omitempty"`
+}
+
+func (a *Application) breakerThreshold() int {
+	if a.BreakerThreshold == 0 {
+		return DefaultBreakerThreshold
+	}
+	return a.BreakerThreshold
+}
+
+func (a *Application) breakerCooldown() time.Duration {
+	if a.BreakerCooldown <= 0 {
+		return DefaultBreakerCooldown
+	}
+	return a.BreakerCooldown
+}
+
+// circuit returns the circuit of the named contract. a.circuitMu must be held.
+func (a *Application) circuit(name string) *circuit {
+	if a.circuits == nil {
+		a.circuits = make(map[string]*circuit)
+	}
+	c, ok := a.circuits[name]
+	if !ok {
+		c = &circuit{state: CircuitClosed}
+		a.circuits[name] = c
+	}
+	return c
+}
+
+// admit reports whether the named contract may execute. A *CircuitOpenError is
+// returned if its breaker is open, or half open with a trial already in progress.
+// Otherwise, the execution is counted as in flight until it is passed to settle.
+func (a *Application) admit(name string) error {
+	a.circuitMu.Lock()
+	defer a.circuitMu.Unlock()
+	c := a.circuit(name)
+	retryAt := c.openedAt.Add(a.breakerCooldown())
+	switch c.state {
+	case CircuitOpen:
+		if time.Now().Before(retryAt) {
+			return &CircuitOpenError{Contract: name, RetryAt: retryAt}
+		}
+		c.state = CircuitHalfOpen
+		c.trial = true
+	case CircuitHalfOpen:
+		if c.trial {
+			return &CircuitOpenError{Contract: name, RetryAt: retryAt}
+		}
+		c.trial = true
+	}
+	c.inFlight++
+	return nil
+}
+
+// settle records the outcome of an execution admitted by admit, tripping the
+// breaker once the contract has failed BreakerThreshold times in a row. A
+// negative BreakerThreshold disables the breaker. A cancelled execution, whose
+// err is context.Canceled, says nothing about the contract's health, so it only
+// ends the execution, letting another trial run if it was one.
+func (a *Application) settle(name string, err error) {
+	a.circuitMu.Lock()
+	defer a.circuitMu.Unlock()
+	c := a.circuit(name)
+	c.inFlight--
+	c.executions++
+	trial := c.state == CircuitHalfOpen && c.trial
+	if trial {
+		c.trial = false
+	}
+	if err == context.Canceled {
+		return
+	}
+	if err == nil {
+		c.consecutiveFailures = 0
+		if trial {
+			c.state = CircuitClosed
+		}
+		return
+	}
+	c.failures++
+	c.consecutiveFailures++
+	c.lastError = err.Error()
+	threshold := a.breakerThreshold()
+	if trial || (threshold > 0 && c.state == CircuitClosed && c.consecutiveFailures >= threshold) {
+		c.state = CircuitOpen
+		c.openedAt = time.Now()
+		a.log().Error("circuit breaker opened", logging.Contract(name), logging.F("failures", c.consecutiveFailures))
+	}
+}
+
+// resetCircuit closes the breaker of the named contract and clears its counters,
+// such as when a new version of the contract is stored.
+func (a *Application) resetCircuit(name string) {
+	a.circuitMu.Lock()
+	defer a.circuitMu.Unlock()
+	if c, ok := a.circuits[name]; ok {
+		*c = circuit{state: CircuitClosed, inFlight: c.inFlight}
+	}
+}
+
+// GetContractStatus returns an HTTP handler function that responds with the state of
+// a contract's circuit breaker, its execution counters since Hatchery started and the
+// progress of its latest registration. A contract whose first version is still being
+// registered, or failed to be, is reported as well, so that clients that registered it
+// asynchronously can follow its progress.
+func (a *Application) GetContractStatus() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		registration := a.registrationStatus(name)
+		if _, err := a.Lib.Manifest(name); err != nil && (err != ErrContractNotExist || registration == nil) {
+			writeErrorFrom(w, err)
+			return
+		}
+		queued := a.execQueue(name).waiting() + a.slots.waiting(name)
+		a.circuitMu.Lock()
+		c := a.circuit(name)
+		status := contractStatus{
+			Contract:            name,
+			Circuit:             c.state,
+			ConsecutiveFailures: c.consecutiveFailures,
+			InFlight:            c.inFlight,
+			Queued:              queued,
+			Executions:          c.executions,
+			Failures:            c.failures,
+			LastError:           c.lastError,
+			Registration:        registration,
+		}
+		if c.state != CircuitClosed {
+			openedAt := c.openedAt.UTC()
+			retryAt := openedAt.Add(a.breakerCooldown())
+			status.OpenedAt = &openedAt
+			status.RetryAt = &retryAt
+		}
+		a.circuitMu.Unlock()
+		writeJSONResponse(w, status)
+	}
+}
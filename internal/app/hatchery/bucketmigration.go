@@ -0,0 +1,132 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// migrationBucket records the one-time migrations of the heap that have been
+// applied, each under its own key.
+const migrationBucket = reservedBucketPrefix + "migrations"
+
+// sharedBucketMigration is the key in migrationBucket of migrateSharedBucket.
+const sharedBucketMigration = "shared_bucket"
+
+// migrateSharedBucket moves the contract output left in Bucket, from before each
+// contract's output was stored in its own bucket, to the bucket of the contract
+// that wrote it. The writer of each key is found by going through the output of
+// the ledger's transactions, stored with the HeapOutput strategy of each
+// contract's current manifest; the last transaction to write a key owns it. A key
+// is only moved if its value is still the one its owner wrote and the owner's
+// bucket doesn't hold the key already, so keys written by hand, or that a newer
+// execution already wrote to the owner's bucket, are left in place and logged.
+// The migration is skipped if SharedBucket is set, and otherwise recorded in
+// migrationBucket so that it runs once.
+func (a *Application) migrateSharedBucket() error {
+	if a.SharedBucket || a.Bucket == "" {
+		return nil
+	}
+	_, err := a.Heap.Get(migrationBucket, sharedBucketMigration)
+	if err == nil {
+		return nil
+	}
+	if err != ErrHeapNotExist {
+		return err
+	}
+	keys, err := a.Heap.Keys(a.Bucket, "")
+	if err != nil && err != ErrHeapNotExist {
+		return err
+	}
+	if len(keys) > 0 {
+		if err := a.moveSharedOutput(keys); err != nil {
+			return err
+		}
+	}
+	return a.Heap.Put(migrationBucket, sharedBucketMigration, []byte(time.Now().UTC().Format(time.RFC3339)))
+}
+
+// moveSharedOutput moves the given keys of Bucket to the buckets of the contracts
+// that last wrote them. See migrateSharedBucket.
+func (a *Application) moveSharedOutput(keys []string) error {
+	type write struct {
+		contract string
+		value    []byte
+	}
+	manifests := make(map[string]*ContractManifest)
+	owners := make(map[string]write)
+	err := a.Ledger.Iterate(func(t *Transaction) bool {
+		if t.InvokerContract == "" {
+			return true
+		}
+		m, ok := manifests[t.InvokerContract]
+		if !ok {
+			m, _ = a.Lib.Manifest(t.InvokerContract)
+			manifests[t.InvokerContract] = m
+		}
+		for _, p := range outputPuts(a.Bucket, t.Content, m) {
+			owners[p.Key] = write{contract: t.InvokerContract, value: p.Value}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	logger := a.log().With(logging.F("bucket", a.Bucket))
+	moved := 0
+	for _, key := range keys {
+		owner, ok := owners[key]
+		if !ok || owner.contract == a.Bucket {
+			logger.Info("leaving heap key without an owning contract in shared bucket", logging.F("key", key))
+			continue
+		}
+		value, err := a.Heap.Get(a.Bucket, key)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(value, owner.value) {
+			logger.Info("leaving heap key changed since its contract wrote it in shared bucket", logging.Contract(owner.contract), logging.F("key", key))
+			continue
+		}
+		_, err = a.Heap.Get(owner.contract, key)
+		if err == nil {
+			logger.Info("leaving heap key its contract's bucket already holds in shared bucket", logging.Contract(owner.contract), logging.F("key", key))
+			continue
+		}
+		if err != ErrHeapNotExist {
+			return err
+		}
+		put := HeapPut{Bucket: owner.contract, Key: key, Value: value}
+		if err := a.Heap.Put(put.Bucket, put.Key, put.Value); err != nil {
+			return err
+		}
+		a.heapWritten(owner.contract, put)
+		if err := a.Heap.Delete(a.Bucket, key); err != nil {
+			return err
+		}
+		a.heapDeleted(a.Bucket, key)
+		moved++
+	}
+	logger.Info("moved contract output out of shared bucket", logging.F("moved", moved), logging.F("kept", len(keys)-moved))
+	return nil
+}
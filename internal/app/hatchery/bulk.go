@@ -0,0 +1,177 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// maxBulkTransactions is the most transactions accepted by a single bulk post,
+// matching DragonChain's limit.
+const maxBulkTransactions = 250
+
+// bulkTransactionResult is the outcome of a single transaction in a bulk post.
+// Exactly one of Transaction and Error is set.
+type bulkTransactionResult struct {
+	Transaction *transactionResponse `json:",omitempty"`
+	Error       string               `json:",omitempty"`
+}
+
+// PostTransactionBulk returns an HTTP handler function that executes a JSON array of
+// transactions and appends the successful ones to the ledger, in the order they were
+// posted, as a single atomic append. The response holds a result for each posted
+// transaction, in the same order, with either the appended transaction or the reason
+// it failed. Content is encoded as requested. See contentEncoding. If any transaction's
+// signature is invalid, or one is unsigned while RequireSignatures is set, none are
+// executed.
+func (a *Application) PostTransactionBulk() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding, ok := contentEncoding(w, r)
+		if !ok {
+			return
+		}
+		var reqs []postTransactionRequest
+		limitBody(w, r, a.maxTransactionSize()*maxBulkTransactions)
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			writeDecodeError(w, ErrCodeBadRequest, "invalid transactions", err)
+			return
+		}
+		if len(reqs) == 0 || len(reqs) > maxBulkTransactions {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("between 1 and %d transactions must be posted", maxBulkTransactions))
+			return
+		}
+		results, ok := a.postBulk(w, r, reqs, encoding)
+		if !ok {
+			return
+		}
+		writeJSONResponse(w, results)
+	}
+}
+
+// postBulk verifies, executes and appends the transactions of a bulk post, and invokes
+// the contracts downstream of the appended ones. It returns the result of each request,
+// in request order. If a signature is invalid or the append fails, an error response
+// is written and false is returned.
+func (a *Application) postBulk(w http.ResponseWriter, r *http.Request, reqs []postTransactionRequest, encoding string) ([]bulkTransactionResult, bool) {
+	for i := range reqs {
+		_, err := a.verifyTransaction(&reqs[i])
+		switch err {
+		case nil:
+		case ErrSignatureRequired, ErrSignatureInvalid:
+			writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, fmt.Sprintf("transaction %d (%s): %s", i, reqs[i].Type, err))
+			return nil, false
+		default:
+			writeErrorFrom(w, err)
+			return nil, false
+		}
+	}
+	results := a.executeBulk(r.Context(), reqs, encoding)
+	var ts []*Transaction
+	for _, res := range results {
+		if res.Transaction != nil {
+			ts = append(ts, res.Transaction.Transaction)
+		}
+	}
+	if len(ts) > 0 {
+		if err := a.append(ts...); err != nil {
+			a.log().Error("failed to append bulk transactions", logging.Err(err))
+			writeErrorFrom(w, err)
+			return nil, false
+		}
+		a.log().Info("bulk transactions appended", logging.F("count", len(ts)))
+		for _, t := range ts {
+			a.invokeDownstream(r.Context(), t)
+		}
+	}
+	return results, true
+}
+
+// executeBulk executes each request and returns their results in request order.
+// Requests for different transaction types execute concurrently. Requests for the
+// same serial contract, or with no contract at all, execute one after another in
+// the order they were posted, while requests for the same parallel contract execute
+// concurrently, subject to the contract's execution queue.
+func (a *Application) executeBulk(ctx context.Context, reqs []postTransactionRequest, encoding string) []bulkTransactionResult {
+	results := make([]bulkTransactionResult, len(reqs))
+	byType := make(map[string][]int)
+	var types []string
+	for i, req := range reqs {
+		if _, ok := byType[req.Type]; !ok {
+			types = append(types, req.Type)
+		}
+		byType[req.Type] = append(byType[req.Type], i)
+	}
+
+	exec := func(i int) {
+		t, puts, err := a.execute(ctx, "", reqs[i].Type, reqs[i].Payload)
+		if err != nil {
+			results[i].Error = err.Error()
+			return
+		}
+		// Signatures are verified before any request executes, so the signer of a
+		// signed request is known to be valid.
+		t.Signer = reqs[i].Signer
+		// The heap is written right away, rather than with the append, so that later
+		// executions of a serial contract see the output of earlier ones.
+		if len(puts) > 0 {
+			if err := a.admitHeap(puts[0].Bucket, puts, false); err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+		}
+		a.putOutput(ctx, t.Type, puts)
+		resp := newTransactionResponse(t, encoding)
+		results[i].Transaction = &resp
+	}
+	var wg sync.WaitGroup
+	for _, txnType := range types {
+		parallel := false
+		if m, err := a.Lib.Manifest(txnType); err == nil {
+			parallel = m.ExecutionOrder != ExecutionOrderSerial
+		}
+		wg.Add(1)
+		go func(indices []int, parallel bool) {
+			defer wg.Done()
+			if !parallel {
+				for _, i := range indices {
+					exec(i)
+				}
+				return
+			}
+			var pwg sync.WaitGroup
+			for _, i := range indices {
+				pwg.Add(1)
+				go func(i int) {
+					defer pwg.Done()
+					exec(i)
+				}(i)
+			}
+			pwg.Wait()
+		}(byType[txnType], parallel)
+	}
+	wg.Wait()
+	return results
+}
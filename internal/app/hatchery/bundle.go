@@ -0,0 +1,241 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+const (
+	// maxBundleSize limits the size, in bytes, of a posted contract bundle.
+	maxBundleSize = 16 << 20
+	// maxBundleManifests is the most manifests a single bundle may hold.
+	maxBundleManifests = 250
+	// bundleWorkers is how many of a bundle's contracts are stored at once. Storing
+	// a contract pulls its image, so this bounds the concurrent pulls.
+	bundleWorkers = 4
+)
+
+// bundleManifest is a manifest read from a contract bundle.
+type bundleManifest struct {
+	file     string
+	manifest *ContractManifest
+	err      error
+}
+
+// bundleResult is the outcome of registering a single manifest of a bundle. Error is
+// set if the manifest could not be registered, along with Violations if it was
+// invalid.
+type bundleResult struct {
+	// File is the path of the manifest in the bundle.
+	File       string      `json:"file"`
+	Contract   string      `json:"contract,omitempty"`
+	Version    int         `json:"version,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// PostContractBundle returns an HTTP handler function that registers every contract
+// manifest in a posted archive, like PostContract, in a single call. The archive is a
+// tar file, optionally gzipped, or a zip file. Every file in it with a .json extension
+// is read as a manifest; other files are ignored. Manifests are validated before any is
+// stored, and then stored by a bounded pool of workers, so that their images are pulled
+// concurrently. The response holds a result for each manifest, in archive order, with
+// either the stored version or the reason it was not registered. Manifests that fail
+// don't prevent the others from being registered.
+func (a *Application) PostContractBundle() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limitBody(w, r, maxBundleSize)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeDecodeError(w, ErrCodeBadRequest, "invalid bundle", err)
+			return
+		}
+		manifests, err := readBundle(body, a.maxContractSize())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid bundle: "+err.Error())
+			return
+		}
+		if len(manifests) == 0 || len(manifests) > maxBundleManifests {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("a bundle must hold between 1 and %d manifests", maxBundleManifests))
+			return
+		}
+		writeJSONResponse(w, a.registerBundle(manifests))
+	}
+}
+
+// registerBundle validates and stores manifests and returns their results in the same
+// order.
+func (a *Application) registerBundle(manifests []bundleManifest) []bundleResult {
+	results := make([]bundleResult, len(manifests))
+	schedules := make([]Schedule, len(manifests))
+	var pending []int
+	seen := make(map[string]string)
+	for i, bm := range manifests {
+		res := &results[i]
+		res.File = bm.file
+		if bm.err != nil {
+			res.Error = bm.err.Error()
+			continue
+		}
+		res.Contract = bm.manifest.Type
+		violations, schedule, err := a.checkManifest(bm.manifest)
+		switch {
+		case err != nil:
+			res.Error = err.Error()
+		case len(violations) > 0:
+			res.Error = "manifest is invalid"
+			res.Violations = violations
+		case seen[bm.manifest.Type] != "":
+			// Storing two versions of a contract at once would race for its version
+			// number, and only the last would take effect anyway.
+			res.Error = fmt.Sprintf("contract %s is also defined by %s", bm.manifest.Type, seen[bm.manifest.Type])
+		default:
+			seen[bm.manifest.Type] = bm.file
+			schedules[i] = schedule
+			pending = append(pending, i)
+		}
+	}
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < bundleWorkers && n < len(pending); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				m := manifests[i].manifest
+				if err := a.Lib.Put(m); err != nil {
+					results[i].Error = err.Error()
+					continue
+				}
+				results[i].Version = m.Version
+				if err := a.registered(m, schedules[i]); err != nil {
+					results[i].Error = err.Error()
+				}
+			}
+		}()
+	}
+	for _, i := range pending {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	registered := 0
+	for _, res := range results {
+		if res.Error == "" {
+			registered++
+		}
+	}
+	a.log().Info("contract bundle registered", logging.F("registered", registered), logging.F("failed", len(results)-registered))
+	return results
+}
+
+// readBundle reads the manifests in a tar, gzipped tar or zip archive, in archive
+// order. Each manifest may be at most limit bytes. Manifests that can't be decoded are returned with their error. An error is
+// returned if the archive itself can't be read.
+func readBundle(b []byte, limit int64) ([]bundleManifest, error) {
+	switch {
+	case bytes.HasPrefix(b, []byte("PK\x03\x04")) || bytes.HasPrefix(b, []byte("PK\x05\x06")):
+		return readZipBundle(b, limit)
+	case bytes.HasPrefix(b, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return readTarBundle(io.LimitReader(gz, maxBundleSize), limit)
+	}
+	return readTarBundle(bytes.NewReader(b), limit)
+}
+
+func readTarBundle(r io.Reader, limit int64) ([]bundleManifest, error) {
+	var manifests []bundleManifest
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return manifests, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !isManifestFile(hdr.Name) {
+			continue
+		}
+		manifests = append(manifests, decodeBundleManifest(hdr.Name, tr, limit))
+	}
+}
+
+func readZipBundle(b []byte, limit int64) ([]bundleManifest, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+	var manifests []bundleManifest
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !isManifestFile(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			manifests = append(manifests, bundleManifest{file: f.Name, err: err})
+			continue
+		}
+		manifests = append(manifests, decodeBundleManifest(f.Name, rc, limit))
+		rc.Close()
+	}
+	return manifests, nil
+}
+
+// isManifestFile reports whether the archive entry name holds a manifest. Hidden
+// files, such as the resource forks macOS adds to archives, are skipped.
+func isManifestFile(name string) bool {
+	base := path.Base(name)
+	return strings.EqualFold(path.Ext(base), ".json") && !strings.HasPrefix(base, ".")
+}
+
+// decodeBundleManifest decodes the manifest named name from r, reading at most limit
+// bytes.
+func decodeBundleManifest(name string, r io.Reader, limit int64) bundleManifest {
+	var m ContractManifest
+	if err := json.NewDecoder(io.LimitReader(r, limit)).Decode(&m); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = errors.New("manifest is empty or truncated")
+		}
+		return bundleManifest{file: name, err: fmt.Errorf("invalid manifest: %s", err)}
+	}
+	return bundleManifest{file: name, manifest: &m}
+}
@@ -0,0 +1,355 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+const (
+	chainBucket = reservedBucketPrefix + "chains"
+	// chainNamespacePrefix begins the bucket namespace of every chain. It is reserved,
+	// so the buckets of chains are hidden from the heap API of the default chain.
+	chainNamespacePrefix = reservedBucketPrefix + "chain_"
+	maxChainIDLength     = 64
+)
+
+var (
+	// ErrChainNotExist is returned when a requested chain does not exist.
+	ErrChainNotExist = errors.New("chain does not exist")
+	// ErrChainExists is returned when creating a chain whose ID is taken.
+	ErrChainExists = errors.New("chain already exists")
+)
+
+// Chain is a virtual chain hosted alongside the default chain. Each chain has its
+// own heap, ledger, contracts, cron jobs and API keys, and is served under
+// /chains/{chain_id}.
+type Chain struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type postChainRequest struct {
+	ID string `json:"id"`
+}
+
+// postChainResponse is the response to creating a chain. It holds the chain's
+// first API key, since API keys are not shared between chains.
+type postChainResponse struct {
+	*Chain
+	APIKey *APIKey `json:"api_key"`
+}
+
+// Chains manages the virtual chains of an Application. The chains are recorded in
+// the Application's Heap and restored when it starts.
+type Chains struct {
+	// New builds the Application for the chain with the given ID. Its Heap, Ledger
+	// and Library must be separate from those of every other chain. See
+	// NamespacedHeap.
+	New func(id string) (*Application, error)
+	// Remove deletes everything stored for the chain with the given ID, after its
+	// Application has been shut down. If nil, nothing is deleted.
+	Remove func(id string) error
+
+	mu     sync.Mutex
+	root   *Application
+	apps   map[string]*Application
+	routes map[string]http.Handler
+}
+
+// start restores every recorded chain and starts its background work.
+func (c *Chains) start(root *Application) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.root = root
+	if c.apps == nil {
+		c.apps = make(map[string]*Application)
+		c.routes = make(map[string]http.Handler)
+	}
+	chains, err := c.list()
+	if err != nil {
+		root.log().Error("failed to restore chains", logging.Err(err))
+		return
+	}
+	for _, chain := range chains {
+		if _, ok := c.apps[chain.ID]; ok {
+			continue
+		}
+		if _, err := c.open(chain.ID); err != nil {
+			root.log().Error("failed to restore chain", logging.F("chain", chain.ID), logging.Err(err))
+		}
+	}
+}
+
+// shutdown shuts down the Application of every chain.
+func (c *Chains) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, app := range c.apps {
+		app.Shutdown()
+	}
+}
+
+// open builds and starts the Application for a chain. The caller must hold mu.
+func (c *Chains) open(id string) (*Application, error) {
+	app, err := c.New(id)
+	if err != nil {
+		return nil, err
+	}
+	app.BaseURL = strings.TrimRight(c.root.BaseURL, "/") + "/chains/" + id
+	app.start()
+	muxer := mux.NewRouter()
+	app.SetupRoutes(muxer)
+	c.apps[id] = app
+	c.routes[id] = http.StripPrefix("/chains/"+id, muxer)
+	return app, nil
+}
+
+// create records a new chain and starts it, returning the chain and its first
+// API key.
+func (c *Chains) create(id string) (*postChainResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.root.Heap.Get(chainBucket, id); err == nil {
+		return nil, ErrChainExists
+	} else if err != ErrHeapNotExist {
+		return nil, err
+	}
+	chain := &Chain{ID: id, Created: time.Now().UTC()}
+	if err := c.root.putJSON(chainBucket, id, chain); err != nil {
+		return nil, err
+	}
+	app, err := c.open(id)
+	if err != nil {
+		c.root.Heap.Delete(chainBucket, id)
+		return nil, err
+	}
+	key, err := app.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	return &postChainResponse{Chain: chain, APIKey: key}, nil
+}
+
+// remove shuts down a chain and deletes it along with everything stored for it.
+func (c *Chains) remove(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.root.Heap.Get(chainBucket, id); err == ErrHeapNotExist {
+		return ErrChainNotExist
+	} else if err != nil {
+		return err
+	}
+	if app, ok := c.apps[id]; ok {
+		app.Shutdown()
+		delete(c.apps, id)
+		delete(c.routes, id)
+	}
+	if c.Remove != nil {
+		if err := c.Remove(id); err != nil {
+			return fmt.Errorf("failed to remove chain data: %s", err)
+		}
+	}
+	return c.root.Heap.Delete(chainBucket, id)
+}
+
+// list returns every recorded chain, sorted by ID.
+func (c *Chains) list() ([]*Chain, error) {
+	all, err := c.root.Heap.GetRange(chainBucket, "", "")
+	if err != nil {
+		return nil, err
+	}
+	chains := make([]*Chain, 0, len(all))
+	for _, v := range all {
+		var chain Chain
+		if err := json.Unmarshal(v, &chain); err != nil {
+			return nil, fmt.Errorf("failed to decode chain: %s", err)
+		}
+		chains = append(chains, &chain)
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].ID < chains[j].ID
+	})
+	return chains, nil
+}
+
+// ServeHTTP serves a request under /chains/{chain_id} with the routes of that
+// chain's Application.
+func (c *Chains) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	h, ok := c.routes[mux.Vars(r)["chain_id"]]
+	c.mu.Unlock()
+	if !ok {
+		writeErrorFrom(w, ErrChainNotExist)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+// PostChain returns an HTTP handler function that creates a virtual chain with the
+// requested ID and responds with it, along with the first API key for its routes.
+func (a *Application) PostChain() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req postChainRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid chain: "+err.Error())
+			return
+		}
+		if !validChainID(req.ID) {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("chain ids must be 1 to %d letters, digits, '-' or '_'", maxChainIDLength))
+			return
+		}
+		resp, err := a.Chains.create(req.ID)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSONResponse(w, resp)
+	}
+}
+
+// ListChains returns an HTTP handler function that responds with every virtual chain,
+// sorted by ID.
+func (a *Application) ListChains() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.Chains.mu.Lock()
+		chains, err := a.Chains.list()
+		a.Chains.mu.Unlock()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, chains)
+	}
+}
+
+// DeleteChain returns an HTTP handler function that stops the requested virtual chain
+// and deletes its heap, ledger, contracts and API keys.
+func (a *Application) DeleteChain() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Chains.remove(mux.Vars(r)["chain_id"]); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func validChainID(id string) bool {
+	if id == "" || len(id) > maxChainIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ChainNamespace returns the bucket namespace of the chain with the given ID.
+func ChainNamespace(id string) string {
+	return chainNamespacePrefix + id + "/"
+}
+
+// NamespacedHeap is a Heap that stores its buckets in another Heap, with Namespace
+// prepended to their names. Buckets outside the namespace are invisible to it.
+type NamespacedHeap struct {
+	Heap      Heap
+	Namespace string
+}
+
+// Put stores value under key in the namespaced bucket.
+func (h *NamespacedHeap) Put(bucket, key string, value []byte) error {
+	return h.Heap.Put(h.Namespace+bucket, key, value)
+}
+
+// CompareAndSwap swaps the value for key in the namespaced bucket if it holds old.
+func (h *NamespacedHeap) CompareAndSwap(bucket, key string, old, new []byte) (bool, error) {
+	return h.Heap.CompareAndSwap(h.Namespace+bucket, key, old, new)
+}
+
+// Get returns the value for key in the namespaced bucket.
+func (h *NamespacedHeap) Get(bucket, key string) ([]byte, error) {
+	return h.Heap.Get(h.Namespace+bucket, key)
+}
+
+// GetAll returns every kvp in the namespaced bucket.
+func (h *NamespacedHeap) GetAll(bucket string) (map[string][]byte, error) {
+	return h.Heap.GetAll(h.Namespace + bucket)
+}
+
+// Iterate calls fn with each kvp in the namespaced bucket whose key is at least
+// start, in ascending order of keys, until fn returns false.
+func (h *NamespacedHeap) Iterate(bucket, start string, fn func(key string, value []byte) bool) error {
+	return backend.IterateHeap(h.Heap, h.Namespace+bucket, start, fn)
+}
+
+// Keys returns the keys in the namespaced bucket that begin with prefix.
+func (h *NamespacedHeap) Keys(bucket, prefix string) ([]string, error) {
+	return h.Heap.Keys(h.Namespace+bucket, prefix)
+}
+
+// GetRange returns the kvps in the namespaced bucket whose keys fall in the range
+// [start, end).
+func (h *NamespacedHeap) GetRange(bucket, start, end string) (map[string][]byte, error) {
+	return h.Heap.GetRange(h.Namespace+bucket, start, end)
+}
+
+// Delete removes key from the namespaced bucket.
+func (h *NamespacedHeap) Delete(bucket, key string) error {
+	return h.Heap.Delete(h.Namespace+bucket, key)
+}
+
+// DeleteBucket removes the namespaced bucket.
+func (h *NamespacedHeap) DeleteBucket(bucket string) error {
+	return h.Heap.DeleteBucket(h.Namespace + bucket)
+}
+
+// Buckets returns the names of the buckets in the namespace, without the
+// namespace, in ascending order.
+func (h *NamespacedHeap) Buckets() ([]string, error) {
+	all, err := h.Heap.Buckets()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, b := range all {
+		if strings.HasPrefix(b, h.Namespace) {
+			names = append(names, strings.TrimPrefix(b, h.Namespace))
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,324 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// Elector elects the leader among the applications of a cluster. See
+// Application.Elector.
+type Elector = backend.Elector
+
+// DefaultClusterSyncInterval is how often the leader of a cluster checks that it
+// still leads and syncs its cron jobs, when Application.ClusterSyncInterval is not
+// set.
+const DefaultClusterSyncInterval = 10 * time.Second
+
+// ClusterStatus is the response of GET /cluster.
+type ClusterStatus struct {
+	// Clustered is whether the node has an Elector. The other fields are only set
+	// if it does.
+	Clustered bool   `json:"clustered"`
+	NodeID    string `json:"node_id,omitempty"`
+	// Leader is whether the node is the leader, which fires cron jobs and one-shot
+	// schedules.
+	Leader bool `json:"leader"`
+	// CronJobs lists the contracts whose cron jobs the node runs.
+	CronJobs []string `json:"cron_jobs"`
+}
+
+// PostgresElector is an Elector that elects the node holding a session-level
+// PostgreSQL advisory lock. The lock is held on a dedicated connection, so the
+// leader loses it, and another node can be elected, as soon as that connection
+// breaks.
+type PostgresElector struct {
+	DB *PostgresDB
+	// Namespace separates the elections of virtual chains, like the Namespace of
+	// a PostgresLedger.
+	Namespace string
+	// Interval is how often nodes that don't lead try to take the lock, and how
+	// often the leader checks its connection. If zero,
+	// DefaultClusterSyncInterval is used.
+	Interval time.Duration
+}
+
+// Campaign tries to take the election's advisory lock every Interval until it
+// succeeds or ctx is done.
+func (e *PostgresElector) Campaign(ctx context.Context) (context.Context, error) {
+	db, err := e.DB.initOnce()
+	if err != nil {
+		return nil, err
+	}
+	interval := e.Interval
+	if interval <= 0 {
+		interval = DefaultClusterSyncInterval
+	}
+	key := "hatchery_leader:" + e.Namespace
+	for {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var locked bool
+		err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, key).Scan(&locked)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if locked {
+			lead, cancel := context.WithCancel(ctx)
+			go e.hold(lead, cancel, conn, key, interval)
+			return lead, nil
+		}
+		conn.Close()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// hold checks the connection holding the lock every interval until it breaks or
+// lead is done, and then releases the lock and the connection.
+func (e *PostgresElector) hold(lead context.Context, cancel context.CancelFunc, conn *sql.Conn, key string, interval time.Duration) {
+	defer conn.Close()
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lead.Done():
+			// The connection is returned to the pool, so the lock must be released
+			// explicitly.
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, key)
+			cancel()
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(lead, interval)
+			_, err := conn.ExecContext(ctx, `SELECT 1`)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// GetCluster returns an HTTP handler function that responds with the node's
+// ClusterStatus.
+func (a *Application) GetCluster() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := ClusterStatus{Clustered: a.Elector != nil, Leader: a.leading(), CronJobs: a.cronJobNames()}
+		if status.Clustered {
+			status.NodeID = a.NodeID
+		}
+		writeJSONResponse(w, status)
+	}
+}
+
+// leading reports whether the application leads its cluster. An application that
+// isn't clustered always leads.
+func (a *Application) leading() bool {
+	if a.Elector == nil {
+		return true
+	}
+	a.clusterMu.Lock()
+	defer a.clusterMu.Unlock()
+	return a.leader
+}
+
+// whileLeading calls f if the application leads its cluster, and returns its error.
+// Leadership doesn't change until f returns, so cron jobs f starts are stopped if
+// the application stops leading.
+func (a *Application) whileLeading(f func() error) error {
+	a.clusterMu.Lock()
+	defer a.clusterMu.Unlock()
+	if a.Elector != nil && !a.leader {
+		return nil
+	}
+	return f()
+}
+
+func (a *Application) clusterSyncInterval() time.Duration {
+	if a.ClusterSyncInterval <= 0 {
+		return DefaultClusterSyncInterval
+	}
+	return a.ClusterSyncInterval
+}
+
+// startCampaign starts campaigning for the leadership of the cluster, if the
+// application has an Elector and isn't campaigning already.
+func (a *Application) startCampaign() {
+	if a.Elector == nil {
+		return
+	}
+	a.clusterMu.Lock()
+	defer a.clusterMu.Unlock()
+	if a.campaignCancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.campaignCancel = cancel
+	a.campaignDone = make(chan struct{})
+	go a.campaign(ctx, a.campaignDone)
+}
+
+// stopCampaign stops campaigning and waits until the application has given up its
+// leadership, if it leads.
+func (a *Application) stopCampaign() {
+	a.clusterMu.Lock()
+	cancel, done := a.campaignCancel, a.campaignDone
+	a.campaignCancel = nil
+	a.clusterMu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// campaign campaigns for leadership until ctx is done, leading whenever it is
+// elected. Failed campaigns are retried every sync interval.
+func (a *Application) campaign(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for {
+		lead, err := a.Elector.Campaign(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			a.log().Error("failed to campaign for cluster leadership", logging.F("node", a.NodeID), logging.Err(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(a.clusterSyncInterval()):
+			}
+			continue
+		}
+		a.lead(lead)
+	}
+}
+
+// lead does the leader's work until lead is done: contract output left in the
+// shared bucket is migrated, cron jobs are started, and every sync interval they
+// are synced with the Library and due one-shot schedules and outbox items are
+// dispatched, since any node may change them. Once lead is done, the cron
+// jobs are stopped.
+func (a *Application) lead(lead context.Context) {
+	logger := a.log().With(logging.F("node", a.NodeID))
+	a.clusterMu.Lock()
+	a.leader = true
+	a.clusterMu.Unlock()
+	logger.Info("elected cluster leader")
+	if err := a.migrateSharedBucket(); err != nil {
+		logger.Error("failed to migrate contract output out of shared bucket", logging.F("bucket", a.Bucket), logging.Err(err))
+	}
+	a.whileLeading(a.syncCronJobs)
+	a.wakeOneShots()
+	a.wakeForwarding()
+	ticker := time.NewTicker(a.clusterSyncInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// Other nodes add one-shot schedules and outbox items without waking
+			// the leader.
+			a.whileLeading(a.syncCronJobs)
+			a.wakeOneShots()
+			a.wakeForwarding()
+		case <-lead.Done():
+			a.clusterMu.Lock()
+			a.leader = false
+			for _, name := range a.cronJobNames() {
+				a.stopCronJob(name)
+			}
+			a.cronVersions = nil
+			a.clusterMu.Unlock()
+			logger.Info("stopped leading cluster")
+			return
+		}
+	}
+}
+
+// syncCronJobs brings the cron jobs of the leader in line with the Library: a job
+// is started for each contract with a cron schedule, rescheduled if its manifest
+// has changed since, and stopped if its contract no longer has one. Contracts whose
+// job can't be started are logged and tried again on the next sync. a.clusterMu
+// must be held.
+func (a *Application) syncCronJobs() error {
+	manifests, err := a.Lib.List()
+	if err != nil {
+		a.log().Error("failed to sync cron jobs", logging.Err(err))
+		return nil
+	}
+	if a.cronVersions == nil {
+		a.cronVersions = make(map[string]int)
+	}
+	scheduled := make(map[string]bool)
+	for _, m := range manifests {
+		if m.Cron == "" {
+			continue
+		}
+		scheduled[m.Type] = true
+		if v, ok := a.cronVersions[m.Type]; ok && v == m.Version {
+			continue
+		}
+		logger := a.log().With(logging.Contract(m.Type), logging.F("cron", m.Cron))
+		schedule, err := ParseSchedule(m.Cron)
+		if err == nil {
+			err = a.rescheduleCronJob(m.Type, schedule)
+		}
+		if err != nil {
+			logger.Error("failed to sync cron job", logging.Err(err))
+			continue
+		}
+		a.cronVersions[m.Type] = m.Version
+	}
+	for _, name := range a.cronJobNames() {
+		if !scheduled[name] {
+			a.stopCronJob(name)
+			delete(a.cronVersions, name)
+		}
+	}
+	return nil
+}
+
+// cronJobNames returns the names of the contracts with a running cron job, sorted.
+func (a *Application) cronJobNames() []string {
+	a.ensureCronTab()
+	a.cronMu.Lock()
+	defer a.cronMu.Unlock()
+	names := make([]string, 0, len(a.cronTab))
+	for name := range a.cronTab {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,421 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/config"
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/output"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// NewApplicationFromConfig builds an Application, along with its Heap, Ledger, Library
+// and Chains, from cfg. An error is returned if the configuration is invalid.
+func NewApplicationFromConfig(cfg *config.Config) (*Application, error) {
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	logger := logging.New(os.Stderr, level)
+
+	var shutdownTimeout time.Duration
+	if cfg.ShutdownTimeout != "" {
+		if shutdownTimeout, err = time.ParseDuration(cfg.ShutdownTimeout); err != nil {
+			return nil, fmt.Errorf("invalid shutdown timeout: %s", err)
+		}
+	}
+
+	var breakerCooldown time.Duration
+	if cfg.BreakerCooldown != "" {
+		if breakerCooldown, err = time.ParseDuration(cfg.BreakerCooldown); err != nil {
+			return nil, fmt.Errorf("invalid breaker cooldown: %s", err)
+		}
+	}
+
+	var gcInterval time.Duration
+	if cfg.GCInterval != "" {
+		if gcInterval, err = time.ParseDuration(cfg.GCInterval); err != nil {
+			return nil, fmt.Errorf("invalid gc interval: %s", err)
+		}
+	}
+
+	var blockInterval time.Duration
+	if cfg.Ledger.BlockInterval != "" {
+		if blockInterval, err = time.ParseDuration(cfg.Ledger.BlockInterval); err != nil {
+			return nil, fmt.Errorf("invalid block interval: %s", err)
+		}
+	}
+
+	var idempotencyWindow time.Duration
+	if cfg.Ledger.IdempotencyWindow != "" {
+		if idempotencyWindow, err = time.ParseDuration(cfg.Ledger.IdempotencyWindow); err != nil {
+			return nil, fmt.Errorf("invalid idempotency window: %s", err)
+		}
+	}
+
+	if !output.ValidPolicy(cfg.Contracts.OutputPolicy) {
+		return nil, fmt.Errorf("invalid output policy %q", cfg.Contracts.OutputPolicy)
+	}
+	outputLimit := output.Limit{
+		Max:    cfg.Contracts.MaxOutputBytes,
+		Policy: cfg.Contracts.OutputPolicy,
+	}
+
+	envPolicy := EnvPolicy{Allow: cfg.Contracts.EnvAllow, Deny: cfg.Contracts.EnvDeny}
+	if err := envPolicy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid contract environment policy: %s", err)
+	}
+
+	retention, err := newRetentionPolicy(cfg.Ledger.Retention)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusterSyncInterval time.Duration
+	if cfg.Cluster.SyncInterval != "" {
+		if clusterSyncInterval, err = time.ParseDuration(cfg.Cluster.SyncInterval); err != nil {
+			return nil, fmt.Errorf("invalid cluster sync interval: %s", err)
+		}
+	}
+	nodeID := cfg.Cluster.NodeID
+	if nodeID == "" && cfg.Cluster.Elector != "" {
+		if nodeID, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("failed to name cluster node: %s", err)
+		}
+	}
+
+	var badgerGCInterval time.Duration
+	if cfg.Heap.BadgerGCInterval != "" {
+		if badgerGCInterval, err = time.ParseDuration(cfg.Heap.BadgerGCInterval); err != nil {
+			return nil, fmt.Errorf("invalid badger gc interval: %s", err)
+		}
+	}
+	if cfg.Heap.BadgerGCDiscardRatio < 0 || cfg.Heap.BadgerGCDiscardRatio >= 1 {
+		return nil, fmt.Errorf("invalid badger gc discard ratio %g: must be at least 0 and less than 1", cfg.Heap.BadgerGCDiscardRatio)
+	}
+
+	var connMaxLifetime time.Duration
+	if cfg.Postgres.ConnMaxLifetime != "" {
+		if connMaxLifetime, err = time.ParseDuration(cfg.Postgres.ConnMaxLifetime); err != nil {
+			return nil, fmt.Errorf("invalid postgres connection lifetime: %s", err)
+		}
+	}
+
+	switch cfg.Contracts.Network {
+	case "", docker.NetworkNone, docker.NetworkBridge, docker.NetworkHost:
+	default:
+		return nil, fmt.Errorf("invalid contract network %q: must be %q, %q or %q", cfg.Contracts.Network, docker.NetworkNone, docker.NetworkBridge, docker.NetworkHost)
+	}
+
+	docker.Configure(docker.Settings{Host: cfg.Docker.Host, Context: cfg.Docker.Context})
+
+	var heap Heap
+	switch cfg.Heap.Backend {
+	case config.BackendBolt:
+		heap = &BoltDBHeap{Path: cfg.Heap.BoltPath, ReadOnly: cfg.Heap.ReadOnly}
+	case config.BackendBadger:
+		heap = &BadgerHeap{
+			Path:           cfg.Heap.BadgerPath,
+			ReadOnly:       cfg.Heap.ReadOnly,
+			GCInterval:     badgerGCInterval,
+			GCDiscardRatio: cfg.Heap.BadgerGCDiscardRatio,
+		}
+	case config.BackendMemory:
+		heap = NewMemHeap()
+	case config.BackendPostgres:
+		heap = &PostgresHeap{DB: &PostgresDB{
+			DSN:             cfg.Postgres.DSN,
+			Driver:          cfg.Postgres.Driver,
+			MaxOpenConns:    cfg.Postgres.MaxOpenConns,
+			MaxIdleConns:    cfg.Postgres.MaxIdleConns,
+			ConnMaxLifetime: connMaxLifetime,
+		}}
+	default:
+		f, ok := backend.LookupHeap(cfg.Heap.Backend)
+		if !ok {
+			return nil, fmt.Errorf("unknown heap backend %q", cfg.Heap.Backend)
+		}
+		if heap, err = f(backend.Options{Settings: cfg.Heap.Options}); err != nil {
+			return nil, fmt.Errorf("failed to create %s heap: %s", cfg.Heap.Backend, err)
+		}
+	}
+
+	bolt, isBolt := heap.(*BoltDBHeap)
+	pg, isPostgres := heap.(*PostgresHeap)
+	if (cfg.Ledger.Backend == config.BackendBolt && !isBolt) || (cfg.Ledger.Backend == config.BackendPostgres && !isPostgres) {
+		return nil, fmt.Errorf("the %s ledger backend requires the %s heap backend", cfg.Ledger.Backend, cfg.Ledger.Backend)
+	}
+	// newLedger creates the ledger of the root chain, whose namespace is empty, or
+	// of a virtual chain, given the chain's heap.
+	newLedger := func(heap Heap, namespace string) (Ledger, error) {
+		switch cfg.Ledger.Backend {
+		case config.BackendBolt:
+			return &BoltDBLedger{Heap: bolt, Namespace: namespace}, nil
+		case config.BackendMemory:
+			return NewMemLedger(), nil
+		case config.BackendPostgres:
+			return &PostgresLedger{DB: pg.DB, Namespace: namespace}, nil
+		}
+		f, ok := backend.LookupLedger(cfg.Ledger.Backend)
+		if !ok {
+			return nil, fmt.Errorf("unknown ledger backend %q", cfg.Ledger.Backend)
+		}
+		ledger, err := f(backend.Options{Settings: cfg.Ledger.Options, Namespace: namespace, Heap: heap})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s ledger: %s", cfg.Ledger.Backend, err)
+		}
+		return ledger, nil
+	}
+	ledger, err := newLedger(heap, "")
+	if err != nil {
+		return nil, err
+	}
+	if outputLimit.Policy == output.PolicySpill && outputLimit.Max > 0 {
+		if _, ok := ledger.(backend.BlobStore); !ok {
+			return nil, fmt.Errorf("output policy %q requires a ledger with a blob store, such as %s or %s", output.PolicySpill, config.BackendBolt, config.BackendPostgres)
+		}
+	}
+
+	// newLibrary creates the contract library of the root chain or of a virtual
+	// chain, like newLedger.
+	newLibrary := func(heap Heap, secrets SecretStore, basePath, chainID, namespace string, logger logging.Logger) (Library, error) {
+		if cfg.Contracts.Backend == config.BackendFS || cfg.Contracts.Backend == "" {
+			return &FSLibrary{
+				BasePath: basePath,
+				Credentials: Credentials{
+					AuthKey:       cfg.DragonChain.AuthKey,
+					AuthID:        cfg.DragonChain.AuthKeyID,
+					DragonChainID: chainID,
+				},
+				Logger:         logger,
+				RemoveImages:   cfg.Contracts.RemoveImages,
+				Sync:           cfg.Contracts.Sync,
+				DefaultNetwork: cfg.Contracts.Network,
+				KeyPath:        cfg.KeyPath,
+				Secrets:        secrets,
+				EnvPolicy:      envPolicy,
+			}, nil
+		}
+		f, ok := backend.LookupLibrary(cfg.Contracts.Backend)
+		if !ok {
+			return nil, fmt.Errorf("unknown library backend %q", cfg.Contracts.Backend)
+		}
+		lib, err := f(backend.Options{Settings: cfg.Contracts.Options, Namespace: namespace, Heap: heap})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s library: %s", cfg.Contracts.Backend, err)
+		}
+		return lib, nil
+	}
+
+	if cfg.Cluster.Elector == config.BackendPostgres && !isPostgres {
+		return nil, errors.New("the postgres cluster elector requires the postgres heap backend")
+	}
+	// newElector creates the elector of the root chain or of a virtual chain, like
+	// newLedger. It returns nil if the node doesn't cluster.
+	newElector := func(heap Heap, namespace string) (Elector, error) {
+		switch cfg.Cluster.Elector {
+		case "":
+			return nil, nil
+		case config.BackendPostgres:
+			return &PostgresElector{DB: pg.DB, Namespace: namespace, Interval: clusterSyncInterval}, nil
+		}
+		f, ok := backend.LookupElector(cfg.Cluster.Elector)
+		if !ok {
+			return nil, fmt.Errorf("unknown cluster elector %q", cfg.Cluster.Elector)
+		}
+		elector, err := f(backend.Options{Settings: cfg.Cluster.Options, Namespace: namespace, Heap: heap})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s elector: %s", cfg.Cluster.Elector, err)
+		}
+		return elector, nil
+	}
+
+	build := func(heap Heap, ledger Ledger, basePath, chainID, namespace string, logger logging.Logger) (*Application, error) {
+		secrets := &HeapSecretStore{Heap: heap, KeyPath: cfg.KeyPath}
+		lib, err := newLibrary(heap, secrets, basePath, chainID, namespace, logger)
+		if err != nil {
+			return nil, err
+		}
+		elector, err := newElector(heap, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := ledger.(backend.Pruner); retention != nil && !ok {
+			return nil, fmt.Errorf("the %s ledger can't be pruned", cfg.Ledger.Backend)
+		}
+		return &Application{
+			Bucket:              cfg.Heap.Bucket,
+			SharedBucket:        cfg.Heap.SharedBucket,
+			Heap:                heap,
+			Ledger:              ledger,
+			Secrets:             secrets,
+			Lib:                 lib,
+			BaseURL:             cfg.BaseURL,
+			MaxConcurrency:      cfg.MaxConcurrency,
+			MaxExecutions:       cfg.MaxExecutions,
+			OutputLimit:         outputLimit,
+			EnvPolicy:           envPolicy,
+			BreakerThreshold:    cfg.BreakerThreshold,
+			BreakerCooldown:     breakerCooldown,
+			GCInterval:          gcInterval,
+			GCKeepVersions:      cfg.GCKeepVersions,
+			Retention:           retention,
+			HeapMaxBytes:        cfg.Heap.MaxBucketBytes,
+			HeapMaxKeys:         cfg.Heap.MaxBucketKeys,
+			RequireAuth:         cfg.RequireAuth,
+			RequireSignatures:   cfg.RequireSignatures,
+			Audit:               cfg.Audit,
+			RateLimit:           cfg.RateLimit,
+			RateBurst:           cfg.RateBurst,
+			MaxTransactionSize:  cfg.MaxTransactionSize,
+			MaxContractSize:     cfg.MaxContractSize,
+			DragonChainID:       chainID,
+			Environment:         cfg.Environment,
+			ManifestVars:        cfg.ManifestVars,
+			Logger:              logger,
+			ShutdownTimeout:     shutdownTimeout,
+			BlockInterval:       blockInterval,
+			IdempotencyWindow:   idempotencyWindow,
+			Elector:             elector,
+			NodeID:              nodeID,
+			ClusterSyncInterval: clusterSyncInterval,
+		}, nil
+	}
+
+	app, err := build(heap, ledger, cfg.Contracts.BasePath, cfg.DragonChain.ID, "", logger)
+	if err != nil {
+		return nil, err
+	}
+	// Only the default chain is forwarded, since virtual chains have no DragonChain
+	// of their own.
+	if cfg.DragonChain.Forward {
+		dc := cfg.DragonChain
+		if dc.ID == "" || dc.AuthKeyID == "" || dc.AuthKey == "" {
+			return nil, errors.New("forwarding to dragonchain requires its id, auth key id and auth key")
+		}
+		endpoint := dc.Endpoint
+		if endpoint == "" {
+			endpoint = "https://" + dc.ID + ".api.dragonchain.com"
+		}
+		app.Forwarder = &Forwarder{
+			Endpoint:    endpoint,
+			Credentials: Credentials{AuthKey: dc.AuthKey, AuthID: dc.AuthKeyID, DragonChainID: dc.ID},
+		}
+	}
+	// Only the default chain is replicated, like forwarding.
+	app.ReplicationAddr = cfg.Replication.Listen
+	if cfg.Replication.Primary != "" {
+		app.Follow = &Follower{
+			Primary: cfg.Replication.Primary,
+			Credentials: Credentials{
+				AuthKey:       cfg.Replication.AuthKey,
+				AuthID:        cfg.Replication.AuthKeyID,
+				DragonChainID: cfg.DragonChain.ID,
+			},
+		}
+	}
+	// Each virtual chain keeps its buckets, ledger included, in its own namespace of
+	// the heap, and its contracts in a hidden directory of the contracts path, which
+	// FSLibrary.List skips.
+	chainPath := func(id string) string {
+		return filepath.Join(cfg.Contracts.BasePath, ".chains", id)
+	}
+	app.Chains = &Chains{
+		New: func(id string) (*Application, error) {
+			namespace := ChainNamespace(id)
+			heap := &NamespacedHeap{Heap: heap, Namespace: namespace}
+			ledger, err := newLedger(heap, namespace)
+			if err != nil {
+				return nil, err
+			}
+			return build(heap, ledger, chainPath(id), id, namespace, logger.With(logging.F("chain", id)))
+		},
+		Remove: func(id string) error {
+			heap := &NamespacedHeap{Heap: heap, Namespace: ChainNamespace(id)}
+			buckets, err := heap.Buckets()
+			if err != nil {
+				return err
+			}
+			for _, b := range buckets {
+				if err := heap.DeleteBucket(b); err != nil && err != ErrHeapNotExist {
+					return err
+				}
+			}
+			if cfg.Ledger.Backend == config.BackendPostgres {
+				ledger := &PostgresLedger{DB: pg.DB, Namespace: ChainNamespace(id)}
+				if err := ledger.Clear(); err != nil {
+					return err
+				}
+			}
+			return os.RemoveAll(chainPath(id))
+		},
+	}
+	return app, nil
+}
+
+// newRetentionPolicy returns the RetentionPolicy configured by cfg, or nil if it
+// prunes nothing.
+func newRetentionPolicy(cfg config.RetentionConfig) (*RetentionPolicy, error) {
+	if cfg.MaxAgeDays < 0 || cfg.MaxEntries < 0 {
+		return nil, errors.New("invalid ledger retention: max_age_days and max_entries must not be negative")
+	}
+	if cfg.MaxAgeDays == 0 && cfg.MaxEntries == 0 {
+		return nil, nil
+	}
+	policy := &RetentionPolicy{
+		MaxAge:     time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+		MaxEntries: cfg.MaxEntries,
+	}
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention interval: %s", err)
+		}
+		policy.Interval = d
+	}
+	switch cfg.Archive {
+	case "":
+	case "file":
+		if cfg.ArchiveDir == "" {
+			return nil, errors.New("archiving pruned transactions to files requires an archive_dir")
+		}
+		policy.Archiver = &FileArchiver{Dir: cfg.ArchiveDir}
+	case "s3":
+		if cfg.S3.Bucket == "" || cfg.S3.Region == "" {
+			return nil, errors.New("archiving pruned transactions to s3 requires a bucket and region")
+		}
+		policy.Archiver = &S3Archiver{
+			Bucket:   cfg.S3.Bucket,
+			Prefix:   cfg.S3.Prefix,
+			Region:   cfg.S3.Region,
+			Endpoint: cfg.S3.Endpoint,
+		}
+	default:
+		return nil, fmt.Errorf("unknown ledger archive %q: must be \"file\" or \"s3\"", cfg.Archive)
+	}
+	return policy, nil
+}
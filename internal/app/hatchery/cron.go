@@ -20,14 +20,23 @@
 package hatchery
 
 import (
+	"context"
 	"errors"
-	"sync/atomic"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
 )
 
 var (
 	// ErrAlreadyRunning is an error returned when a cron job is already running.
 	ErrAlreadyRunning = errors.New("cron is already running")
+	// ErrCronStopped is an error returned when a stopped cron job is run.
+	ErrCronStopped = errors.New("cron has been stopped")
 )
 
 // Executable is an executable process. Executables are executed in the background
@@ -36,70 +45,510 @@ type Executable interface {
 	// Execute start process exectuion. This is called in the background by a CronJob
 	// on interval. The payload is passed to the executable's stdin. The output of the
 	// executable is returned, along with any errors that occur during exectuion.
-	Execute(payload []byte) ([]byte, error)
+	// Execution is aborted if ctx is cancelled.
+	Execute(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// OverlapPolicy determines what a CronJob does when its schedule activates while
+// the previous execution is still running.
+type OverlapPolicy string
+
+const (
+	// OverlapConcurrent starts another execution alongside the running one.
+	OverlapConcurrent OverlapPolicy = "concurrent"
+	// OverlapSkip skips the activation.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue starts another execution once the running one finishes. At most
+	// one execution waits; further activations while one is waiting are skipped.
+	OverlapQueue OverlapPolicy = "queue"
+)
+
+// Schedule describes when a CronJob should execute.
+type Schedule interface {
+	// Next returns the next activation time strictly after t. A zero time
+	// is returned if the schedule will never activate again.
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a schedule specification. Standard five-field cron expressions
+// (minute, hour, day of month, month, day of week) such as "0 */5 * * *" are supported,
+// including lists, ranges, steps, and month/weekday names. The descriptors @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight and @hourly are also accepted, as is
+// "@every <duration>". For backward compatibility, a plain duration such as "30s" behaves
+// the same as @every. An error is returned if spec cannot be parsed.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, errors.New("empty schedule")
+	}
+	if strings.HasPrefix(spec, "@") {
+		return parseDescriptor(spec)
+	}
+	if d, err := time.ParseDuration(spec); err == nil {
+		return newIntervalSchedule(d)
+	}
+	return parseCronExpr(spec)
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func parseDescriptor(spec string) (Schedule, error) {
+	const every = "@every "
+	if strings.HasPrefix(spec, every) {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len(every):]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid @every duration: %s", err)
+		}
+		return newIntervalSchedule(d)
+	}
+	expr, ok := descriptors[spec]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized descriptor %q", spec)
+	}
+	return parseCronExpr(expr)
+}
+
+// intervalSchedule activates at a fixed interval.
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func newIntervalSchedule(d time.Duration) (Schedule, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", d)
+	}
+	return intervalSchedule{interval: d}, nil
+}
+
+// Next returns t plus the schedule's interval.
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// cronSchedule is a parsed five-field cron expression. Each field is stored
+// as a bitset of allowed values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record whether the day fields were unrestricted, which
+	// changes how they are combined. See matchDay.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Both 0 and 7 are accepted for Sunday.
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+func parseCronExpr(spec string) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression %q, found %d", spec, len(fields))
+	}
+	var (
+		s   cronSchedule
+		err error
+	)
+	if s.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if s.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if s.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if s.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if s.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	// Fold 7 into 0 so that Sunday has a single representation.
+	if s.dow&(1<<7) != 0 {
+		s.dow = (s.dow | 1) &^ (1 << 7)
+	}
+	s.domStar = isStar(fields[2])
+	s.dowStar = isStar(fields[4])
+	return &s, nil
+}
+
+func isStar(field string) bool {
+	return field == "*" || field == "?"
 }
 
-// CronJob executes an Executable in the background on interval until stoppped.
+// parse parses a single cron field into a bitset.
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		b, err := f.parsePart(part)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s field %q: %s", f.name, field, err)
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func (f cronField) parsePart(part string) (uint64, error) {
+	rng, step := part, uint(1)
+	if i := strings.Index(part, "/"); i >= 0 {
+		rng = part[:i]
+		n, err := strconv.ParseUint(part[i+1:], 10, 8)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid step %q", part[i+1:])
+		}
+		step = uint(n)
+	}
+	var lo, hi uint
+	switch {
+	case isStar(rng):
+		lo, hi = f.min, f.max
+	case strings.Contains(rng, "-"):
+		bounds := strings.SplitN(rng, "-", 2)
+		var err error
+		if lo, err = f.value(bounds[0]); err != nil {
+			return 0, err
+		}
+		if hi, err = f.value(bounds[1]); err != nil {
+			return 0, err
+		}
+	default:
+		v, err := f.value(rng)
+		if err != nil {
+			return 0, err
+		}
+		lo, hi = v, v
+		// "a/n" means every n starting at a.
+		if step > 1 {
+			hi = f.max
+		}
+	}
+	if lo > hi {
+		return 0, fmt.Errorf("range start %d is after end %d", lo, hi)
+	}
+	var bits uint64
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << v
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (uint, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	v := uint(n)
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the next time after t that matches the cron expression. The search is
+// bounded to five years; if no match is found in that window, a zero time is returned.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay reports whether t matches the day of month and day of week fields. As with
+// traditional cron, if both fields are restricted, a day matches if either field matches.
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// CronJob executes an Executable in the background on a Schedule until stopped. A
+// CronJob runs at most once: after Stop, it can't be run again. Its schedule can be
+// changed while it runs with Reschedule.
 type CronJob struct {
-	inverval    time.Duration
+	// Logger receives the CronJob's logs. It must be set before Run is called.
+	// If nil, logging.Default() is used.
+	Logger logging.Logger
+	// Overlap determines what happens when the schedule activates while the
+	// previous execution is still running. It must be set before Run is called.
+	// If empty, OverlapConcurrent is used.
+	Overlap OverlapPolicy
+	// Jitter is an upper bound on a random delay added to each activation. It
+	// must be set before Run is called.
+	Jitter time.Duration
+	// Payload returns the payload passed to each execution, when the execution
+	// begins. It must be set before Run is called. If nil, executions are passed
+	// a nil payload.
+	Payload func() ([]byte, error)
+
+	schedule    Schedule
 	executable  Executable
-	runningFlag int32
-	ticker      *time.Ticker
+	mu          sync.Mutex
+	running     bool
+	stopped     bool
+	next        time.Time
+	stopCh      chan struct{}
+	rescheduled chan struct{}
+	done        chan struct{}
+	executions  sync.WaitGroup
 	errorCh     chan error
 	outCh       chan []byte
 }
 
-// NewCronJob returns a new CronJob that will execute executable every interval.
-// The provided payload is passed into the executable's stdin each time it is executed.
-func NewCronJob(interval time.Duration, executable Executable) *CronJob {
+// NewCronJob returns a new CronJob that will execute executable at each activation
+// of schedule.
+func NewCronJob(schedule Schedule, executable Executable) *CronJob {
 	return &CronJob{
-		inverval:   interval,
-		executable: executable,
-		errorCh:    make(chan error),
-		outCh:      make(chan []byte),
+		schedule:    schedule,
+		executable:  executable,
+		stopCh:      make(chan struct{}),
+		rescheduled: make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		errorCh:     make(chan error),
+		outCh:       make(chan []byte),
 	}
 }
 
-// Run begins the execution loop. The first execution will begin after the configured interval
-// and repeat over and over every interval until Stop is called. ErrAlreadyRunning is returned
-// if the CronJob is already running. This function is blocking, so it is usually called in a
-// separate goroutine.
+// Run begins the execution loop. The first execution will begin at the schedule's next
+// activation and repeat at every subsequent activation until Stop is called or the
+// schedule never activates again. Run then waits for executions that are underway to
+// finish, closes the Errors and Output channels, and returns. ErrAlreadyRunning is
+// returned if the CronJob is already running, and ErrCronStopped if it has been stopped.
+// This function is blocking, so it is usually called in a separate goroutine.
 func (c *CronJob) Run() error {
-	if !atomic.CompareAndSwapInt32(&c.runningFlag, 0, 1) {
+	c.mu.Lock()
+	switch {
+	case c.stopped:
+		c.mu.Unlock()
+		return ErrCronStopped
+	case c.running:
+		c.mu.Unlock()
 		return ErrAlreadyRunning
 	}
-	c.ticker = time.NewTicker(c.inverval)
-	for range c.ticker.C {
+	c.running = true
+	c.mu.Unlock()
+	logger := c.Logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+	logger.Info("cron job started")
+	defer func() {
+		c.executions.Wait()
+		close(c.errorCh)
+		close(c.outCh)
+		close(c.done)
+		logger.Info("cron job stopped")
+	}()
+	// running holds a token while an execution started under OverlapSkip is
+	// underway, and queue holds the execution waiting under OverlapQueue.
+	running := make(chan struct{}, 1)
+	queue := make(chan struct{}, 1)
+	if c.Overlap == OverlapQueue {
+		c.executions.Add(1)
 		go func() {
-			b, err := c.executable.Execute(nil)
-			if err != nil {
-				c.errorCh <- err
-				return
-			}
-			if b != nil {
-				c.outCh <- b
+			defer c.executions.Done()
+			for {
+				select {
+				case <-c.stopCh:
+					return
+				case <-queue:
+					c.execute()
+				}
 			}
 		}()
 	}
+	for {
+		now := time.Now()
+		c.mu.Lock()
+		next := c.schedule.Next(now)
+		c.mu.Unlock()
+		if next.IsZero() {
+			// Nothing more will be queued, so the queue worker can exit.
+			c.Stop()
+			return nil
+		}
+		if c.Jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(c.Jitter))))
+		}
+		c.mu.Lock()
+		c.next = next
+		c.mu.Unlock()
+		logger.Debug("next execution scheduled", logging.F("at", next.Format(time.RFC3339)))
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-c.stopCh:
+			timer.Stop()
+			return nil
+		case <-c.rescheduled:
+			// The pending activation belongs to the old schedule, so the next one is
+			// computed again from the new schedule.
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+		switch c.Overlap {
+		case OverlapSkip:
+			select {
+			case running <- struct{}{}:
+				c.executions.Add(1)
+				go func() {
+					defer c.executions.Done()
+					c.execute()
+					<-running
+				}()
+			default:
+				logger.Info("execution skipped, previous execution still running")
+			}
+		case OverlapQueue:
+			select {
+			case queue <- struct{}{}:
+			default:
+				logger.Info("execution skipped, an execution is already queued")
+			}
+		default:
+			c.executions.Add(1)
+			go func() {
+				defer c.executions.Done()
+				c.execute()
+			}()
+		}
+	}
+}
+
+// execute executes the executable once, sending its output or error to the
+// CronJob's channels.
+func (c *CronJob) execute() {
+	var payload []byte
+	if c.Payload != nil {
+		var err error
+		if payload, err = c.Payload(); err != nil {
+			c.errorCh <- fmt.Errorf("failed to read payload: %s", err)
+			return
+		}
+	}
+	b, err := c.executable.Execute(context.Background(), payload)
+	if err != nil {
+		c.errorCh <- err
+		return
+	}
+	if b != nil {
+		c.outCh <- b
+	}
+}
+
+// Reschedule replaces the CronJob's schedule. If the CronJob is running, its pending
+// activation is cancelled and the next one is computed from schedule, while executions
+// that are already underway finish undisturbed. ErrCronStopped is returned if the
+// CronJob has been stopped.
+func (c *CronJob) Reschedule(schedule Schedule) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return ErrCronStopped
+	}
+	c.schedule = schedule
+	select {
+	case c.rescheduled <- struct{}{}:
+	default:
+		// The loop has yet to pick up an earlier change, and will see this one too.
+	}
 	return nil
 }
 
-// Stop stops the cron loop. If an execution is already underway, it will still finish in the background,
-// but no further exectuions will occur.
+// Next returns the time of the CronJob's pending activation, including its jitter.
+// The zero time is returned if the CronJob isn't waiting for an activation.
+func (c *CronJob) Next() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return time.Time{}
+	}
+	return c.next
+}
+
+// Stop stops the cron loop. No further executions will begin, but executions that
+// are already underway still finish in the background. Use Done to wait for them.
+// If the CronJob was never run, its channels are closed right away. Stop may be
+// called more than once.
 func (c *CronJob) Stop() {
-	if atomic.CompareAndSwapInt32(&c.runningFlag, 1, 0) {
-		c.ticker.Stop()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+	c.stopped = true
+	close(c.stopCh)
+	if !c.running {
+		close(c.errorCh)
+		close(c.outCh)
+		close(c.done)
 	}
 }
 
+// Done returns a channel that is closed once the CronJob has stopped and its last
+// execution has finished.
+func (c *CronJob) Done() <-chan struct{} {
+	return c.done
+}
+
 // Errors returns a channel for listening for errors returned by the executable on execution.
-// This channel is unbuffered, so it should be aggressively consumed.
+// This channel is unbuffered, so it should be aggressively consumed. It is closed once the
+// CronJob has stopped and its last execution has finished.
 func (c *CronJob) Errors() <-chan error {
 	return c.errorCh
 }
 
 // Output returns a channel for listening for output from the executable on execution.
-// This cahnnel is unbuffered, so it should be aggressively consumed.
+// This channel is unbuffered, so it should be aggressively consumed. It is closed once the
+// CronJob has stopped and its last execution has finished.
 func (c *CronJob) Output() <-chan []byte {
 	return c.outCh
 }
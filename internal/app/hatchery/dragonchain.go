@@ -0,0 +1,609 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// The routes under /v1 emulate the public REST API of a DragonChain L1, with its paths,
+// request bodies and response envelopes, so that SDKs written for DragonChain work
+// against Hatchery unmodified. Contracts are identified by their name, which serves as
+// both their ID and their transaction type.
+
+// dragonchainErrorTypes maps error codes to the error types of DragonChain's error
+// envelope. Codes that aren't listed are reported as internal errors.
+var dragonchainErrorTypes = map[string]string{
+	ErrCodeBadRequest:          "BAD_REQUEST",
+	ErrCodeInvalidManifest:     "VALIDATION_ERROR",
+	ErrCodeSchemaViolation:     "VALIDATION_ERROR",
+	ErrCodePayloadTooLarge:     "VALIDATION_ERROR",
+	ErrCodeUnauthorized:        "INVALID_AUTH",
+	ErrCodeHeapAccessDenied:    "ACTION_FORBIDDEN",
+	ErrCodeReadOnly:            "ACTION_FORBIDDEN",
+	ErrCodeNotFound:            "NOT_FOUND",
+	ErrCodeContractNotFound:    "NOT_FOUND",
+	ErrCodeVersionNotFound:     "NOT_FOUND",
+	ErrCodeTransactionNotFound: "NOT_FOUND",
+	ErrCodeHeapMiss:            "NOT_FOUND",
+	ErrCodeConflict:            "CONTRACT_CONFLICT",
+	ErrCodeRateLimited:         "TOO_MANY_REQUESTS",
+	ErrCodeQuotaExceeded:       "TOO_MANY_REQUESTS",
+}
+
+// dragonchainErrorResponse is DragonChain's error envelope.
+type dragonchainErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Details string `json:"details"`
+	} `json:"error"`
+}
+
+// dragonchainRecorder holds back the error responses of a handler, so that they can be
+// rewritten in DragonChain's error envelope.
+type dragonchainRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *dragonchainRecorder) WriteHeader(status int) {
+	if status >= http.StatusBadRequest {
+		r.status = status
+		return
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *dragonchainRecorder) Write(p []byte) (int, error) {
+	if r.status != 0 {
+		return r.body.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// dragonchain wraps a handler of the DragonChain API so that it is protected like the
+// rest of the API, and its errors are reported in DragonChain's error envelope.
+func (a *Application) dragonchain(next http.HandlerFunc) http.HandlerFunc {
+	return dragonchainErrors(a.protected(next))
+}
+
+// dragonchainErrors rewrites the error envelopes next responds with as DragonChain's.
+func dragonchainErrors(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &dragonchainRecorder{ResponseWriter: w}
+		next(rec, r)
+		if rec.status == 0 {
+			return
+		}
+		var in errorResponse
+		json.Unmarshal(rec.body.Bytes(), &in)
+		var out dragonchainErrorResponse
+		out.Error.Type = "INTERNAL_SERVER_ERROR"
+		if t, ok := dragonchainErrorTypes[in.Error.Code]; ok {
+			out.Error.Type = t
+		}
+		out.Error.Details = in.Error.Message
+		w.Header().Set("Content-type", "application/json")
+		w.WriteHeader(rec.status)
+		writeJSONResponse(w, out)
+	}
+}
+
+// dragonchainStatus is the response of DragonChain's GET /v1/status.
+type dragonchainStatus struct {
+	ID       string `json:"id"`
+	Level    int    `json:"level"`
+	URL      string `json:"url"`
+	HashAlgo string `json:"hashAlgo"`
+	Scheme   string `json:"scheme"`
+	Version  string `json:"version"`
+}
+
+// DragonChainStatus returns an HTTP handler function that describes the node like a
+// DragonChain L1 describes itself.
+func (a *Application) DragonChainStatus() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, dragonchainStatus{
+			ID:       a.DragonChainID,
+			Level:    1,
+			URL:      a.BaseURL,
+			HashAlgo: "sha256",
+			Scheme:   "trust",
+			Version:  "hatchery",
+		})
+	}
+}
+
+// dragonchainTransactionRequest is the body of DragonChain's POST /v1/transaction,
+// and an element of the body of POST /v1/transaction_bulk. See dragonchainTransaction.
+type dragonchainTransactionRequest struct {
+	Version string          `json:"version"`
+	TxnType string          `json:"txn_type"`
+	Payload json.RawMessage `json:"payload"`
+	Tag     string          `json:"tag"`
+}
+
+// content returns the payload of the transaction, as contracts receive it: payloads
+// that are JSON strings are passed without their quotes, like DragonChain does, and
+// any other payload as JSON.
+func (req *dragonchainTransactionRequest) content() []byte {
+	var s string
+	if err := json.Unmarshal(req.Payload, &s); err == nil {
+		return []byte(s)
+	}
+	return req.Payload
+}
+
+var errTxnTypeRequired = errors.New("txn_type is required")
+
+// dragonchainTransactionCreated is the response of DragonChain's POST /v1/transaction.
+type dragonchainTransactionCreated struct {
+	TransactionID string `json:"transaction_id"`
+}
+
+// dragonchainEnqueue queues the transaction of req and returns its ID. Like on
+// DragonChain, the transaction is processed asynchronously.
+func (a *Application) dragonchainEnqueue(r *http.Request, req *dragonchainTransactionRequest) (string, error) {
+	if req.TxnType == "" {
+		return "", errTxnTypeRequired
+	}
+	txn := postTransactionRequest{Type: req.TxnType, Payload: req.content()}
+	signer, err := a.verifyTransaction(&txn)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.New().String()
+	// The outcome is dropped, since the transaction is read back with
+	// DragonChainGetTransaction.
+	if _, err := a.enqueue(r.Context(), id, txn.Type, txn.Payload, signer); err != nil {
+		return "", err
+	}
+	auditNote(r, "txn_id", id)
+	return id, nil
+}
+
+// DragonChainPostTransaction returns an HTTP handler function that queues a transaction
+// posted in DragonChain's format, and responds with its ID without waiting for its
+// contract to execute. Transactions are processed like those posted asynchronously to
+// PostTransaction. Their tag is not stored.
+func (a *Application) DragonChainPostTransaction() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dragonchainTransactionRequest
+		limitBody(w, r, a.maxTransactionSize())
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, ErrCodeBadRequest, "invalid transaction", err)
+			return
+		}
+		if req.TxnType == "" {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, errTxnTypeRequired.Error())
+			return
+		}
+		auditNote(r, "txn_type", req.TxnType)
+		id, err := a.dragonchainEnqueue(r, &req)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.Header().Set("Content-type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSONResponse(w, dragonchainTransactionCreated{TransactionID: id})
+	}
+}
+
+// DragonChainPostTransactionBulk returns an HTTP handler function that queues each
+// transaction of a JSON array posted in DragonChain's format, like
+// DragonChainPostTransaction. It responds with a 207 status holding the IDs of the
+// queued transactions under "201", and the transactions that couldn't be queued under
+// "400", as DragonChain does.
+func (a *Application) DragonChainPostTransactionBulk() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var reqs []dragonchainTransactionRequest
+		limitBody(w, r, a.maxTransactionSize()*maxBulkTransactions)
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			writeDecodeError(w, ErrCodeBadRequest, "invalid transactions", err)
+			return
+		}
+		if len(reqs) == 0 || len(reqs) > maxBulkTransactions {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("between 1 and %d transactions must be posted", maxBulkTransactions))
+			return
+		}
+		resp := map[string]interface{}{}
+		queued := []string{}
+		var failed []dragonchainTransactionRequest
+		for i := range reqs {
+			id, err := a.dragonchainEnqueue(r, &reqs[i])
+			if err != nil {
+				a.log().Info("bulk transaction rejected", logging.F("txn_type", reqs[i].TxnType), logging.Err(err))
+				failed = append(failed, reqs[i])
+				continue
+			}
+			queued = append(queued, id)
+		}
+		resp["201"] = queued
+		if len(failed) > 0 {
+			resp["400"] = failed
+		}
+		w.Header().Set("Content-type", "application/json")
+		w.WriteHeader(http.StatusMultiStatus)
+		writeJSONResponse(w, resp)
+	}
+}
+
+// dragonchainTransactionResponse is a transaction in DragonChain's format, as returned
+// by its GET /v1/transaction/{id}.
+type dragonchainTransactionResponse struct {
+	Version string               `json:"version"`
+	DCRN    string               `json:"dcrn"`
+	Header  dragonchainTxnHeader `json:"header"`
+	Payload interface{}          `json:"payload"`
+	Proof   dragonchainTxnProof  `json:"proof"`
+}
+
+type dragonchainTxnHeader struct {
+	TxnType   string `json:"txn_type"`
+	DCID      string `json:"dc_id"`
+	TxnID     string `json:"txn_id"`
+	BlockID   string `json:"block_id"`
+	Timestamp string `json:"timestamp"`
+	Tag       string `json:"tag"`
+	Invoker   string `json:"invoker"`
+}
+
+type dragonchainTxnProof struct {
+	Full     string `json:"full"`
+	Stripped string `json:"stripped"`
+}
+
+// dragonchainPending is DragonChain's response for a transaction that is still being
+// processed.
+type dragonchainPending struct {
+	TxnID   string `json:"txn_id"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// newDragonChainTransaction returns t in DragonChain's format. Its payload is the
+// payload it was posted with, like on DragonChain, whose contract output is stored in
+// the heap rather than on the posted transaction. Payloads that are JSON objects or
+// arrays are inlined, and others are returned as strings. The proof holds the
+// transaction's hash.
+func (a *Application) newDragonChainTransaction(t *Transaction) dragonchainTransactionResponse {
+	payload := t.Payload
+	if t.InvokerContract == "" {
+		payload = t.Content
+	}
+	resp := dragonchainTransactionResponse{
+		Version: "2",
+		DCRN:    "Transaction::L1::FullTransaction",
+		Header: dragonchainTxnHeader{
+			TxnType:   t.Type,
+			DCID:      a.DragonChainID,
+			TxnID:     t.ID,
+			Timestamp: strconv.FormatInt(t.Timestamp.Unix(), 10),
+			Invoker:   t.InvokerContract,
+		},
+		Payload: string(payload),
+		Proof:   dragonchainTxnProof{Full: t.Hash},
+	}
+	if trimmed := bytes.TrimSpace(payload); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		resp.Payload = json.RawMessage(trimmed)
+	}
+	return resp
+}
+
+// DragonChainGetTransaction returns an HTTP handler function that responds with a
+// transaction in DragonChain's format. Transactions still in the work queue are
+// reported as pending, like DragonChain reports transactions that aren't in a block
+// yet, and those that failed as not found.
+func (a *Application) DragonChainGetTransaction() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		t, err := a.Ledger.Find(id)
+		if err == nil {
+			writeJSONResponse(w, a.newDragonChainTransaction(t))
+			return
+		}
+		if err != ErrTransactionNotExist {
+			writeErrorFrom(w, err)
+			return
+		}
+		item, err := a.queuedItem(id)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if item == nil {
+			writeErrorFrom(w, ErrTransactionNotExist)
+			return
+		}
+		if item.Status == QueueStatusFailed || item.Status == QueueStatusCancelled {
+			writeError(w, http.StatusNotFound, ErrCodeTransactionNotFound, "transaction failed: "+item.LastError)
+			return
+		}
+		writeJSONResponse(w, dragonchainPending{
+			TxnID:   id,
+			Status:  "pending",
+			Message: "This transaction is waiting to be included in a block",
+		})
+	}
+}
+
+// dragonchainContract is a contract in DragonChain's format, as returned by its
+// GET /v1/contract/{id}.
+type dragonchainContract struct {
+	DCRN            string                    `json:"dcrn"`
+	Version         string                    `json:"version"`
+	ID              string                    `json:"id"`
+	TxnType         string                    `json:"txn_type"`
+	Status          dragonchainContractStatus `json:"status"`
+	Image           string                    `json:"image"`
+	ImageDigest     string                    `json:"image_digest"`
+	Cmd             string                    `json:"cmd"`
+	Args            []string                  `json:"args"`
+	Env             map[string]string         `json:"env"`
+	ExistingSecrets []string                  `json:"existing_secrets"`
+	Cron            *string                   `json:"cron"`
+	Seconds         *int                      `json:"seconds"`
+	ExecutionOrder  string                    `json:"execution_order"`
+}
+
+type dragonchainContractStatus struct {
+	State     string `json:"state"`
+	Msg       string `json:"msg"`
+	Timestamp string `json:"timestamp"`
+}
+
+// newDragonChainContract returns the contract of m in DragonChain's format, in the state
+// of its latest registration, if the node has one.
+func (a *Application) newDragonChainContract(m *ContractManifest) dragonchainContract {
+	c := dragonchainContract{
+		DCRN:            "SmartContract::L1::AtRest",
+		Version:         "1",
+		ID:              m.Type,
+		TxnType:         m.Type,
+		Status:          dragonchainContractStatus{State: "active", Msg: "Contract active"},
+		Image:           m.Image,
+		ImageDigest:     m.ImageDigest,
+		Cmd:             m.Cmd,
+		Args:            m.Args,
+		Env:             m.Env,
+		ExistingSecrets: []string{},
+		ExecutionOrder:  string(m.ExecutionOrder),
+	}
+	if c.Args == nil {
+		c.Args = []string{}
+	}
+	if c.Env == nil {
+		c.Env = map[string]string{}
+	}
+	for name := range m.Secrets {
+		c.ExistingSecrets = append(c.ExistingSecrets, name)
+	}
+	sort.Strings(c.ExistingSecrets)
+	if m.Cron != "" {
+		every := strings.TrimPrefix(m.Cron, "@every ")
+		if d, err := time.ParseDuration(every); err == nil && d%time.Second == 0 {
+			seconds := int(d / time.Second)
+			c.Seconds = &seconds
+		} else {
+			cron := m.Cron
+			c.Cron = &cron
+		}
+	}
+	if reg := a.registrationStatus(m.Type); reg != nil {
+		switch reg.State {
+		case RegistrationPulling:
+			c.Status = dragonchainContractStatus{State: "pending", Msg: "Contract creating", Timestamp: reg.Started.Format(time.RFC3339)}
+		case RegistrationFailed:
+			c.Status = dragonchainContractStatus{State: "error", Msg: reg.Error}
+		}
+		if reg.Finished != nil {
+			c.Status.Timestamp = reg.Finished.Format(time.RFC3339)
+		}
+	}
+	return c
+}
+
+// DragonChainListContracts returns an HTTP handler function that responds with every
+// contract in DragonChain's format, in DragonChain's list envelope.
+func (a *Application) DragonChainListContracts() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		manifests, err := a.Lib.List()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		items := make([]dragonchainContract, 0, len(manifests))
+		for i := range manifests {
+			items = append(items, a.newDragonChainContract(&manifests[i]))
+		}
+		writeJSONResponse(w, map[string]interface{}{"items": items})
+	}
+}
+
+// DragonChainGetContract returns an HTTP handler function that responds with a contract
+// in DragonChain's format. The contract is identified by its name, whether the request
+// addresses it by ID or by transaction type.
+func (a *Application) DragonChainGetContract() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, err := a.Lib.Manifest(mux.Vars(r)["name"])
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, a.newDragonChainContract(m))
+	}
+}
+
+// dragonchainContractRequest is the body of DragonChain's POST /v1/contract.
+type dragonchainContractRequest struct {
+	Version        string            `json:"version"`
+	TxnType        string            `json:"txn_type"`
+	Image          string            `json:"image"`
+	Cmd            string            `json:"cmd"`
+	Args           []string          `json:"args"`
+	ExecutionOrder string            `json:"execution_order"`
+	Env            map[string]string `json:"env"`
+	// Secrets holds the values of secrets, by the name of the environment
+	// variable they are passed in.
+	Secrets map[string]string `json:"secrets"`
+	Seconds int               `json:"seconds"`
+	Cron    string            `json:"cron"`
+	// Auth is the registry credential the image is pulled with, as
+	// ContractManifest.Auth.
+	Auth string `json:"auth"`
+}
+
+// manifest returns the manifest of the contract req creates. Its secrets are named
+// after the contract and the environment variable they are passed in.
+func (req *dragonchainContractRequest) manifest() *ContractManifest {
+	m := &ContractManifest{
+		Type:           req.TxnType,
+		Image:          req.Image,
+		Cmd:            req.Cmd,
+		Args:           req.Args,
+		ExecutionOrder: ExecutionOrder(req.ExecutionOrder),
+		Env:            req.Env,
+		Cron:           req.Cron,
+		Auth:           req.Auth,
+	}
+	if req.Seconds > 0 {
+		m.Cron = fmt.Sprintf("@every %ds", req.Seconds)
+	}
+	if len(req.Secrets) > 0 {
+		m.Secrets = make(map[string]string, len(req.Secrets))
+		for name := range req.Secrets {
+			m.Secrets[name] = dragonchainSecretName(req.TxnType, name)
+		}
+	}
+	return m
+}
+
+// dragonchainSecretName returns the name of the secret a contract created through the
+// DragonChain API is passed in the environment variable env.
+func dragonchainSecretName(contract, env string) string {
+	return contract + "." + env
+}
+
+// DragonChainPostContract returns an HTTP handler function that creates a contract
+// posted in DragonChain's format. The contract's secrets are stored in the node's
+// secret store first, and removed again if the contract is invalid. Like on DragonChain, the contract is created asynchronously: the
+// request is answered with a 202 holding the pending contract, which becomes active
+// once its image has been pulled. A contract with the same transaction type must not
+// exist already.
+func (a *Application) DragonChainPostContract() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req dragonchainContractRequest
+		limitBody(w, r, a.maxContractSize())
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, ErrCodeInvalidManifest, "invalid contract", err)
+			return
+		}
+		if req.Seconds > 0 && req.Cron != "" {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidManifest, "only one of seconds and cron may be set")
+			return
+		}
+		if _, err := a.Lib.Manifest(req.TxnType); err == nil {
+			writeError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("contract %s already exists", req.TxnType))
+			return
+		} else if err != ErrContractNotExist {
+			writeErrorFrom(w, err)
+			return
+		}
+		if len(req.Secrets) > 0 && a.Secrets == nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidManifest, "no secret store is configured")
+			return
+		}
+		for env := range req.Secrets {
+			if !validSecretName(dragonchainSecretName(req.TxnType, env)) {
+				writeError(w, http.StatusBadRequest, ErrCodeInvalidManifest, fmt.Sprintf("invalid secret name %q", env))
+				return
+			}
+		}
+		// The secrets are stored before the manifest is validated, since validation
+		// requires the secrets it references to exist. They are removed again if the
+		// contract isn't created.
+		var stored []string
+		discard := func() {
+			for _, name := range stored {
+				a.Secrets.DeleteSecret(name)
+			}
+		}
+		for env, value := range req.Secrets {
+			name := dragonchainSecretName(req.TxnType, env)
+			if _, err := a.Secrets.PutSecret(name, value); err != nil {
+				discard()
+				writeErrorFrom(w, err)
+				return
+			}
+			stored = append(stored, name)
+		}
+		m := req.manifest()
+		schedule, ok := a.validateManifest(w, m)
+		if !ok {
+			discard()
+			return
+		}
+		auditNote(r, "contract", m.Type)
+		reg := a.beginRegistration(m)
+		go a.storeContractAsync(reg, m, schedule, a.Lib.Put)
+		w.Header().Set("Content-type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		writeJSONResponse(w, a.newDragonChainContract(m))
+	}
+}
+
+// DragonChainDeleteContract returns an HTTP handler function that deletes a contract
+// like DeleteContract, and responds like DragonChain does.
+func (a *Application) DragonChainDeleteContract() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := a.deleteContract(mux.Vars(r)["name"]); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.Header().Set("Content-type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		writeJSONResponse(w, map[string]bool{"success": true})
+	}
+}
+
+// dragonchainRaw makes GetSCHeap respond with the raw bytes of heap values, as
+// DragonChain's GET /v1/get/{id}/{key} does.
+func dragonchainRaw(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		q.Set("format", heapFormatRaw)
+		r.URL.RawQuery = q.Encode()
+		next(w, r)
+	}
+}
@@ -0,0 +1,160 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+	"github.com/summerplaygames/hatchery/internal/app/process"
+	"github.com/summerplaygames/hatchery/internal/app/wasm"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+type testContractRequest struct {
+	Manifest ContractManifest `json:"manifest"`
+	Payload  json.RawMessage  `json:"payload"`
+}
+
+// testContractResponse is the outcome of a dry run. Stdout and Stderr are returned
+// as text for readability.
+type testContractResponse struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	Duration string `json:"duration"`
+}
+
+// TestContract returns an HTTP handler function that executes the posted manifest's
+// contract with the posted payload and responds with its stdout, stderr, exit code
+// and duration. The contract is not stored in the Library, its output is not written
+// to the heap, and nothing is appended to the ledger. It is not given a heap token,
+// so it cannot write to the heap itself either. A non-zero exit code is reported in
+// the response rather than as an error.
+func (a *Application) TestContract() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req testContractRequest
+		limitBody(w, r, a.maxContractSize()+a.maxTransactionSize())
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, ErrCodeBadRequest, "invalid request", err)
+			return
+		}
+		manifest := &req.Manifest
+		if _, ok := a.validateManifest(w, manifest); !ok {
+			return
+		}
+		runtime, err := LookupRuntime(manifest.Runtime)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if err := runtime.Prepare(manifest); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidManifest, err.Error())
+			return
+		}
+		contract, err := a.Lib.Contract(manifest)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if e, ok := contract.(Environ); ok {
+			e.SetEnv(HatcheryURL, a.BaseURL)
+		}
+		if err := a.interpolateEnv(contract, manifest); err != nil {
+			writeErrorFrom(w, &ExecutionError{Contract: manifest.Type, Err: err})
+			return
+		}
+		a.limitOutput(contract)
+		if c, ok := contract.(*docker.Contract); ok && c.Warm > 0 {
+			// The warm protocol is exercised in a container of its own, which is
+			// removed afterwards instead of joining the contract's pool.
+			pool := &docker.Pool{NoPrewarm: true}
+			defer pool.Close()
+			c.Pool = pool
+		}
+		ctx := backend.WithInvocation(r.Context(), backend.Invocation{TxnType: manifest.Type, Timestamp: time.Now().UTC()})
+		res, err := runContract(ctx, contract, req.Payload)
+		if err != nil {
+			writeErrorFrom(w, &ExecutionError{Contract: manifest.Type, Err: err})
+			return
+		}
+		writeJSONResponse(w, testContractResponse{
+			Stdout:   string(res.Stdout),
+			Stderr:   string(res.Stderr),
+			ExitCode: res.ExitCode,
+			Duration: res.Duration.String(),
+		})
+	}
+}
+
+// runResult is the complete outcome of an execution.
+type runResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+}
+
+// runContract executes contract and reports the complete outcome when the contract's
+// runtime provides it. For other contracts, only stdout and the duration are known,
+// and a failed execution is returned as an error. A non-zero exit code is not
+// considered an error.
+func runContract(ctx context.Context, contract Contract, payload []byte) (*runResult, error) {
+	switch c := contract.(type) {
+	case *queuedContract:
+		if err := c.queue.acquire(ctx, c.limit); err != nil {
+			return nil, err
+		}
+		defer c.queue.release()
+		if err := c.slots.acquire(ctx, c.name, c.priority, c.maxSlots); err != nil {
+			return nil, err
+		}
+		defer c.slots.release()
+		defer c.flushOutput()
+		return runContract(ctx, c.contract, payload)
+	case *docker.Contract:
+		res, err := c.Run(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		return &runResult{Stdout: res.Stdout, Stderr: res.Stderr, ExitCode: res.ExitCode, Duration: res.Duration}, nil
+	case *process.Contract:
+		res, err := c.Run(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		return &runResult{Stdout: res.Stdout, Stderr: res.Stderr, ExitCode: res.ExitCode, Duration: res.Duration}, nil
+	case *wasm.Contract:
+		res, err := c.Run(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		return &runResult{Stdout: res.Stdout, Stderr: res.Stderr, ExitCode: res.ExitCode, Duration: res.Duration}, nil
+	}
+	start := time.Now()
+	out, err := contract.Execute(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &runResult{Stdout: out, Duration: time.Since(start)}, nil
+}
@@ -0,0 +1,106 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// reservedEnv holds the environment variables Hatchery passes to every contract.
+// Manifests can't set them, so a contract can't be misled about its own name, its
+// transaction or the credentials of its chain.
+var reservedEnv = map[string]bool{
+	SCName:                  true,
+	AuthKey:                 true,
+	AuthID:                  true,
+	DragonChainID:           true,
+	HatcheryURL:             true,
+	HeapTokenKey:            true,
+	backend.TxnIDEnv:        true,
+	backend.TxnTypeEnv:      true,
+	backend.TxnTimestampEnv: true,
+	backend.InvokerEnv:      true,
+}
+
+// isReservedEnv reports whether the environment variable name is set by Hatchery.
+func isReservedEnv(name string) bool {
+	return reservedEnv[name]
+}
+
+// EnvPolicy restricts the environment variables that manifests may set in Env and
+// Secrets. Patterns are variable names, optionally ending in * to match every name
+// that begins with the rest of the pattern, such as "APP_*". The variables Hatchery
+// reserves are never permitted, whatever the policy.
+type EnvPolicy struct {
+	// Allow lists the patterns a variable must match. If empty, every variable
+	// that isn't denied is permitted.
+	Allow []string
+	// Deny lists the patterns of variables that are never permitted, even if they
+	// are allowed.
+	Deny []string
+}
+
+// Validate returns an error if any of the policy's patterns is invalid.
+func (p EnvPolicy) Validate() error {
+	for _, patterns := range [][]string{p.Allow, p.Deny} {
+		for _, pattern := range patterns {
+			if pattern != "*" && !validEnvName(strings.TrimSuffix(pattern, "*")) {
+				return fmt.Errorf("invalid environment variable pattern %q", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Permits reports whether a manifest may set the environment variable name.
+func (p EnvPolicy) Permits(name string) bool {
+	if isReservedEnv(name) || envPatternsMatch(p.Deny, name) {
+		return false
+	}
+	return len(p.Allow) == 0 || envPatternsMatch(p.Allow, name)
+}
+
+// violation explains why name isn't permitted, or returns "" if it is.
+func (p EnvPolicy) violation(name string) string {
+	switch {
+	case isReservedEnv(name):
+		return "is reserved by Hatchery"
+	case !p.Permits(name):
+		return "is not permitted by the node's environment policy"
+	}
+	return ""
+}
+
+// envPatternsMatch reports whether name matches any of the patterns of an EnvPolicy.
+func envPatternsMatch(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import "testing"
+
+func TestEnvPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  EnvPolicy
+		wantErr bool
+	}{
+		{"empty", EnvPolicy{}, false},
+		{"names", EnvPolicy{Allow: []string{"APP_NAME", "_DEBUG"}, Deny: []string{"PATH"}}, false},
+		{"prefix", EnvPolicy{Allow: []string{"APP_*"}}, false},
+		{"everything", EnvPolicy{Deny: []string{"*"}}, false},
+		{"empty pattern", EnvPolicy{Allow: []string{""}}, true},
+		{"inner star", EnvPolicy{Allow: []string{"APP_*_KEY"}}, true},
+		{"leading digit", EnvPolicy{Deny: []string{"1APP"}}, true},
+		{"invalid character", EnvPolicy{Deny: []string{"APP-NAME"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvPolicyPermits(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    EnvPolicy
+		env       string
+		want      bool
+		violation string
+	}{
+		{"no policy", EnvPolicy{}, "APP_NAME", true, ""},
+		{"allowed name", EnvPolicy{Allow: []string{"APP_NAME"}}, "APP_NAME", true, ""},
+		{"name not allowed", EnvPolicy{Allow: []string{"APP_NAME"}}, "APP_NAMES", false, "is not permitted by the node's environment policy"},
+		{"allowed prefix", EnvPolicy{Allow: []string{"APP_*"}}, "APP_NAME", true, ""},
+		{"prefix matches itself", EnvPolicy{Allow: []string{"APP_*"}}, "APP_", true, ""},
+		{"prefix not allowed", EnvPolicy{Allow: []string{"APP_*"}}, "DB_NAME", false, "is not permitted by the node's environment policy"},
+		{"denied name", EnvPolicy{Deny: []string{"LD_PRELOAD"}}, "LD_PRELOAD", false, "is not permitted by the node's environment policy"},
+		{"other name not denied", EnvPolicy{Deny: []string{"LD_PRELOAD"}}, "LD_LIBRARY_PATH", true, ""},
+		{"denied prefix", EnvPolicy{Deny: []string{"LD_*"}}, "LD_LIBRARY_PATH", false, "is not permitted by the node's environment policy"},
+		{"deny wins over allow", EnvPolicy{Allow: []string{"APP_*"}, Deny: []string{"APP_SECRET"}}, "APP_SECRET", false, "is not permitted by the node's environment policy"},
+		{"deny prefix wins over allow", EnvPolicy{Allow: []string{"APP_SECRET"}, Deny: []string{"APP_*"}}, "APP_SECRET", false, "is not permitted by the node's environment policy"},
+		{"deny everything", EnvPolicy{Deny: []string{"*"}}, "APP_NAME", false, "is not permitted by the node's environment policy"},
+		{"reserved name", EnvPolicy{}, SCName, false, "is reserved by Hatchery"},
+		{"reserved key", EnvPolicy{}, AuthKey, false, "is reserved by Hatchery"},
+		{"reserved key id", EnvPolicy{}, AuthID, false, "is reserved by Hatchery"},
+		{"reserved chain", EnvPolicy{}, DragonChainID, false, "is reserved by Hatchery"},
+		{"reserved heap token", EnvPolicy{}, HeapTokenKey, false, "is reserved by Hatchery"},
+		{"reserved transaction", EnvPolicy{}, "TXN_ID", false, "is reserved by Hatchery"},
+		{"reserved even if allowed", EnvPolicy{Allow: []string{"*"}}, AuthKey, false, "is reserved by Hatchery"},
+		{"reserved even if allowed by name", EnvPolicy{Allow: []string{SCName}}, SCName, false, "is reserved by Hatchery"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Permits(tt.env); got != tt.want {
+				t.Errorf("Permits(%q) = %t, want %t", tt.env, got, tt.want)
+			}
+			if got := tt.policy.violation(tt.env); got != tt.violation {
+				t.Errorf("violation(%q) = %q, want %q", tt.env, got, tt.violation)
+			}
+		})
+	}
+}
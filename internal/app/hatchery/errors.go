@@ -0,0 +1,217 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+	"github.com/summerplaygames/hatchery/internal/app/output"
+)
+
+// Error codes returned in the error envelope of unsuccessful API responses.
+const (
+	ErrCodeBadRequest          = "bad_request"
+	ErrCodeUnauthorized        = "unauthorized"
+	ErrCodeNotFound            = "not_found"
+	ErrCodeMethodNotAllowed    = "method_not_allowed"
+	ErrCodeContractNotFound    = "contract_not_found"
+	ErrCodeVersionNotFound     = "version_not_found"
+	ErrCodeTransactionNotFound = "transaction_not_found"
+	ErrCodeBlockNotFound       = "block_not_found"
+	ErrCodeChainNotFound       = "chain_not_found"
+	ErrCodeHeapMiss            = "heap_miss"
+	ErrCodeInvalidManifest     = "invalid_manifest"
+	ErrCodeExecutionFailed     = "execution_failed"
+	ErrCodeConflict            = "conflict"
+	ErrCodeRateLimited         = "rate_limited"
+	ErrCodePayloadTooLarge     = "payload_too_large"
+	ErrCodeCircuitOpen         = "circuit_open"
+	ErrCodeImageDrifted        = "image_drifted"
+	ErrCodeReadOnly            = "read_only"
+	ErrCodeInternal            = "internal_error"
+)
+
+// ExecutionError is returned when a smart contract fails to execute.
+type ExecutionError struct {
+	Contract string
+	Err      error
+}
+
+func (e *ExecutionError) Error() string {
+	return fmt.Sprintf("contract %s failed: %s", e.Contract, e.Err)
+}
+
+// ExitError is returned when a smart contract exits with a non-zero status.
+type ExitError struct {
+	Code int
+	// Stderr is everything the contract wrote to stderr.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("contract exited with status %d", e.Code)
+	if s := stderrTail(e.Stderr); s != "" {
+		msg += ": " + s
+	}
+	return msg
+}
+
+// ExitCode returns the exit status of the contract.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// stderrTail returns the end of stderr, trimmed of surrounding whitespace, for
+// inclusion in error messages.
+func stderrTail(stderr []byte) string {
+	const max = 512
+	s := strings.TrimSpace(string(stderr))
+	if len(s) > max {
+		s = "..." + s[len(s)-max:]
+	}
+	return s
+}
+
+// errorResponse is the envelope of every unsuccessful API response.
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// writeError responds with the given status and an error envelope holding code
+// and message.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetails(w, status, code, message, nil)
+}
+
+// writeErrorDetails responds like writeError, with additional details about
+// the error in the envelope.
+func writeErrorDetails(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(status)
+	writeJSONResponse(w, errorResponse{Error: errorBody{
+		Code:    code,
+		Message: message,
+		Details: details,
+	}})
+}
+
+// writeErrorFrom responds with an error envelope describing err. Known errors
+// are mapped to their status and code, and any other error is reported as an
+// internal error.
+func writeErrorFrom(w http.ResponseWriter, err error) {
+	if e, ok := err.(*ExecutionError); ok && outputTooLarge(e) {
+		details := map[string]interface{}{"contract": e.Contract, "max_output_bytes": e.Err.(*output.TooLargeError).Max}
+		writeErrorDetails(w, http.StatusInternalServerError, ErrCodeOutputTooLarge, e.Error(), details)
+		return
+	}
+	if e, ok := err.(*ExecutionError); ok {
+		details := map[string]string{"contract": e.Contract}
+		if exit, ok := e.Err.(*ExitError); ok && len(exit.Stderr) > 0 {
+			details["stderr"] = stderrTail(exit.Stderr)
+		}
+		writeErrorDetails(w, http.StatusInternalServerError, ErrCodeExecutionFailed, e.Error(), details)
+		return
+	}
+	if e, ok := err.(*CircuitOpenError); ok {
+		retryAfter := int(math.Ceil(time.Until(e.RetryAt).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		details := map[string]interface{}{"contract": e.Contract, "retry_at": e.RetryAt.UTC()}
+		writeErrorDetails(w, http.StatusServiceUnavailable, ErrCodeCircuitOpen, e.Error(), details)
+		return
+	}
+	if e, ok := err.(*HeapQuotaError); ok {
+		details := map[string]interface{}{"bucket": e.Bucket, "limit": e.Limit, "max": e.Max, "needed": e.Needed}
+		writeErrorDetails(w, http.StatusInsufficientStorage, ErrCodeQuotaExceeded, e.Error(), details)
+		return
+	}
+	if e, ok := err.(*HeapSchemaError); ok {
+		details := map[string]interface{}{"bucket": e.Bucket, "key": e.Key, "prefix": e.Prefix, "path": e.Path}
+		writeErrorDetails(w, http.StatusUnprocessableEntity, ErrCodeSchemaViolation, e.Error(), details)
+		return
+	}
+	if e, ok := err.(*HeapAccessError); ok {
+		details := map[string]interface{}{"contract": e.Contract, "bucket": e.Bucket, "write": e.Write}
+		writeErrorDetails(w, http.StatusForbidden, ErrCodeHeapAccessDenied, e.Error(), details)
+		return
+	}
+	if e, ok := err.(*docker.PullError); ok {
+		status, code := pullErrorResponse(e)
+		details := map[string]string{"image": e.Image, "reason": e.Reason}
+		writeErrorDetails(w, status, code, e.Error(), details)
+		return
+	}
+	if e, ok := err.(*ImageDriftError); ok {
+		details := map[string]string{"contract": e.Contract, "image": e.Image, "pinned": e.Pinned, "current": e.Current}
+		writeErrorDetails(w, http.StatusConflict, ErrCodeImageDrifted, e.Error(), details)
+		return
+	}
+	switch err {
+	case ErrContractNotExist:
+		writeError(w, http.StatusNotFound, ErrCodeContractNotFound, err.Error())
+	case ErrVersionNotExist:
+		writeError(w, http.StatusNotFound, ErrCodeVersionNotFound, err.Error())
+	case ErrTransactionNotExist:
+		writeError(w, http.StatusNotFound, ErrCodeTransactionNotFound, err.Error())
+	case ErrBlockNotExist:
+		writeError(w, http.StatusNotFound, ErrCodeBlockNotFound, err.Error())
+	case ErrHeapNotExist:
+		writeError(w, http.StatusNotFound, ErrCodeHeapMiss, err.Error())
+	case ErrAPIKeyNotExist, ErrBlobNotExist, ErrSubscriptionNotExist, ErrSecretNotExist, ErrSigningKeyNotExist:
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case ErrChainNotExist:
+		writeError(w, http.StatusNotFound, ErrCodeChainNotFound, err.Error())
+	case ErrIdempotencyInProgress, ErrIdempotencyMismatch, ErrChainExists, ErrSigningKeyExists, ErrReplayBucketNotEmpty, ErrNotFollower, ErrReplicationDiverged, ErrLedgerImportDiverged:
+		writeError(w, http.StatusConflict, ErrCodeConflict, err.Error())
+	case ErrSignatureRequired, ErrSignatureInvalid:
+		writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+	case ErrRuntimeNotExist:
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidManifest, err.Error())
+	case ErrReplayTooLarge, ErrPruneUnsupported:
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+	}
+}
+
+// notFound responds with a not_found error envelope. It is used for requests
+// that match no route.
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, ErrCodeNotFound, "no route for "+r.URL.Path)
+}
+
+// methodNotAllowed responds with a method_not_allowed error envelope.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, r.Method+" is not allowed for "+r.URL.Path)
+}
@@ -0,0 +1,168 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published on an Application's event bus. The transaction and
+// execution events are also pushed to stream clients, under the same names.
+const (
+	// EventContractRegistered is published when a contract, or a new version of
+	// it, is stored in the Library.
+	EventContractRegistered = "contract_registered"
+	// EventExecutionStarted is published when a contract begins executing.
+	EventExecutionStarted = "execution_started"
+	// EventExecutionFinished is published when an execution succeeds.
+	EventExecutionFinished = "execution_finished"
+	// EventExecutionFailed is published when an execution fails.
+	EventExecutionFailed = "execution_failed"
+	// EventExecutionCancelled is published when an execution is cancelled, such
+	// as when the client that posted its transaction disconnects, and its
+	// container or process is killed.
+	EventExecutionCancelled = "execution_cancelled"
+	// EventTransaction is published when a transaction is appended to the ledger.
+	EventTransaction = "transaction"
+	// EventHeapWrite is published when a value is written to a contract's heap.
+	EventHeapWrite = "heap_write"
+	// EventHeapDelete is published when a key, or a whole bucket, is deleted from a
+	// contract's heap through the API.
+	EventHeapDelete = "heap_delete"
+)
+
+// Event describes something that happened in an Application. Which of its fields
+// are set depends on its Type. Events are shared by every handler, so they must
+// not be modified.
+type Event struct {
+	Type string
+	// Contract is the name of the contract, or the transaction type, the event
+	// concerns.
+	Contract string
+	Time     time.Time
+	// Manifest is the stored manifest, set for EventContractRegistered.
+	Manifest *ContractManifest
+	// Duration is how long the execution took, set for EventExecutionFinished,
+	// EventExecutionFailed and EventExecutionCancelled.
+	Duration time.Duration
+	// Err describes why an execution failed, set for EventExecutionFailed.
+	Err error
+	// Transaction is the appended transaction, set for EventTransaction.
+	Transaction *Transaction
+	// Bucket, Key and Value describe the write, set for EventHeapWrite. Bucket and
+	// Key are also set for EventHeapDelete, where an empty Key means the whole
+	// bucket was deleted.
+	Bucket string
+	Key    string
+	Value  []byte
+}
+
+// EventBus passes Events from the parts of an Application that publish them to
+// the handlers subscribed to them, within the process. The zero value is an
+// EventBus with no subscribers.
+type EventBus struct {
+	mu       sync.Mutex
+	nextID   int
+	handlers map[int]*eventHandler
+}
+
+type eventHandler struct {
+	types map[string]bool
+	f     func(*Event)
+}
+
+// Subscribe calls f with every published event whose type is one of types, or with
+// every event if no types are given. Handlers are called synchronously by Publish,
+// so they must not block; slow work should be done in another goroutine. The
+// returned function unsubscribes f.
+func (b *EventBus) Subscribe(f func(*Event), types ...string) (unsubscribe func()) {
+	h := &eventHandler{f: f}
+	if len(types) > 0 {
+		h.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			h.types[t] = true
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = make(map[int]*eventHandler)
+	}
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = h
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish calls every handler subscribed to e's type. If e's Time is zero, it is
+// set to the current time.
+func (b *EventBus) Publish(e *Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	// The handlers are called without holding the lock, so that they may subscribe
+	// or unsubscribe.
+	b.mu.Lock()
+	handlers := make([]*eventHandler, 0, len(b.handlers))
+	for _, h := range b.handlers {
+		if h.types == nil || h.types[e.Type] {
+			handlers = append(handlers, h)
+		}
+	}
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h.f(e)
+	}
+}
+
+// Subscribe calls f with the Application's events whose type is one of types, or
+// with all of them if no types are given. See EventBus.Subscribe.
+func (a *Application) Subscribe(f func(*Event), types ...string) (unsubscribe func()) {
+	return a.bus().Subscribe(f, types...)
+}
+
+// bus returns the Application's event bus. The first time it is used, the built-in
+// subscribers, webhooks and stream clients, are subscribed to it.
+func (a *Application) bus() *EventBus {
+	a.eventsOnce.Do(func() {
+		a.events.Subscribe(a.notifySubscribers, EventTransaction)
+		a.events.Subscribe(a.publishStream, EventTransaction, EventExecutionStarted, EventExecutionFinished, EventExecutionFailed, EventExecutionCancelled, EventHeapWrite, EventHeapDelete)
+	})
+	return &a.events
+}
+
+// heapWritten publishes an EventHeapWrite for each of puts, made on behalf of the
+// named contract.
+func (a *Application) heapWritten(contract string, puts ...HeapPut) {
+	for _, p := range puts {
+		a.bus().Publish(&Event{Type: EventHeapWrite, Contract: contract, Bucket: p.Bucket, Key: p.Key, Value: p.Value})
+	}
+}
+
+// heapDeleted publishes an EventHeapDelete for key in bucket, or for the whole
+// bucket if key is empty.
+func (a *Application) heapDeleted(bucket, key string) {
+	a.bus().Publish(&Event{Type: EventHeapDelete, Contract: bucket, Bucket: bucket, Key: key})
+}
@@ -0,0 +1,350 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	executionBucketPrefix = reservedBucketPrefix + "executions_"
+	// maxExecutionHistory is how many executions are kept for each contract. The
+	// oldest are discarded first.
+	maxExecutionHistory = 1000
+	// maxExecutionStderr is how much of a contract's stderr is kept with each
+	// execution. Output beyond it is discarded from the start.
+	maxExecutionStderr = 16 << 10
+	// defaultLogLines and maxLogLines bound the number of lines returned by
+	// ContractLogs.
+	defaultLogLines = 100
+	maxLogLines     = 10000
+)
+
+// Execution statuses.
+const (
+	ExecutionStatusSuccess   = "success"
+	ExecutionStatusFailed    = "failed"
+	ExecutionStatusCancelled = "cancelled"
+)
+
+// Execution triggers.
+const (
+	TriggerTransaction = "transaction"
+	TriggerCron        = "cron"
+	TriggerReplay      = "replay"
+)
+
+// Execution records a single execution of a contract.
+type Execution struct {
+	ID       string `json:"id"`
+	Contract string `json:"contract"`
+	// Trigger is TriggerTransaction, TriggerCron or TriggerReplay.
+	Trigger string    `json:"trigger"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Status  string    `json:"status"`
+	// ExitCode is the contract's exit status. It is -1 if the contract failed
+	// without exiting, for example because it timed out.
+	ExitCode int `json:"exit_code"`
+	// PayloadHash is the hex encoded SHA-256 hash of the payload.
+	PayloadHash string `json:"payload_hash"`
+	// TxnID is the ID of the transaction holding the output, if there is one.
+	TxnID string `json:"txn_id,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Stderr is the end of what the contract wrote to stderr, if its runtime
+	// captures it.
+	Stderr string `json:"stderr,omitempty"`
+}
+
+// LogLine is a line a contract wrote to stderr.
+type LogLine struct {
+	ExecutionID string    `json:"execution_id"`
+	Time        time.Time `json:"time"`
+	Line        string    `json:"line"`
+}
+
+// ExecutionLog stores the execution history of contracts.
+type ExecutionLog interface {
+	// Record adds e to the history of its contract.
+	Record(e *Execution) error
+	// Executions returns up to limit of the most recent executions of the named
+	// contract, newest first.
+	Executions(contract string, limit int) ([]*Execution, error)
+	// Clear removes the history of the named contract.
+	Clear(contract string) error
+}
+
+// HeapExecutionLog is an ExecutionLog that keeps each contract's history in a
+// reserved bucket of a Heap, retaining the most recent maxExecutionHistory executions.
+type HeapExecutionLog struct {
+	Heap Heap
+}
+
+// Record stores e, discarding the contract's oldest executions if its history
+// is full.
+func (l *HeapExecutionLog) Record(e *Execution) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	bucket := executionBucketPrefix + e.Contract
+	if err := l.Heap.Put(bucket, executionKey(e), b); err != nil {
+		return err
+	}
+	keys, err := l.Heap.Keys(bucket, "")
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(keys)-maxExecutionHistory; i++ {
+		if err := l.Heap.Delete(bucket, keys[i]); err != nil && err != ErrHeapNotExist {
+			return err
+		}
+	}
+	return nil
+}
+
+// Executions returns up to limit of the most recent executions of the named
+// contract, newest first.
+func (l *HeapExecutionLog) Executions(contract string, limit int) ([]*Execution, error) {
+	bucket := executionBucketPrefix + contract
+	keys, err := l.Heap.Keys(bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	execs := make([]*Execution, 0, limit)
+	for i := len(keys) - 1; i >= 0 && len(execs) < limit; i-- {
+		b, err := l.Heap.Get(bucket, keys[i])
+		if err == ErrHeapNotExist {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e Execution
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("failed to decode execution: %s", err)
+		}
+		execs = append(execs, &e)
+	}
+	return execs, nil
+}
+
+// Clear removes the history of the named contract.
+func (l *HeapExecutionLog) Clear(contract string) error {
+	err := l.Heap.DeleteBucket(executionBucketPrefix + contract)
+	if err == ErrHeapNotExist {
+		return nil
+	}
+	return err
+}
+
+// executionKey orders executions by start time.
+func executionKey(e *Execution) string {
+	return fmt.Sprintf("%020d-%s", e.Start.UnixNano(), e.ID)
+}
+
+// ListExecutions returns an HTTP handler function that responds with the most recent
+// executions of a contract, newest first. The number of executions is limited by the
+// optional limit query parameter, which defaults to defaultPageLimit and may not exceed
+// maxPageLimit.
+func (a *Application) ListExecutions() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		limit, err := queryInt(r, "limit", defaultPageLimit)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", maxPageLimit))
+			return
+		}
+		if _, err := a.Lib.Manifest(name); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		execs, err := a.executionLog().Executions(name, limit)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, execs)
+	}
+}
+
+// ContractLogs returns an HTTP handler function that responds with the most recent lines
+// a contract wrote to stderr, oldest first, as recorded in its execution history. The
+// number of lines is limited by the optional lines query parameter, which defaults to
+// defaultLogLines and may not exceed maxLogLines.
+func (a *Application) ContractLogs() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		n, err := queryInt(r, "lines", defaultLogLines)
+		if err != nil || n <= 0 || n > maxLogLines {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("lines must be an integer between 1 and %d", maxLogLines))
+			return
+		}
+		if _, err := a.Lib.Manifest(name); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		execs, err := a.executionLog().Executions(name, maxExecutionHistory)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		// Executions are newest first, so lines are gathered backwards and then
+		// reversed.
+		lines := make([]LogLine, 0, n)
+		for _, e := range execs {
+			if len(lines) == n {
+				break
+			}
+			if e.Stderr == "" {
+				continue
+			}
+			stderr := strings.Split(strings.TrimRight(e.Stderr, "\n"), "\n")
+			for i := len(stderr) - 1; i >= 0 && len(lines) < n; i-- {
+				lines = append(lines, LogLine{ExecutionID: e.ID, Time: e.End, Line: stderr[i]})
+			}
+		}
+		for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+			lines[i], lines[j] = lines[j], lines[i]
+		}
+		writeJSONResponse(w, lines)
+	}
+}
+
+func (a *Application) executionLog() ExecutionLog {
+	if a.Executions == nil {
+		return &HeapExecutionLog{Heap: a.Heap}
+	}
+	return a.Executions
+}
+
+// run executes contract, publishing its progress on the event bus and recording it
+// in the execution log under the given trigger and transaction ID. If the contract's
+// circuit breaker is open, a *CircuitOpenError is returned without executing it. If ctx
+// is cancelled before the contract finishes, it is killed, context.Canceled is returned
+// and the execution is recorded as cancelled. The execution is traced as a span of the
+// trace in ctx. If the execution fails, the heap writes the contract made through the
+// heap API while it ran are undone. See heapJournal.
+func (a *Application) run(ctx context.Context, name, trigger, txnID string, contract Contract, payload []byte) ([]byte, error) {
+	if err := a.admit(name); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	a.bus().Publish(&Event{Type: EventExecutionStarted, Contract: name, Time: start.UTC()})
+	ctx, span := tracing.Start(ctx, "contract.execute",
+		attribute.String("hatchery.contract", name),
+		attribute.String("hatchery.trigger", trigger),
+	)
+	journal := a.beginHeapJournal(name, txnID)
+	res, err := runContract(ctx, contract, payload)
+	if err == nil && res.ExitCode != 0 {
+		err = &ExitError{Code: res.ExitCode, Stderr: res.Stderr}
+	}
+	a.endHeapJournal(journal, err != nil)
+	cancelled := err != nil && ctx.Err() == context.Canceled
+	if cancelled {
+		// Runtimes may wrap the cancellation, for example if it interrupted
+		// the creation of a container.
+		err = context.Canceled
+	}
+	tracing.End(span, err)
+	a.settle(name, err)
+	finished := &Event{Type: EventExecutionFinished, Contract: name, Duration: time.Since(start)}
+	switch {
+	case cancelled:
+		finished.Type = EventExecutionCancelled
+		finished.Err = err
+	case err != nil:
+		finished.Type = EventExecutionFailed
+		finished.Err = err
+	}
+	a.bus().Publish(finished)
+
+	hash := sha256.Sum256(payload)
+	e := &Execution{
+		ID:          uuid.New().String(),
+		Contract:    name,
+		Trigger:     trigger,
+		Start:       start.UTC(),
+		End:         time.Now().UTC(),
+		Status:      ExecutionStatusSuccess,
+		PayloadHash: hex.EncodeToString(hash[:]),
+		TxnID:       txnID,
+	}
+	if res != nil {
+		stderr := res.Stderr
+		if len(stderr) > maxExecutionStderr {
+			stderr = stderr[len(stderr)-maxExecutionStderr:]
+		}
+		e.Stderr = string(stderr)
+	}
+	if err != nil {
+		e.Status = ExecutionStatusFailed
+		e.ExitCode = -1
+		if exit, ok := err.(interface{ ExitCode() int }); ok {
+			e.ExitCode = exit.ExitCode()
+		}
+		e.Error = err.Error()
+		e.TxnID = ""
+	}
+	if cancelled {
+		e.Status = ExecutionStatusCancelled
+	}
+	if rerr := a.executionLog().Record(e); rerr != nil {
+		a.log().Error("failed to record execution", logging.Contract(name), logging.Err(rerr))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.Stdout, nil
+}
+
+// cronExecutable executes a contract on behalf of a CronJob, recording each
+// execution. The contract is looked up for every execution, so that the heap
+// references in its environment are resolved when it runs.
+type cronExecutable struct {
+	app  *Application
+	name string
+}
+
+func (c *cronExecutable) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	contract, err := c.app.contract(c.name)
+	if err != nil {
+		return nil, err
+	}
+	// Scheduled executions have no transaction, but are told their type and when
+	// they were activated.
+	ctx = backend.WithInvocation(ctx, backend.Invocation{TxnType: c.name, Timestamp: time.Now().UTC()})
+	return c.app.run(ctx, c.name, TriggerCron, "", contract, payload)
+}
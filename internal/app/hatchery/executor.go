@@ -0,0 +1,192 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+)
+
+// execQueue limits the number of concurrent executions of a single contract.
+// Executions beyond the limit wait their turn and are admitted in the order
+// they arrived.
+type execQueue struct {
+	mu      sync.Mutex
+	running int
+	waiters *list.List
+}
+
+func newExecQueue() *execQueue {
+	return &execQueue{waiters: list.New()}
+}
+
+// acquire blocks until an execution slot is available. A limit of zero or
+// less means executions are never limited. If ctx is cancelled while waiting,
+// ctx.Err() is returned and no slot is held.
+func (q *execQueue) acquire(ctx context.Context, limit int) error {
+	q.mu.Lock()
+	if limit <= 0 || (q.running < limit && q.waiters.Len() == 0) {
+		q.running++
+		q.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	elem := q.waiters.PushBack(ch)
+	q.mu.Unlock()
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+	q.mu.Lock()
+	select {
+	case <-ch:
+		// The slot was handed to us as we gave up waiting, so pass it on.
+		q.mu.Unlock()
+		q.release()
+	default:
+		q.waiters.Remove(elem)
+		q.mu.Unlock()
+	}
+	return ctx.Err()
+}
+
+// release frees an execution slot. If executions are waiting, the slot is
+// handed directly to the one that has waited the longest.
+func (q *execQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if front := q.waiters.Front(); front != nil {
+		q.waiters.Remove(front)
+		close(front.Value.(chan struct{}))
+		return
+	}
+	q.running--
+}
+
+// waiting returns the number of executions waiting for a slot.
+func (q *execQueue) waiting() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.waiters.Len()
+}
+
+// queuedContract is a Contract whose executions are admitted through the
+// contract's execQueue according to its ExecutionOrder, and then through the
+// node's fairQueue according to its Priority.
+type queuedContract struct {
+	contract Contract
+	queue    *execQueue
+	limit    int
+	name     string
+	slots    *fairQueue
+	priority int
+	maxSlots int
+	// output holds the writers that copy the contract's output to its output log,
+	// if its runtime supports it. See captureOutput.
+	output []*outputWriter
+}
+
+// Execute waits for an execution slot of the contract and then for one of the node,
+// and executes the underlying contract.
+func (c *queuedContract) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	if err := c.queue.acquire(ctx, c.limit); err != nil {
+		return nil, err
+	}
+	defer c.queue.release()
+	if err := c.slots.acquire(ctx, c.name, c.priority, c.maxSlots); err != nil {
+		return nil, err
+	}
+	defer c.slots.release()
+	defer c.flushOutput()
+	return c.contract.Execute(ctx, payload)
+}
+
+// flushOutput appends the last line of the execution's output to the output log.
+func (c *queuedContract) flushOutput() {
+	for _, w := range c.output {
+		w.Flush()
+	}
+}
+
+// contract returns the named contract from the Library, wrapped so that its
+// executions honor the manifest's ExecutionOrder. Serial contracts execute one
+// at a time in FIFO order, while parallel contracts execute concurrently, up to
+// MaxConcurrency executions at once. Executions of every contract then share the
+// node's MaxExecutions, by Priority. If the contract implements Environ, it is
+// given the details it needs to reach the heap API, if it implements OutputSetter,
+// its output is streamed to ContractLogStream, and if it implements OutputLimiter,
+// its output is limited to OutputLimit.
+func (a *Application) contract(name string) (Contract, error) {
+	manifest, err := a.Lib.Manifest(name)
+	if err != nil {
+		return nil, err
+	}
+	contract, err := a.Lib.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := contract.(Environ); ok {
+		token, err := a.heapToken(name)
+		if err != nil {
+			return nil, err
+		}
+		e.SetEnv(HeapTokenKey, token)
+		e.SetEnv(HatcheryURL, a.BaseURL)
+	}
+	if err := a.interpolateEnv(contract, manifest); err != nil {
+		return nil, &ExecutionError{Contract: name, Err: err}
+	}
+	a.limitOutput(contract)
+	if c, ok := contract.(*docker.Contract); ok {
+		c.Pool = &a.warm
+	}
+	limit := a.MaxConcurrency
+	if manifest.ExecutionOrder == ExecutionOrderSerial {
+		limit = 1
+	}
+	return &queuedContract{
+		contract: contract,
+		queue:    a.execQueue(name),
+		limit:    limit,
+		name:     name,
+		slots:    &a.slots,
+		priority: manifest.Priority,
+		maxSlots: a.MaxExecutions,
+		output:   a.captureOutput(name, contract),
+	}, nil
+}
+
+func (a *Application) execQueue(name string) *execQueue {
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	if a.queues == nil {
+		a.queues = make(map[string]*execQueue)
+	}
+	q, ok := a.queues[name]
+	if !ok {
+		q = newExecQueue()
+		a.queues[name] = q
+	}
+	return q
+}
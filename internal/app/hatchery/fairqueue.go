@@ -0,0 +1,155 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// fairQueue limits the number of concurrent executions across every contract on the
+// node. Executions beyond the limit wait their turn, and whenever a slot frees up,
+// the contracts with waiting executions are admitted in weighted round-robin order,
+// each in proportion to its priority, so that a contract that posts many executions
+// can't keep the others from running. A contract's own executions are admitted in
+// the order they arrived. Its zero value is ready to use.
+//
+// Turns are tracked with stride scheduling: every contract has a pass, which each
+// admission advances by the inverse of its priority, and the waiting contract with
+// the lowest pass is admitted next. A contract that starts waiting again after being
+// idle resumes from the pass of the last admission, so idleness doesn't earn credit.
+type fairQueue struct {
+	mu      sync.Mutex
+	running int
+	// pass is the pass of the last admission.
+	pass  float64
+	flows map[string]*fairFlow
+}
+
+// fairFlow holds the waiting executions of a single contract.
+type fairFlow struct {
+	pass     float64
+	priority int
+	waiters  *list.List
+}
+
+// acquire blocks until an execution slot is available for the named contract, whose
+// executions are admitted with the given priority. A limit of zero or less means
+// executions are never limited. If ctx is cancelled while waiting, ctx.Err() is
+// returned and no slot is held.
+func (q *fairQueue) acquire(ctx context.Context, name string, priority, limit int) error {
+	q.mu.Lock()
+	if q.flows == nil {
+		q.flows = make(map[string]*fairFlow)
+	}
+	f, ok := q.flows[name]
+	if !ok {
+		f = &fairFlow{waiters: list.New()}
+		q.flows[name] = f
+	}
+	f.priority = priority
+	if f.waiters.Len() == 0 && f.pass < q.pass {
+		f.pass = q.pass
+	}
+	if limit <= 0 || (q.running < limit && q.waitingLocked() == 0) {
+		q.running++
+		q.admit(f)
+		q.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	elem := f.waiters.PushBack(ch)
+	q.mu.Unlock()
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+	q.mu.Lock()
+	select {
+	case <-ch:
+		// The slot was handed to us as we gave up waiting, so pass it on.
+		q.mu.Unlock()
+		q.release()
+	default:
+		f.waiters.Remove(elem)
+		q.mu.Unlock()
+	}
+	return ctx.Err()
+}
+
+// release frees an execution slot. If executions are waiting, the slot is handed
+// directly to the first execution of the contract whose turn is next.
+func (q *fairQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var next *fairFlow
+	for _, f := range q.flows {
+		if f.waiters.Len() > 0 && (next == nil || f.pass < next.pass) {
+			next = f
+		}
+	}
+	if next == nil {
+		q.running--
+		return
+	}
+	close(next.waiters.Remove(next.waiters.Front()).(chan struct{}))
+	q.admit(next)
+}
+
+// admit advances the pass of f, whose execution has just been given a slot, and
+// records its previous pass as the pass of the last admission.
+func (q *fairQueue) admit(f *fairFlow) {
+	priority := f.priority
+	if priority <= 0 {
+		priority = 1
+	}
+	q.pass = f.pass
+	f.pass += 1 / float64(priority)
+}
+
+// waiting returns the number of executions of the named contract waiting for a slot.
+func (q *fairQueue) waiting(name string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if f, ok := q.flows[name]; ok {
+		return f.waiters.Len()
+	}
+	return 0
+}
+
+func (q *fairQueue) waitingLocked() int {
+	n := 0
+	for _, f := range q.flows {
+		n += f.waiters.Len()
+	}
+	return n
+}
+
+// forget discards the turn of the named contract, such as when it is deleted. Its
+// waiting executions, if any, keep their turn.
+func (q *fairQueue) forget(name string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if f, ok := q.flows[name]; ok && f.waiters.Len() == 0 {
+		delete(q.flows, name)
+	}
+}
@@ -0,0 +1,107 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// fanoutRequest is the body of POST /transaction/fanout.
+type fanoutRequest struct {
+	// Types are the transaction types the payload is posted as. Each may only
+	// appear once.
+	Types   []string `json:"txn_types"`
+	Payload json.RawMessage
+	// Signer is the ID of the signing key the transactions are signed with, and
+	// Signatures holds the signature of each transaction, by type. See
+	// verifyTransaction.
+	Signer     string            `json:"signer,omitempty"`
+	Signatures map[string]string `json:"signatures,omitempty"`
+}
+
+// fanoutResponse is the response of POST /transaction/fanout.
+type fanoutResponse struct {
+	// Output holds the content of each successful transaction, by type, encoded
+	// like the Content of a transactionResponse.
+	Output map[string]interface{}
+	// Failed holds the error of each failed transaction, by type.
+	Failed map[string]string `json:",omitempty"`
+	// Results holds the outcome of each transaction, in the order of the
+	// request's types.
+	Results []bulkTransactionResult
+}
+
+// PostTransactionFanout returns an HTTP handler function that posts a single payload
+// as a transaction of each of several types, executing each type's contract with it.
+// The transactions are posted like those of PostTransactionBulk, executing concurrently
+// within the limits of each contract, and the successful ones are appended to the ledger in
+// the order of their types, as a single atomic append. The response combines the
+// output of every successful transaction, by type, with the outcome of each. If any
+// transaction's signature is invalid, or one is unsigned while RequireSignatures is
+// set, none are executed.
+func (a *Application) PostTransactionFanout() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding, ok := contentEncoding(w, r)
+		if !ok {
+			return
+		}
+		var req fanoutRequest
+		limitBody(w, r, a.maxTransactionSize())
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeDecodeError(w, ErrCodeBadRequest, "invalid fan-out", err)
+			return
+		}
+		if len(req.Types) == 0 || len(req.Types) > maxBulkTransactions {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("between 1 and %d txn_types must be given", maxBulkTransactions))
+			return
+		}
+		reqs := make([]postTransactionRequest, len(req.Types))
+		seen := make(map[string]bool, len(req.Types))
+		for i, txnType := range req.Types {
+			if txnType == "" || seen[txnType] {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("txn_types must be distinct and not empty: %q", txnType))
+				return
+			}
+			seen[txnType] = true
+			reqs[i] = postTransactionRequest{Type: txnType, Payload: req.Payload}
+			if sig, ok := req.Signatures[txnType]; ok {
+				reqs[i].Signer, reqs[i].Signature = req.Signer, sig
+			}
+		}
+		results, ok := a.postBulk(w, r, reqs, encoding)
+		if !ok {
+			return
+		}
+		resp := fanoutResponse{Output: make(map[string]interface{}), Results: results}
+		for i, res := range results {
+			if res.Transaction == nil {
+				if resp.Failed == nil {
+					resp.Failed = make(map[string]string)
+				}
+				resp.Failed[req.Types[i]] = res.Error
+				continue
+			}
+			resp.Output[req.Types[i]] = res.Transaction.Content
+		}
+		writeJSONResponse(w, resp)
+	}
+}
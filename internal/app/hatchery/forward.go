@@ -0,0 +1,352 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+const (
+	outboxBucket = reservedBucketPrefix + "outbox"
+
+	// maxForwardAttempts is how many times a transaction is posted to DragonChain
+	// before its outbox item is marked as failed.
+	maxForwardAttempts = 10
+	// initialForwardBackoff is the delay before the first retry of a forwarded
+	// transaction. It doubles with each subsequent retry, up to maxForwardBackoff.
+	initialForwardBackoff = time.Second
+	maxForwardBackoff     = 5 * time.Minute
+	// forwardTimeout bounds a single attempt at forwarding a transaction.
+	forwardTimeout = 30 * time.Second
+
+	// dragonchainTimestampLayout is the layout of the timestamp header DragonChain
+	// expects on signed requests.
+	dragonchainTimestampLayout = "2006-01-02T15:04:05.000000Z"
+)
+
+// Outbox item statuses.
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusFailed  = "failed"
+)
+
+// OutboxItem is a committed transaction waiting to be forwarded to DragonChain.
+// Items are removed from the outbox once DragonChain accepts their transaction.
+// Items that exhaust their attempts, or that DragonChain rejects, remain in the
+// outbox with OutboxStatusFailed.
+type OutboxItem struct {
+	// ID is the ID of the transaction on Hatchery's ledger.
+	ID          string    `json:"id"`
+	TxnType     string    `json:"txn_type"`
+	Content     []byte    `json:"content"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	Created     time.Time `json:"created"`
+}
+
+// Forwarder posts transactions to a DragonChain L1 through its REST API, signed
+// with the same credentials FSLibrary passes to contracts.
+type Forwarder struct {
+	// Endpoint is the URL of the DragonChain's API, such as
+	// "https://<chain id>.api.dragonchain.com".
+	Endpoint    string
+	Credentials Credentials
+	// Client is used to make requests. If nil, a client that times out after
+	// forwardTimeout is used.
+	Client *http.Client
+}
+
+// forwardError is returned by Forward when DragonChain rejects a transaction, in
+// which case retrying won't help.
+type forwardError struct {
+	status int
+	body   string
+}
+
+func (e *forwardError) Error() string {
+	return fmt.Sprintf("dragonchain rejected the transaction with status %d: %s", e.status, e.body)
+}
+
+// dragonchainTransaction is the body of DragonChain's POST /transaction.
+type dragonchainTransaction struct {
+	Version string      `json:"version"`
+	TxnType string      `json:"txn_type"`
+	Payload interface{} `json:"payload"`
+	Tag     string      `json:"tag"`
+}
+
+// Forward posts the transaction of item to DragonChain and returns the ID DragonChain
+// gave it. Content that is a JSON object is sent as the payload as-is, and any other
+// content as a string. The transaction is tagged with its ID on Hatchery's ledger. If
+// DragonChain rejects the transaction, a *forwardError is returned.
+func (f *Forwarder) Forward(ctx context.Context, item *OutboxItem) (string, error) {
+	txn := dragonchainTransaction{Version: "1", TxnType: item.TxnType, Payload: string(item.Content), Tag: "hatchery:" + item.ID}
+	if trimmed := bytes.TrimSpace(item.Content); len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		txn.Payload = json.RawMessage(trimmed)
+	}
+	body, err := json.Marshal(txn)
+	if err != nil {
+		return "", err
+	}
+	const path = "/transaction"
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(f.Endpoint, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	timestamp := time.Now().UTC().Format(dragonchainTimestampLayout)
+	sig := SignRequest(f.Credentials.AuthKey, http.MethodPost, path, f.Credentials.DragonChainID, timestamp, "application/json", body)
+	req.Header.Set("Authorization", hmacScheme+" "+f.Credentials.AuthID+":"+sig)
+	req.Header.Set("dragonchain", f.Credentials.DragonChainID)
+	req.Header.Set("timestamp", timestamp)
+
+	client := f.Client
+	if client == nil {
+		client = &http.Client{Timeout: forwardTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return "", fmt.Errorf("dragonchain responded with status %d", resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return "", &forwardError{status: resp.StatusCode, body: strings.TrimSpace(string(b))}
+	}
+	var created struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	json.Unmarshal(b, &created)
+	return created.TransactionID, nil
+}
+
+// ListOutbox returns an HTTP handler function that responds with the transactions
+// waiting to be forwarded to DragonChain, and those that failed to be, oldest first.
+// It responds with an empty list if forwarding is disabled.
+func (a *Application) ListOutbox() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, err := a.outboxItems()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, items)
+	}
+}
+
+// addToOutbox records ts in the outbox to be forwarded, if forwarding is enabled.
+// Transactions that can't be recorded are logged and are not forwarded.
+func (a *Application) addToOutbox(ts ...*Transaction) {
+	if a.Forwarder == nil {
+		return
+	}
+	for _, t := range ts {
+		now := time.Now().UTC()
+		item := &OutboxItem{
+			ID:          t.ID,
+			TxnType:     t.Type,
+			Content:     t.Content,
+			Status:      OutboxStatusPending,
+			NextAttempt: now,
+			Created:     now,
+		}
+		if err := a.putJSON(outboxBucket, outboxKey(item), item); err != nil {
+			a.log().Error("failed to add transaction to outbox", logging.TxnID(t.ID), logging.Err(err))
+		}
+	}
+	a.wakeForwarding()
+}
+
+// outboxKey orders outbox items by the time they were added, which is the order
+// their transactions were appended to the ledger.
+func outboxKey(item *OutboxItem) string {
+	return fmt.Sprintf("%020d-%s", item.Created.UnixNano(), item.ID)
+}
+
+// startForwarding starts forwarding the transactions in the outbox, if forwarding
+// is enabled and it hasn't been started already.
+func (a *Application) startForwarding() {
+	if a.Forwarder == nil {
+		return
+	}
+	a.forwardOnce.Do(func() {
+		a.forwardMu.Lock()
+		a.forwardWake = make(chan struct{}, 1)
+		a.forwardDone = make(chan struct{})
+		a.forwardMu.Unlock()
+		go a.forward(a.forwardDone)
+	})
+}
+
+// stopForwarding stops forwarding transactions. Transactions left in the outbox
+// are forwarded the next time the application starts.
+func (a *Application) stopForwarding() {
+	a.forwardMu.Lock()
+	defer a.forwardMu.Unlock()
+	if a.forwardDone == nil {
+		return
+	}
+	select {
+	case <-a.forwardDone:
+	default:
+		close(a.forwardDone)
+	}
+}
+
+func (a *Application) wakeForwarding() {
+	a.forwardMu.Lock()
+	wake := a.forwardWake
+	a.forwardMu.Unlock()
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// forward forwards the transactions in the outbox as they become due, until done
+// is closed.
+func (a *Application) forward(done chan struct{}) {
+	for {
+		next, err := a.forwardDue(done)
+		if err != nil {
+			a.log().Error("failed to read outbox", logging.Err(err))
+			next = time.Now().Add(initialForwardBackoff)
+		}
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+		select {
+		case <-a.forwardWake:
+		case <-due:
+		case <-done:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
+
+// forwardDue forwards pending outbox items in order, and returns when the next
+// pending item will be due. Items are forwarded one at a time, and an item waiting
+// for a retry holds back the items after it, so that DragonChain receives the
+// transactions in ledger order. The zero time is returned if the outbox is empty.
+// Only the leader of a cluster forwards; it is woken when it is elected.
+func (a *Application) forwardDue(done chan struct{}) (time.Time, error) {
+	if !a.leading() {
+		return time.Time{}, nil
+	}
+	items, err := a.outboxItems()
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, item := range items {
+		if item.Status != OutboxStatusPending {
+			continue
+		}
+		if item.NextAttempt.After(time.Now()) {
+			return item.NextAttempt, nil
+		}
+		select {
+		case <-done:
+			return time.Time{}, nil
+		default:
+		}
+		logger := a.log().With(logging.Contract(item.TxnType), logging.TxnID(item.ID))
+		item.Attempts++
+		ctx, cancel := context.WithTimeout(context.Background(), forwardTimeout)
+		dcID, err := a.Forwarder.Forward(ctx, item)
+		cancel()
+		if err == nil {
+			if err := a.Heap.Delete(outboxBucket, outboxKey(item)); err != nil {
+				logger.Error("failed to remove forwarded transaction from outbox", logging.Err(err))
+			}
+			logger.Info("transaction forwarded to dragonchain", logging.F("dragonchain_txn_id", dcID))
+			continue
+		}
+		item.LastError = err.Error()
+		_, rejected := err.(*forwardError)
+		if rejected || item.Attempts >= maxForwardAttempts {
+			item.Status = OutboxStatusFailed
+			logger.Error("failed to forward transaction to dragonchain", logging.F("attempts", item.Attempts), logging.Err(err))
+		} else {
+			backoff := initialForwardBackoff << uint(item.Attempts-1)
+			if backoff > maxForwardBackoff {
+				backoff = maxForwardBackoff
+			}
+			item.NextAttempt = time.Now().UTC().Add(backoff)
+		}
+		if err := a.putJSON(outboxBucket, outboxKey(item), item); err != nil {
+			return time.Time{}, err
+		}
+		if item.Status == OutboxStatusPending {
+			return item.NextAttempt, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// outboxItems returns every item in the outbox, oldest first.
+func (a *Application) outboxItems() ([]*OutboxItem, error) {
+	all, err := a.Heap.GetRange(outboxBucket, "", "")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(all))
+	for k, v := range all {
+		if len(v) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	items := make([]*OutboxItem, 0, len(keys))
+	for _, k := range keys {
+		var item OutboxItem
+		if err := json.Unmarshal(all[k], &item); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	return items, nil
+}
@@ -22,11 +22,15 @@ package hatchery
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/summerplaygames/hatchery/internal/app/docker"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
 )
 
 // Environment keys
@@ -37,6 +41,14 @@ const (
 	DragonChainID = "DRAGONCHAIN_ID"
 )
 
+// versionsDir is the directory, within an FSLibrary's BasePath, that holds the
+// manifest of every stored version of each contract.
+const versionsDir = ".versions"
+
+// garbageDir is the directory, within an FSLibrary's BasePath, that holds the
+// manifests of deleted contracts until CollectGarbage removes their images.
+const garbageDir = ".garbage"
+
 // Credentials are the credentials used to access the DragonChain
 // API for a particular chain.
 type Credentials struct {
@@ -51,68 +63,524 @@ type FSLibrary struct {
 	BasePath string
 	// Crednentials are the credentials used to access a DragonChain.
 	Credentials Credentials
+	// Logger is passed to the contracts returned by Get. If nil,
+	// logging.Default() is used.
+	Logger logging.Logger
+	// RemoveImages determines whether a contract's Docker image is removed
+	// when the contract is deleted from the library.
+	RemoveImages bool
+	// DefaultNetwork is the network policy, such as docker.NetworkNone, of contracts
+	// whose manifest doesn't set Network. If empty, such containers are attached to
+	// Docker's default network.
+	DefaultNetwork string
+	// Sync determines whether each manifest, and the directory holding it, is
+	// flushed to disk before Put and Update return, so that stored contracts
+	// survive a crash of the machine.
+	Sync bool
+	// KeyPath is the file holding the node key, which encrypts secrets, such as
+	// registry credentials, in stored manifests. It is created with a new random
+	// key if it doesn't exist. If empty, a file named .key in BasePath is used.
+	KeyPath string
+
+	// Secrets resolves the secrets that manifests reference. If nil, contracts
+	// that reference secrets cannot be executed.
+	Secrets SecretStore
+	// EnvPolicy restricts the environment variables that manifests may set.
+	// Contracts whose manifest sets a variable it doesn't permit can't be
+	// executed.
+	EnvPolicy EnvPolicy
 
 	once sync.Once
+	key  nodeKey
 }
 
-// Get returns the DockerContract for the given name.
-// If no contract with requested name exists in the Library,
+// Get returns the Contract for the latest version of the contract with the
+// given name. If no contract with requested name exists in the Library,
 // ErrContractNotExist is returned. Otherwise, an error is returned
 // only if the manifest cannot be JSON decoded.
 func (l *FSLibrary) Get(name string) (Contract, error) {
 	l.ensurePath()
-	f, err := os.Open(filepath.Join(l.BasePath, name))
+	manifest, err := l.readManifest(name)
 	if err != nil {
-		return nil, ErrContractNotExist
+		return nil, err
 	}
-	defer f.Close()
-	var manifest ContractManifest
-	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
-		return nil, fmt.Errorf("failed to read JSON manifest: %s", err)
+	return l.Contract(manifest)
+}
+
+// GetVersion returns the Contract for the given version of the contract
+// with the given name. ErrContractNotExist is returned if the contract doesn't
+// exist and ErrVersionNotExist is returned if it has no such version.
+func (l *FSLibrary) GetVersion(name string, version int) (Contract, error) {
+	l.ensurePath()
+	if _, err := l.readManifest(name); err != nil {
+		return nil, err
+	}
+	manifest, err := l.readManifestFile(l.versionPath(name, version))
+	if err == ErrContractNotExist {
+		return nil, ErrVersionNotExist
 	}
-	env := map[string]string{
-		SCName:        manifest.Type,
-		AuthKey:       l.Credentials.AuthKey,
-		AuthID:        l.Credentials.AuthID,
-		DragonChainID: l.Credentials.DragonChainID,
+	if err != nil {
+		return nil, err
 	}
+	return l.Contract(manifest)
+}
+
+// Contract returns a Contract that executes the contract described by manifest,
+// with the DragonChain credentials and referenced secrets in its environment. The
+// manifest need not be stored in the library. The manifest's Env and Secrets are
+// merged first and every variable Hatchery reserves is then removed, so only the
+// values Hatchery sets itself reach the contract, and an error is returned if they
+// set any other variable the library's EnvPolicy doesn't permit.
+func (l *FSLibrary) Contract(manifest *ContractManifest) (Contract, error) {
+	runtime, err := LookupRuntime(manifest.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Network == "" && l.DefaultNetwork != "" {
+		m := *manifest
+		m.Network = l.DefaultNetwork
+		manifest = &m
+	}
+	if v, ok := runtime.(Verifier); ok && manifest.DigestPolicy != "" {
+		if err := l.verify(v, manifest); err != nil {
+			return nil, err
+		}
+	}
+	env := make(map[string]string, len(manifest.Env)+len(manifest.Secrets)+4)
 	for k, v := range manifest.Env {
+		if !isReservedEnv(k) && !l.EnvPolicy.Permits(k) {
+			return nil, fmt.Errorf("contract %s sets environment variable %s, which is not permitted", manifest.Type, k)
+		}
+		env[k] = v
+	}
+	if len(manifest.Secrets) > 0 && l.Secrets == nil {
+		return nil, fmt.Errorf("contract %s references secrets, but no secret store is configured", manifest.Type)
+	}
+	for k, name := range manifest.Secrets {
+		if !isReservedEnv(k) && !l.EnvPolicy.Permits(k) {
+			return nil, fmt.Errorf("contract %s sets environment variable %s, which is not permitted", manifest.Type, k)
+		}
+		v, err := l.Secrets.Secret(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve secret %s for %s: %s", name, k, err)
+		}
 		env[k] = v
 	}
-	return &docker.Contract{
-		Name:    manifest.Type,
-		Env:     env,
-		Image:   manifest.Image,
-		Command: manifest.Cmd,
-		Args:    manifest.Args,
-	}, nil
-}
-
-// Put creates a new contract defined by the provided ContractManifest.
-// The image defined in the manifest is pulled down from DockerHub and the
-// manfiest is stored on disk. An error is returned in the following scenarios:
-//   1. The docker image could not be pulled from DockerHub.
-//   2. The manifest file could not be opened for writing.
-//   3. The manifest file could not be JSON encoded.
-//   4. The JSON encoded manifest could not be written to disk.
+	for k := range reservedEnv {
+		delete(env, k)
+	}
+	env[SCName] = manifest.Type
+	env[AuthKey] = l.Credentials.AuthKey
+	env[AuthID] = l.Credentials.AuthID
+	env[DragonChainID] = l.Credentials.DragonChainID
+	return runtime.Contract(manifest, env, l.Logger)
+}
+
+// Manifest returns the stored ContractManifest for the contract with the given
+// name. ErrContractNotExist is returned if no manifest exists for the contract.
+func (l *FSLibrary) Manifest(name string) (*ContractManifest, error) {
+	l.ensurePath()
+	return l.readManifest(name)
+}
+
+// List reads every manifest in BasePath and returns them sorted by contract
+// name. An error is returned if the directory or any manifest cannot be read.
+func (l *FSLibrary) List() ([]ContractManifest, error) {
+	l.ensurePath()
+	infos, err := ioutil.ReadDir(l.BasePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library: %s", err)
+	}
+	manifests := make([]ContractManifest, 0, len(infos))
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		manifest, err := l.readManifest(info.Name())
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, *manifest)
+	}
+	return manifests, nil
+}
+
+// Versions returns the version history of the contract with the given name,
+// oldest first. ErrContractNotExist is returned if no manifest exists for the
+// contract. Contracts stored before versioning was introduced report their
+// current manifest as version 1.
+func (l *FSLibrary) Versions(name string) ([]ContractVersion, error) {
+	l.ensurePath()
+	latest, err := l.readManifest(name)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Version == 0 {
+		info, err := os.Stat(filepath.Join(l.BasePath, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat manifest: %s", err)
+		}
+		return []ContractVersion{{
+			Version:     1,
+			Image:       latest.Image,
+			ImageDigest: latest.ImageDigest,
+			Created:     info.ModTime().UTC(),
+		}}, nil
+	}
+	versions := make([]ContractVersion, 0, latest.Version)
+	for v := 1; v <= latest.Version; v++ {
+		path := l.versionPath(name, v)
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat manifest: %s", err)
+		}
+		manifest, err := l.readManifestFile(path)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, ContractVersion{
+			Version:     manifest.Version,
+			Image:       manifest.Image,
+			ImageDigest: manifest.ImageDigest,
+			Created:     info.ModTime().UTC(),
+		})
+	}
+	return versions, nil
+}
+
+// Put stores the contract defined by the provided ContractManifest. If the
+// contract already exists, the manifest is stored as its next version.
+// The contract's Runtime is prepared, which for Docker contracts pulls the image
+// and records its digest, and the manfiest is stored on disk. An error is
+// returned if the runtime could not be prepared or the manifest could not be written.
 func (l *FSLibrary) Put(manifest *ContractManifest) error {
 	l.ensurePath()
-	if err := docker.PullImage(manifest.Image); err != nil {
-		return fmt.Errorf("failed to pull image: %s", err)
+	return l.store(manifest)
+}
+
+// Update stores the manifest as the next version of an existing contract. The
+// contract's Runtime is prepared before the manifest is written.
+// ErrContractNotExist is returned if no manifest exists for the contract.
+func (l *FSLibrary) Update(manifest *ContractManifest) error {
+	l.ensurePath()
+	if _, err := os.Stat(filepath.Join(l.BasePath, manifest.Type)); os.IsNotExist(err) {
+		return ErrContractNotExist
+	}
+	return l.store(manifest)
+}
+
+// store prepares the manifest's runtime and writes the manifest as both the next
+// version of the contract and its latest manifest.
+func (l *FSLibrary) store(manifest *ContractManifest) error {
+	runtime, err := LookupRuntime(manifest.Runtime)
+	if err != nil {
+		return err
+	}
+	// The version is assigned before the runtime is prepared, so that images built
+	// from source can be tagged with it.
+	manifest.Version = 1
+	prev, err := l.readManifest(manifest.Type)
+	if err == nil {
+		manifest.Version = prev.Version + 1
+		if prev.Version == 0 {
+			manifest.Version = 2
+		}
+	} else {
+		prev = nil
+	}
+	if manifest.Auth == "" {
+		err = runtime.Prepare(manifest)
+	} else {
+		err = l.prepareWithAuth(runtime, manifest)
 	}
-	f, err := os.OpenFile(filepath.Join(l.BasePath, manifest.Type), os.O_WRONLY, 0600)
 	if err != nil {
-		return fmt.Errorf("failed to create manifest: %s", err)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(l.BasePath, versionsDir, manifest.Type), 0700); err != nil {
+		return fmt.Errorf("failed to create version directory: %s", err)
+	}
+	if prev != nil && prev.Version == 0 {
+		// The previous manifest predates versioning, so keep it as version 1.
+		prev.Version = 1
+		if err := l.writeManifest(l.versionPath(prev.Type, prev.Version), prev); err != nil {
+			return err
+		}
+	}
+	if err := l.writeManifest(l.versionPath(manifest.Type, manifest.Version), manifest); err != nil {
+		return err
+	}
+	return l.writeManifest(filepath.Join(l.BasePath, manifest.Type), manifest)
+}
+
+// Delete removes the manifest and version history of the contract with the provided name. If
+// RemoveImages is set, the resources held by the contract's Runtime, such as its Docker image,
+// are removed as well. Otherwise, its manifests are kept until CollectGarbage removes them
+// along with those resources.
+// ErrContractNotExist is returned if no manifest exists for the contract.
+func (l *FSLibrary) Delete(name string) error {
+	l.ensurePath()
+	path := filepath.Join(l.BasePath, name)
+	manifest, err := l.readManifest(name)
+	if err != nil {
+		return err
+	}
+	versions := filepath.Join(l.BasePath, versionsDir, name)
+	if !l.RemoveImages {
+		garbage := filepath.Join(l.BasePath, garbageDir, fmt.Sprintf("%s-%d", name, time.Now().UnixNano()))
+		if err := os.MkdirAll(filepath.Dir(garbage), 0700); err != nil {
+			return fmt.Errorf("failed to create garbage directory: %s", err)
+		}
+		if err := os.Rename(versions, garbage); os.IsNotExist(err) {
+			err = os.Mkdir(garbage, 0700)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to move manifest versions: %s", err)
+		}
+		if err := os.Rename(path, filepath.Join(garbage, "latest")); err != nil {
+			return fmt.Errorf("failed to remove manifest: %s", err)
+		}
+		return nil
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove manifest: %s", err)
+	}
+	if err := os.RemoveAll(versions); err != nil {
+		return fmt.Errorf("failed to remove manifest versions: %s", err)
+	}
+	runtime, err := LookupRuntime(manifest.Runtime)
+	if err != nil {
+		return err
+	}
+	return runtime.Remove(manifest)
+}
+
+// CollectGarbage removes every version of each contract except the latest keepVersions,
+// and then the runtime resources, such as Docker images, of deleted contracts and removed
+// versions that no remaining version uses. If keepVersions is not positive, no versions are
+// removed. What was reclaimed is added to report. Resources that could not be removed are
+// listed in report.Errors and retried by the next collection.
+func (l *FSLibrary) CollectGarbage(keepVersions int, report *GCReport) error {
+	l.ensurePath()
+	manifests, err := l.List()
+	if err != nil {
+		return err
+	}
+	var candidates []*ContractManifest
+	inUse := make(map[string]bool)
+	for i := range manifests {
+		latest := &manifests[i]
+		inUse[runtimeResource(latest)] = true
+		for v := 1; v < latest.Version; v++ {
+			path := l.versionPath(latest.Type, v)
+			m, err := l.readManifestFile(path)
+			if err == ErrContractNotExist {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if keepVersions <= 0 || v > latest.Version-keepVersions {
+				inUse[runtimeResource(m)] = true
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("failed to stat manifest: %s", err)
+			}
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove manifest version: %s", err)
+			}
+			report.PrunedVersions++
+			report.VersionBytes += info.Size()
+			candidates = append(candidates, m)
+		}
+	}
+
+	garbage := filepath.Join(l.BasePath, garbageDir)
+	dirs, err := ioutil.ReadDir(garbage)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read garbage: %s", err)
+	}
+	// failed holds the resources that could not be removed, so that the manifests
+	// referring to them are kept for the next collection.
+	failed := make(map[string]bool)
+	removed := make(map[string]bool)
+	remove := func(m *ContractManifest) {
+		res := runtimeResource(m)
+		if inUse[res] || removed[res] || failed[res] {
+			return
+		}
+		runtime, err := LookupRuntime(m.Runtime)
+		if err == nil {
+			err = runtime.Remove(m)
+		}
+		if err != nil {
+			failed[res] = true
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", m.Type, err))
+			return
+		}
+		removed[res] = true
+		if m.Image != "" {
+			report.RemovedImages = append(report.RemovedImages, m.Image)
+		}
+	}
+	for _, m := range candidates {
+		remove(m)
+	}
+	for _, d := range dirs {
+		dir := filepath.Join(garbage, d.Name())
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read garbage: %s", err)
+		}
+		keep := false
+		for _, f := range files {
+			m, err := l.readManifestFile(filepath.Join(dir, f.Name()))
+			if err != nil {
+				continue
+			}
+			remove(m)
+			keep = keep || failed[runtimeResource(m)]
+		}
+		if !keep {
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("failed to remove garbage: %s", err)
+			}
+		}
+	}
+	return nil
+}
+
+// runtimeResource identifies the resource, such as an image, that Runtime.Remove
+// releases for manifest.
+func runtimeResource(m *ContractManifest) string {
+	runtime := m.Runtime
+	if runtime == "" {
+		runtime = RuntimeDocker
+	}
+	return runtime + ":" + m.Image
+}
+
+// prepareWithAuth prepares runtime with the manifest's Auth in plaintext, and then
+// seals Auth so that it is encrypted when the manifest is written.
+func (l *FSLibrary) prepareWithAuth(runtime Runtime, manifest *ContractManifest) error {
+	s, err := l.sealer()
+	if err != nil {
+		return err
+	}
+	if manifest.Auth, err = s.open(manifest.Auth); err != nil {
+		return fmt.Errorf("invalid registry auth: %s", err)
+	}
+	if err := runtime.Prepare(manifest); err != nil {
+		return err
+	}
+	if manifest.Auth, err = s.seal(manifest.Auth); err != nil {
+		return fmt.Errorf("failed to encrypt registry auth: %s", err)
+	}
+	return nil
+}
+
+// verify checks the manifest against its pinned image with its Auth in plaintext.
+// The manifest itself is left sealed.
+func (l *FSLibrary) verify(v Verifier, manifest *ContractManifest) error {
+	m := *manifest
+	if m.Auth != "" {
+		s, err := l.sealer()
+		if err != nil {
+			return err
+		}
+		if m.Auth, err = s.open(m.Auth); err != nil {
+			return fmt.Errorf("invalid registry auth: %s", err)
+		}
+	}
+	logger := l.Logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+	return v.Verify(&m, logger.With(logging.Contract(m.Type)))
+}
+
+func (l *FSLibrary) sealer() (*sealer, error) {
+	path := l.KeyPath
+	if path == "" {
+		path = filepath.Join(l.BasePath, ".key")
+	}
+	return l.key.sealer(path)
+}
+
+func (l *FSLibrary) readManifest(name string) (*ContractManifest, error) {
+	return l.readManifestFile(filepath.Join(l.BasePath, name))
+}
+
+func (l *FSLibrary) readManifestFile(path string) (*ContractManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ErrContractNotExist
 	}
 	defer f.Close()
+	var manifest ContractManifest
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to read JSON manifest: %s", err)
+	}
+	return &manifest, nil
+}
+
+// writeManifest writes manifest to path, creating the file if it doesn't exist. The
+// manifest is written to a temporary file in the same directory, which is then
+// renamed over path, so that a manifest is never left partially written.
+func (l *FSLibrary) writeManifest(path string, manifest *ContractManifest) error {
+	dir := filepath.Dir(path)
+	// The temporary file is hidden, so that List skips it.
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %s", err)
+	}
+	defer os.Remove(f.Name())
 	if err := json.NewEncoder(f).Encode(manifest); err != nil {
+		f.Close()
 		return fmt.Errorf("failed to write JSON manifest: %s", err)
 	}
+	if l.Sync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to sync manifest: %s", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write JSON manifest: %s", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to store manifest: %s", err)
+	}
+	if l.Sync {
+		if err := syncDir(dir); err != nil {
+			return fmt.Errorf("failed to sync manifest directory: %s", err)
+		}
+	}
 	return nil
 }
 
+// syncDir flushes the entries of the directory at path to disk, so that files
+// created or renamed in it are durable.
+func syncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// versionPath returns the path of the manifest for the given version of a contract.
+func (l *FSLibrary) versionPath(name string, version int) string {
+	return filepath.Join(l.BasePath, versionsDir, name, strconv.Itoa(version))
+}
+
 func (l *FSLibrary) ensurePath() {
 	l.once.Do(func() {
-		os.MkdirAll(l.BasePath, 0600)
+		os.MkdirAll(l.BasePath, 0700)
 	})
 }
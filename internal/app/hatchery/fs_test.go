@@ -0,0 +1,185 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// envRuntime is a Runtime whose Contracts record the environment they were given.
+type envRuntime struct{}
+
+type envContract struct {
+	env map[string]string
+}
+
+func (envContract) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func (envRuntime) Prepare(manifest *ContractManifest) error { return nil }
+
+func (envRuntime) Contract(manifest *ContractManifest, env map[string]string, logger logging.Logger) (Contract, error) {
+	return envContract{env: env}, nil
+}
+
+func (envRuntime) Remove(manifest *ContractManifest) error { return nil }
+
+const envRuntimeName = "envtest"
+
+func init() {
+	RegisterRuntime(envRuntimeName, envRuntime{})
+}
+
+func newEnvTestLibrary(t *testing.T, policy EnvPolicy) *FSLibrary {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "hatchery-fs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	secrets := &HeapSecretStore{Heap: NewMemHeap(), KeyPath: filepath.Join(dir, ".key")}
+	for name, value := range map[string]string{
+		"fake_name":   "secret-name",
+		"fake_key":    "secret-key",
+		"fake_key_id": "secret-key-id",
+		"fake_chain":  "secret-chain",
+		"api_token":   "token",
+		"fake_txn_id": "secret-txn-id",
+	} {
+		if _, err := secrets.PutSecret(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &FSLibrary{
+		BasePath: dir,
+		Credentials: Credentials{
+			AuthKey:       "node-key",
+			AuthID:        "node-key-id",
+			DragonChainID: "node-chain",
+		},
+		Secrets:   secrets,
+		EnvPolicy: policy,
+	}
+}
+
+func TestFSLibraryContractReservedEnv(t *testing.T) {
+	want := map[string]string{
+		SCName:        "mycontract",
+		AuthKey:       "node-key",
+		AuthID:        "node-key-id",
+		DragonChainID: "node-chain",
+	}
+	tests := []struct {
+		name     string
+		manifest ContractManifest
+	}{
+		{"env", ContractManifest{Env: map[string]string{
+			SCName:        "impostor",
+			AuthKey:       "fake-key",
+			AuthID:        "fake-key-id",
+			DragonChainID: "fake-chain",
+		}}},
+		{"secrets", ContractManifest{Secrets: map[string]string{
+			SCName:        "fake_name",
+			AuthKey:       "fake_key",
+			AuthID:        "fake_key_id",
+			DragonChainID: "fake_chain",
+		}}},
+		{"env and secrets", ContractManifest{
+			Env:     map[string]string{SCName: "impostor", AuthKey: "fake-key"},
+			Secrets: map[string]string{AuthID: "fake_key_id", DragonChainID: "fake_chain"},
+		}},
+		{"heap token", ContractManifest{Env: map[string]string{HeapTokenKey: "fake-token"}}},
+		{"transaction id", ContractManifest{Secrets: map[string]string{backend.TxnIDEnv: "fake_txn_id"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lib := newEnvTestLibrary(t, EnvPolicy{})
+			m := tt.manifest
+			m.Type, m.Runtime = "mycontract", envRuntimeName
+			c, err := lib.Contract(&m)
+			if err != nil {
+				t.Fatalf("Contract() failed: %s", err)
+			}
+			env := c.(envContract).env
+			if !reflect.DeepEqual(env, want) {
+				t.Errorf("env = %v, want %v", env, want)
+			}
+		})
+	}
+}
+
+func TestFSLibraryContractEnvPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   EnvPolicy
+		manifest ContractManifest
+		wantErr  bool
+		wantEnv  map[string]string
+	}{
+		{
+			name:     "permitted",
+			policy:   EnvPolicy{Allow: []string{"APP_*"}},
+			manifest: ContractManifest{Env: map[string]string{"APP_NAME": "demo"}, Secrets: map[string]string{"APP_TOKEN": "api_token"}},
+			wantEnv:  map[string]string{"APP_NAME": "demo", "APP_TOKEN": "token"},
+		},
+		{
+			name:     "env not allowed",
+			policy:   EnvPolicy{Allow: []string{"APP_*"}},
+			manifest: ContractManifest{Env: map[string]string{"DB_NAME": "demo"}},
+			wantErr:  true,
+		},
+		{
+			name:     "secret denied",
+			policy:   EnvPolicy{Deny: []string{"APP_TOKEN"}},
+			manifest: ContractManifest{Secrets: map[string]string{"APP_TOKEN": "api_token"}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lib := newEnvTestLibrary(t, tt.policy)
+			m := tt.manifest
+			m.Type, m.Runtime = "mycontract", envRuntimeName
+			c, err := lib.Contract(&m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Contract() = %v, want error: %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			env := c.(envContract).env
+			for k, v := range tt.wantEnv {
+				if env[k] != v {
+					t.Errorf("%s = %q, want %q", k, env[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// GCReport describes what a garbage collection reclaimed.
+type GCReport struct {
+	// RemovedImages are the images of deleted contracts, and of removed contract
+	// versions, that were removed.
+	RemovedImages []string `json:"removed_images"`
+	// PrunedVersions is the number of old contract versions removed.
+	PrunedVersions int `json:"pruned_versions"`
+	// VersionBytes is the size of the removed version manifests.
+	VersionBytes int64 `json:"version_bytes"`
+	// HeapBytes is how much the heap's file shrank when it was compacted.
+	HeapBytes int64 `json:"heap_bytes"`
+	// Errors describes the resources that could not be removed.
+	Errors []string `json:"errors,omitempty"`
+	// Duration is how long the collection took.
+	Duration string `json:"duration"`
+}
+
+// LibraryCollector is implemented by Libraries that can reclaim the storage held by
+// deleted contracts and old contract versions.
+type LibraryCollector interface {
+	// CollectGarbage removes every version of each contract except the latest
+	// keepVersions, or none if keepVersions is not positive, along with the
+	// resources of deleted contracts and removed versions, and adds what was
+	// reclaimed to report.
+	CollectGarbage(keepVersions int, report *GCReport) error
+}
+
+// Compactor is implemented by Heaps whose storage can be compacted.
+type Compactor interface {
+	// Compact reclaims the space left unused by deleted and overwritten values,
+	// and returns the number of bytes reclaimed.
+	Compact() (int64, error)
+}
+
+// CollectGarbage returns an HTTP handler function that collects garbage right away
+// and responds with a GCReport. The optional keep_versions query parameter overrides
+// GCKeepVersions.
+func (a *Application) CollectGarbage() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keep, err := queryInt(r, "keep_versions", a.GCKeepVersions)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "keep_versions must be an integer")
+			return
+		}
+		report, err := a.collectGarbage(keep)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, report)
+	}
+}
+
+// collectGarbage prunes contract versions beyond the latest keepVersions, removes the
+// images of deleted contracts and compacts the heap, as far as the Library and Heap
+// support it. Only one collection runs at a time.
+func (a *Application) collectGarbage(keepVersions int) (*GCReport, error) {
+	a.gcMu.Lock()
+	defer a.gcMu.Unlock()
+	start := time.Now()
+	report := &GCReport{RemovedImages: []string{}}
+	if c, ok := a.Lib.(LibraryCollector); ok {
+		if err := c.CollectGarbage(keepVersions, report); err != nil {
+			return nil, err
+		}
+	}
+	if c, ok := a.Heap.(Compactor); ok {
+		n, err := c.Compact()
+		if err != nil {
+			return nil, err
+		}
+		report.HeapBytes = n
+	}
+	report.Duration = time.Since(start).String()
+	a.log().Info("garbage collected",
+		logging.F("removed_images", len(report.RemovedImages)),
+		logging.F("pruned_versions", report.PrunedVersions),
+		logging.F("version_bytes", report.VersionBytes),
+		logging.F("heap_bytes", report.HeapBytes),
+		logging.F("errors", len(report.Errors)))
+	return report, nil
+}
+
+// startGC begins collecting garbage every GCInterval. It does nothing if GCInterval
+// is not positive.
+func (a *Application) startGC() {
+	if a.GCInterval <= 0 {
+		return
+	}
+	a.gcOnce.Do(func() {
+		a.gcLoopMu.Lock()
+		a.gcDone = make(chan struct{})
+		a.gcLoopMu.Unlock()
+		go a.collectPeriodically(a.gcDone)
+	})
+}
+
+// stopGC stops collecting garbage. A collection that is underway finishes first.
+func (a *Application) stopGC() {
+	a.gcLoopMu.Lock()
+	if a.gcDone != nil {
+		select {
+		case <-a.gcDone:
+		default:
+			close(a.gcDone)
+		}
+	}
+	a.gcLoopMu.Unlock()
+	a.gcMu.Lock()
+	a.gcMu.Unlock()
+}
+
+func (a *Application) collectPeriodically(done chan struct{}) {
+	ticker := time.NewTicker(a.GCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := a.collectGarbage(a.GCKeepVersions); err != nil {
+				a.log().Error("garbage collection failed", logging.Err(err))
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+)
+
+// readinessTimeout bounds each dependency check made by /readyz, so that a hung
+// dependency fails its check instead of hanging the probe.
+const readinessTimeout = 2 * time.Second
+
+// Statuses reported by the health endpoints.
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// healthCheck checks that one of the application's dependencies is usable.
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// dependencyStatus is the result of a single healthCheck.
+type dependencyStatus struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+type readinessResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// readinessChecks returns the checks /readyz makes: the heap can be read, the
+// Docker daemon responds and the contract library can be listed.
+func (a *Application) readinessChecks() []healthCheck {
+	return []healthCheck{
+		{name: "heap", check: func(ctx context.Context) error {
+			_, err := a.Heap.Buckets()
+			return err
+		}},
+		{name: "docker", check: docker.Ping},
+		{name: "library", check: func(ctx context.Context) error {
+			_, err := a.Lib.List()
+			return err
+		}},
+	}
+}
+
+// Healthz reports that the process is alive. It checks no dependencies, so it is
+// suitable as a liveness probe.
+func (a *Application) Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, map[string]string{"status": HealthOK})
+	}
+}
+
+// Readyz reports whether the application's dependencies are usable, with the
+// status of each. It responds 503 if any check fails, so it is suitable as a
+// readiness probe.
+func (a *Application) Readyz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checks := a.readinessChecks()
+		statuses := make([]dependencyStatus, len(checks))
+		var wg sync.WaitGroup
+		for i, c := range checks {
+			wg.Add(1)
+			go func(i int, c healthCheck) {
+				defer wg.Done()
+				statuses[i] = runCheck(r.Context(), c)
+			}(i, c)
+		}
+		wg.Wait()
+
+		resp := readinessResponse{Status: HealthOK, Dependencies: make(map[string]dependencyStatus, len(checks))}
+		for i, c := range checks {
+			if statuses[i].Status != HealthOK {
+				resp.Status = HealthUnavailable
+			}
+			resp.Dependencies[c.name] = statuses[i]
+		}
+		if resp.Status != HealthOK {
+			w.Header().Set("Content-type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		writeJSONResponse(w, resp)
+	}
+}
+
+// runCheck runs c, giving up after readinessTimeout. Checks that can't be
+// cancelled keep running in the background after a timeout.
+func runCheck(ctx context.Context, c healthCheck) dependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	status := dependencyStatus{Status: HealthOK, LatencyMS: int64(time.Since(start) / time.Millisecond)}
+	if err != nil {
+		status.Status = HealthUnavailable
+		status.Error = err.Error()
+	}
+	return status
+}
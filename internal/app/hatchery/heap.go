@@ -0,0 +1,510 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Environment keys passed to contracts so they can write to the heap API.
+const (
+	HatcheryURL  = "HATCHERY_URL"
+	HeapTokenKey = "HEAP_TOKEN"
+)
+
+// Heap buckets whose name begins with reservedBucketPrefix are used internally
+// by Hatchery and are never exposed through the heap API.
+const (
+	reservedBucketPrefix = "__hatchery_"
+	heapTokenBucket      = reservedBucketPrefix + "heap_tokens"
+)
+
+// Formats accepted by the format query parameter of GetSCHeap.
+const (
+	heapFormatRaw  = "raw"
+	heapFormatJSON = "json"
+)
+
+// Environ is implemented by Contracts that accept additional environment
+// variables before they are executed.
+type Environ interface {
+	// SetEnv sets the environment variable key to value for subsequent executions.
+	SetEnv(key, value string)
+}
+
+func isReservedBucket(bucket string) bool {
+	return strings.HasPrefix(bucket, reservedBucketPrefix)
+}
+
+// PostSCHeap returns an HTTP handler function that writes key value pairs to the heap
+// of the requested contract. The request body must be a JSON object; each of its members
+// is stored as a separate heap entry holding the member's raw JSON value. Requests must be
+// authorized with the contract's heap token as a bearer token, or the token of a contract
+// listed in its HeapWriters. Contracts receive their token in the HEAP_TOKEN environment
+// variable. Writes that don't fit within the heap's quota fail
+// with a quota_exceeded error, and writes whose values don't satisfy the schemas
+// declared in the contract's manifest fail with a schema_violation error; both leave
+// the heap untouched. See admitHeap.
+func (a *Application) PostSCHeap() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["sc_name"]
+		caller, ok := a.authorizeHeap(w, r, name, true)
+		if !ok {
+			return
+		}
+		var kvps map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&kvps); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "heap values must be a JSON object: "+err.Error())
+			return
+		}
+		puts := make([]HeapPut, 0, len(kvps))
+		for k, v := range kvps {
+			puts = append(puts, HeapPut{Bucket: name, Key: k, Value: v})
+		}
+		if err := a.admitHeap(name, puts, false); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		_, span := tracing.Start(r.Context(), "heap.put",
+			attribute.String("hatchery.heap.bucket", name),
+			attribute.Int("hatchery.heap.keys", len(kvps)),
+		)
+		journal := a.heapJournal(r, caller)
+		for k, v := range kvps {
+			err := journal.before(a.Heap, name, k)
+			if err == nil {
+				err = a.Heap.Put(name, k, v)
+			}
+			if err != nil {
+				tracing.End(span, err)
+				writeErrorFrom(w, err)
+				return
+			}
+			journal.wrote(name, k, v)
+			a.heapWritten(name, HeapPut{Bucket: name, Key: k, Value: v})
+		}
+		span.End()
+		a.touchPuts(puts)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// heapSwap is the request body of CompareAndSwapSCHeap. Old is compared byte for byte
+// with the value stored under the key, as returned by GetSCHeap with format=raw; if it
+// is omitted, the key must not exist.
+type heapSwap struct {
+	Old json.RawMessage `json:"old,omitempty"`
+	New json.RawMessage `json:"new"`
+}
+
+// CompareAndSwapSCHeap returns an HTTP handler function that writes a value to a key
+// in the heap of the requested contract only if the key still holds the value the
+// client last read, so that contracts coordinating through the heap can update it
+// optimistically instead of overwriting each other's writes. If the key holds
+// anything else, nothing is written and the request fails with a conflict error, and
+// the client should read the key again and retry. Requests are authorized and
+// admitted like those of PostSCHeap.
+func (a *Application) CompareAndSwapSCHeap() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		name, key := vars["sc_name"], vars["key"]
+		caller, ok := a.authorizeHeap(w, r, name, true)
+		if !ok {
+			return
+		}
+		var swap heapSwap
+		if err := json.NewDecoder(r.Body).Decode(&swap); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "compare and swap must be a JSON object: "+err.Error())
+			return
+		}
+		if swap.New == nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "compare and swap requires a new value")
+			return
+		}
+		put := HeapPut{Bucket: name, Key: key, Value: swap.New}
+		if err := a.admitHeap(name, []HeapPut{put}, false); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		journal := a.heapJournal(r, caller)
+		if err := journal.before(a.Heap, name, key); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		_, span := tracing.Start(r.Context(), "heap.compare_and_swap",
+			attribute.String("hatchery.heap.bucket", name),
+		)
+		swapped, err := a.Heap.CompareAndSwap(name, key, swap.Old, swap.New)
+		tracing.End(span, err)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if !swapped {
+			writeError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("heap key %q in %s no longer holds the expected value", key, name))
+			return
+		}
+		journal.wrote(name, key, swap.New)
+		a.heapWritten(name, put)
+		a.touchPuts([]HeapPut{put})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteSCHeapKey returns an HTTP handler function that removes a key from the heap of
+// the requested contract. Like PostSCHeap, requests must be authorized with the heap
+// token of the contract or of one of its HeapWriters.
+func (a *Application) DeleteSCHeapKey() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		caller, ok := a.authorizeHeap(w, r, vars["sc_name"], true)
+		if !ok {
+			return
+		}
+		journal := a.heapJournal(r, caller)
+		err := journal.before(a.Heap, vars["sc_name"], vars["key"])
+		if err == nil {
+			err = a.Heap.Delete(vars["sc_name"], vars["key"])
+		}
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		journal.wrote(vars["sc_name"], vars["key"], nil)
+		a.heapDeleted(vars["sc_name"], vars["key"])
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListHeaps returns an HTTP handler function that responds with the names of every
+// contract heap bucket. Buckets reserved for Hatchery's internal use are omitted.
+func (a *Application) ListHeaps() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buckets, err := a.Heap.Buckets()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		names := make([]string, 0, len(buckets))
+		for _, b := range buckets {
+			if !isReservedBucket(b) {
+				names = append(names, b)
+			}
+		}
+		writeJSONResponse(w, names)
+	}
+}
+
+// DeleteSCHeap returns an HTTP handler function that drops the requested contract's heap
+// bucket along with everything in it. It is an administrative route, so it is authorized
+// like the rest of the API rather than with the contract's heap token.
+func (a *Application) DeleteSCHeap() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["sc_name"]
+		if isReservedBucket(name) {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "heap does not exist")
+			return
+		}
+		if err := a.Heap.DeleteBucket(name); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		a.forgetHeapAccess(name)
+		a.heapDeleted(name, "")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// authorizeHeap checks that r carries the heap token of a contract that may read, or
+// if write is set write, the heap of the named contract: the contract itself, or one
+// its manifest grants access to. See checkHeapACL. The name of the calling contract
+// is returned. If it doesn't, an error response is written and false is returned.
+func (a *Application) authorizeHeap(w http.ResponseWriter, r *http.Request, name string, write bool) (string, bool) {
+	if isReservedBucket(name) {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "heap does not exist")
+		return "", false
+	}
+	caller, ok, err := a.heapCaller(r, name)
+	if err != nil {
+		writeErrorFrom(w, err)
+		return "", false
+	}
+	if !ok {
+		writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid heap token")
+		return "", false
+	}
+	auditCallerAs(r, "contract:"+caller)
+	if err := a.checkHeapACL(caller, name, write); err != nil {
+		writeErrorFrom(w, err)
+		return "", false
+	}
+	return caller, true
+}
+
+// writeHeapValue responds with the heap value v in the given format. If no format is
+// given, v is served with contentType, the type declared for its key, unless that is
+// empty or a JSON type that v doesn't satisfy. See GetSCHeap.
+func writeHeapValue(w http.ResponseWriter, v []byte, format, contentType string) {
+	isJSON := json.Valid(v)
+	switch {
+	case format == "" && contentType != "" && (isJSON || !isJSONContentType(contentType)):
+		w.Header().Set("Content-type", contentType)
+		w.Write(v)
+	case format == heapFormatRaw || (format == "" && !isJSON):
+		w.Header().Set("Content-type", "application/octet-stream")
+		w.Write(v)
+	case isJSON:
+		w.Header().Set("Content-type", "application/json")
+		w.Write(v)
+	default:
+		writeJSONResponse(w, v)
+	}
+}
+
+// putJSON stores the JSON encoding of v in the heap.
+func (a *Application) putJSON(bucket, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return a.Heap.Put(bucket, key, b)
+}
+
+// heapToken returns the heap token for the named contract, issuing a new one if
+// the contract doesn't have one yet.
+func (a *Application) heapToken(name string) (string, error) {
+	a.tokenMu.Lock()
+	defer a.tokenMu.Unlock()
+	token, err := a.Heap.Get(heapTokenBucket, name)
+	if err == nil {
+		return string(token), nil
+	}
+	if err != ErrHeapNotExist {
+		return "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token = []byte(hex.EncodeToString(b))
+	if err := a.Heap.Put(heapTokenBucket, name, token); err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+// validBearer reports whether the request carries token as its bearer token.
+func validBearer(r *http.Request, token []byte) bool {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), token) == 1
+}
+
+// MemHeap is an in-memory Heap implementation. Its contents are lost when the
+// process exits, which makes it useful for testing.
+type MemHeap struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemHeap returns a new, empty MemHeap.
+func NewMemHeap() *MemHeap {
+	return &MemHeap{buckets: make(map[string]map[string][]byte)}
+}
+
+// Put stores a copy of value under key in the given bucket. An error is never returned.
+func (h *MemHeap) Put(bucket, key string, value []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buck, ok := h.buckets[bucket]
+	if !ok {
+		buck = make(map[string][]byte)
+		h.buckets[bucket] = buck
+	}
+	buck[key] = copyBytes(value)
+	return nil
+}
+
+// CompareAndSwap stores a copy of new under key in the given bucket if the key holds
+// old, or if old is nil, if it doesn't exist. An error is never returned.
+func (h *MemHeap) CompareAndSwap(bucket, key string, old, new []byte) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.buckets[bucket][key]
+	if !casMatches(v, ok, old) {
+		return false, nil
+	}
+	buck, ok := h.buckets[bucket]
+	if !ok {
+		buck = make(map[string][]byte)
+		h.buckets[bucket] = buck
+	}
+	buck[key] = copyBytes(new)
+	return true, nil
+}
+
+// casMatches reports whether a key holding current, if exists is set, matches the old
+// value of a compare-and-swap. A nil old only matches a key that doesn't exist.
+func casMatches(current []byte, exists bool, old []byte) bool {
+	if old == nil {
+		return !exists
+	}
+	return exists && bytes.Equal(current, old)
+}
+
+// Get returns a copy of the value for key in the given bucket. ErrHeapNotExist
+// is returned if there is no such value.
+func (h *MemHeap) Get(bucket, key string) ([]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	v, ok := h.buckets[bucket][key]
+	if !ok {
+		return nil, ErrHeapNotExist
+	}
+	return copyBytes(v), nil
+}
+
+// GetAll returns a copy of every kvp in the given bucket. An error is never returned.
+func (h *MemHeap) GetAll(bucket string) (map[string][]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	all := make(map[string][]byte, len(h.buckets[bucket]))
+	for k, v := range h.buckets[bucket] {
+		all[k] = copyBytes(v)
+	}
+	return all, nil
+}
+
+// Iterate calls fn with a copy of each kvp in the given bucket whose key is at
+// least start, in ascending order of keys, until fn returns false. The keys are
+// listed up front and each value is read as it is reached, so fn may write to the
+// heap; keys deleted in the meantime are skipped. An error is never returned.
+func (h *MemHeap) Iterate(bucket, start string, fn func(key string, value []byte) bool) error {
+	h.mu.RLock()
+	keys := make([]string, 0, len(h.buckets[bucket]))
+	for k := range h.buckets[bucket] {
+		if k >= start {
+			keys = append(keys, k)
+		}
+	}
+	h.mu.RUnlock()
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, err := h.Get(bucket, k)
+		if err != nil {
+			continue
+		}
+		if !fn(k, v) {
+			break
+		}
+	}
+	return nil
+}
+
+// Keys returns the keys in the given bucket that begin with prefix, sorted in
+// ascending order. An error is never returned.
+func (h *MemHeap) Keys(bucket, prefix string) ([]string, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	keys := []string{}
+	for k := range h.buckets[bucket] {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// GetRange returns a copy of every kvp in the given bucket whose key is at least
+// start and, if end is not empty, less than end. An error is never returned.
+func (h *MemHeap) GetRange(bucket, start, end string) (map[string][]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	kvps := make(map[string][]byte)
+	for k, v := range h.buckets[bucket] {
+		if k >= start && (end == "" || k < end) {
+			kvps[k] = copyBytes(v)
+		}
+	}
+	return kvps, nil
+}
+
+// Delete removes key from the given bucket. ErrHeapNotExist is returned if there
+// is no such key.
+func (h *MemHeap) Delete(bucket, key string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.buckets[bucket][key]; !ok {
+		return ErrHeapNotExist
+	}
+	delete(h.buckets[bucket], key)
+	return nil
+}
+
+// DeleteBucket removes the given bucket and its contents. ErrHeapNotExist is
+// returned if there is no such bucket.
+func (h *MemHeap) DeleteBucket(bucket string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.buckets[bucket]; !ok {
+		return ErrHeapNotExist
+	}
+	delete(h.buckets, bucket)
+	return nil
+}
+
+// Buckets returns the names of every bucket, sorted in ascending order. An error
+// is never returned.
+func (h *MemHeap) Buckets() ([]string, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	names := make([]string, 0, len(h.buckets))
+	for name := range h.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
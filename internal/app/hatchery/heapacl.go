@@ -0,0 +1,139 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// HeapACLAny in a manifest's HeapReaders or HeapWriters grants access to every
+// contract.
+const HeapACLAny = "*"
+
+// ErrCodeHeapAccessDenied is the error code of heap requests a contract isn't
+// allowed to make.
+const ErrCodeHeapAccessDenied = "heap_access_denied"
+
+// HeapAccessError is returned when a contract reads or writes the heap of another
+// contract that hasn't granted it access.
+type HeapAccessError struct {
+	Contract string
+	Bucket   string
+	// Write is set if the contract tried to write to the bucket, rather than read it.
+	Write bool
+}
+
+func (e *HeapAccessError) Error() string {
+	access := "read"
+	if e.Write {
+		access = "write"
+	}
+	return fmt.Sprintf("contract %s may not %s heap bucket %s", e.Contract, access, e.Bucket)
+}
+
+// checkHeapACL returns a *HeapAccessError unless the named contract may read, or if
+// write is set write, bucket. A contract always has full access to the bucket named
+// after it. Another contract's bucket may be read by the contracts listed in its
+// manifest's HeapReaders and written by those listed in its HeapWriters; a writer
+// may also read. Buckets that don't belong to a contract, such as the shared bucket
+// of contract output, are open to every contract, and reserved buckets to
+// none.
+func (a *Application) checkHeapACL(contract, bucket string, write bool) error {
+	if isReservedBucket(bucket) {
+		return &HeapAccessError{Contract: contract, Bucket: bucket, Write: write}
+	}
+	if contract == bucket {
+		return nil
+	}
+	m, err := a.Lib.Manifest(bucket)
+	if err == ErrContractNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if heapACLAllows(m.HeapWriters, contract) || (!write && heapACLAllows(m.HeapReaders, contract)) {
+		return nil
+	}
+	return &HeapAccessError{Contract: contract, Bucket: bucket, Write: write}
+}
+
+func heapACLAllows(acl []string, contract string) bool {
+	for _, name := range acl {
+		if name == HeapACLAny || name == contract {
+			return true
+		}
+	}
+	return false
+}
+
+// heapCaller returns the name of the contract whose heap token r carries as its
+// bearer token, or false if it doesn't carry one. The token of the bucket being
+// accessed is tried first, since contracts mostly access their own heap.
+func (a *Application) heapCaller(r *http.Request, bucket string) (string, bool, error) {
+	token, err := a.Heap.Get(heapTokenBucket, bucket)
+	if err != nil && err != ErrHeapNotExist {
+		return "", false, err
+	}
+	if err == nil && validBearer(r, token) {
+		return bucket, true, nil
+	}
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return "", false, nil
+	}
+	bearer := []byte(auth[len(prefix):])
+	var caller string
+	err = backend.IterateHeap(a.Heap, heapTokenBucket, "", func(name string, token []byte) bool {
+		if subtle.ConstantTimeCompare(bearer, token) == 1 {
+			caller = name
+			return false
+		}
+		return true
+	})
+	if err != nil && err != ErrHeapNotExist {
+		return "", false, err
+	}
+	return caller, caller != "", nil
+}
+
+// heapReadable wraps a heap read handler so that contracts may call it with their
+// heap token, subject to the read ACL of the bucket, instead of an API key. Requests
+// without a bearer token are protected like the rest of the API.
+func (a *Application) heapReadable(next http.HandlerFunc) http.HandlerFunc {
+	protected := a.protected(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			protected(w, r)
+			return
+		}
+		if _, ok := a.authorizeHeap(w, r, mux.Vars(r)["sc_name"], false); !ok {
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,138 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// heapRefPattern matches references to heap values in the Env of a manifest, such
+// as "${heap:mycontract/config_key}".
+var heapRefPattern = regexp.MustCompile(`\$\{heap:([^}]*)\}`)
+
+// parseHeapRef splits the body of a heap reference into its bucket and key. The
+// bucket ends at the first slash. An error is returned if either is empty or the
+// bucket is reserved.
+func parseHeapRef(ref string) (bucket, key string, err error) {
+	i := strings.Index(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", fmt.Errorf("invalid heap reference %q: must have the form ${heap:<bucket>/<key>}", ref)
+	}
+	bucket, key = ref[:i], ref[i+1:]
+	if isReservedBucket(bucket) {
+		return "", "", fmt.Errorf("invalid heap reference %q: bucket is reserved", ref)
+	}
+	return bucket, key, nil
+}
+
+// parsePayloadSource splits the CronPayloadSource of a manifest, which has the
+// form "heap:<bucket>/<key>", into its bucket and key.
+func parsePayloadSource(source string) (bucket, key string, err error) {
+	if !strings.HasPrefix(source, "heap:") {
+		return "", "", fmt.Errorf("invalid payload source %q: must have the form heap:<bucket>/<key>", source)
+	}
+	return parseHeapRef(strings.TrimPrefix(source, "heap:"))
+}
+
+// heapRefViolations returns the reason each heap reference in value is invalid.
+func heapRefViolations(value string) []string {
+	var reasons []string
+	for _, m := range heapRefPattern.FindAllStringSubmatch(value, -1) {
+		if _, _, err := parseHeapRef(m[1]); err != nil {
+			reasons = append(reasons, err.Error())
+		}
+	}
+	return reasons
+}
+
+// interpolateEnv resolves the heap references in the Env of manifest from the heap
+// and sets the resulting values in the environment of contract, if it accepts one.
+// Variables that are also set from Secrets are left alone, since secrets take
+// precedence, and so are variables the application's EnvPolicy doesn't permit, such
+// as the ones Hatchery reserves. Heap values that are JSON strings are substituted without their quotes;
+// other values are substituted as stored. An error is returned if a referenced value
+// doesn't exist, or is in the heap of another contract that doesn't let this one read
+// it. See checkHeapACL.
+func (a *Application) interpolateEnv(contract Contract, manifest *ContractManifest) error {
+	e, ok := contract.(Environ)
+	if !ok {
+		return nil
+	}
+	for k, v := range manifest.Env {
+		if _, ok := manifest.Secrets[k]; ok || !strings.Contains(v, "${heap:") || !a.EnvPolicy.Permits(k) {
+			continue
+		}
+		var err error
+		resolved := heapRefPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			if err != nil {
+				return ""
+			}
+			var bucket, key string
+			bucket, key, err = parseHeapRef(heapRefPattern.FindStringSubmatch(ref)[1])
+			if err != nil {
+				return ""
+			}
+			if err = a.checkHeapACL(manifest.Type, bucket, false); err != nil {
+				err = fmt.Errorf("failed to resolve %s for %s: %s", ref, k, err)
+				return ""
+			}
+			var b []byte
+			if b, err = a.Heap.Get(bucket, key); err != nil {
+				err = fmt.Errorf("failed to resolve %s for %s: %s", ref, k, err)
+				return ""
+			}
+			var s string
+			if json.Unmarshal(b, &s) == nil {
+				return s
+			}
+			return string(b)
+		})
+		if err != nil {
+			return err
+		}
+		e.SetEnv(k, resolved)
+	}
+	return nil
+}
+
+// cronPayload returns the payload of a scheduled execution of the contract described
+// by manifest: the heap value named by its CronPayloadSource, if set and present,
+// and otherwise its CronPayload. Like heap references in Env, the source must be
+// readable by the contract.
+func (a *Application) cronPayload(manifest *ContractManifest) ([]byte, error) {
+	if manifest.CronPayloadSource == "" {
+		return manifest.CronPayload, nil
+	}
+	bucket, key, err := parsePayloadSource(manifest.CronPayloadSource)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkHeapACL(manifest.Type, bucket, false); err != nil {
+		return nil, err
+	}
+	b, err := a.Heap.Get(bucket, key)
+	if err == ErrHeapNotExist {
+		return manifest.CronPayload, nil
+	}
+	return b, err
+}
@@ -0,0 +1,263 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// Formats accepted by the format query parameter of ExportSCHeap.
+const (
+	exportFormatJSON   = "json"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportBatchSize is how many heap entries ExportSCHeap reads at a time.
+const exportBatchSize = 500
+
+// HeapEntry is a key value pair of an exported heap. Values that are valid JSON are
+// held in Value as-is, and any other value in Base64, so that an export restores
+// every value byte for byte.
+type HeapEntry struct {
+	Key    string          `json:"key"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Base64 []byte          `json:"base64,omitempty"`
+}
+
+// newHeapEntry returns the HeapEntry of the heap value v.
+func newHeapEntry(key string, v []byte) HeapEntry {
+	if json.Valid(v) {
+		return HeapEntry{Key: key, Value: v}
+	}
+	return HeapEntry{Key: key, Base64: v}
+}
+
+// value returns the heap value of e. An error is returned if e has no key, or
+// doesn't have exactly one of Value and Base64.
+func (e *HeapEntry) value() ([]byte, error) {
+	switch {
+	case e.Key == "":
+		return nil, errors.New("entries must have a key")
+	case e.Value != nil && e.Base64 != nil:
+		return nil, fmt.Errorf("entry %q has both a value and base64", e.Key)
+	case e.Value != nil:
+		return e.Value, nil
+	case e.Base64 != nil:
+		return e.Base64, nil
+	}
+	return nil, fmt.Errorf("entry %q has no value", e.Key)
+}
+
+type importHeapResponse struct {
+	Imported int `json:"imported"`
+}
+
+// ExportSCHeap returns an HTTP handler function that streams every key value pair in
+// the requested contract's heap, in ascending key order, as HeapEntries. The optional
+// format query parameter selects a JSON array, which is the default, or "ndjson" for
+// one entry per line, and the optional offset and limit query parameters select a page
+// of the entries. Like DeleteSCHeap, it is an administrative route.
+func (a *Application) ExportSCHeap() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["sc_name"]
+		if isReservedBucket(name) {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "heap does not exist")
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = exportFormatJSON
+		}
+		if format != exportFormatJSON && format != exportFormatNDJSON {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "format must be json or ndjson")
+			return
+		}
+		offset, limit, ok := pageParams(w, r)
+		if !ok {
+			return
+		}
+		// The first batch is read before the response begins, so that a heap that
+		// can't be read is reported with an error status.
+		start, skipped, n := "", 0, 0
+		batch, err := a.exportBatch(name, start)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+
+		// Entries are read a batch at a time, so the bucket is never held in memory
+		// at once, and a slow client doesn't hold the heap open. An error after the
+		// response has begun can only be logged, leaving the client with a truncated
+		// export.
+		if format == exportFormatNDJSON {
+			w.Header().Set("Content-type", "application/x-ndjson")
+		} else {
+			w.Header().Set("Content-type", "application/json")
+			io.WriteString(w, "[\n")
+		}
+	write:
+		for {
+			for _, e := range batch {
+				if skipped < offset {
+					skipped++
+					continue
+				}
+				if limit > 0 && n == limit {
+					break write
+				}
+				b, err := json.Marshal(e)
+				if err != nil {
+					return
+				}
+				if format == exportFormatJSON && n > 0 {
+					io.WriteString(w, ",\n")
+				}
+				w.Write(b)
+				if format == exportFormatNDJSON {
+					io.WriteString(w, "\n")
+				}
+				n++
+			}
+			if len(batch) < exportBatchSize {
+				break
+			}
+			// The next batch starts right after the last key of this one.
+			start = batch[len(batch)-1].Key + "\x00"
+			if batch, err = a.exportBatch(name, start); err != nil {
+				a.log().Error("failed to export heap", logging.F("bucket", name), logging.Err(err))
+				return
+			}
+		}
+		if format == exportFormatJSON {
+			io.WriteString(w, "\n]\n")
+		}
+	}
+}
+
+// exportBatch returns up to exportBatchSize entries of a heap bucket, starting at
+// the key start.
+func (a *Application) exportBatch(bucket, start string) ([]HeapEntry, error) {
+	batch := make([]HeapEntry, 0, exportBatchSize)
+	err := backend.IterateHeap(a.Heap, bucket, start, func(key string, value []byte) bool {
+		batch = append(batch, newHeapEntry(key, copyBytes(value)))
+		return len(batch) < exportBatchSize
+	})
+	return batch, err
+}
+
+// ImportSCHeap returns an HTTP handler function that writes the posted HeapEntries to
+// the requested contract's heap, as exported by ExportSCHeap in either format. If the
+// replace query parameter is true, the heap is emptied first, so that it holds exactly
+// the posted entries. The entries are all decoded before anything is written, so a
+// malformed body leaves the heap untouched. It responds with the number of entries
+// written. Like DeleteSCHeap, it is an administrative route. Like PostSCHeap, the entries
+// must fit within the heap's quota and satisfy its schemas.
+func (a *Application) ImportSCHeap() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["sc_name"]
+		if isReservedBucket(name) {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "heap does not exist")
+			return
+		}
+		replace := r.URL.Query().Get("replace") == "true"
+		entries, err := decodeHeapEntries(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid heap entries: "+err.Error())
+			return
+		}
+		values := make([][]byte, len(entries))
+		for i := range entries {
+			if values[i], err = entries[i].value(); err != nil {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid heap entries: "+err.Error())
+				return
+			}
+		}
+		puts := make([]HeapPut, len(entries))
+		for i, e := range entries {
+			puts[i] = HeapPut{Bucket: name, Key: e.Key, Value: values[i]}
+		}
+		if err := a.admitHeap(name, puts, replace); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if replace {
+			if err := a.Heap.DeleteBucket(name); err != nil && err != ErrHeapNotExist {
+				writeErrorFrom(w, err)
+				return
+			}
+			a.forgetHeapAccess(name)
+			a.heapDeleted(name, "")
+		}
+		for _, p := range puts {
+			if err := a.Heap.Put(name, p.Key, p.Value); err != nil {
+				writeErrorFrom(w, err)
+				return
+			}
+			a.heapWritten(name, p)
+		}
+		a.touchPuts(puts)
+		writeJSONResponse(w, importHeapResponse{Imported: len(entries)})
+	}
+}
+
+// decodeHeapEntries decodes the HeapEntries in r, which holds either a JSON array of
+// entries or a stream of entries, such as NDJSON.
+func decodeHeapEntries(r io.Reader) ([]HeapEntry, error) {
+	dec := json.NewDecoder(r)
+	var entries []HeapEntry
+	var first json.RawMessage
+	if err := dec.Decode(&first); err == io.EOF {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if len(first) > 0 && first[0] == '[' {
+		if err := json.Unmarshal(first, &entries); err != nil {
+			return nil, err
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, errors.New("unexpected data after the array of entries")
+		}
+		return entries, nil
+	}
+	raw := first
+	for {
+		var e HeapEntry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+		var next json.RawMessage
+		if err := dec.Decode(&next); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		raw = next
+	}
+}
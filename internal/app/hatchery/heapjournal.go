@@ -0,0 +1,236 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// TxnIDHeader identifies the execution a contract's heap request is made by. A
+// contract sets it to its TXN_ID, so that the heap writes of concurrent executions
+// of the contract can be told apart. See heapJournal.
+const TxnIDHeader = "X-Hatchery-Txn-Id"
+
+// heapJournal records the heap writes a contract makes through the heap API during a
+// single execution, along with what each key held before the execution first wrote
+// it, so that the writes can be undone if the execution fails. Writes are attributed
+// to the execution whose transaction ID they carry in TxnIDHeader, or to the only
+// execution of the contract in progress. If a write can't be attributed because
+// several executions of the contract are in progress, their journals are abandoned
+// and none of them are undone. A nil *heapJournal records nothing.
+type heapJournal struct {
+	contract string
+	txnID    string
+
+	mu        sync.Mutex
+	entries   []*heapJournalEntry
+	keys      map[heapJournalKey]*heapJournalEntry
+	abandoned bool
+}
+
+type heapJournalKey struct {
+	bucket, key string
+}
+
+// heapJournalEntry is what a key held before an execution first wrote it, and the
+// last value the execution wrote to it.
+type heapJournalEntry struct {
+	heapJournalKey
+	old     []byte
+	existed bool
+	value   []byte
+	deleted bool
+}
+
+// beginHeapJournal starts journaling the heap writes of an execution of the named
+// contract for the transaction with the given ID, which may be empty. It must be
+// ended with endHeapJournal.
+func (a *Application) beginHeapJournal(contract, txnID string) *heapJournal {
+	j := &heapJournal{contract: contract, txnID: txnID, keys: make(map[heapJournalKey]*heapJournalEntry)}
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	if a.journals == nil {
+		a.journals = make(map[string][]*heapJournal)
+	}
+	a.journals[contract] = append(a.journals[contract], j)
+	return j
+}
+
+// endHeapJournal stops journaling j's execution, and undoes its heap writes if failed
+// is set.
+func (a *Application) endHeapJournal(j *heapJournal, failed bool) {
+	a.journalMu.Lock()
+	journals := a.journals[j.contract]
+	for i, other := range journals {
+		if other == j {
+			journals = append(journals[:i:i], journals[i+1:]...)
+			break
+		}
+	}
+	if len(journals) == 0 {
+		delete(a.journals, j.contract)
+	} else {
+		a.journals[j.contract] = journals
+	}
+	a.journalMu.Unlock()
+	if failed {
+		a.rollbackHeap(j)
+	}
+}
+
+// heapJournal returns the journal of the execution of the calling contract that r is
+// made by, or nil if there is none.
+func (a *Application) heapJournal(r *http.Request, caller string) *heapJournal {
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	journals := a.journals[caller]
+	if txnID := r.Header.Get(TxnIDHeader); txnID != "" {
+		for _, j := range journals {
+			if j.txnID == txnID {
+				return j
+			}
+		}
+	}
+	switch len(journals) {
+	case 0:
+		return nil
+	case 1:
+		return journals[0]
+	}
+	for _, j := range journals {
+		j.abandon()
+	}
+	a.log().Info("heap write of a concurrent execution can't be journaled, so its writes won't be undone if it fails",
+		logging.Contract(caller),
+		logging.F("executions", len(journals)),
+	)
+	return nil
+}
+
+// before records what key in bucket holds before the execution first writes it. It
+// must be called before every write.
+func (j *heapJournal) before(heap Heap, bucket, key string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	k := heapJournalKey{bucket, key}
+	if j.abandoned || j.keys[k] != nil {
+		return nil
+	}
+	old, err := heap.Get(bucket, key)
+	if err != nil && err != ErrHeapNotExist {
+		return err
+	}
+	e := &heapJournalEntry{heapJournalKey: k, old: old, existed: err == nil}
+	j.keys[k] = e
+	j.entries = append(j.entries, e)
+	return nil
+}
+
+// wrote records that the execution wrote value to key in bucket, or deleted it if
+// value is nil.
+func (j *heapJournal) wrote(bucket, key string, value []byte) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if e := j.keys[heapJournalKey{bucket, key}]; e != nil {
+		e.value, e.deleted = value, value == nil
+	}
+}
+
+func (j *heapJournal) abandon() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.abandoned = true
+	j.entries, j.keys = nil, nil
+}
+
+// rollbackHeap restores every key j's execution wrote to what it held before, in the
+// reverse order of the first writes. A key is only restored if it still holds what
+// the execution last wrote to it, so that writes made since by other executions or
+// clients are kept.
+func (a *Application) rollbackHeap(j *heapJournal) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.abandoned || len(j.entries) == 0 {
+		return
+	}
+	logger := a.log().With(logging.Contract(j.contract))
+	restored := 0
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		e := j.entries[i]
+		ok, err := a.restoreHeapKey(e)
+		if err != nil {
+			logger.Error("failed to undo heap write of failed execution",
+				logging.F("bucket", e.bucket), logging.F("key", e.key), logging.Err(err))
+			continue
+		}
+		if !ok {
+			logger.Info("heap key changed since the failed execution wrote it, so it was kept",
+				logging.F("bucket", e.bucket), logging.F("key", e.key))
+			continue
+		}
+		restored++
+	}
+	logger.Info("heap writes of failed execution undone", logging.F("keys", restored))
+}
+
+// restoreHeapKey restores the key of e to what it held before the execution wrote
+// it, and reports whether it did.
+func (a *Application) restoreHeapKey(e *heapJournalEntry) (bool, error) {
+	if e.existed {
+		var current []byte
+		if !e.deleted {
+			current = e.value
+		}
+		ok, err := a.Heap.CompareAndSwap(e.bucket, e.key, current, e.old)
+		if ok {
+			a.heapWritten(e.bucket, HeapPut{Bucket: e.bucket, Key: e.key, Value: e.old})
+		}
+		return ok, err
+	}
+	if e.deleted {
+		// The key didn't exist before, and the execution deleted it again.
+		return true, nil
+	}
+	current, err := a.Heap.Get(e.bucket, e.key)
+	if err == ErrHeapNotExist {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(current, e.value) {
+		return false, nil
+	}
+	if err := a.Heap.Delete(e.bucket, e.key); err != nil {
+		return false, err
+	}
+	a.heapDeleted(e.bucket, e.key)
+	return true, nil
+}
@@ -0,0 +1,139 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Strategies for storing a contract's output in the heap. See
+// ContractManifest.HeapOutput.
+const (
+	// HeapOutputTopLevel stores each member of a JSON object output under its
+	// own key. It is the default.
+	HeapOutputTopLevel = "top_level"
+	// HeapOutputFlatten stores each leaf of a JSON object output under the path
+	// of object keys that leads to it, joined with dots, such as "a.b.c". Arrays
+	// are leaves.
+	HeapOutputFlatten = "flatten"
+	// HeapOutputSingle stores the whole output, JSON or not, under a single key.
+	HeapOutputSingle = "single"
+	// HeapOutputEnvelope stores each member of the "heap" object of a JSON
+	// object output under its own key, like DragonChain, and ignores the rest of
+	// the output.
+	HeapOutputEnvelope = "envelope"
+)
+
+const (
+	// defaultHeapOutputKey is the key HeapOutputSingle stores output under if the
+	// manifest doesn't set HeapOutputKey.
+	defaultHeapOutputKey = "output"
+	// heapEnvelopeKey is the member of a contract's output that HeapOutputEnvelope
+	// stores.
+	heapEnvelopeKey = "heap"
+)
+
+// validHeapOutput returns an error if strategy is not one of the HeapOutput
+// strategies.
+func validHeapOutput(strategy string) error {
+	switch strategy {
+	case "", HeapOutputTopLevel, HeapOutputFlatten, HeapOutputSingle, HeapOutputEnvelope:
+		return nil
+	}
+	return fmt.Errorf("must be %q, %q, %q or %q", HeapOutputTopLevel, HeapOutputFlatten, HeapOutputSingle, HeapOutputEnvelope)
+}
+
+// outputBucket returns the heap bucket the named contract's output is stored in: the
+// contract's own bucket, or Bucket if SharedBucket is set.
+func (a *Application) outputBucket(name string) string {
+	if a.SharedBucket {
+		return a.Bucket
+	}
+	return name
+}
+
+// contractOutputPuts returns the heap writes of the named contract's output to bucket,
+// made with the HeapOutput strategy of its manifest. If the manifest can't be read,
+// HeapOutputTopLevel is used.
+func (a *Application) contractOutputPuts(name, bucket string, output []byte) []HeapPut {
+	m, err := a.Lib.Manifest(name)
+	if err != nil {
+		return outputPuts(bucket, output, nil)
+	}
+	return outputPuts(bucket, output, m)
+}
+
+// outputPuts returns the heap writes of a contract's output to bucket, made with the
+// HeapOutput strategy of manifest, which may be nil. JSON values are stored raw, so
+// strings, objects and arrays round trip unchanged. Except under HeapOutputSingle,
+// output that isn't a JSON object isn't stored, and neither is the invoke member that
+// invokes other contracts.
+func outputPuts(bucket string, output []byte, manifest *ContractManifest) []HeapPut {
+	strategy := HeapOutputTopLevel
+	if manifest != nil && manifest.HeapOutput != "" {
+		strategy = manifest.HeapOutput
+	}
+	if strategy == HeapOutputSingle {
+		key := manifest.HeapOutputKey
+		if key == "" {
+			key = defaultHeapOutputKey
+		}
+		return []HeapPut{{Bucket: bucket, Key: key, Value: output}}
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(output, &members); err != nil {
+		return nil
+	}
+	delete(members, invokeKey)
+	var puts []HeapPut
+	switch strategy {
+	case HeapOutputFlatten:
+		puts = flattenPuts(bucket, "", members, puts)
+	case HeapOutputEnvelope:
+		var heap map[string]json.RawMessage
+		if err := json.Unmarshal(members[heapEnvelopeKey], &heap); err != nil {
+			return nil
+		}
+		for k, v := range heap {
+			puts = append(puts, HeapPut{Bucket: bucket, Key: k, Value: v})
+		}
+	default:
+		for k, v := range members {
+			puts = append(puts, HeapPut{Bucket: bucket, Key: k, Value: v})
+		}
+	}
+	return puts
+}
+
+// flattenPuts appends the writes of the leaves of members, whose keys are prefixed
+// with prefix, to puts. Empty objects are leaves, so that they aren't lost.
+func flattenPuts(bucket, prefix string, members map[string]json.RawMessage, puts []HeapPut) []HeapPut {
+	for k, v := range members {
+		key := prefix + k
+		var nested map[string]json.RawMessage
+		if json.Unmarshal(v, &nested) == nil && len(nested) > 0 {
+			puts = flattenPuts(bucket, key+".", nested, puts)
+			continue
+		}
+		puts = append(puts, HeapPut{Bucket: bucket, Key: key, Value: v})
+	}
+	return puts
+}
@@ -0,0 +1,545 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// ErrCodeSchemaViolation is the error code of heap writes whose values don't satisfy
+// the schema of their key.
+const ErrCodeSchemaViolation = "schema_violation"
+
+// HeapSchemaError is returned when a value written to the heap doesn't satisfy the
+// schema its contract's manifest declares for the value's key.
+type HeapSchemaError struct {
+	Bucket string
+	Key    string
+	// Prefix is the prefix of the schema that applies to Key.
+	Prefix string
+	// Path locates the offending part of the value, such as "$.players[2].score".
+	Path   string
+	Reason string
+}
+
+func (e *HeapSchemaError) Error() string {
+	return fmt.Sprintf("heap key %s of bucket %s violates its schema: %s %s", e.Key, e.Bucket, e.Path, e.Reason)
+}
+
+// heapSchemas returns the heap schemas declared by the manifest of the contract that
+// owns bucket, if any.
+func (a *Application) heapSchemas(bucket string) []backend.HeapSchema {
+	if isReservedBucket(bucket) {
+		return nil
+	}
+	m, err := a.Lib.Manifest(bucket)
+	if err != nil {
+		return nil
+	}
+	return m.HeapSchemas
+}
+
+// heapSchemaFor returns the index of the schema in schemas that applies to key, the one
+// with the longest matching prefix, or -1 if none does.
+func heapSchemaFor(schemas []backend.HeapSchema, key string) int {
+	match := -1
+	for i, s := range schemas {
+		if strings.HasPrefix(key, s.Prefix) && (match < 0 || len(s.Prefix) > len(schemas[match].Prefix)) {
+			match = i
+		}
+	}
+	return match
+}
+
+// heapContentType returns the content type declared for key of bucket, or "" if its
+// contract declares none.
+func (a *Application) heapContentType(bucket, key string) string {
+	schemas := a.heapSchemas(bucket)
+	i := heapSchemaFor(schemas, key)
+	switch {
+	case i < 0:
+		return ""
+	case schemas[i].ContentType != "":
+		return schemas[i].ContentType
+	case len(schemas[i].Schema) > 0:
+		return "application/json"
+	}
+	return ""
+}
+
+// checkHeapSchemas returns a *HeapSchemaError if the value of any of puts, which are
+// about to be written to bucket, doesn't satisfy the schema of its key. Values of keys
+// whose schema only declares a JSON content type must be valid JSON.
+func (a *Application) checkHeapSchemas(bucket string, puts []HeapPut) error {
+	schemas := a.heapSchemas(bucket)
+	if len(schemas) == 0 {
+		return nil
+	}
+	compiled := make(map[int]*jsonSchema)
+	for _, p := range puts {
+		i := heapSchemaFor(schemas, p.Key)
+		if i < 0 {
+			continue
+		}
+		hs := schemas[i]
+		fail := func(path, reason string) error {
+			return &HeapSchemaError{Bucket: bucket, Key: p.Key, Prefix: hs.Prefix, Path: path, Reason: reason}
+		}
+		if len(hs.Schema) == 0 {
+			if isJSONContentType(hs.ContentType) && !json.Valid(p.Value) {
+				return fail("$", "is not valid JSON")
+			}
+			continue
+		}
+		s, ok := compiled[i]
+		if !ok {
+			var err error
+			if s, err = parseJSONSchema(hs.Schema); err != nil {
+				return fmt.Errorf("heap schema %q of contract %s is invalid: %s", hs.Prefix, bucket, err)
+			}
+			compiled[i] = s
+		}
+		if path, reason := s.validateJSON(p.Value); reason != "" {
+			return fail(path, reason)
+		}
+	}
+	return nil
+}
+
+// admitHeap checks that puts, which are about to be written to bucket, satisfy its
+// schemas, and makes room for them within its quota. See checkHeapSchemas and
+// reserveHeap.
+func (a *Application) admitHeap(bucket string, puts []HeapPut, replace bool) error {
+	if err := a.checkHeapSchemas(bucket, puts); err != nil {
+		return err
+	}
+	return a.reserveHeap(bucket, puts, replace)
+}
+
+// heapSchemaViolations returns a description of everything wrong with schemas, keyed
+// by the field at fault, in the order of schemas.
+func heapSchemaViolations(schemas []backend.HeapSchema) []Violation {
+	var violations []Violation
+	prefixes := make(map[string]bool)
+	for i, hs := range schemas {
+		field := fmt.Sprintf("HeapSchemas[%d]", i)
+		if prefixes[hs.Prefix] {
+			violations = append(violations, Violation{Field: field + ".Prefix", Message: fmt.Sprintf("%q is declared more than once", hs.Prefix)})
+		}
+		prefixes[hs.Prefix] = true
+		if hs.ContentType != "" {
+			if _, _, err := mime.ParseMediaType(hs.ContentType); err != nil {
+				violations = append(violations, Violation{Field: field + ".ContentType", Message: err.Error()})
+				continue
+			}
+		}
+		if len(hs.Schema) == 0 {
+			continue
+		}
+		if hs.ContentType != "" && !isJSONContentType(hs.ContentType) {
+			violations = append(violations, Violation{Field: field + ".ContentType", Message: "must be a JSON media type when Schema is set"})
+		}
+		if _, err := parseJSONSchema(hs.Schema); err != nil {
+			violations = append(violations, Violation{Field: field + ".Schema", Message: err.Error()})
+		}
+	}
+	return violations
+}
+
+// isJSONContentType reports whether the media type ct is JSON, such as
+// "application/json" or "application/ld+json".
+func isJSONContentType(ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	return err == nil && (mt == "application/json" || strings.HasSuffix(mt, "+json"))
+}
+
+// jsonSchema is a parsed JSON Schema. Only the subset of the specification that
+// heap values are typically held to is supported: the type, enum and const
+// keywords, and the keywords that constrain numbers, strings, arrays and objects.
+// Patterns use Go's regular expression syntax. Schemas that use any other
+// validation keyword are rejected rather than partially enforced.
+type jsonSchema struct {
+	// never is set for the false schema, which no value satisfies.
+	never bool
+
+	types         []string
+	enum          []interface{}
+	minimum       *float64
+	maximum       *float64
+	exclusiveMin  *float64
+	exclusiveMax  *float64
+	multipleOf    *float64
+	minLength     int
+	maxLength     int
+	pattern       *regexp.Regexp
+	items         *jsonSchema
+	minItems      int
+	maxItems      int
+	uniqueItems   bool
+	properties    map[string]*jsonSchema
+	required      []string
+	additional    *jsonSchema
+	minProperties int
+	maxProperties int
+}
+
+// jsonSchemaAnnotations are the keywords that don't constrain values, and are
+// accepted and ignored.
+var jsonSchemaAnnotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+// jsonTypes are the type names of JSON Schema.
+var jsonTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "string": true, "integer": true,
+}
+
+// parseJSONSchema parses the JSON Schema raw. See jsonSchema.
+func parseJSONSchema(raw json.RawMessage) (*jsonSchema, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return &jsonSchema{never: !b, maxLength: -1, maxItems: -1, maxProperties: -1}, nil
+	}
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keywords); err != nil {
+		return nil, fmt.Errorf("a schema must be an object or a boolean")
+	}
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	s := &jsonSchema{maxLength: -1, maxItems: -1, maxProperties: -1}
+	for _, name := range names {
+		v := keywords[name]
+		var err error
+		switch name {
+		case "type":
+			err = s.parseType(v)
+		case "enum":
+			err = decodeJSONValue(v, &s.enum)
+		case "const":
+			var c interface{}
+			if err = decodeJSONValue(v, &c); err == nil {
+				s.enum = []interface{}{c}
+			}
+		case "minimum":
+			err = json.Unmarshal(v, &s.minimum)
+		case "maximum":
+			err = json.Unmarshal(v, &s.maximum)
+		case "exclusiveMinimum":
+			err = json.Unmarshal(v, &s.exclusiveMin)
+		case "exclusiveMaximum":
+			err = json.Unmarshal(v, &s.exclusiveMax)
+		case "multipleOf":
+			if err = json.Unmarshal(v, &s.multipleOf); err == nil && (s.multipleOf == nil || *s.multipleOf <= 0) {
+				err = fmt.Errorf("must be a positive number")
+			}
+		case "minLength":
+			err = parseSchemaCount(v, &s.minLength)
+		case "maxLength":
+			err = parseSchemaCount(v, &s.maxLength)
+		case "pattern":
+			var p string
+			if err = json.Unmarshal(v, &p); err == nil {
+				s.pattern, err = regexp.Compile(p)
+			}
+		case "items":
+			s.items, err = parseJSONSchema(v)
+		case "minItems":
+			err = parseSchemaCount(v, &s.minItems)
+		case "maxItems":
+			err = parseSchemaCount(v, &s.maxItems)
+		case "uniqueItems":
+			err = json.Unmarshal(v, &s.uniqueItems)
+		case "properties":
+			var props map[string]json.RawMessage
+			if err = json.Unmarshal(v, &props); err == nil {
+				s.properties = make(map[string]*jsonSchema, len(props))
+				for prop, raw := range props {
+					if s.properties[prop], err = parseJSONSchema(raw); err != nil {
+						err = fmt.Errorf("property %q: %s", prop, err)
+						break
+					}
+				}
+			}
+		case "required":
+			err = json.Unmarshal(v, &s.required)
+		case "additionalProperties":
+			s.additional, err = parseJSONSchema(v)
+		case "minProperties":
+			err = parseSchemaCount(v, &s.minProperties)
+		case "maxProperties":
+			err = parseSchemaCount(v, &s.maxProperties)
+		default:
+			if !jsonSchemaAnnotations[name] {
+				return nil, fmt.Errorf("keyword %q is not supported", name)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	return s, nil
+}
+
+// parseType parses the type keyword, which is a type name or an array of them.
+func (s *jsonSchema) parseType(v json.RawMessage) error {
+	var name string
+	if err := json.Unmarshal(v, &name); err == nil {
+		s.types = []string{name}
+	} else if err := json.Unmarshal(v, &s.types); err != nil {
+		return fmt.Errorf("must be a type name or an array of them")
+	}
+	for _, t := range s.types {
+		if !jsonTypes[t] {
+			return fmt.Errorf("%q is not a JSON type", t)
+		}
+	}
+	return nil
+}
+
+// parseSchemaCount parses a keyword whose value is a non-negative integer into n.
+func parseSchemaCount(v json.RawMessage, n *int) error {
+	if err := json.Unmarshal(v, n); err != nil || *n < 0 {
+		return fmt.Errorf("must be a non-negative integer")
+	}
+	return nil
+}
+
+// decodeJSONValue decodes data into v, keeping numbers as json.Number.
+func decodeJSONValue(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// validateJSON validates the JSON document data against s. If data doesn't satisfy s,
+// the path of the offending value and the reason are returned, and otherwise an
+// empty reason.
+func (s *jsonSchema) validateJSON(data []byte) (path, reason string) {
+	var v interface{}
+	if !json.Valid(data) || decodeJSONValue(data, &v) != nil {
+		return "$", "is not valid JSON"
+	}
+	return s.validate(v, "$")
+}
+
+// validate validates the decoded JSON value v, found at path, against s. See
+// validateJSON.
+func (s *jsonSchema) validate(v interface{}, path string) (string, string) {
+	if s.never {
+		return path, "is not allowed"
+	}
+	if len(s.types) > 0 && !s.hasType(v) {
+		return path, "must be of type " + strings.Join(s.types, " or ")
+	}
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if equalJSON(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return path, "is not one of the allowed values"
+		}
+	}
+	switch v := v.(type) {
+	case json.Number:
+		return s.validateNumber(v, path)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if n < s.minLength {
+			return path, fmt.Sprintf("must be at least %d characters long", s.minLength)
+		}
+		if s.maxLength >= 0 && n > s.maxLength {
+			return path, fmt.Sprintf("must be at most %d characters long", s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return path, fmt.Sprintf("must match %q", s.pattern)
+		}
+	case []interface{}:
+		if len(v) < s.minItems {
+			return path, fmt.Sprintf("must have at least %d items", s.minItems)
+		}
+		if s.maxItems >= 0 && len(v) > s.maxItems {
+			return path, fmt.Sprintf("must have at most %d items", s.maxItems)
+		}
+		for i, item := range v {
+			if s.uniqueItems {
+				for _, prev := range v[:i] {
+					if equalJSON(item, prev) {
+						return path, "must not contain duplicate items"
+					}
+				}
+			}
+			if s.items != nil {
+				if p, reason := s.items.validate(item, path+"["+strconv.Itoa(i)+"]"); reason != "" {
+					return p, reason
+				}
+			}
+		}
+	case map[string]interface{}:
+		if len(v) < s.minProperties {
+			return path, fmt.Sprintf("must have at least %d members", s.minProperties)
+		}
+		if s.maxProperties >= 0 && len(v) > s.maxProperties {
+			return path, fmt.Sprintf("must have at most %d members", s.maxProperties)
+		}
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				return path, fmt.Sprintf("is missing required member %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			member := s.properties[name]
+			if member == nil {
+				member = s.additional
+			}
+			if member == nil {
+				continue
+			}
+			if p, reason := member.validate(v[name], path+"."+name); reason != "" {
+				return p, reason
+			}
+		}
+	}
+	return "", ""
+}
+
+// validateNumber validates the number n, found at path, against the numeric keywords
+// of s.
+func (s *jsonSchema) validateNumber(n json.Number, path string) (string, string) {
+	f, err := n.Float64()
+	if err != nil {
+		return path, "is not a representable number"
+	}
+	switch {
+	case s.minimum != nil && f < *s.minimum:
+		return path, fmt.Sprintf("must be at least %v", *s.minimum)
+	case s.maximum != nil && f > *s.maximum:
+		return path, fmt.Sprintf("must be at most %v", *s.maximum)
+	case s.exclusiveMin != nil && f <= *s.exclusiveMin:
+		return path, fmt.Sprintf("must be greater than %v", *s.exclusiveMin)
+	case s.exclusiveMax != nil && f >= *s.exclusiveMax:
+		return path, fmt.Sprintf("must be less than %v", *s.exclusiveMax)
+	case s.multipleOf != nil && !isWhole(f / *s.multipleOf):
+		return path, fmt.Sprintf("must be a multiple of %v", *s.multipleOf)
+	}
+	return "", ""
+}
+
+// hasType reports whether v is of one of the types of s.
+func (s *jsonSchema) hasType(v interface{}) bool {
+	for _, t := range s.types {
+		switch v := v.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if f, err := v.Float64(); t == "integer" && err == nil && isWhole(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isWhole reports whether f is a whole number.
+func isWhole(f float64) bool {
+	return !math.IsInf(f, 0) && f == math.Trunc(f)
+}
+
+// equalJSON reports whether the decoded JSON values a and b are equal. Numbers are
+// equal if they have the same value, regardless of how they are written.
+func equalJSON(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := a.Float64()
+		fb, errB := b.Float64()
+		return errA == nil && errB == nil && fa == fb
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !equalJSON(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
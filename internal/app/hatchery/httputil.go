@@ -21,10 +21,185 @@ package hatchery
 
 import (
 	"encoding/json"
+	"fmt"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Default limits on the size of request bodies. See Application.
+const (
+	DefaultMaxTransactionSize = 1 << 20
+	DefaultMaxContractSize    = 64 << 10
+)
+
+// Bounds for paginated list endpoints.
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
 )
 
 func writeJSONResponse(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-type", "application/json")
 	json.NewEncoder(w).Encode(v)
 }
+
+// limitBody limits the body of r to n bytes. Reading beyond the limit fails with
+// an *http.MaxBytesError, which writeDecodeError reports as payload_too_large.
+func limitBody(w http.ResponseWriter, r *http.Request, n int64) {
+	r.Body = http.MaxBytesReader(w, r.Body, n)
+}
+
+// writeDecodeError responds to a request whose body could not be decoded. Bodies
+// over their limit receive a 413 response, and other errors a 400 response with
+// the given code and message.
+func writeDecodeError(w http.ResponseWriter, code, message string, err error) {
+	if e, ok := err.(*http.MaxBytesError); ok {
+		writeError(w, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, fmt.Sprintf("request body may not exceed %d bytes", e.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, code, message+": "+err.Error())
+}
+
+func (a *Application) maxTransactionSize() int64 {
+	if a.MaxTransactionSize <= 0 {
+		return DefaultMaxTransactionSize
+	}
+	return a.MaxTransactionSize
+}
+
+func (a *Application) maxContractSize() int64 {
+	if a.MaxContractSize <= 0 {
+		return DefaultMaxContractSize
+	}
+	return a.MaxContractSize
+}
+
+// contentEncoding returns the encoding of transaction content requested by r, with
+// either the content query parameter or the content parameter of an application/json
+// media range in the Accept header, such as "application/json; content=json". The
+// encoding is ContentEncodingBase64 unless ContentEncodingJSON is requested. If an
+// unknown encoding is requested, an error response is written and false is returned.
+func contentEncoding(w http.ResponseWriter, r *http.Request) (string, bool) {
+	encoding := r.URL.Query().Get("content")
+	if encoding == "" {
+		for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+			if err == nil && mediaType == "application/json" && params["content"] != "" {
+				encoding = params["content"]
+				break
+			}
+		}
+	}
+	switch encoding {
+	case "", ContentEncodingBase64:
+		return ContentEncodingBase64, true
+	case ContentEncodingJSON:
+		return ContentEncodingJSON, true
+	}
+	writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("content encoding must be %q or %q", ContentEncodingBase64, ContentEncodingJSON))
+	return "", false
+}
+
+// queryInt parses the named query parameter as an integer. If the parameter
+// is absent, def is returned instead.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// pageParams parses the optional offset and limit query parameters of routes that
+// respond with everything unless asked for a page. A limit of zero means no limit.
+// If either is invalid, the request is answered with a 400 error and ok is false.
+func pageParams(w http.ResponseWriter, r *http.Request) (offset, limit int, ok bool) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "offset must be a non-negative integer")
+		return 0, 0, false
+	}
+	limit, err = queryInt(r, "limit", 0)
+	if err != nil || limit < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "limit must be a non-negative integer")
+		return 0, 0, false
+	}
+	return offset, limit, true
+}
+
+// statusRecorder is an http.ResponseWriter that records the response status.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush implements http.Flusher, so that handlers such as Stream can flush through
+// the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// traced is middleware that traces every request handled by next as a span named
+// after the matched route. Requests that carry a traceparent header, such as those
+// contracts make with their TRACEPARENT environment variable, continue the trace of
+// the caller.
+func (a *Application) traced(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if cur := mux.CurrentRoute(r); cur != nil {
+			if tmpl, err := cur.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		ctx, span := tracing.StartServer(r, r.Method+" "+route,
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", r.URL.Path),
+		)
+		defer span.End()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// accessLog is middleware that logs every request handled by next. Requests to
+// the health endpoints, which probes make every few seconds, are logged at debug
+// level.
+func (a *Application) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log := a.log().Info
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			log = a.log().Debug
+		}
+		log("request",
+			logging.F("method", r.Method),
+			logging.F("path", r.URL.Path),
+			logging.F("status", rec.status),
+			logging.F("duration", time.Since(start).String()),
+			logging.F("remote", r.RemoteAddr),
+		)
+	})
+}
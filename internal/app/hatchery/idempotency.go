@@ -0,0 +1,159 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+const (
+	idempotencyBucket = reservedBucketPrefix + "idempotency"
+	// IdempotencyKeyHeader is the request header that carries a client supplied
+	// idempotency key. See PostTransaction.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// ReplayedHeader is set to "true" on responses that return the transaction of
+	// an earlier request with the same idempotency key.
+	ReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyWindow is how long idempotency keys are remembered if the
+	// Application's IdempotencyWindow is zero.
+	DefaultIdempotencyWindow = 24 * time.Hour
+
+	maxIdempotencyKeyLength = 255
+)
+
+var (
+	// ErrIdempotencyInProgress is returned when a transaction is posted with the
+	// idempotency key of a transaction that has not yet been appended.
+	ErrIdempotencyInProgress = errors.New("a transaction with this idempotency key is still being processed")
+	// ErrIdempotencyMismatch is returned when an idempotency key is reused for a
+	// transaction of a different type.
+	ErrIdempotencyMismatch = errors.New("idempotency key was used for a different txn_type")
+)
+
+// idempotencyRecord remembers the transaction posted with an idempotency key.
+type idempotencyRecord struct {
+	TxnID   string    `json:"txn_id"`
+	TxnType string    `json:"txn_type"`
+	Created time.Time `json:"created"`
+}
+
+// claimIdempotencyKey looks up the transaction posted with key within the idempotency
+// window. If the transaction has been appended to the ledger, it is returned. Otherwise
+// the key is claimed for a new transaction of the given type, and the ID the transaction
+// must be queued with is returned. ErrIdempotencyInProgress is returned if the earlier
+// transaction is still queued, and ErrIdempotencyMismatch if it had a different type.
+// Transactions that exhausted their attempts release their key, so that the request
+// can be retried.
+func (a *Application) claimIdempotencyKey(key, txnType string) (*Transaction, string, error) {
+	a.idemMu.Lock()
+	defer a.idemMu.Unlock()
+	a.pruneIdempotencyKeys()
+	rec, err := a.idempotencyRecord(key)
+	if err != nil {
+		return nil, "", err
+	}
+	if rec != nil {
+		if rec.TxnType != txnType {
+			return nil, "", ErrIdempotencyMismatch
+		}
+		t, err := a.Ledger.Find(rec.TxnID)
+		if err == nil {
+			return t, "", nil
+		}
+		if err != ErrTransactionNotExist {
+			return nil, "", err
+		}
+		queued, err := a.queuedItem(rec.TxnID)
+		if err != nil {
+			return nil, "", err
+		}
+		if queued != nil && queued.Status != QueueStatusFailed && queued.Status != QueueStatusCancelled {
+			return nil, "", ErrIdempotencyInProgress
+		}
+	}
+	rec = &idempotencyRecord{
+		TxnID:   uuid.New().String(),
+		TxnType: txnType,
+		Created: time.Now().UTC(),
+	}
+	if err := a.putJSON(idempotencyBucket, key, rec); err != nil {
+		return nil, "", err
+	}
+	return nil, rec.TxnID, nil
+}
+
+// idempotencyRecord returns the record for key, or nil if there is none or it
+// has expired.
+func (a *Application) idempotencyRecord(key string) (*idempotencyRecord, error) {
+	b, err := a.Heap.Get(idempotencyBucket, key)
+	if err == ErrHeapNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec idempotencyRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency record: %s", err)
+	}
+	if time.Since(rec.Created) > a.idempotencyWindow() {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// pruneIdempotencyKeys deletes expired idempotency records. It does the work at most
+// once per window, so that the cost is spread across many requests. The caller must
+// hold idemMu.
+func (a *Application) pruneIdempotencyKeys() {
+	window := a.idempotencyWindow()
+	if time.Since(a.idemPruned) < window {
+		return
+	}
+	a.idemPruned = time.Now()
+	all, err := a.Heap.GetRange(idempotencyBucket, "", "")
+	if err != nil {
+		a.log().Error("failed to prune idempotency keys", logging.Err(err))
+		return
+	}
+	for key, b := range all {
+		var rec idempotencyRecord
+		if json.Unmarshal(b, &rec) == nil && time.Since(rec.Created) <= window {
+			continue
+		}
+		if err := a.Heap.Delete(idempotencyBucket, key); err != nil && err != ErrHeapNotExist {
+			a.log().Error("failed to prune idempotency keys", logging.Err(err))
+			return
+		}
+	}
+}
+
+func (a *Application) idempotencyWindow() time.Duration {
+	if a.IdempotencyWindow <= 0 {
+		return DefaultIdempotencyWindow
+	}
+	return a.IdempotencyWindow
+}
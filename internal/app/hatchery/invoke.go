@@ -0,0 +1,140 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+)
+
+const (
+	// invokeKey is the reserved member of a contract's JSON output that requests
+	// the invocation of other contracts. Its value is either a single invocation
+	// or an array of them, each of the form {"txn_type": ..., "payload": ...}.
+	// It is not written to the heap.
+	invokeKey = "invoke"
+	// maxInvocationDepth is the longest invocation chain that may be built, which
+	// stops contracts that invoke each other from doing so forever.
+	maxInvocationDepth = 16
+)
+
+// invocation is a request, made in a contract's output, to invoke a contract.
+type invocation struct {
+	Type    string          `json:"txn_type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// parseInvocations returns the invocations requested in the output of a contract.
+// No invocations are returned if the output is not a JSON object or has no
+// invokeKey member.
+func parseInvocations(output []byte) ([]invocation, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(output, &obj); err != nil {
+		return nil, nil
+	}
+	raw, ok := obj[invokeKey]
+	if !ok {
+		return nil, nil
+	}
+	raw = bytes.TrimSpace(raw)
+	var invs []invocation
+	if len(raw) > 0 && raw[0] == '[' {
+		if err := json.Unmarshal(raw, &invs); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", invokeKey, err)
+		}
+	} else {
+		var inv invocation
+		if err := json.Unmarshal(raw, &inv); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", invokeKey, err)
+		}
+		invs = []invocation{inv}
+	}
+	for _, inv := range invs {
+		if inv.Type == "" {
+			return nil, fmt.Errorf("invalid %s: txn_type is required", invokeKey)
+		}
+	}
+	return invs, nil
+}
+
+// invokeDownstream queues a transaction for each contract invoked by the output of t.
+// The queued transactions extend t's invocation chain with t. Invocations that would
+// exceed maxInvocationDepth are dropped. The queued transactions are traced as part
+// of the trace in ctx, so that a chain of contracts can be followed end to end.
+func (a *Application) invokeDownstream(ctx context.Context, t *Transaction) {
+	if t.InvokerContract == "" {
+		return
+	}
+	logger := a.log().With(logging.Contract(t.Type), logging.TxnID(t.ID))
+	invs, err := parseInvocations(t.Content)
+	if err != nil {
+		logger.Error("failed to parse invocations", logging.Err(err))
+		return
+	}
+	if len(invs) == 0 {
+		return
+	}
+	if len(t.InvocationChain) >= maxInvocationDepth {
+		logger.Error("invocation chain is too long", logging.F("depth", len(t.InvocationChain)))
+		return
+	}
+	chain := make([]string, len(t.InvocationChain), len(t.InvocationChain)+1)
+	copy(chain, t.InvocationChain)
+	chain = append(chain, t.ID)
+	for _, inv := range invs {
+		now := time.Now().UTC()
+		item := &QueueItem{
+			ID:              uuid.New().String(),
+			TxnType:         inv.Type,
+			Payload:         inv.Payload,
+			Status:          QueueStatusPending,
+			NextAttempt:     now,
+			Created:         now,
+			InvocationChain: chain,
+			TraceParent:     tracing.Parent(ctx),
+		}
+		if err := a.enqueueItem(item); err != nil {
+			logger.Error("failed to queue invocation", logging.F("invoked", inv.Type), logging.Err(err))
+			continue
+		}
+		logger.Info("contract invoked", logging.F("invoked", inv.Type), logging.F("invoked_txn_id", item.ID))
+	}
+}
+
+// invoker returns the name of the contract whose output invoked the transaction
+// at the end of chain, which is the type of that transaction. It is empty if chain
+// is empty, or if the transaction can't be found.
+func (a *Application) invoker(chain []string) string {
+	if len(chain) == 0 {
+		return ""
+	}
+	t, err := a.Ledger.Find(chain[len(chain)-1])
+	if err != nil {
+		return ""
+	}
+	return t.Type
+}
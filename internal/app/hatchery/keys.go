@@ -0,0 +1,230 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/pkg/client"
+)
+
+const signingKeyBucket = reservedBucketPrefix + "signing_keys"
+
+var (
+	// ErrSigningKeyNotExist is returned when a requested signing key does not exist.
+	ErrSigningKeyNotExist = errors.New("signing key does not exist")
+	// ErrSigningKeyExists is returned when a signing key is registered with the ID
+	// of an existing one.
+	ErrSigningKeyExists = errors.New("signing key already exists")
+	// ErrSignatureRequired is returned when an unsigned transaction is posted and
+	// RequireSignatures is set.
+	ErrSignatureRequired = errors.New("transaction must be signed")
+	// ErrSignatureInvalid is returned when a posted transaction's signature does
+	// not verify against the public key of its signer.
+	ErrSignatureInvalid = errors.New("invalid transaction signature")
+)
+
+// SigningKey is an ed25519 public key that transactions may be signed with. The
+// ID of the key that signed a transaction is recorded as the transaction's Signer.
+type SigningKey struct {
+	// ID identifies the key and is sent as the signer of signed transactions.
+	ID string `json:"id"`
+	// PublicKey is the ed25519 public key, base64 encoded in JSON.
+	PublicKey []byte `json:"public_key"`
+	// Created is when the key was registered.
+	Created time.Time `json:"created"`
+}
+
+// RegisterSigningKey stores an ed25519 public key under the given ID, or under a
+// random ID if id is empty. ErrSigningKeyExists is returned if a key with the ID
+// is already registered.
+func (a *Application) RegisterSigningKey(id string, publicKey []byte) (*SigningKey, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	if id == "" {
+		var err error
+		if id, err = randomKeyID(); err != nil {
+			return nil, err
+		}
+	}
+	switch _, err := a.SigningKey(id); err {
+	case nil:
+		return nil, ErrSigningKeyExists
+	case ErrSigningKeyNotExist:
+	default:
+		return nil, err
+	}
+	key := &SigningKey{ID: id, PublicKey: publicKey, Created: time.Now().UTC()}
+	if err := a.putJSON(signingKeyBucket, key.ID, key); err != nil {
+		return nil, fmt.Errorf("failed to store signing key: %s", err)
+	}
+	return key, nil
+}
+
+// SigningKey returns the signing key with the provided ID. ErrSigningKeyNotExist
+// is returned if no such key exists.
+func (a *Application) SigningKey(id string) (*SigningKey, error) {
+	b, err := a.Heap.Get(signingKeyBucket, id)
+	if err == ErrHeapNotExist || (err == nil && len(b) == 0) {
+		return nil, ErrSigningKeyNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	var key SigningKey
+	if err := json.Unmarshal(b, &key); err != nil {
+		return nil, fmt.Errorf("failed to decode signing key: %s", err)
+	}
+	return &key, nil
+}
+
+// SigningKeys returns every registered signing key, sorted by ID.
+func (a *Application) SigningKeys() ([]*SigningKey, error) {
+	all, err := a.Heap.GetRange(signingKeyBucket, "", "")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*SigningKey, 0, len(all))
+	for _, b := range all {
+		var key SigningKey
+		if err := json.Unmarshal(b, &key); err != nil {
+			return nil, fmt.Errorf("failed to decode signing key: %s", err)
+		}
+		keys = append(keys, &key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+type postSigningKeyRequest struct {
+	ID        string `json:"id"`
+	PublicKey []byte `json:"public_key"`
+}
+
+// PostSigningKey returns an HTTP handler function that registers the ed25519 public
+// key in the request body and responds with the registered key.
+func (a *Application) PostSigningKey() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req postSigningKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid signing key: "+err.Error())
+			return
+		}
+		if len(req.PublicKey) != ed25519.PublicKeySize {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("public_key must be a base64 encoded %d byte ed25519 public key", ed25519.PublicKeySize))
+			return
+		}
+		key, err := a.RegisterSigningKey(req.ID, req.PublicKey)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSONResponse(w, key)
+	}
+}
+
+// ListSigningKeys returns an HTTP handler function that responds with every
+// registered signing key.
+func (a *Application) ListSigningKeys() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := a.SigningKeys()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, keys)
+	}
+}
+
+// GetSigningKey returns an HTTP handler function that responds with the requested
+// signing key.
+func (a *Application) GetSigningKey() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := a.SigningKey(mux.Vars(r)["id"])
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, key)
+	}
+}
+
+// DeleteSigningKey returns an HTTP handler function that removes the requested
+// signing key. Transactions it signed keep it as their Signer.
+func (a *Application) DeleteSigningKey() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		if _, err := a.SigningKey(id); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if err := a.Heap.Delete(signingKeyBucket, id); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verifyTransaction checks the signature of a posted transaction and returns the ID
+// of its signer, which is empty for unsigned transactions. The signature is the
+// base64 encoded ed25519 signature of the message built by client.TransactionMessage.
+// ErrSignatureRequired is returned for unsigned transactions if RequireSignatures is
+// set, and ErrSignatureInvalid if the signature doesn't verify or the signer isn't a
+// registered key.
+func (a *Application) verifyTransaction(req *postTransactionRequest) (string, error) {
+	if req.Signer == "" && req.Signature == "" {
+		if a.RequireSignatures {
+			return "", ErrSignatureRequired
+		}
+		return "", nil
+	}
+	key, err := a.SigningKey(req.Signer)
+	if err == ErrSigningKeyNotExist {
+		return "", ErrSignatureInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	sig, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+	msg, err := client.TransactionMessage(req.Type, req.Payload)
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key.PublicKey), msg, sig) {
+		return "", ErrSignatureInvalid
+	}
+	return key.ID, nil
+}
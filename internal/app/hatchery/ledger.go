@@ -19,47 +19,157 @@
 
 package hatchery
 
-import "container/list"
+import (
+	"sync"
+	"time"
 
-// MemLedger is a in-memory Ledger implementation that uses
-// a doubly linked list to store Transactions.
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// blobGracePeriod is how long the ledgers that implement backend.BlobStore keep a
+// blob that no transaction references, so that a blob isn't swept before the
+// transaction that references it has been appended.
+const blobGracePeriod = time.Hour
+
+// MemLedger is an in-memory Ledger implementation. Transactions are kept in
+// append order, with an index by ID so that Find doesn't need to walk the ledger.
+// It is safe for concurrent use.
 type MemLedger struct {
-	ledger *list.List
+	mu    sync.RWMutex
+	txns  []*Transaction
+	index map[string]*Transaction
+	// anchor is the hash of the last transaction pruned, and pruned how many
+	// transactions have been pruned.
+	anchor string
+	pruned int
 }
 
 // NewMemLedger returns a new MemLedger.
 func NewMemLedger() *MemLedger {
 	return &MemLedger{
-		ledger: list.New(),
+		index: make(map[string]*Transaction),
 	}
 }
 
 // Head returns the first item in the ledger.
 // If the ledger is currently empty, nil is returned instead.
-func (l *MemLedger) Head() *Transaction {
-	if l.ledger.Len() == 0 {
-		return nil
+func (l *MemLedger) Head() (*Transaction, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.txns) == 0 {
+		return nil, nil
+	}
+	return l.txns[0], nil
+}
+
+// Find returns the Transaction with the requested ID. If no such
+// Transaction exists, ErrTransactionNotExist is returned.
+func (l *MemLedger) Find(id string) (*Transaction, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	t, ok := l.index[id]
+	if !ok {
+		return nil, ErrTransactionNotExist
+	}
+	return t, nil
+}
+
+// Len returns the number of transactions in the MemLedger.
+func (l *MemLedger) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.txns)
+}
+
+// Append links each Transaction to the current tail and adds it to the end
+// of the MemLedger. An error is never returned.
+func (l *MemLedger) Append(ts ...*Transaction) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var prev *Transaction
+	if n := len(l.txns); n > 0 {
+		prev = l.txns[n-1]
+	}
+	for _, t := range ts {
+		t.Link(prev)
+		l.txns = append(l.txns, t)
+		l.index[t.ID] = t
+		prev = t
 	}
-	return l.ledger.Front().Value.(*Transaction)
+	return nil
 }
 
-// Find iterates the MemLedger until it finds a Transaction with
-// an ID that matches the requested transaction ID. The second
-// return parameter is whether or not a Transaction with the requested
-// ID was found.
-func (l *MemLedger) Find(id string) (*Transaction, bool) {
-	curr := l.ledger.Front()
-	for curr != nil {
-		txn := curr.Value.(*Transaction)
-		if txn.ID == id {
-			return txn, true
+// Iterate walks a snapshot of the MemLedger from front to back, calling fn for
+// each Transaction until fn returns false. Transactions appended during the walk
+// are not visited, and fn may safely call other MemLedger methods. An error is
+// never returned.
+func (l *MemLedger) Iterate(fn func(t *Transaction) bool) error {
+	_, snapshot := l.snapshot()
+	for _, t := range snapshot {
+		if !fn(t) {
+			break
 		}
-		curr = curr.Next()
 	}
-	return nil, false
+	return nil
+}
+
+// Verify walks the MemLedger and reports the first broken link in the chain
+// of transaction hashes, if any.
+func (l *MemLedger) Verify() error {
+	anchor, snapshot := l.snapshot()
+	return backend.VerifyChainFrom(anchor, func(fn func(t *Transaction) bool) error {
+		for _, t := range snapshot {
+			if !fn(t) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Prune removes every transaction up to and including the one with the given ID.
+// ErrTransactionNotExist is returned if there is no such transaction, and
+// ErrPruneLatest if it is the latest.
+func (l *MemLedger) Prune(throughID string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	through, ok := l.index[throughID]
+	if !ok {
+		return 0, ErrTransactionNotExist
+	}
+	n := 0
+	for l.txns[n] != through {
+		n++
+	}
+	n++
+	if n == len(l.txns) {
+		return 0, backend.ErrPruneLatest
+	}
+	for _, t := range l.txns[:n] {
+		delete(l.index, t.ID)
+	}
+	// The remaining transactions are copied, rather than resliced, so that
+	// snapshots taken before the prune stay intact.
+	l.txns = append([]*Transaction(nil), l.txns[n:]...)
+	l.anchor = through.Hash
+	l.pruned += n
+	return n, nil
+}
+
+// Pruned returns the hash of the last transaction pruned and how many have been
+// pruned. An error is never returned.
+func (l *MemLedger) Pruned() (string, int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.anchor, l.pruned, nil
 }
 
-// Append adds a Transaction to the end of the MemLedger.
-func (l *MemLedger) Append(t *Transaction) {
-	l.ledger.PushBack(t)
+// snapshot returns the hash of the last transaction pruned and the transactions
+// in the MemLedger, consistently with each other.
+func (l *MemLedger) snapshot() (string, []*Transaction) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	// Appends never modify the existing elements, so the slice header is a
+	// consistent snapshot.
+	return l.anchor, l.txns[:len(l.txns):len(l.txns)]
 }
@@ -0,0 +1,185 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// ErrLedgerImportDiverged is returned when the transactions of an imported ledger
+// don't continue from the latest transaction in the ledger.
+var ErrLedgerImportDiverged = errors.New("the imported transactions don't follow the ledger's latest transaction")
+
+// LedgerEntry is a transaction of an exported ledger, one per line of JSON Lines.
+// Content, which the JSON encoding of a Transaction leaves out, is held alongside
+// it, base64 encoded, so that an export restores every transaction byte for byte
+// and keeps its hash.
+type LedgerEntry struct {
+	Transaction *Transaction `json:"transaction"`
+	Content     []byte       `json:"content,omitempty"`
+}
+
+type importLedgerResponse struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// ExportLedger returns an HTTP handler function that streams every transaction in the
+// ledger, in the order they were appended, as LedgerEntries in JSON Lines, including
+// the hashes that link them. The ledger is read before the response begins, so that a
+// ledger that can't be read is reported with an error status. It is an administrative
+// route.
+func (a *Application) ExportLedger() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var txns []*Transaction
+		err := a.Ledger.Iterate(func(t *Transaction) bool {
+			txns = append(txns, t)
+			return true
+		})
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.Header().Set("Content-type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for _, t := range txns {
+			if err := enc.Encode(&LedgerEntry{Transaction: t, Content: t.Content}); err != nil {
+				a.log().Error("failed to export ledger", logging.F("txn_id", t.ID), logging.Err(err))
+				return
+			}
+		}
+	}
+}
+
+// ImportLedger returns an HTTP handler function that appends the posted LedgerEntries,
+// as exported by ExportLedger, to the ledger. Every entry is decoded and its hash and
+// link to the previous entry checked before anything is appended, and the entries are
+// appended atomically, so a malformed or tampered body leaves the ledger untouched.
+// Entries already in the ledger are skipped, so an export can be imported again, and
+// the first new entry must follow the ledger's latest transaction; otherwise the import
+// fails with ErrLedgerImportDiverged. Imported transactions are bundled into blocks and
+// published to subscribers, followers and stream clients, but not forwarded to
+// DragonChain. It responds with the number of entries appended and skipped. It is an
+// administrative route.
+func (a *Application) ImportLedger() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, err := decodeLedgerEntries(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid ledger entries: "+err.Error())
+			return
+		}
+		var last *Transaction
+		err = a.Ledger.Iterate(func(t *Transaction) bool {
+			last = t
+			return true
+		})
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		var (
+			txns    []*Transaction
+			skipped int
+		)
+		for _, e := range entries {
+			t := e.Transaction
+			existing, err := a.Ledger.Find(t.ID)
+			switch {
+			case err == nil && existing.Hash == t.Hash && len(txns) == 0:
+				skipped++
+				continue
+			case err == nil:
+				writeError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("transaction %s is already in the ledger", t.ID))
+				return
+			case err != ErrTransactionNotExist:
+				writeErrorFrom(w, err)
+				return
+			}
+			txns = append(txns, t)
+		}
+		if len(txns) == 0 {
+			writeJSONResponse(w, importLedgerResponse{Skipped: skipped})
+			return
+		}
+		tail := ""
+		if last != nil {
+			tail = last.Hash
+		}
+		if txns[0].PrevHash != tail {
+			writeErrorFrom(w, ErrLedgerImportDiverged)
+			return
+		}
+		hashes := make([]string, len(txns))
+		for i, t := range txns {
+			hashes[i] = t.Hash
+		}
+		if err := a.Ledger.Append(txns...); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		// A transaction appended while the import was checked would shift the
+		// imported ones, changing their hashes. There's nothing to undo by then, but
+		// it is reported rather than passed off as a faithful import.
+		for i, t := range txns {
+			if t.Hash != hashes[i] {
+				writeError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("transaction %s hashed to %s rather than %s; the ledger changed during the import", t.ID, t.Hash, hashes[i]))
+				return
+			}
+		}
+		a.addToBlock(txns...)
+		for _, t := range txns {
+			a.bus().Publish(&Event{Type: EventTransaction, Contract: t.Type, Transaction: t})
+		}
+		writeJSONResponse(w, importLedgerResponse{Imported: len(txns), Skipped: skipped})
+	}
+}
+
+// decodeLedgerEntries decodes the LedgerEntries in r, and checks that each one's hash
+// matches its contents and that each links to the one before it.
+func decodeLedgerEntries(r io.Reader) ([]LedgerEntry, error) {
+	dec := json.NewDecoder(r)
+	var entries []LedgerEntry
+	for i := 0; ; i++ {
+		var e LedgerEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		t := e.Transaction
+		if t == nil || t.ID == "" {
+			return nil, fmt.Errorf("entry %d has no transaction", i)
+		}
+		t.Content = e.Content
+		if i > 0 && t.PrevHash != entries[i-1].Transaction.Hash {
+			return nil, &BrokenLinkError{Index: i, ID: t.ID, Reason: "previous hash does not match"}
+		}
+		if t.Hash != t.ComputeHash() {
+			return nil, &BrokenLinkError{Index: i, ID: t.ID, Reason: "hash does not match content"}
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,142 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+)
+
+// lintAvailabilityTimeout bounds the check made by ValidateContract that a manifest's
+// image can be pulled.
+const lintAvailabilityTimeout = 30 * time.Second
+
+// AvailabilityChecker is implemented by Runtimes that can check, without preparing
+// a contract, that Prepare would be able to fetch what it executes, such as its
+// image.
+type AvailabilityChecker interface {
+	// CheckAvailable returns an error if what the manifest executes can't be
+	// fetched. It is called with the manifest's Auth in plaintext.
+	CheckAvailable(ctx context.Context, manifest *ContractManifest) error
+}
+
+// LintReport is the outcome of checking a manifest with ValidateContract. Errors
+// would prevent the manifest from being registered, and warnings point out settings
+// that are allowed but probably not intended.
+type LintReport struct {
+	Valid    bool        `json:"valid"`
+	Errors   []Violation `json:"errors"`
+	Warnings []Violation `json:"warnings"`
+}
+
+// ValidateContract returns an HTTP handler function that checks the posted manifest
+// without registering it and responds with a LintReport. The manifest is checked
+// exactly as PostContract checks it, and if it has no errors, its runtime checks that
+// its image can be pulled, without pulling it. A manifest with errors is reported
+// with a 200 response, like any other.
+func (a *Application) ValidateContract() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var m ContractManifest
+		limitBody(w, r, a.maxContractSize())
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			writeDecodeError(w, ErrCodeInvalidManifest, "invalid manifest", err)
+			return
+		}
+		report, err := a.lintManifest(r.Context(), &m)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, report)
+	}
+}
+
+// lintManifest checks m and returns its errors and warnings. See checkManifest and
+// manifestWarnings. An error is returned if the manifest could not be checked.
+func (a *Application) lintManifest(ctx context.Context, m *ContractManifest) (*LintReport, error) {
+	violations, _, err := a.checkManifest(m)
+	if err != nil {
+		return nil, err
+	}
+	report := &LintReport{Errors: violations, Warnings: a.manifestWarnings(m)}
+	runtime, _ := LookupRuntime(m.Runtime)
+	if c, ok := runtime.(AvailabilityChecker); ok && len(report.Errors) == 0 {
+		ctx, cancel := context.WithTimeout(ctx, lintAvailabilityTimeout)
+		err := c.CheckAvailable(ctx, m)
+		cancel()
+		if _, unreachable := err.(*docker.DaemonError); unreachable {
+			report.Warnings = append(report.Warnings, Violation{Field: "Image", Message: fmt.Sprintf("could not be checked: %s", err)})
+		} else if err != nil {
+			report.Errors = append(report.Errors, Violation{Field: "Image", Message: fmt.Sprintf("can't be pulled: %s", err)})
+		}
+	}
+	report.Valid = len(report.Errors) == 0
+	return report, nil
+}
+
+// manifestWarnings returns a Violation for each setting of m that is valid but likely
+// to behave differently than intended.
+func (a *Application) manifestWarnings(m *ContractManifest) []Violation {
+	warnings := []Violation{}
+	add := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if existing, err := a.Lib.Manifest(m.Type); err == nil {
+		add("txn_type", "contract already exists; registering the manifest creates version %d", existing.Version+1)
+	}
+	runtime, _ := LookupRuntime(m.Runtime)
+	if _, ok := runtime.(dockerRuntime); ok && m.Source == "" && m.DigestPolicy == "" && docker.FloatingTag(m.Image) {
+		add("Image", "refers to the latest tag, which may be pushed again; use a specific tag or digest, or set DigestPolicy")
+	}
+	if m.ExecutionTimeout == "" {
+		add("ExecutionTimeout", "is not set, so executions run for as long as they take")
+	}
+	if a.MaxConcurrency > 0 && m.ExecutionOrder != ExecutionOrderSerial && m.WarmPool > a.MaxConcurrency {
+		add("WarmPool", "exceeds the node's limit of %d concurrent executions, so some warm containers are never used", a.MaxConcurrency)
+	}
+	if m.ExecutionOrder == ExecutionOrderSerial && m.WarmPool > 1 {
+		add("WarmPool", "exceeds 1, but serial contracts execute one at a time, so some warm containers are never used")
+	}
+	if m.Priority > 0 && a.MaxExecutions <= 0 {
+		add("Priority", "has no effect because the node doesn't limit its concurrent executions with max_executions")
+	}
+	if m.WarmMaxUses > 0 && m.WarmPool == 0 {
+		add("WarmMaxUses", "has no effect without WarmPool")
+	}
+	if m.Cron == "" {
+		if m.CronOverlap != "" {
+			add("CronOverlap", "has no effect without Cron")
+		}
+		if m.CronJitter != "" {
+			add("CronJitter", "has no effect without Cron")
+		}
+		if len(m.CronPayload) > 0 || m.CronPayloadSource != "" {
+			add("CronPayload", "has no effect without Cron")
+		}
+	} else if m.RetryPolicy != nil {
+		add("RetryPolicy", "applies only to posted transactions; scheduled executions are not retried")
+	}
+	return warnings
+}
@@ -0,0 +1,317 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// Contract output streams.
+const (
+	LogStreamStdout = "stdout"
+	LogStreamStderr = "stderr"
+)
+
+const (
+	// logTailLines is how many of the most recent output lines of each contract
+	// are kept in memory for the tail of ContractLogStream.
+	logTailLines = 1000
+	// maxLogLineLength is the length of the longest output line. Longer lines are
+	// split.
+	maxLogLineLength = 16 << 10
+)
+
+// OutputSetter is implemented by Contracts whose runtime can copy their output to
+// writers as it is written, so that it can be streamed while they execute.
+type OutputSetter interface {
+	// SetOutput sets the writers that receive a copy of the contract's stdout and
+	// stderr in subsequent executions.
+	SetOutput(stdout, stderr io.Writer)
+}
+
+// OutputLine is a line a contract wrote to stdout or stderr, pushed to the clients of
+// ContractLogStream.
+type OutputLine struct {
+	Contract string `json:"contract"`
+	// Stream is LogStreamStdout or LogStreamStderr.
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+	Line   string    `json:"line"`
+}
+
+// outputLog holds the most recent output lines of a contract, and the channels of the
+// clients following it.
+type outputLog struct {
+	lines     []*OutputLine
+	next      int
+	followers map[chan *OutputLine]struct{}
+}
+
+// ContractLogStream returns an HTTP handler function that streams the output of a
+// contract's executions on this node, stdout and stderr combined, line by line as
+// Server-Sent Events named "log", each holding an OutputLine. The optional tail query
+// parameter sends up to that many of the most recent lines first, and defaults to
+// none. If the optional follow query parameter is false, the response ends after the
+// tail; otherwise new lines are streamed until the client disconnects. Only contracts
+// whose runtime implements OutputSetter are streamed.
+func (a *Application) ContractLogStream() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		tail, err := queryInt(r, "tail", 0)
+		if err != nil || tail < 0 || tail > logTailLines {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("tail must be an integer between 0 and %d", logTailLines))
+			return
+		}
+		follow := true
+		if v := r.URL.Query().Get("follow"); v != "" {
+			if follow, err = strconv.ParseBool(v); err != nil {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "follow must be true or false")
+				return
+			}
+		}
+		if _, err := a.Lib.Manifest(name); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, "streaming is not supported")
+			return
+		}
+		lines, ch := a.followOutput(name, tail, follow)
+		if ch != nil {
+			defer a.unfollowOutput(name, ch)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		for _, l := range lines {
+			if !a.writeOutputLine(w, l) {
+				return
+			}
+		}
+		flusher.Flush()
+		if ch == nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case l, ok := <-ch:
+				if !ok {
+					return
+				}
+				if !a.writeOutputLine(w, l) {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeOutputLine writes l as a Server-Sent Event and reports whether it succeeded.
+func (a *Application) writeOutputLine(w io.Writer, l *OutputLine) bool {
+	b, err := json.Marshal(l)
+	if err != nil {
+		a.log().Error("failed to encode output line", logging.Err(err))
+		return true
+	}
+	_, err = fmt.Fprintf(w, "event: log\ndata: %s\n\n", b)
+	return err == nil
+}
+
+// followOutput returns up to tail of the most recent output lines of the named
+// contract, oldest first, and if follow is set, a channel that receives its
+// subsequent lines until it is passed to unfollowOutput. The channel is closed if
+// the client falls more than streamBuffer lines behind, the contract is deleted or
+// the application shuts down.
+func (a *Application) followOutput(name string, tail int, follow bool) ([]*OutputLine, chan *OutputLine) {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	l := a.outputLog(name)
+	n := len(l.lines)
+	if tail > n {
+		tail = n
+	}
+	lines := make([]*OutputLine, 0, tail)
+	for i := n - tail; i < n; i++ {
+		lines = append(lines, l.lines[(l.next+i)%n])
+	}
+	if !follow {
+		return lines, nil
+	}
+	ch := make(chan *OutputLine, streamBuffer)
+	l.followers[ch] = struct{}{}
+	return lines, ch
+}
+
+// unfollowOutput stops sending the named contract's output to ch, and closes it
+// unless it was closed already.
+func (a *Application) unfollowOutput(name string, ch chan *OutputLine) {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	if l, ok := a.outputs[name]; ok {
+		if _, ok := l.followers[ch]; ok {
+			delete(l.followers, ch)
+			close(ch)
+		}
+	}
+}
+
+// outputLog returns the output log of the named contract. a.outputMu must be held.
+func (a *Application) outputLog(name string) *outputLog {
+	if a.outputs == nil {
+		a.outputs = make(map[string]*outputLog)
+	}
+	l, ok := a.outputs[name]
+	if !ok {
+		l = &outputLog{followers: make(map[chan *OutputLine]struct{})}
+		a.outputs[name] = l
+	}
+	return l
+}
+
+// appendOutput adds line to the output log of its contract and sends it to the
+// contract's followers. Followers that have fallen too far behind are disconnected.
+func (a *Application) appendOutput(line *OutputLine) {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	l := a.outputLog(line.Contract)
+	if len(l.lines) < logTailLines {
+		l.lines = append(l.lines, line)
+	} else {
+		l.lines[l.next] = line
+		l.next = (l.next + 1) % logTailLines
+	}
+	for ch := range l.followers {
+		select {
+		case ch <- line:
+		default:
+			delete(l.followers, ch)
+			close(ch)
+		}
+	}
+}
+
+// forgetOutput discards the output log of the named contract, such as when it is
+// deleted, and disconnects its followers.
+func (a *Application) forgetOutput(name string) {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	if l, ok := a.outputs[name]; ok {
+		for ch := range l.followers {
+			close(ch)
+		}
+		delete(a.outputs, name)
+	}
+}
+
+// closeOutputStreams disconnects every client of ContractLogStream.
+func (a *Application) closeOutputStreams() {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+	for _, l := range a.outputs {
+		for ch := range l.followers {
+			delete(l.followers, ch)
+			close(ch)
+		}
+	}
+}
+
+// outputWriter splits what a contract writes to one of its output streams into
+// lines, and appends them to the contract's output log.
+type outputWriter struct {
+	app      *Application
+	contract string
+	stream   string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 && len(w.buf) < maxLogLineLength {
+			break
+		}
+		if i < 0 || i > maxLogLineLength {
+			i = maxLogLineLength
+			w.emit(w.buf[:i])
+			w.buf = w.buf[i:]
+			continue
+		}
+		w.emit(bytes.TrimSuffix(w.buf[:i], []byte("\r")))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush appends the last line, if it didn't end with a newline.
+func (w *outputWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+}
+
+// emit appends line to the output log. w.mu must be held.
+func (w *outputWriter) emit(line []byte) {
+	w.app.appendOutput(&OutputLine{Contract: w.contract, Stream: w.stream, Time: time.Now().UTC(), Line: string(line)})
+}
+
+// captureOutput makes contract, an instance of the named contract, copy its output to
+// the contract's output log if its runtime supports it, and returns the writers that
+// must be flushed after each execution. See OutputSetter.
+func (a *Application) captureOutput(name string, contract Contract) []*outputWriter {
+	o, ok := contract.(OutputSetter)
+	if !ok {
+		return nil
+	}
+	stdout := &outputWriter{app: a, contract: name, stream: LogStreamStdout}
+	stderr := &outputWriter{app: a, contract: name, stream: LogStreamStderr}
+	o.SetOutput(stdout, stderr)
+	return []*outputWriter{stdout, stderr}
+}
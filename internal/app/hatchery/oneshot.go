@@ -0,0 +1,211 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+const oneShotBucket = reservedBucketPrefix + "one_shots"
+
+// OneShot is a contract execution scheduled to happen once, at ScheduleAt. When it
+// is due, its transaction is added to the work queue with the OneShot's ID, so the
+// contract is executed exactly once even if Hatchery restarts in between.
+type OneShot struct {
+	ID         string          `json:"id"`
+	TxnType    string          `json:"txn_type"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	ScheduleAt time.Time       `json:"schedule_at"`
+	Created    time.Time       `json:"created"`
+}
+
+type postScheduleRequest struct {
+	TxnType    string          `json:"txn_type"`
+	Payload    json.RawMessage `json:"payload"`
+	ScheduleAt time.Time       `json:"schedule_at"`
+}
+
+// PostSchedule returns an HTTP handler function that schedules a contract to execute once
+// at the requested time, which is given as an RFC 3339 timestamp. Times in the past are
+// executed immediately. The schedule is persisted in the heap, so it survives a restart.
+// It responds with the created OneShot.
+func (a *Application) PostSchedule() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req postScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid schedule: "+err.Error())
+			return
+		}
+		if req.ScheduleAt.IsZero() {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "schedule_at is required")
+			return
+		}
+		if _, err := a.Lib.Manifest(req.TxnType); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		s := &OneShot{
+			ID:         uuid.New().String(),
+			TxnType:    req.TxnType,
+			Payload:    req.Payload,
+			ScheduleAt: req.ScheduleAt.UTC(),
+			Created:    time.Now().UTC(),
+		}
+		if err := a.putJSON(oneShotBucket, s.ID, s); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		a.startOneShots()
+		a.wakeOneShots()
+		w.WriteHeader(http.StatusCreated)
+		writeJSONResponse(w, s)
+	}
+}
+
+// startOneShots starts dispatching one-shot schedules as they become due, if it
+// hasn't been started already. Schedules that became due while the application was
+// stopped are dispatched right away.
+func (a *Application) startOneShots() {
+	a.oneShotOnce.Do(func() {
+		a.oneShotMu.Lock()
+		a.oneShotWake = make(chan struct{}, 1)
+		a.oneShotDone = make(chan struct{})
+		a.oneShotMu.Unlock()
+		go a.dispatchOneShots()
+	})
+}
+
+// stopOneShots stops dispatching one-shot schedules.
+func (a *Application) stopOneShots() {
+	a.oneShotMu.Lock()
+	defer a.oneShotMu.Unlock()
+	if a.oneShotDone == nil {
+		return
+	}
+	select {
+	case <-a.oneShotDone:
+	default:
+		close(a.oneShotDone)
+	}
+}
+
+func (a *Application) wakeOneShots() {
+	select {
+	case a.oneShotWake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchOneShots queues each one-shot schedule's transaction as soon as it is
+// due, until one-shots are stopped.
+func (a *Application) dispatchOneShots() {
+	for {
+		next, err := a.dispatchDueOneShots()
+		if err != nil {
+			a.log().Error("failed to read one-shot schedules", logging.Err(err))
+			next = time.Now().Add(initialExecutionBackoff)
+		}
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+		select {
+		case <-a.oneShotWake:
+		case <-due:
+		case <-a.oneShotDone:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-a.oneShotDone:
+			return
+		default:
+		}
+	}
+}
+
+// dispatchDueOneShots moves every due one-shot schedule into the work queue and
+// returns when the next one will be due. The zero time is returned if there is
+// none. A schedule is only removed once its transaction is queued. If Hatchery
+// stops in between, it is queued again with the same ID on the next start, which
+// the work queue does not execute twice. Only the leader of a cluster dispatches
+// schedules; it is woken when it is elected.
+func (a *Application) dispatchDueOneShots() (time.Time, error) {
+	if !a.leading() {
+		return time.Time{}, nil
+	}
+	shots, err := a.oneShots()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var next time.Time
+	now := time.Now()
+	for _, s := range shots {
+		if s.ScheduleAt.After(now) {
+			next = s.ScheduleAt
+			break
+		}
+		err := a.enqueueItem(&QueueItem{
+			ID:          s.ID,
+			TxnType:     s.TxnType,
+			Payload:     s.Payload,
+			Status:      QueueStatusPending,
+			NextAttempt: now.UTC(),
+			Created:     now.UTC(),
+		})
+		if err != nil {
+			return time.Time{}, err
+		}
+		if err := a.Heap.Delete(oneShotBucket, s.ID); err != nil && err != ErrHeapNotExist {
+			return time.Time{}, err
+		}
+		a.log().Info("one-shot schedule queued", logging.Contract(s.TxnType), logging.TxnID(s.ID))
+	}
+	return next, nil
+}
+
+// oneShots returns every pending one-shot schedule, soonest first.
+func (a *Application) oneShots() ([]*OneShot, error) {
+	all, err := a.Heap.GetRange(oneShotBucket, "", "")
+	if err != nil {
+		return nil, err
+	}
+	shots := make([]*OneShot, 0, len(all))
+	for _, v := range all {
+		var s OneShot
+		if err := json.Unmarshal(v, &s); err != nil {
+			return nil, err
+		}
+		shots = append(shots, &s)
+	}
+	sort.Slice(shots, func(i, j int) bool {
+		return shots[i].ScheduleAt.Before(shots[j].ScheduleAt)
+	})
+	return shots, nil
+}
@@ -0,0 +1,556 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the document served
+// at /openapi.json conforms to.
+const openAPIVersion = "3.0.3"
+
+// apiVersion is the version of the Hatchery API described by the document.
+const apiVersion = "1.0.0"
+
+// openAPIObject is a JSON object of an OpenAPI document.
+type openAPIObject map[string]interface{}
+
+// apiParam is a non-path parameter of an apiRoute.
+type apiParam struct {
+	// in is "query" or "header".
+	in          string
+	name        string
+	typ         string
+	description string
+}
+
+// apiRoute describes a route of the API for the OpenAPI document. Request and
+// response are values of the types of the request and response bodies, whose
+// schemas are generated from their JSON encoding, or nil if there is none.
+type apiRoute struct {
+	method      string
+	path        string
+	operationID string
+	tag         string
+	summary     string
+	// public routes are not authenticated, and heap routes are authorized with
+	// the contract's heap token instead of an API key. heapRead routes accept
+	// either.
+	public   bool
+	heap     bool
+	heapRead bool
+	params   []apiParam
+	request  interface{}
+	response interface{}
+	status   int
+	// contentTypes are the media types of the response. It defaults to
+	// application/json.
+	contentTypes []string
+	// requestTypes are the media types of the request body. It defaults to
+	// application/json. Other types are documented as binary strings.
+	requestTypes []string
+}
+
+var (
+	contentParam       = apiParam{"query", "content", "string", "Encoding of transaction content in the response: base64 (the default) or json, which inlines content that is valid JSON. It may instead be given as a content parameter of the Accept header."}
+	limitParam         = apiParam{"query", "limit", "integer", "Maximum number of items to respond with."}
+	offsetParam        = apiParam{"query", "offset", "integer", "Number of items to skip."}
+	asyncContractParam = apiParam{"query", "async", "boolean", "Respond immediately with a 202 and the registration's status instead of waiting for the contract's image to be pulled. GET /contract/{name}/status reports its progress. A Prefer: respond-async header does the same."}
+
+	// anyJSON stands for a body that may be any JSON value.
+	anyJSON = json.RawMessage(nil)
+)
+
+// apiRoutes describes every route SetupRoutes registers, for the OpenAPI document.
+// Routes added to SetupRoutes must be added here too.
+func (a *Application) apiRoutes() []apiRoute {
+	routes := []apiRoute{
+		{method: http.MethodGet, path: "/healthz", operationID: "Healthz", tag: "health", public: true,
+			summary: "Report that the process is alive", response: map[string]string{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/readyz", operationID: "Readyz", tag: "health", public: true,
+			summary: "Report the status of each dependency, responding 503 if any is unavailable", response: readinessResponse{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/openapi.json", operationID: "OpenAPI", tag: "health", public: true,
+			summary: "Describe the API", response: anyJSON, status: http.StatusOK},
+		{method: http.MethodGet, path: "/get/{sc_name}/{key}", operationID: "GetSCHeap", tag: "heap", heapRead: true,
+			summary:  "Get a value from a contract's heap",
+			params:   []apiParam{{"query", "format", "string", "raw to always respond with the raw bytes, or json to always respond with JSON, encoding non-JSON values as base64 strings."}},
+			response: anyJSON, status: http.StatusOK, contentTypes: []string{"application/json", "application/octet-stream"}},
+		{method: http.MethodGet, path: "/list/{sc_name}", operationID: "ListSCHeap", tag: "heap", heapRead: true,
+			summary: "List the keys in a contract's heap", params: []apiParam{offsetParam, limitParam}, response: []string{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/list/{sc_name}/{prefix}", operationID: "ListSCHeapPrefix", tag: "heap", heapRead: true,
+			summary: "List the keys in a contract's heap that begin with a prefix", params: []apiParam{offsetParam, limitParam}, response: []string{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/heap", operationID: "ListHeaps", tag: "heap",
+			summary: "List the contract heaps", response: []string{}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/heap/{sc_name}", operationID: "PostSCHeap", tag: "heap", heap: true,
+			summary: "Write each member of an object to a contract's heap", request: map[string]json.RawMessage{}, status: http.StatusNoContent},
+		{method: http.MethodDelete, path: "/heap/{sc_name}", operationID: "DeleteSCHeap", tag: "heap",
+			summary: "Delete a contract's heap", status: http.StatusNoContent},
+		{method: http.MethodGet, path: "/heap/{sc_name}/export", operationID: "ExportSCHeap", tag: "heap",
+			summary: "Export every key value pair in a contract's heap",
+			params: []apiParam{{"query", "format", "string", "json, the default, for a JSON array of entries, or ndjson for one entry per line."},
+				offsetParam, limitParam},
+			response: []HeapEntry{}, status: http.StatusOK, contentTypes: []string{"application/json", "application/x-ndjson"}},
+		{method: http.MethodPost, path: "/heap/{sc_name}/import", operationID: "ImportSCHeap", tag: "heap",
+			summary: "Import exported key value pairs into a contract's heap",
+			params:  []apiParam{{"query", "replace", "boolean", "Empty the heap before importing, so it holds exactly the imported entries."}},
+			request: []HeapEntry{}, response: importHeapResponse{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/heap/{sc_name}/usage", operationID: "GetHeapUsage", tag: "heap",
+			summary: "Get the size of a contract's heap and its quota", response: HeapUsage{}, status: http.StatusOK},
+		{method: http.MethodDelete, path: "/heap/{sc_name}/{key}", operationID: "DeleteSCHeapKey", tag: "heap", heap: true,
+			summary: "Delete a key from a contract's heap", status: http.StatusNoContent},
+		{method: http.MethodPost, path: "/heap/{sc_name}/{key}/cas", operationID: "CompareAndSwapSCHeap", tag: "heap", heap: true,
+			summary: "Write a key in a contract's heap only if it holds an expected value", request: heapSwap{}, status: http.StatusNoContent},
+		{method: http.MethodPost, path: "/transaction", operationID: "PostTransaction", tag: "transactions",
+			summary: "Post a transaction, executing its contract if it has one",
+			params: []apiParam{contentParam,
+				{"header", IdempotencyKeyHeader, "string", "Makes the request safe to retry. Requests repeating a recent key respond with the transaction posted by the first."},
+				{"query", "async", "boolean", "Respond immediately with a 202 and the pending transaction's ID instead of waiting for it. A Prefer: respond-async header does the same."}},
+			request: postTransactionRequest{}, response: transactionResponse{}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/transaction/bulk", operationID: "PostTransactionBulk", tag: "transactions",
+			summary: "Post up to " + strconv.Itoa(maxBulkTransactions) + " transactions, appending the successful ones atomically",
+			params:  []apiParam{contentParam}, request: []postTransactionRequest{}, response: []bulkTransactionResult{}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/transaction/fanout", operationID: "PostTransactionFanout", tag: "transactions",
+			summary: "Post one payload as a transaction of each of several types, appending the successful ones atomically",
+			params:  []apiParam{contentParam}, request: fanoutRequest{}, response: fanoutResponse{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/transaction/{id}", operationID: "GetTransaction", tag: "transactions",
+			summary: "Get a transaction", params: []apiParam{contentParam}, response: transactionResponse{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/transaction/{id}/status", operationID: "GetTransactionStatus", tag: "transactions",
+			summary: "Get whether a posted transaction is pending, succeeded or failed",
+			params:  []apiParam{contentParam}, response: transactionStatus{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/transaction/{id}/output", operationID: "GetTransactionOutput", tag: "transactions",
+			summary:  "Get the raw output of the contract that produced a transaction, including output spilled to the blob store",
+			response: anyJSON, status: http.StatusOK, contentTypes: []string{"application/octet-stream"}},
+		{method: http.MethodGet, path: "/ledger/export", operationID: "ExportLedger", tag: "transactions",
+			summary:  "Export every transaction in the ledger, with its hashes, as JSON Lines",
+			response: LedgerEntry{}, status: http.StatusOK, contentTypes: []string{"application/x-ndjson"}},
+		{method: http.MethodPost, path: "/ledger/import", operationID: "ImportLedger", tag: "transactions",
+			summary: "Append exported transactions that continue from the ledger's latest transaction",
+			request: LedgerEntry{}, requestTypes: []string{"application/x-ndjson"}, response: importLedgerResponse{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/transactions", operationID: "ListTransactions", tag: "transactions",
+			summary: "List the transactions in the ledger in the order they were appended",
+			params: []apiParam{contentParam,
+				{"query", "offset", "integer", "Number of transactions to skip."},
+				limitParam},
+			response: listTransactionsResponse{}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/replay", operationID: "Replay", tag: "transactions",
+			summary: "Execute a range of contract transactions again, writing their output to a fresh heap bucket",
+			request: ReplayRequest{}, response: ReplayReport{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/block/{id}", operationID: "GetBlock", tag: "transactions",
+			summary: "Get a block and its transactions", params: []apiParam{contentParam}, response: blockResponse{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/queue", operationID: "ListQueue", tag: "transactions",
+			summary:  "List the transactions in the work queue",
+			params:   []apiParam{{"query", "status", "string", "Only list items with this status."}},
+			response: []QueueItem{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/outbox", operationID: "ListOutbox", tag: "transactions",
+			summary:  "List the transactions waiting to be forwarded to DragonChain, or that failed to be",
+			response: []OutboxItem{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/stream", operationID: "Stream", tag: "transactions",
+			summary: "Stream appended transactions, contract executions and heap changes as Server-Sent Events",
+			params: []apiParam{
+				{"query", "txn_type", "string", "Only stream events of these transaction types. It may be repeated or comma separated."},
+				{"query", "heap", "string", "Stream the writes and deletions in a contract's heap, given as a bucket name optionally followed by a slash and a key prefix. It may be repeated."},
+			},
+			response: StreamEvent{}, status: http.StatusOK, contentTypes: []string{"text/event-stream"}},
+		{method: http.MethodGet, path: "/contract", operationID: "ListContracts", tag: "contracts",
+			summary: "List the contracts", response: []ContractManifest{}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/contract", operationID: "PostContract", tag: "contracts",
+			summary: "Create a contract, or a new version of an existing one",
+			params:  []apiParam{asyncContractParam}, request: ContractManifest{}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/contract/test", operationID: "TestContract", tag: "contracts",
+			summary: "Execute a contract without storing it or its output",
+			request: testContractRequest{}, response: testContractResponse{}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/contract/validate", operationID: "ValidateContract", tag: "contracts",
+			summary: "Check a contract manifest, and that its image can be pulled, without registering it",
+			request: ContractManifest{}, response: LintReport{}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/contract/bundle", operationID: "PostContractBundle", tag: "contracts",
+			summary: "Create or update every contract in a tar, gzipped tar or zip archive of manifests",
+			request: []byte{}, response: []bundleResult{}, status: http.StatusOK,
+			requestTypes: []string{"application/x-tar", "application/gzip", "application/zip"}},
+		{method: http.MethodPut, path: "/contract/{name}", operationID: "PutContract", tag: "contracts",
+			summary: "Update a contract", params: []apiParam{asyncContractParam}, request: ContractManifest{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/contract/{name}/versions", operationID: "ListContractVersions", tag: "contracts",
+			summary: "List the versions of a contract, oldest first", response: []ContractVersion{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/contract/{name}/executions", operationID: "ListExecutions", tag: "contracts",
+			summary: "List the most recent executions of a contract, newest first",
+			params:  []apiParam{limitParam}, response: []Execution{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/contract/{name}/status", operationID: "GetContractStatus", tag: "contracts",
+			summary:  "Get the state of a contract's circuit breaker, its execution counters and the progress of its registration",
+			response: contractStatus{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/contract/{name}/logs", operationID: "ContractLogs", tag: "contracts",
+			summary:  "List the most recent lines a contract wrote to stderr, oldest first",
+			params:   []apiParam{{"query", "lines", "integer", "Maximum number of lines to respond with."}},
+			response: []LogLine{}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/contract/{name}/logs/stream", operationID: "ContractLogStream", tag: "contracts",
+			summary: "Stream the stdout and stderr lines of a contract's executions as Server-Sent Events",
+			params: []apiParam{
+				{"query", "tail", "integer", "Number of the most recent lines to send first."},
+				{"query", "follow", "boolean", "Whether to keep streaming new lines. Defaults to true."},
+			},
+			response: OutputLine{}, status: http.StatusOK, contentTypes: []string{"text/event-stream"}},
+		{method: http.MethodDelete, path: "/contract/{name}", operationID: "DeleteContract", tag: "contracts",
+			summary: "Delete a contract", status: http.StatusNoContent},
+		{method: http.MethodPost, path: "/schedule", operationID: "PostSchedule", tag: "contracts",
+			summary: "Schedule a contract to execute once", request: postScheduleRequest{}, response: OneShot{}, status: http.StatusCreated},
+		{method: http.MethodGet, path: "/schedule", operationID: "GetSchedule", tag: "contracts",
+			summary:  "List the upcoming runs of every cron and one-shot job",
+			params:   []apiParam{{"query", "next", "integer", "The number of upcoming runs to list per cron job, from 1 to 100. Defaults to 5."}},
+			response: ScheduleCalendar{}, status: http.StatusOK},
+	}
+	if a.Chains != nil {
+		routes = append(routes,
+			apiRoute{method: http.MethodPost, path: "/chains", operationID: "PostChain", tag: "chains",
+				summary: "Create a virtual chain", request: postChainRequest{}, response: postChainResponse{}, status: http.StatusCreated},
+			apiRoute{method: http.MethodGet, path: "/chains", operationID: "ListChains", tag: "chains",
+				summary: "List the virtual chains", response: []Chain{}, status: http.StatusOK},
+			apiRoute{method: http.MethodDelete, path: "/chains/{chain_id}", operationID: "DeleteChain", tag: "chains",
+				summary: "Delete a virtual chain and everything stored for it", status: http.StatusNoContent},
+		)
+	}
+	return append(routes,
+		apiRoute{method: http.MethodPost, path: "/subscription", operationID: "PostSubscription", tag: "subscriptions",
+			summary: "Subscribe to appended transactions", request: postSubscriptionRequest{}, response: Subscription{}, status: http.StatusCreated},
+		apiRoute{method: http.MethodGet, path: "/subscription", operationID: "ListSubscriptions", tag: "subscriptions",
+			summary: "List the subscriptions", response: []Subscription{}, status: http.StatusOK},
+		apiRoute{method: http.MethodDelete, path: "/subscription/{id}", operationID: "DeleteSubscription", tag: "subscriptions",
+			summary: "Delete a subscription", status: http.StatusNoContent},
+		apiRoute{method: http.MethodGet, path: "/subscription/{id}/deliveries", operationID: "ListDeliveries", tag: "subscriptions",
+			summary: "List the callbacks made to a subscription", response: []Delivery{}, status: http.StatusOK},
+		apiRoute{method: http.MethodPost, path: "/api-key", operationID: "PostAPIKey", tag: "admin",
+			summary: "Generate an API key", response: APIKey{}, status: http.StatusCreated},
+		apiRoute{method: http.MethodDelete, path: "/api-key/{id}", operationID: "DeleteAPIKey", tag: "admin",
+			summary: "Delete an API key", status: http.StatusNoContent},
+		apiRoute{method: http.MethodPost, path: "/keys", operationID: "PostSigningKey", tag: "admin",
+			summary: "Register an ed25519 public key that transactions can be signed with", request: postSigningKeyRequest{}, response: SigningKey{}, status: http.StatusCreated},
+		apiRoute{method: http.MethodGet, path: "/keys", operationID: "ListSigningKeys", tag: "admin",
+			summary: "List the registered signing keys", response: []SigningKey{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/keys/{id}", operationID: "GetSigningKey", tag: "admin",
+			summary: "Get a signing key", response: SigningKey{}, status: http.StatusOK},
+		apiRoute{method: http.MethodDelete, path: "/keys/{id}", operationID: "DeleteSigningKey", tag: "admin",
+			summary: "Delete a signing key", status: http.StatusNoContent},
+		apiRoute{method: http.MethodPost, path: "/secret", operationID: "PostSecret", tag: "admin",
+			summary: "Store a secret", request: postSecretRequest{}, response: Secret{}, status: http.StatusCreated},
+		apiRoute{method: http.MethodGet, path: "/secret", operationID: "ListSecrets", tag: "admin",
+			summary: "List the secrets, without their values", response: []Secret{}, status: http.StatusOK},
+		apiRoute{method: http.MethodDelete, path: "/secret/{name}", operationID: "DeleteSecret", tag: "admin",
+			summary: "Delete a secret", status: http.StatusNoContent},
+		apiRoute{method: http.MethodPost, path: "/gc", operationID: "CollectGarbage", tag: "admin",
+			summary:  "Remove the images of deleted contracts, prune old contract versions and compact the heap",
+			params:   []apiParam{{"query", "keep_versions", "integer", "How many of the latest versions of each contract to keep. Defaults to gc_keep_versions."}},
+			response: GCReport{}, status: http.StatusOK},
+		apiRoute{method: http.MethodPost, path: "/ledger/prune", operationID: "PruneLedger", tag: "admin",
+			summary: "Prune, and optionally archive, the oldest transactions in the ledger according to the retention policy",
+			params: []apiParam{
+				{"query", "max_age", "string", "Prune transactions older than this duration, such as 720h. Defaults to the retention policy's."},
+				{"query", "max_entries", "integer", "Prune the oldest transactions beyond this many. Defaults to the retention policy's."},
+				{"query", "dry_run", "boolean", "Report what would be pruned without pruning or archiving anything."},
+			},
+			response: PruneRun{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/ledger/prune", operationID: "GetPruneStatus", tag: "admin",
+			summary:  "Get the retention policy, how much of the ledger has been pruned and the latest pruning runs",
+			response: PruneStatus{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/audit", operationID: "ListAudit", tag: "admin",
+			summary: "List the audit log of mutating API calls, oldest first",
+			params: []apiParam{
+				{"query", "caller", "string", "Only list calls made with this API key ID, or contract:<name> for heap writes made with a contract's heap token."},
+				{"query", "method", "string", "Only list calls with this HTTP method."},
+				{"query", "contract", "string", "Only list calls concerning this contract."},
+				{"query", "status", "string", "Only list calls that succeeded (success) or failed (failure)."},
+				{"query", "since", "string", "Only list calls made at or after this RFC 3339 timestamp."},
+				{"query", "until", "string", "Only list calls made before this RFC 3339 timestamp."},
+				limitParam},
+			response: []AuditEntry{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/replication", operationID: "GetReplication", tag: "admin",
+			summary: "Get the node's replication role and the state of its followers", response: ReplicationStatus{}, status: http.StatusOK},
+		apiRoute{method: http.MethodPost, path: "/replication/promote", operationID: "Promote", tag: "admin",
+			summary: "Promote a follower to a primary", response: ReplicationStatus{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/cluster", operationID: "GetCluster", tag: "admin",
+			summary: "Get the node's ID and whether it leads its cluster", response: ClusterStatus{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/v1/status", operationID: "DragonChainStatus", tag: "dragonchain",
+			summary: "Describe the node like DragonChain's GET /v1/status", response: dragonchainStatus{}, status: http.StatusOK},
+		apiRoute{method: http.MethodPost, path: "/v1/transaction", operationID: "DragonChainPostTransaction", tag: "dragonchain",
+			summary: "Queue a transaction posted in DragonChain's format and respond with its ID",
+			request: dragonchainTransactionRequest{}, response: dragonchainTransactionCreated{}, status: http.StatusCreated},
+		apiRoute{method: http.MethodPost, path: "/v1/transaction_bulk", operationID: "DragonChainPostTransactionBulk", tag: "dragonchain",
+			summary: "Queue up to " + strconv.Itoa(maxBulkTransactions) + " transactions posted in DragonChain's format",
+			request: []dragonchainTransactionRequest{}, response: anyJSON, status: http.StatusMultiStatus},
+		apiRoute{method: http.MethodGet, path: "/v1/transaction/{id}", operationID: "DragonChainGetTransaction", tag: "dragonchain",
+			summary: "Get a transaction in DragonChain's format", response: dragonchainTransactionResponse{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/v1/contract", operationID: "DragonChainListContracts", tag: "dragonchain",
+			summary: "List the contracts in DragonChain's format", response: anyJSON, status: http.StatusOK},
+		apiRoute{method: http.MethodPost, path: "/v1/contract", operationID: "DragonChainPostContract", tag: "dragonchain",
+			summary: "Create a contract posted in DragonChain's format",
+			request: dragonchainContractRequest{}, response: dragonchainContract{}, status: http.StatusAccepted},
+		apiRoute{method: http.MethodGet, path: "/v1/contract/txn_type/{name}", operationID: "DragonChainGetContractByTxnType", tag: "dragonchain",
+			summary: "Get a contract in DragonChain's format by its transaction type", response: dragonchainContract{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/v1/contract/{name}", operationID: "DragonChainGetContract", tag: "dragonchain",
+			summary: "Get a contract in DragonChain's format", response: dragonchainContract{}, status: http.StatusOK},
+		apiRoute{method: http.MethodDelete, path: "/v1/contract/{name}", operationID: "DragonChainDeleteContract", tag: "dragonchain",
+			summary: "Delete a contract", response: anyJSON, status: http.StatusAccepted},
+		apiRoute{method: http.MethodGet, path: "/v1/get/{sc_name}/{key}", operationID: "DragonChainGetSCHeap", tag: "dragonchain", heapRead: true,
+			summary:  "Get the raw bytes of a value from a contract's heap",
+			response: anyJSON, status: http.StatusOK, contentTypes: []string{"application/octet-stream"}},
+		apiRoute{method: http.MethodGet, path: "/v1/list/{sc_name}", operationID: "DragonChainListSCHeap", tag: "dragonchain", heapRead: true,
+			summary: "List the keys in a contract's heap", response: []string{}, status: http.StatusOK},
+		apiRoute{method: http.MethodGet, path: "/v1/list/{sc_name}/{prefix}", operationID: "DragonChainListSCHeapPrefix", tag: "dragonchain", heapRead: true,
+			summary: "List the keys in a contract's heap that begin with a prefix", response: []string{}, status: http.StatusOK},
+	)
+}
+
+// OpenAPI returns an HTTP handler function that responds with an OpenAPI 3 document
+// describing the API, for generating clients and browsing the API with Swagger UI.
+func (a *Application) OpenAPI() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, a.openAPIDocument())
+	}
+}
+
+var pathParamPattern = regexp.MustCompile(`{([^}]+)}`)
+
+// openAPIDocument builds the OpenAPI document of the API from apiRoutes. Schemas
+// are generated from the JSON encoding of the request and response types.
+func (a *Application) openAPIDocument() openAPIObject {
+	g := &schemaGenerator{schemas: openAPIObject{}}
+	errorSchema := g.schema(reflect.TypeOf(errorResponse{}))
+	paths := openAPIObject{}
+	for _, route := range a.apiRoutes() {
+		op := openAPIObject{
+			"operationId": route.operationID,
+			"summary":     route.summary,
+			"tags":        []string{route.tag},
+		}
+		switch {
+		case route.public:
+			op["security"] = []openAPIObject{}
+		case route.heap:
+			op["security"] = []openAPIObject{{"heapToken": []string{}}}
+		case route.heapRead:
+			op["security"] = []openAPIObject{{"dragonchainHMAC": []string{}}, {"heapToken": []string{}}}
+		}
+
+		var params []openAPIObject
+		for _, m := range pathParamPattern.FindAllStringSubmatch(route.path, -1) {
+			params = append(params, openAPIObject{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   openAPIObject{"type": "string"},
+			})
+		}
+		for _, p := range route.params {
+			params = append(params, openAPIObject{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"schema":      openAPIObject{"type": p.typ},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if route.request != nil {
+			requestTypes := route.requestTypes
+			if len(requestTypes) == 0 {
+				requestTypes = []string{"application/json"}
+			}
+			content := openAPIObject{}
+			for _, ct := range requestTypes {
+				schema := openAPIObject{"type": "string", "format": "binary"}
+				if ct == "application/json" {
+					schema = g.schema(reflect.TypeOf(route.request))
+				}
+				content[ct] = openAPIObject{"schema": schema}
+			}
+			op["requestBody"] = openAPIObject{"required": true, "content": content}
+		}
+
+		resp := openAPIObject{"description": http.StatusText(route.status)}
+		if route.response != nil {
+			contentTypes := route.contentTypes
+			if len(contentTypes) == 0 {
+				contentTypes = []string{"application/json"}
+			}
+			content := openAPIObject{}
+			for _, ct := range contentTypes {
+				schema := g.schema(reflect.TypeOf(route.response))
+				if ct == "application/octet-stream" {
+					schema = openAPIObject{"type": "string", "format": "binary"}
+				}
+				content[ct] = openAPIObject{"schema": schema}
+			}
+			resp["content"] = content
+		}
+		op["responses"] = openAPIObject{
+			strconv.Itoa(route.status): resp,
+			"default": openAPIObject{
+				"description": "Error",
+				"content": openAPIObject{
+					"application/json": openAPIObject{"schema": errorSchema},
+				},
+			},
+		}
+
+		item, ok := paths[route.path].(openAPIObject)
+		if !ok {
+			item = openAPIObject{}
+			paths[route.path] = item
+		}
+		item[strings.ToLower(route.method)] = op
+	}
+
+	doc := openAPIObject{
+		"openapi": openAPIVersion,
+		"info": openAPIObject{
+			"title":       "Hatchery",
+			"description": "A mock DragonChain transaction API. When virtual chains are enabled, every route is also served under /chains/{chain_id}.",
+			"version":     apiVersion,
+		},
+		"paths": paths,
+		"components": openAPIObject{
+			"schemas": g.schemas,
+			"securitySchemes": openAPIObject{
+				"dragonchainHMAC": openAPIObject{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Authorization",
+					"description": "DragonChain's HMAC signature scheme: \"" + hmacScheme + " <key id>:<signature>\", with the dragonchain and timestamp headers. Only required when authentication is enabled.",
+				},
+				"heapToken": openAPIObject{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "A contract's heap token, which contracts receive in the HEAP_TOKEN environment variable. It authorizes access to the contract's own heap and to the heaps of contracts that list it in their HeapReaders or HeapWriters.",
+				},
+			},
+		},
+		"security": []openAPIObject{{"dragonchainHMAC": []string{}}},
+	}
+	if a.BaseURL != "" {
+		doc["servers"] = []openAPIObject{{"url": a.BaseURL}}
+	}
+	return doc
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schemaGenerator generates the schemas of Go types from their JSON encoding.
+// Named struct types are added to schemas and referenced by name.
+type schemaGenerator struct {
+	schemas openAPIObject
+}
+
+// schema returns the schema of the JSON encoding of t.
+func (g *schemaGenerator) schema(t reflect.Type) openAPIObject {
+	switch t {
+	case timeType:
+		return openAPIObject{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return openAPIObject{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return openAPIObject{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return openAPIObject{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return openAPIObject{"type": "number"}
+	case reflect.String:
+		return openAPIObject{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return openAPIObject{"type": "string", "format": "byte"}
+		}
+		return openAPIObject{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return openAPIObject{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := g.schemas[name]; !ok {
+			// The entry is reserved before the schema is generated, so that
+			// recursive types refer to it instead of recursing forever.
+			g.schemas[name] = openAPIObject{}
+			g.schemas[name] = g.structSchema(t)
+		}
+		return openAPIObject{"$ref": "#/components/schemas/" + name}
+	}
+	// Interfaces may hold any JSON value.
+	return openAPIObject{}
+}
+
+// structSchema returns the schema of a struct type.
+func (g *schemaGenerator) structSchema(t reflect.Type) openAPIObject {
+	props := openAPIObject{}
+	g.addFields(t, props)
+	return openAPIObject{"type": "object", "properties": props}
+}
+
+// addFields adds the properties of the JSON encoding of struct type t to props.
+// Like encoding/json, the fields of embedded structs are promoted unless a field of
+// the same name is shallower.
+func (g *schemaGenerator) addFields(t reflect.Type, props openAPIObject) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, ft)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+	}
+	for _, et := range embedded {
+		promoted := openAPIObject{}
+		g.addFields(et, promoted)
+		for name, schema := range promoted {
+			if _, ok := props[name]; !ok {
+				props[name] = schema
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import "github.com/summerplaygames/hatchery/internal/app/output"
+
+// ErrCodeOutputTooLarge is the error code of executions whose output exceeds
+// OutputLimit under output.PolicyFail.
+const ErrCodeOutputTooLarge = "output_too_large"
+
+// OutputLimiter is implemented by Contracts whose runtime can limit the output
+// captured from their executions.
+type OutputLimiter interface {
+	// SetOutputLimit sets the limit of the output captured from subsequent
+	// executions.
+	SetOutputLimit(limit output.Limit)
+}
+
+// limitOutput applies OutputLimit to contract, if it implements OutputLimiter.
+func (a *Application) limitOutput(contract Contract) {
+	if l, ok := contract.(OutputLimiter); ok {
+		l.SetOutputLimit(a.OutputLimit)
+	}
+}
+
+// outputTooLarge reports whether err is an execution failure caused by output
+// exceeding OutputLimit.
+func outputTooLarge(err error) bool {
+	if e, ok := err.(*ExecutionError); ok {
+		err = e.Err
+	}
+	_, ok := err.(*output.TooLargeError)
+	return ok
+}
@@ -0,0 +1,848 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// postgresMigrations are applied in order to bring a database up to the current
+// schema. The number of applied migrations is recorded in
+// hatchery_schema_migrations, so new migrations must only ever be appended.
+// Keys and buckets use the "C" collation so that they sort byte by byte, like
+// the keys of the other Heap implementations. Timestamps are stored in
+// nanoseconds because they are covered by transaction hashes.
+var postgresMigrations = []string{
+	`CREATE TABLE hatchery_heap (
+		bucket TEXT COLLATE "C" NOT NULL,
+		key TEXT COLLATE "C" NOT NULL,
+		value BYTEA NOT NULL,
+		PRIMARY KEY (bucket, key)
+	)`,
+	`CREATE TABLE hatchery_ledger (
+		seq BIGSERIAL PRIMARY KEY,
+		namespace TEXT NOT NULL,
+		id TEXT NOT NULL,
+		txn_type TEXT NOT NULL,
+		invoker_contract TEXT NOT NULL,
+		status TEXT NOT NULL,
+		content BYTEA NOT NULL,
+		timestamp_ns BIGINT NOT NULL,
+		prev_hash TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		invocation_chain TEXT NOT NULL,
+		UNIQUE (namespace, id)
+	)`,
+	`CREATE INDEX hatchery_ledger_txn_type ON hatchery_ledger (namespace, txn_type)`,
+	`CREATE INDEX hatchery_ledger_timestamp ON hatchery_ledger (namespace, timestamp_ns)`,
+	`ALTER TABLE hatchery_ledger ADD COLUMN signer TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE hatchery_ledger ADD COLUMN payload BYTEA NOT NULL DEFAULT ''`,
+	`ALTER TABLE hatchery_ledger ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0, ADD COLUMN last_error TEXT NOT NULL DEFAULT ''`,
+	`CREATE TABLE hatchery_blobs (
+		address TEXT PRIMARY KEY,
+		data BYTEA NOT NULL
+	)`,
+	`ALTER TABLE hatchery_ledger ADD COLUMN content_ref TEXT NOT NULL DEFAULT '', ADD COLUMN payload_ref TEXT NOT NULL DEFAULT ''`,
+	`CREATE TABLE hatchery_ledger_pruned (
+		namespace TEXT PRIMARY KEY,
+		hash TEXT NOT NULL,
+		count BIGINT NOT NULL
+	)`,
+	`CREATE INDEX hatchery_ledger_content_ref ON hatchery_ledger (content_ref) WHERE content_ref <> ''`,
+	`CREATE INDEX hatchery_ledger_payload_ref ON hatchery_ledger (payload_ref) WHERE payload_ref <> ''`,
+	`ALTER TABLE hatchery_ledger ADD COLUMN hash_version INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE hatchery_blob_chunks (
+		address TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		chunk TEXT NOT NULL,
+		stored_ns BIGINT NOT NULL,
+		PRIMARY KEY (address, seq)
+	)`,
+	`CREATE INDEX hatchery_blob_chunks_chunk ON hatchery_blob_chunks (chunk)`,
+	`ALTER TABLE hatchery_ledger ADD COLUMN output_ref TEXT NOT NULL DEFAULT '', ADD COLUMN output_size BIGINT NOT NULL DEFAULT 0`,
+	`CREATE INDEX hatchery_ledger_output_ref ON hatchery_ledger (output_ref) WHERE output_ref <> ''`,
+}
+
+// PostgresDB is a pool of connections to a PostgreSQL database, shared by a
+// PostgresHeap and PostgresLedger. The database schema is migrated the first
+// time it is used.
+type PostgresDB struct {
+	// DSN is the connection string of the database.
+	DSN string
+	// Driver is the name of the database/sql driver used to connect, such as
+	// "postgres" for github.com/lib/pq. The driver must be linked into the
+	// binary. If empty, "postgres" is used.
+	Driver string
+	// MaxOpenConns limits the number of open connections. If zero, the number
+	// is not limited.
+	MaxOpenConns int
+	// MaxIdleConns is how many idle connections are kept in the pool. If zero,
+	// database/sql's default is used.
+	MaxIdleConns int
+	// ConnMaxLifetime is how long a connection may be reused. If zero,
+	// connections are reused forever.
+	ConnMaxLifetime time.Duration
+
+	once sync.Once
+	db   *sql.DB
+	err  error
+}
+
+func (p *PostgresDB) initOnce() (*sql.DB, error) {
+	p.once.Do(func() {
+		driver := p.Driver
+		if driver == "" {
+			driver = "postgres"
+		}
+		db, err := sql.Open(driver, p.DSN)
+		if err != nil {
+			p.err = fmt.Errorf("failed to open database: %s", err)
+			return
+		}
+		db.SetMaxOpenConns(p.MaxOpenConns)
+		if p.MaxIdleConns > 0 {
+			db.SetMaxIdleConns(p.MaxIdleConns)
+		}
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+		if err := migratePostgres(db); err != nil {
+			db.Close()
+			p.err = fmt.Errorf("failed to migrate database: %s", err)
+			return
+		}
+		p.db = db
+	})
+	return p.db, p.err
+}
+
+// Close closes the connection pool. The database can't be used afterwards.
+func (p *PostgresDB) Close() error {
+	p.once.Do(func() {
+		p.err = errors.New("database is closed")
+	})
+	if p.db == nil {
+		return nil
+	}
+	return p.db.Close()
+}
+
+// migratePostgres applies the postgresMigrations that haven't been applied yet.
+// The migrations table is locked for the duration, so concurrent nodes starting
+// against the same database migrate it once.
+func migratePostgres(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS hatchery_schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return err
+	}
+	return postgresTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`LOCK TABLE hatchery_schema_migrations IN EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		var version int
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM hatchery_schema_migrations`).Scan(&version); err != nil {
+			return err
+		}
+		for i := version; i < len(postgresMigrations); i++ {
+			if _, err := tx.Exec(postgresMigrations[i]); err != nil {
+				return fmt.Errorf("migration %d: %s", i+1, err)
+			}
+			if _, err := tx.Exec(`INSERT INTO hatchery_schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// postgresTx calls fn within a database transaction, which is committed if fn
+// returns nil and rolled back otherwise.
+func postgresTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// PostgresHeap is a Heap implementation backed by PostgreSQL. Buckets only exist
+// while they hold at least one kvp.
+type PostgresHeap struct {
+	DB *PostgresDB
+}
+
+// Put stores the kvp in the given bucket, replacing any existing value for key.
+func (h *PostgresHeap) Put(bucket, key string, value []byte) error {
+	db, err := h.DB.initOnce()
+	if err != nil {
+		return err
+	}
+	if err := putPostgres(db, bucket, key, value); err != nil {
+		return fmt.Errorf("put failed: %s", err)
+	}
+	return nil
+}
+
+// putPostgres upserts a kvp using db, which may be a database transaction.
+func putPostgres(db interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, bucket, key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := db.Exec(`INSERT INTO hatchery_heap (bucket, key, value) VALUES ($1, $2, $3)
+		ON CONFLICT (bucket, key) DO UPDATE SET value = EXCLUDED.value`, bucket, key, value)
+	return err
+}
+
+// CompareAndSwap stores new under key in the given bucket if the key holds old, or if
+// old is nil, if it doesn't exist. The comparison is part of the statement that
+// writes the value, so it is atomic.
+func (h *PostgresHeap) CompareAndSwap(bucket, key string, old, new []byte) (bool, error) {
+	db, err := h.DB.initOnce()
+	if err != nil {
+		return false, err
+	}
+	if new == nil {
+		new = []byte{}
+	}
+	var res sql.Result
+	if old == nil {
+		res, err = db.Exec(`INSERT INTO hatchery_heap (bucket, key, value) VALUES ($1, $2, $3)
+			ON CONFLICT (bucket, key) DO NOTHING`, bucket, key, new)
+	} else {
+		res, err = db.Exec(`UPDATE hatchery_heap SET value = $4 WHERE bucket = $1 AND key = $2 AND value = $3`,
+			bucket, key, old, new)
+	}
+	if err != nil {
+		return false, fmt.Errorf("compare and swap failed: %s", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("compare and swap failed: %s", err)
+	}
+	return n > 0, nil
+}
+
+// Get returns the value for the provided key and bucket. ErrHeapNotExist is
+// returned if there is no such value.
+func (h *PostgresHeap) Get(bucket, key string) ([]byte, error) {
+	db, err := h.DB.initOnce()
+	if err != nil {
+		return nil, err
+	}
+	var v []byte
+	err = db.QueryRow(`SELECT value FROM hatchery_heap WHERE bucket = $1 AND key = $2`, bucket, key).Scan(&v)
+	if err == sql.ErrNoRows {
+		return nil, ErrHeapNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get failed: %s", err)
+	}
+	return v, nil
+}
+
+// GetAll returns every kvp in the given bucket. If the bucket doesn't exist, an
+// empty map is returned.
+func (h *PostgresHeap) GetAll(bucket string) (map[string][]byte, error) {
+	return h.GetRange(bucket, "", "")
+}
+
+// Keys returns the keys in the given bucket that begin with prefix, in ascending
+// order.
+func (h *PostgresHeap) Keys(bucket, prefix string) ([]string, error) {
+	db, err := h.DB.initOnce()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT key FROM hatchery_heap
+		WHERE bucket = $1 AND left(key, length($2)) = $2 ORDER BY key`, bucket, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("keys failed: %s", err)
+	}
+	defer rows.Close()
+	keys := []string{}
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("keys failed: %s", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("keys failed: %s", err)
+	}
+	return keys, nil
+}
+
+// GetRange returns the kvps in the given bucket whose keys are at least start
+// and, if end is not empty, less than end.
+func (h *PostgresHeap) GetRange(bucket, start, end string) (map[string][]byte, error) {
+	db, err := h.DB.initOnce()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT key, value FROM hatchery_heap
+		WHERE bucket = $1 AND key >= $2 AND ($3 = '' OR key < $3)`, bucket, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("get range failed: %s", err)
+	}
+	defer rows.Close()
+	kvps := make(map[string][]byte)
+	for rows.Next() {
+		var (
+			k string
+			v []byte
+		)
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, fmt.Errorf("get range failed: %s", err)
+		}
+		kvps[k] = v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get range failed: %s", err)
+	}
+	return kvps, nil
+}
+
+// Iterate calls fn with each kvp in the given bucket whose key is at least start,
+// in ascending order of keys, until fn returns false. The kvps are streamed from
+// a single query, so fn must not write to the heap.
+func (h *PostgresHeap) Iterate(bucket, start string, fn func(key string, value []byte) bool) error {
+	db, err := h.DB.initOnce()
+	if err != nil {
+		return err
+	}
+	rows, err := db.Query(`SELECT key, value FROM hatchery_heap
+		WHERE bucket = $1 AND key >= $2 ORDER BY key`, bucket, start)
+	if err != nil {
+		return fmt.Errorf("iterate failed: %s", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			k string
+			v []byte
+		)
+		if err := rows.Scan(&k, &v); err != nil {
+			return fmt.Errorf("iterate failed: %s", err)
+		}
+		if !fn(k, v) {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate failed: %s", err)
+	}
+	return nil
+}
+
+// Delete removes key from the given bucket. ErrHeapNotExist is returned if there
+// is no such key.
+func (h *PostgresHeap) Delete(bucket, key string) error {
+	return h.delete(`DELETE FROM hatchery_heap WHERE bucket = $1 AND key = $2`, bucket, key)
+}
+
+// DeleteBucket removes every kvp in the given bucket. ErrHeapNotExist is returned
+// if the bucket is empty.
+func (h *PostgresHeap) DeleteBucket(bucket string) error {
+	return h.delete(`DELETE FROM hatchery_heap WHERE bucket = $1`, bucket)
+}
+
+func (h *PostgresHeap) delete(query string, args ...interface{}) error {
+	db, err := h.DB.initOnce()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("delete failed: %s", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrHeapNotExist
+	}
+	return nil
+}
+
+// Buckets returns the names of every bucket, in ascending order.
+func (h *PostgresHeap) Buckets() ([]string, error) {
+	db, err := h.DB.initOnce()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT DISTINCT bucket FROM hatchery_heap ORDER BY bucket`)
+	if err != nil {
+		return nil, fmt.Errorf("buckets failed: %s", err)
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("buckets failed: %s", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("buckets failed: %s", err)
+	}
+	return names, nil
+}
+
+// Close closes the heap's connection pool.
+func (h *PostgresHeap) Close() error {
+	return h.DB.Close()
+}
+
+// PostgresLedger is a Ledger implementation backed by PostgreSQL. It shares its
+// database with a PostgresHeap, so it also implements backend.HeapAppender.
+type PostgresLedger struct {
+	DB *PostgresDB
+	// Namespace separates the transactions of several ledgers stored in the same
+	// table, and is prepended to the buckets written by AppendWithHeap, like the
+	// Namespace of a NamespacedHeap.
+	Namespace string
+}
+
+// postgresTxnColumns are the columns transactions are inserted with. Large content
+// and payloads are stored once in hatchery_blobs, keyed by their content address,
+// and referenced by content_ref and payload_ref. See backend.SplitBlobs. Spilled
+// output is referenced by output_ref. See PutBlob.
+const postgresTxnColumns = `id, txn_type, invoker_contract, status, content, timestamp_ns, prev_hash, hash, invocation_chain, signer, payload, attempts, last_error, content_ref, payload_ref, hash_version, output_ref, output_size`
+
+// postgresTxnSelect selects transactions, with their blobs restored, for
+// scanTransaction.
+const postgresTxnSelect = `SELECT id, txn_type, invoker_contract, status, COALESCE(content_blob.data, content),
+	timestamp_ns, prev_hash, hash, invocation_chain, signer, COALESCE(payload_blob.data, payload), attempts,
+	last_error, content_ref, payload_ref, hash_version, output_ref, output_size
+	FROM hatchery_ledger
+	LEFT JOIN hatchery_blobs content_blob ON content_blob.address = content_ref
+	LEFT JOIN hatchery_blobs payload_blob ON payload_blob.address = payload_ref`
+
+// Head returns the first transaction in the ledger, or nil if the ledger is empty.
+func (l *PostgresLedger) Head() (*Transaction, error) {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return nil, err
+	}
+	t, err := scanTransaction(db.QueryRow(postgresTxnSelect+`
+		WHERE namespace = $1 ORDER BY seq LIMIT 1`, l.Namespace))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// Find returns the transaction with the provided ID. ErrTransactionNotExist is
+// returned if no such transaction exists.
+func (l *PostgresLedger) Find(id string) (*Transaction, error) {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return nil, err
+	}
+	t, err := scanTransaction(db.QueryRow(postgresTxnSelect+`
+		WHERE namespace = $1 AND id = $2`, l.Namespace, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotExist
+	}
+	return t, err
+}
+
+// Append links each transaction to the current tail and stores it at the end of
+// the ledger, all within a single database transaction. An error is returned, and
+// nothing is appended, if a transaction with the same ID already exists or a
+// transaction could not be written.
+func (l *PostgresLedger) Append(ts ...*Transaction) error {
+	return l.AppendWithHeap(nil, ts...)
+}
+
+// AppendWithHeap writes puts to the heap and appends ts to the ledger within a
+// single database transaction.
+func (l *PostgresLedger) AppendWithHeap(puts []HeapPut, ts ...*Transaction) error {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return err
+	}
+	err = postgresTx(db, func(tx *sql.Tx) error {
+		for _, p := range puts {
+			if err := putPostgres(tx, l.Namespace+p.Bucket, p.Key, p.Value); err != nil {
+				return err
+			}
+		}
+		// Appends to the same ledger are serialized, so that each links to the
+		// tail written by the one before it.
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, "hatchery_ledger:"+l.Namespace); err != nil {
+			return err
+		}
+		prev, err := scanTransaction(tx.QueryRow(postgresTxnSelect+`
+			WHERE namespace = $1 ORDER BY seq DESC LIMIT 1`, l.Namespace))
+		if err == sql.ErrNoRows {
+			prev, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, t := range ts {
+			t.Link(prev)
+			stored, blobs := backend.SplitBlobs(t)
+			for addr, blob := range blobs {
+				_, err := tx.Exec(`INSERT INTO hatchery_blobs (address, data) VALUES ($1, $2)
+					ON CONFLICT (address) DO NOTHING`, addr, blob)
+				if err != nil {
+					return err
+				}
+			}
+			chain, err := json.Marshal(t.InvocationChain)
+			if err != nil {
+				return err
+			}
+			content, payload := stored.Content, stored.Payload
+			if content == nil {
+				content = []byte{}
+			}
+			if payload == nil {
+				payload = []byte{}
+			}
+			_, err = tx.Exec(`INSERT INTO hatchery_ledger (namespace, `+postgresTxnColumns+`)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`,
+				l.Namespace, t.ID, t.Type, t.InvokerContract, string(t.Status), content,
+				t.Timestamp.UnixNano(), t.PrevHash, t.Hash, string(chain), t.Signer, payload,
+				t.Attempts, t.LastError, stored.ContentRef, stored.PayloadRef, t.HashVersion, t.OutputRef, t.OutputSize)
+			if err != nil {
+				return err
+			}
+			prev = t
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("append failed: %s", err)
+	}
+	return nil
+}
+
+// Iterate calls fn for each transaction in append order until fn returns false.
+// Transactions are read from a single query, so fn must not modify the ledger.
+func (l *PostgresLedger) Iterate(fn func(t *Transaction) bool) error {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return err
+	}
+	return l.iterate(db, fn)
+}
+
+// iterate calls fn for each transaction, queried with q, in append order until fn
+// returns false.
+func (l *PostgresLedger) iterate(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, fn func(t *Transaction) bool) error {
+	rows, err := q.Query(postgresTxnSelect+`
+		WHERE namespace = $1 ORDER BY seq`, l.Namespace)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if !fn(t) {
+			return nil
+		}
+	}
+	return rows.Err()
+}
+
+// Clear removes every transaction from the ledger, and forgets that it was pruned.
+func (l *PostgresLedger) Clear() error {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return err
+	}
+	err = postgresTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM hatchery_ledger WHERE namespace = $1`, l.Namespace); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM hatchery_ledger_pruned WHERE namespace = $1`, l.Namespace)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("clear failed: %s", err)
+	}
+	return nil
+}
+
+// Verify walks the ledger and reports the first broken link in the chain of
+// transaction hashes, if any. The ledger is read from a single snapshot of the
+// database, so that a concurrent Prune doesn't appear to break the chain.
+func (l *PostgresLedger) Verify() error {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var anchor string
+	err = tx.QueryRow(`SELECT hash FROM hatchery_ledger_pruned WHERE namespace = $1`, l.Namespace).Scan(&anchor)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return backend.VerifyChainFrom(anchor, func(fn func(t *Transaction) bool) error {
+		return l.iterate(tx, fn)
+	})
+}
+
+// Prune removes every transaction up to and including the one with the given ID,
+// and then the blobs no transaction references. ErrTransactionNotExist is returned
+// if there is no such transaction, and ErrPruneLatest if it is the latest.
+func (l *PostgresLedger) Prune(throughID string) (int, error) {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = postgresTx(db, func(tx *sql.Tx) error {
+		// Appends are held off, so that the latest transaction stays the latest.
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, "hatchery_ledger:"+l.Namespace); err != nil {
+			return err
+		}
+		var (
+			seq    int64
+			anchor string
+			latest bool
+		)
+		err := tx.QueryRow(`SELECT seq, hash FROM hatchery_ledger WHERE namespace = $1 AND id = $2`,
+			l.Namespace, throughID).Scan(&seq, &anchor)
+		if err == sql.ErrNoRows {
+			return ErrTransactionNotExist
+		}
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRow(`SELECT NOT EXISTS (SELECT 1 FROM hatchery_ledger WHERE namespace = $1 AND seq > $2)`,
+			l.Namespace, seq).Scan(&latest)
+		if err != nil {
+			return err
+		}
+		if latest {
+			return backend.ErrPruneLatest
+		}
+		res, err := tx.Exec(`DELETE FROM hatchery_ledger WHERE namespace = $1 AND seq <= $2`, l.Namespace, seq)
+		if err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO hatchery_ledger_pruned (namespace, hash, count) VALUES ($1, $2, $3)
+			ON CONFLICT (namespace) DO UPDATE SET hash = EXCLUDED.hash, count = hatchery_ledger_pruned.count + EXCLUDED.count`,
+			l.Namespace, anchor, n)
+		return err
+	})
+	if err == ErrTransactionNotExist || err == backend.ErrPruneLatest {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("prune failed: %s", err)
+	}
+	if err := sweepPostgresBlobs(db); err != nil {
+		return int(n), fmt.Errorf("failed to remove unreferenced blobs: %s", err)
+	}
+	return int(n), nil
+}
+
+// Pruned returns the hash of the last transaction pruned and how many have been
+// pruned.
+func (l *PostgresLedger) Pruned() (string, int, error) {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return "", 0, err
+	}
+	var (
+		hash  string
+		count int
+	)
+	err = db.QueryRow(`SELECT hash, count FROM hatchery_ledger_pruned WHERE namespace = $1`, l.Namespace).Scan(&hash, &count)
+	if err == sql.ErrNoRows {
+		return "", 0, nil
+	}
+	return hash, count, err
+}
+
+// sweepPostgresBlobs deletes the blobs that no transaction, in any namespace,
+// references. The chunk lists of blobs stored with PutBlob that no transaction has
+// referenced within blobGracePeriod are deleted first, and the chunks of the rest
+// are kept. The tables are locked against inserts while they are swept, and the lock
+// waits for appends and chunks that are underway, so a blob an append is about to
+// reference is never deleted from under it.
+func sweepPostgresBlobs(db *sql.DB) error {
+	return postgresTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`LOCK TABLE hatchery_blobs, hatchery_blob_chunks IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM hatchery_blob_chunks WHERE address IN (
+			SELECT address FROM hatchery_blob_chunks GROUP BY address HAVING max(stored_ns) < $1)
+			AND NOT EXISTS (SELECT 1 FROM hatchery_ledger WHERE output_ref = hatchery_blob_chunks.address)`,
+			time.Now().Add(-blobGracePeriod).UnixNano())
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM hatchery_blobs WHERE NOT EXISTS (
+			SELECT 1 FROM hatchery_ledger
+			WHERE content_ref = hatchery_blobs.address OR payload_ref = hatchery_blobs.address)
+			AND NOT EXISTS (SELECT 1 FROM hatchery_blob_chunks WHERE chunk = hatchery_blobs.address)`)
+		return err
+	})
+}
+
+// PutBlob implements backend.BlobStore. The blob is split into chunks with
+// backend.WriteChunks, which are stored in hatchery_blobs like the blobs of
+// transactions, each in its own database transaction so that no more than a chunk
+// is held in memory, and listed in hatchery_blob_chunks under the blob's address.
+func (l *PostgresLedger) PutBlob(r io.Reader) (string, int64, error) {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return "", 0, err
+	}
+	// The chunks stored so far are listed under a pending address, so that
+	// sweepPostgresBlobs keeps them while the rest are stored.
+	pending := pendingChunksPrefix + uuid.New().String()
+	seq := 0
+	addr, _, size, err := backend.WriteChunks(r, func(addr string, chunk []byte) error {
+		defer func() { seq++ }()
+		return postgresTx(db, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO hatchery_blobs (address, data) VALUES ($1, $2)
+				ON CONFLICT (address) DO NOTHING`, addr, chunk)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`INSERT INTO hatchery_blob_chunks (address, seq, chunk, stored_ns) VALUES ($1, $2, $3, $4)`,
+				pending, seq, addr, time.Now().UnixNano())
+			return err
+		})
+	})
+	if err == nil {
+		err = postgresTx(db, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO hatchery_blob_chunks (address, seq, chunk, stored_ns)
+				SELECT $1, seq, chunk, $3 FROM hatchery_blob_chunks WHERE address = $2
+				ON CONFLICT (address, seq) DO UPDATE SET stored_ns = EXCLUDED.stored_ns`,
+				addr, pending, time.Now().UnixNano())
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`DELETE FROM hatchery_blob_chunks WHERE address = $1`, pending)
+			return err
+		})
+	}
+	if err != nil {
+		if seq > 0 {
+			// sweepPostgresBlobs removes the chunks once their pending list is gone.
+			db.Exec(`DELETE FROM hatchery_blob_chunks WHERE address = $1`, pending)
+		}
+		return "", 0, fmt.Errorf("failed to store blob: %s", err)
+	}
+	return addr, size, nil
+}
+
+// OpenBlob implements backend.BlobStore. Each chunk of the blob is queried as the
+// blob is read.
+func (l *PostgresLedger) OpenBlob(address string) (io.ReadCloser, error) {
+	db, err := l.DB.initOnce()
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(address, pendingChunksPrefix) {
+		return nil, backend.ErrBlobNotExist
+	}
+	rows, err := db.Query(`SELECT chunk FROM hatchery_blob_chunks WHERE address = $1 ORDER BY seq`, address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var chunks []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, backend.ErrBlobNotExist
+	}
+	return ioutil.NopCloser(backend.NewChunkReader(address, chunks, func(addr string) ([]byte, error) {
+		var chunk []byte
+		err := db.QueryRow(`SELECT data FROM hatchery_blobs WHERE address = $1`, addr).Scan(&chunk)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return chunk, err
+	})), nil
+}
+
+// scanTransaction scans a row selected by postgresTxnSelect into a Transaction, and
+// checks its blobs.
+func scanTransaction(row interface {
+	Scan(dest ...interface{}) error
+}) (*Transaction, error) {
+	var (
+		t      Transaction
+		status string
+		ns     int64
+		chain  string
+	)
+	err := row.Scan(&t.ID, &t.Type, &t.InvokerContract, &status, &t.Content, &ns, &t.PrevHash, &t.Hash, &chain, &t.Signer, &t.Payload, &t.Attempts, &t.LastError, &t.ContentRef, &t.PayloadRef, &t.HashVersion, &t.OutputRef, &t.OutputSize)
+	if err != nil {
+		return nil, err
+	}
+	t.Status = TransactionStatus(status)
+	t.Timestamp = time.Unix(0, ns).UTC()
+	if err := json.Unmarshal([]byte(chain), &t.InvocationChain); err != nil {
+		return nil, fmt.Errorf("invalid invocation chain: %s", err)
+	}
+	if err := backend.CheckBlobs(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,276 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// Eviction policies of a heap bucket that is full. See ContractManifest.HeapEviction.
+const (
+	HeapEvictionReject = "reject"
+	HeapEvictionLRU    = "lru"
+)
+
+// heapAccessPrefix prefixes the buckets recording when each key of a bucket with the
+// HeapEvictionLRU policy was last read or written.
+const heapAccessPrefix = reservedBucketPrefix + "heap_access_"
+
+// ErrCodeQuotaExceeded is the error code of writes that would exceed a heap quota.
+const ErrCodeQuotaExceeded = "quota_exceeded"
+
+// HeapQuota limits the size of a heap bucket. Zero limits are not enforced.
+type HeapQuota struct {
+	// MaxBytes limits the total size of the bucket's keys and values.
+	MaxBytes int64 `json:"max_bytes"`
+	// MaxKeys limits the number of keys in the bucket.
+	MaxKeys int `json:"max_keys"`
+	// Eviction is HeapEvictionReject or HeapEvictionLRU.
+	Eviction string `json:"eviction"`
+}
+
+func (q HeapQuota) limited() bool {
+	return q.MaxBytes > 0 || q.MaxKeys > 0
+}
+
+// HeapUsage is the size of a heap bucket and the quota it is held to.
+type HeapUsage struct {
+	Bucket string    `json:"bucket"`
+	Bytes  int64     `json:"bytes"`
+	Keys   int       `json:"keys"`
+	Quota  HeapQuota `json:"quota"`
+}
+
+// HeapQuotaError is returned when a write would take a heap bucket beyond its quota
+// and the bucket's eviction policy can't make room for it.
+type HeapQuotaError struct {
+	Bucket string
+	// Limit is "bytes" or "keys", whichever limit the write would exceed.
+	Limit string
+	// Max is the quota's value of Limit, and Needed what the bucket would hold
+	// after the write.
+	Max    int64
+	Needed int64
+}
+
+func (e *HeapQuotaError) Error() string {
+	return fmt.Sprintf("heap bucket %s would hold %d %s, exceeding its quota of %d", e.Bucket, e.Needed, e.Limit, e.Max)
+}
+
+// heapQuota returns the quota of bucket. A contract's manifest may override the
+// application's default limits for the bucket named after the contract, and choose
+// its eviction policy.
+func (a *Application) heapQuota(bucket string) HeapQuota {
+	q := HeapQuota{MaxBytes: a.HeapMaxBytes, MaxKeys: a.HeapMaxKeys, Eviction: HeapEvictionReject}
+	if isReservedBucket(bucket) {
+		return HeapQuota{}
+	}
+	m, err := a.Lib.Manifest(bucket)
+	if err != nil {
+		return q
+	}
+	if m.HeapMaxBytes != 0 {
+		q.MaxBytes = m.HeapMaxBytes
+	}
+	if m.HeapMaxKeys != 0 {
+		q.MaxKeys = m.HeapMaxKeys
+	}
+	if m.HeapEviction != "" {
+		q.Eviction = m.HeapEviction
+	}
+	return q
+}
+
+// heapSizes returns the size of each key in bucket, counting the key and its value.
+func (a *Application) heapSizes(bucket string) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	err := backend.IterateHeap(a.Heap, bucket, "", func(key string, value []byte) bool {
+		sizes[key] = int64(len(key) + len(value))
+		return true
+	})
+	if err != nil && err != ErrHeapNotExist {
+		return nil, err
+	}
+	return sizes, nil
+}
+
+// reserveHeap makes room in bucket for puts, which are about to be written to it. If
+// replace is set, the bucket is about to be emptied first. Writes that fit within the
+// bucket's quota are left alone. Otherwise, under HeapEvictionLRU, the least recently
+// used keys that puts don't overwrite are evicted until the writes fit, and under
+// HeapEvictionReject, or if evicting every other key would not be enough, a
+// *HeapQuotaError is returned and nothing is evicted.
+//
+// Usage is measured by reading the bucket, so concurrent writes to the same bucket may
+// briefly take it beyond its quota.
+func (a *Application) reserveHeap(bucket string, puts []HeapPut, replace bool) error {
+	quota := a.heapQuota(bucket)
+	if !quota.limited() {
+		return nil
+	}
+	sizes := make(map[string]int64)
+	if !replace {
+		var err error
+		if sizes, err = a.heapSizes(bucket); err != nil {
+			return err
+		}
+	}
+	var candidates []string
+	for key := range sizes {
+		candidates = append(candidates, key)
+	}
+	for _, p := range puts {
+		sizes[p.Key] = int64(len(p.Key) + len(p.Value))
+	}
+	var bytes int64
+	for _, n := range sizes {
+		bytes += n
+	}
+	fits := func() bool {
+		return (quota.MaxBytes <= 0 || bytes <= quota.MaxBytes) && (quota.MaxKeys <= 0 || len(sizes) <= quota.MaxKeys)
+	}
+	if fits() {
+		return nil
+	}
+	var evict []string
+	if quota.Eviction == HeapEvictionLRU {
+		written := make(map[string]bool, len(puts))
+		for _, p := range puts {
+			written[p.Key] = true
+		}
+		used, err := a.heapAccessTimes(bucket)
+		if err != nil {
+			return err
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			ti, tj := used[candidates[i]], used[candidates[j]]
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return candidates[i] < candidates[j]
+		})
+		for _, key := range candidates {
+			if fits() {
+				break
+			}
+			if written[key] {
+				continue
+			}
+			bytes -= sizes[key]
+			delete(sizes, key)
+			evict = append(evict, key)
+		}
+	}
+	if !fits() {
+		if quota.MaxKeys > 0 && len(sizes) > quota.MaxKeys {
+			return &HeapQuotaError{Bucket: bucket, Limit: "keys", Max: int64(quota.MaxKeys), Needed: int64(len(sizes))}
+		}
+		return &HeapQuotaError{Bucket: bucket, Limit: "bytes", Max: quota.MaxBytes, Needed: bytes}
+	}
+	for _, key := range evict {
+		if err := a.Heap.Delete(bucket, key); err != nil && err != ErrHeapNotExist {
+			return err
+		}
+		a.heapDeleted(bucket, key)
+		if err := a.Heap.Delete(heapAccessPrefix+bucket, key); err != nil && err != ErrHeapNotExist {
+			a.log().Error("failed to forget evicted heap key", logging.F("bucket", bucket), logging.F("key", key), logging.Err(err))
+		}
+	}
+	if len(evict) > 0 {
+		a.log().Info("heap keys evicted", logging.F("bucket", bucket), logging.F("count", len(evict)))
+	}
+	return nil
+}
+
+// touchHeap records that keys of bucket were just used, if the bucket's eviction
+// policy is HeapEvictionLRU. Failures are only logged, since they merely make the
+// keys look older than they are.
+func (a *Application) touchHeap(bucket string, keys ...string) {
+	if len(keys) == 0 || a.heapQuota(bucket).Eviction != HeapEvictionLRU {
+		return
+	}
+	now := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	for _, key := range keys {
+		if err := a.Heap.Put(heapAccessPrefix+bucket, key, now); err != nil {
+			a.log().Error("failed to record heap access", logging.F("bucket", bucket), logging.F("key", key), logging.Err(err))
+			return
+		}
+	}
+}
+
+// touchPuts records that the keys written by puts were just used. See touchHeap.
+func (a *Application) touchPuts(puts []HeapPut) {
+	for _, p := range puts {
+		a.touchHeap(p.Bucket, p.Key)
+	}
+}
+
+// heapAccessTimes returns when each key of bucket was last used, as recorded by
+// touchHeap. Keys that were never recorded are missing, so they sort as the least
+// recently used.
+func (a *Application) heapAccessTimes(bucket string) (map[string]time.Time, error) {
+	times := make(map[string]time.Time)
+	err := backend.IterateHeap(a.Heap, heapAccessPrefix+bucket, "", func(key string, value []byte) bool {
+		if t, err := time.Parse(time.RFC3339Nano, string(value)); err == nil {
+			times[key] = t
+		}
+		return true
+	})
+	if err != nil && err != ErrHeapNotExist {
+		return nil, err
+	}
+	return times, nil
+}
+
+// forgetHeapAccess removes the access times of bucket, once the bucket is deleted.
+func (a *Application) forgetHeapAccess(bucket string) {
+	if err := a.Heap.DeleteBucket(heapAccessPrefix + bucket); err != nil && err != ErrHeapNotExist {
+		a.log().Error("failed to delete heap access times", logging.F("bucket", bucket), logging.Err(err))
+	}
+}
+
+// GetHeapUsage returns an HTTP handler function that responds with the size of the
+// requested contract's heap bucket, in bytes and keys, along with its quota.
+func (a *Application) GetHeapUsage() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["sc_name"]
+		if isReservedBucket(name) {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "heap does not exist")
+			return
+		}
+		sizes, err := a.heapSizes(name)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		usage := HeapUsage{Bucket: name, Keys: len(sizes), Quota: a.heapQuota(name)}
+		for _, n := range sizes {
+			usage.Bytes += n
+		}
+		writeJSONResponse(w, usage)
+	}
+}
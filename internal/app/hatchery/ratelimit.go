@@ -0,0 +1,135 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle token buckets are discarded.
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket is a token bucket that holds up to burst tokens and is refilled at
+// rate tokens per second.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket and removes a token from it if one is available. If
+// none is, take returns how long it will be until one is.
+func (b *tokenBucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// rateLimiter keeps a token bucket for each client.
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// allow takes a token from the client's bucket, creating a full bucket for clients
+// that have none. If the client has no tokens left, false is returned along with
+// how long it should wait before retrying.
+func (l *rateLimiter) allow(client string, rate float64, burst int) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+	}
+	if now.Sub(l.lastSweep) > rateLimitSweepInterval {
+		l.sweep(now, rate, burst)
+	}
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[client] = b
+	}
+	return b.take(now, rate, burst)
+}
+
+// sweep discards the buckets that would be full by now, since they are no
+// different from the bucket a returning client would be given.
+func (l *rateLimiter) sweep(now time.Time, rate float64, burst int) {
+	l.lastSweep = now
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= float64(burst) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// rateLimited wraps next so that each client may make RateLimit requests per second,
+// with bursts of up to RateBurst requests. Clients are identified by the ID of the API
+// key they sign requests with when RequireAuth is set, and by their IP address
+// otherwise. Requests over the limit receive a 429 response with a Retry-After header.
+// Requests are not limited if RateLimit is not positive.
+func (a *Application) rateLimited(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.RateLimit <= 0 {
+			next(w, r)
+			return
+		}
+		ok, wait := a.limiter.allow(a.rateLimitClient(r), a.RateLimit, a.rateBurst())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "rate limit exceeded")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateLimitClient identifies the client that made r for rate limiting.
+func (a *Application) rateLimitClient(r *http.Request) string {
+	if a.RequireAuth {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, hmacScheme+" ") {
+			creds := strings.SplitN(strings.TrimPrefix(auth, hmacScheme+" "), ":", 2)
+			return "key:" + creds[0]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func (a *Application) rateBurst() int {
+	if a.RateBurst > 0 {
+		return a.RateBurst
+	}
+	return int(math.Max(1, math.Ceil(a.RateLimit)))
+}
@@ -0,0 +1,199 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+// Contract registration states, reported by GetContractStatus.
+const (
+	// RegistrationPulling means the contract's runtime is being prepared, such as
+	// by pulling its image, and the contract hasn't been stored yet.
+	RegistrationPulling = "pulling"
+	// RegistrationReady means the contract was stored and can be executed.
+	RegistrationReady = "ready"
+	// RegistrationFailed means the contract could not be stored.
+	RegistrationFailed = "failed"
+)
+
+// Error codes of images that can't be pulled.
+const (
+	ErrCodeImageUnauthorized = "image_unauthorized"
+	ErrCodeImageNotFound     = "image_not_found"
+	ErrCodeImagePullFailed   = "image_pull_failed"
+)
+
+// pullErrorResponse returns the status and error code that e is reported with. An
+// image that the registry refuses or doesn't have is a problem with the manifest, and
+// any other failure is the registry's.
+func pullErrorResponse(e *docker.PullError) (int, string) {
+	switch e.Reason {
+	case docker.PullUnauthorized:
+		return http.StatusUnprocessableEntity, ErrCodeImageUnauthorized
+	case docker.PullNotFound:
+		return http.StatusUnprocessableEntity, ErrCodeImageNotFound
+	}
+	return http.StatusBadGateway, ErrCodeImagePullFailed
+}
+
+// registrationStatus is the progress of the latest registration of a contract, as
+// reported by GetContractStatus.
+type registrationStatus struct {
+	State string `json:"state"`
+	Image string `json:"image,omitempty"`
+	// Version is the version the contract was stored as, once it is ready.
+	Version int `json:"version,omitempty"`
+	// Layers is the progress of each layer of the image while it is pulled, in the
+	// order the daemon first reported them.
+	Layers []docker.LayerProgress `json:"layers,omitempty"`
+	// Error is why the registration failed, and ErrorCode the code it was reported
+	// with, such as image_not_found.
+	Error     string     `json:"error,omitempty"`
+	ErrorCode string     `json:"error_code,omitempty"`
+	Started   time.Time  `json:"started"`
+	Finished  *time.Time `json:"finished,omitempty"`
+}
+
+// registration tracks the registration of a contract.
+type registration struct {
+	mu     sync.Mutex
+	status registrationStatus
+	order  []string
+	layers map[string]docker.LayerProgress
+}
+
+func (r *registration) progress(p docker.LayerProgress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.layers[p.ID]; !ok {
+		r.order = append(r.order, p.ID)
+	}
+	r.layers[p.ID] = p
+}
+
+// snapshot returns the current status of the registration.
+func (r *registration) snapshot() registrationStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.status
+	s.Layers = make([]docker.LayerProgress, 0, len(r.order))
+	for _, id := range r.order {
+		s.Layers = append(s.Layers, r.layers[id])
+	}
+	return s
+}
+
+func (r *registration) finish(version int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	r.status.Finished = &now
+	if err == nil {
+		r.status.State = RegistrationReady
+		r.status.Version = version
+		return
+	}
+	r.status.State = RegistrationFailed
+	r.status.Error = err.Error()
+	if e, ok := err.(*docker.PullError); ok {
+		_, r.status.ErrorCode = pullErrorResponse(e)
+	}
+}
+
+// storeContract stores m with store, which is Library.Put or Library.Update, and
+// announces it with registered. The registration, begun with beginRegistration, is
+// tracked with the progress of its image pull, so that GetContractStatus can report it
+// while it is in progress, and how it ended once it is done. Registrations of the same
+// image that overlap share its pull, and so its progress; see docker.PullImage. The
+// error of store or registered is returned.
+func (a *Application) storeContract(reg *registration, m *ContractManifest, schedule Schedule, store func(*ContractManifest) error) error {
+	stop := docker.WatchPulls(m.Image, reg.progress)
+	err := store(m)
+	stop()
+	if err == nil {
+		err = a.registered(m, schedule)
+	}
+	reg.finish(m.Version, err)
+	return err
+}
+
+// storeContractAsync stores m like storeContract, logging its error, if any, for
+// contracts registered asynchronously.
+func (a *Application) storeContractAsync(reg *registration, m *ContractManifest, schedule Schedule, store func(*ContractManifest) error) {
+	if err := a.storeContract(reg, m, schedule, store); err != nil {
+		a.log().Error("failed to register contract", logging.Contract(m.Type), logging.Err(err))
+	}
+}
+
+// beginRegistration starts tracking a registration of m, replacing the status of any
+// previous one.
+func (a *Application) beginRegistration(m *ContractManifest) *registration {
+	reg := &registration{
+		status: registrationStatus{
+			State:   RegistrationPulling,
+			Image:   m.Image,
+			Started: time.Now().UTC(),
+		},
+		layers: make(map[string]docker.LayerProgress),
+	}
+	a.registrationMu.Lock()
+	defer a.registrationMu.Unlock()
+	if a.registrations == nil {
+		a.registrations = make(map[string]*registration)
+	}
+	a.registrations[m.Type] = reg
+	return reg
+}
+
+// registrationStatus returns the status of the latest registration of the named
+// contract since the application started, or nil if there was none.
+func (a *Application) registrationStatus(name string) *registrationStatus {
+	a.registrationMu.Lock()
+	reg, ok := a.registrations[name]
+	a.registrationMu.Unlock()
+	if !ok {
+		return nil
+	}
+	s := reg.snapshot()
+	return &s
+}
+
+// forgetRegistration discards the registration status of the named contract, such as
+// when it is deleted.
+func (a *Application) forgetRegistration(name string) {
+	a.registrationMu.Lock()
+	defer a.registrationMu.Unlock()
+	delete(a.registrations, name)
+}
+
+// writeRegistrationAccepted responds to a contract registered asynchronously, pointing
+// the client at its status.
+func writeRegistrationAccepted(w http.ResponseWriter, name string, reg *registration) {
+	w.Header().Set("Location", "/contract/"+name+"/status")
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSONResponse(w, reg.snapshot())
+}
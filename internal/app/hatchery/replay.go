@@ -0,0 +1,220 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// maxReplayTransactions is the most transactions a single replay executes.
+const maxReplayTransactions = 10000
+
+var (
+	// ErrReplayBucketNotEmpty is returned when a replay targets a heap bucket that
+	// already holds values.
+	ErrReplayBucketNotEmpty = errors.New("replay bucket is not empty")
+	// ErrReplayTooLarge is returned when a replay selects more than
+	// maxReplayTransactions transactions.
+	ErrReplayTooLarge = fmt.Errorf("a replay may not execute more than %d transactions", maxReplayTransactions)
+)
+
+// ReplayRequest selects the transactions a replay executes again. Every bound is
+// optional, and the bounds combine, so a replay with none executes every contract
+// transaction in the ledger.
+type ReplayRequest struct {
+	// From and To are the IDs of the first and last transactions replayed.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Since and Until restrict the replay to transactions created at or after
+	// Since and before Until.
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+	// Contracts restricts the replay to the transactions of these contracts.
+	Contracts []string `json:"contracts,omitempty"`
+	// Bucket is the heap bucket the output of the replayed executions is written
+	// to. It must be empty or not exist. If empty, a new bucket is named.
+	Bucket string `json:"bucket,omitempty"`
+}
+
+// ReplayResult is the outcome of replaying a single transaction.
+type ReplayResult struct {
+	ID       string `json:"id"`
+	Contract string `json:"contract"`
+	Status   string `json:"status"`
+	// Changed is whether the output differs from the transaction's content.
+	Changed bool   `json:"changed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ReplayReport describes a replay.
+type ReplayReport struct {
+	// Bucket is the heap bucket the replayed output was written to.
+	Bucket string `json:"bucket"`
+	// Replayed, Failed and Changed count the replayed transactions, those whose
+	// execution failed, and those whose output differs from the ledger's.
+	Replayed int `json:"replayed"`
+	Failed   int `json:"failed"`
+	Changed  int `json:"changed"`
+	// Results holds the outcome of each replayed transaction, in ledger order.
+	Results  []ReplayResult `json:"results"`
+	Duration string         `json:"duration"`
+}
+
+// Replay returns an HTTP handler function that executes the contract transactions
+// selected by a ReplayRequest again, in ledger order, with the payloads they were
+// originally executed with, and responds with a ReplayReport. Contracts execute at
+// their current version, and their output is written to a fresh heap bucket instead
+// of the contract heap, so state can be rebuilt, or a contract upgrade tested against
+// historical inputs, without touching the ledger or the heap contracts read.
+func (a *Application) Replay() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ReplayRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid replay: "+err.Error())
+			return
+		}
+		if isReservedBucket(req.Bucket) {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "bucket is reserved for internal use")
+			return
+		}
+		report, err := a.replay(r, &req)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, report)
+	}
+}
+
+func (a *Application) replay(r *http.Request, req *ReplayRequest) (*ReplayReport, error) {
+	start := time.Now()
+	bucket := req.Bucket
+	if bucket == "" {
+		bucket = "replay_" + uuid.New().String()
+	}
+	keys, err := a.Heap.Keys(bucket, "")
+	if err != nil && err != ErrHeapNotExist {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		return nil, ErrReplayBucketNotEmpty
+	}
+	ts, err := a.replayRange(req)
+	if err != nil {
+		return nil, err
+	}
+	logger := a.log().With(logging.F("bucket", bucket))
+	logger.Info("replaying transactions", logging.F("count", len(ts)))
+	report := &ReplayReport{Bucket: bucket, Results: make([]ReplayResult, 0, len(ts))}
+	for _, t := range ts {
+		if r.Context().Err() != nil {
+			return nil, r.Context().Err()
+		}
+		res := ReplayResult{ID: t.ID, Contract: t.InvokerContract, Status: ExecutionStatusSuccess}
+		output, err := a.replayTransaction(r, t)
+		if err == nil {
+			for _, p := range a.contractOutputPuts(t.InvokerContract, bucket, output) {
+				if err = a.Heap.Put(p.Bucket, p.Key, p.Value); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			res.Status = ExecutionStatusFailed
+			res.Error = err.Error()
+			report.Failed++
+		} else if !bytes.Equal(output, t.Content) {
+			res.Changed = true
+			report.Changed++
+		}
+		report.Replayed++
+		report.Results = append(report.Results, res)
+	}
+	report.Duration = time.Since(start).String()
+	logger.Info("replay finished", logging.F("replayed", report.Replayed), logging.F("failed", report.Failed), logging.F("changed", report.Changed))
+	return report, nil
+}
+
+// replayRange returns the contract transactions selected by req, in ledger order.
+// ErrTransactionNotExist is returned if req.From or req.To is not in the ledger.
+func (a *Application) replayRange(req *ReplayRequest) ([]*Transaction, error) {
+	contracts := make(map[string]bool, len(req.Contracts))
+	for _, c := range req.Contracts {
+		contracts[c] = true
+	}
+	var (
+		ts      []*Transaction
+		started = req.From == ""
+		ended   bool
+		tooMany bool
+	)
+	err := a.Ledger.Iterate(func(t *Transaction) bool {
+		if t.ID == req.From {
+			started = true
+		}
+		inRange := started &&
+			(req.Since.IsZero() || !t.Timestamp.Before(req.Since)) &&
+			(req.Until.IsZero() || t.Timestamp.Before(req.Until))
+		if inRange && t.InvokerContract != "" && (len(contracts) == 0 || contracts[t.InvokerContract]) {
+			if len(ts) == maxReplayTransactions {
+				tooMany = true
+				return false
+			}
+			ts = append(ts, t)
+		}
+		ended = started && t.ID == req.To
+		return !ended
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case !started || (req.To != "" && !ended):
+		return nil, ErrTransactionNotExist
+	case tooMany:
+		return nil, ErrReplayTooLarge
+	}
+	return ts, nil
+}
+
+// replayTransaction executes the contract of t with t's payload and returns its
+// output. The contract is passed t's original ID, type, timestamp and invoker, as
+// it was when t was first executed.
+func (a *Application) replayTransaction(r *http.Request, t *Transaction) ([]byte, error) {
+	contract, err := a.contract(t.InvokerContract)
+	if err != nil {
+		return nil, err
+	}
+	ctx := backend.WithInvocation(r.Context(), backend.Invocation{
+		TxnID:     t.ID,
+		TxnType:   t.Type,
+		Timestamp: t.Timestamp,
+		Invoker:   a.invoker(t.InvocationChain),
+	})
+	return a.run(ctx, t.InvokerContract, TriggerReplay, "", contract, t.Payload)
+}
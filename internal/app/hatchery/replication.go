@@ -0,0 +1,681 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/replication"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	replicationBucket = reservedBucketPrefix + "replication"
+	// promotedKey marks, in replicationBucket, that a follower has been promoted, so
+	// that it stays a primary when it restarts.
+	promotedKey = "promoted"
+
+	// replicationBuffer is how many records may be waiting to be written to a
+	// follower. Followers that fall further behind are disconnected, and catch up
+	// again when they reconnect.
+	replicationBuffer = 4096
+	// initialReplicationBackoff is how long a follower waits before reconnecting to
+	// its primary. It doubles with each failed attempt, up to maxReplicationBackoff.
+	initialReplicationBackoff = time.Second
+	maxReplicationBackoff     = time.Minute
+)
+
+// Application roles reported by GET /replication.
+const (
+	RolePrimary  = "primary"
+	RoleFollower = "follower"
+)
+
+var (
+	// ErrNotFollower is returned when promoting an application that isn't a follower.
+	ErrNotFollower = errors.New("this node is not a follower")
+	// ErrReplicationDiverged is returned to a follower whose latest transaction is
+	// not in the primary's ledger.
+	ErrReplicationDiverged = errors.New("the follower's ledger has diverged from the primary's")
+)
+
+// Follower configures an Application to replicate the ledger and contract heaps
+// of a primary, rather than executing transactions of its own.
+type Follower struct {
+	// Primary is the address of the primary's gRPC replication service, such as
+	// "primary:9090". See Application.ReplicationServer.
+	Primary string
+	// Credentials sign the calls made to the primary, if AuthID is set.
+	Credentials Credentials
+	// DialOptions are used to connect to the primary. If nil, the connection is
+	// not encrypted.
+	DialOptions []grpc.DialOption
+}
+
+// ReplicationStatus is the response of GET /replication.
+type ReplicationStatus struct {
+	// Role is RolePrimary or RoleFollower.
+	Role string `json:"role"`
+	// Primary is the address of the primary a follower replicates.
+	Primary string `json:"primary,omitempty"`
+	// Connected is whether a follower is receiving its primary's stream.
+	Connected bool `json:"connected"`
+	// LastTransaction is the ID of the latest transaction a follower applied.
+	LastTransaction string `json:"last_transaction,omitempty"`
+	// Error describes why a follower last lost its connection to the primary.
+	Error string `json:"error,omitempty"`
+	// Followers are the followers connected to this node.
+	Followers []ReplicaStatus `json:"followers"`
+}
+
+// ReplicaStatus describes a follower connected to the replication service.
+type ReplicaStatus struct {
+	Addr      string    `json:"addr"`
+	Connected time.Time `json:"connected"`
+	// LastTransaction is the ID of the latest transaction sent to the follower.
+	LastTransaction string `json:"last_transaction,omitempty"`
+}
+
+// replica is a follower connected to the replication service.
+type replica struct {
+	addr      string
+	connected time.Time
+	records   chan *replication.Record
+	done      chan struct{}
+	once      sync.Once
+
+	mu      sync.Mutex
+	lastTxn string
+}
+
+// disconnect makes the stream of r end. It is safe to call more than once.
+func (r *replica) disconnect() {
+	r.once.Do(func() { close(r.done) })
+}
+
+func (r *replica) sent(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastTxn = id
+}
+
+// send queues rec for the follower, disconnecting it if its buffer is full.
+func (r *replica) send(rec *replication.Record) {
+	select {
+	case r.records <- rec:
+	default:
+		r.disconnect()
+	}
+}
+
+// replicate queues the record of a transaction or heap event from the event bus.
+// Reserved buckets are not replicated.
+func (r *replica) replicate(e *Event) {
+	switch e.Type {
+	case EventTransaction:
+		r.send(transactionRecord(e.Transaction))
+	case EventHeapWrite:
+		if !isReservedBucket(e.Bucket) {
+			r.send(heapWriteRecord(e.Bucket, e.Key, e.Value))
+		}
+	case EventHeapDelete:
+		if !isReservedBucket(e.Bucket) {
+			r.send(&replication.Record{Record: &replication.Record_HeapDelete{
+				HeapDelete: &replication.HeapDelete{Bucket: e.Bucket, Key: e.Key},
+			}})
+		}
+	}
+}
+
+// ReplicationServer returns a gRPC server that serves the replication service to
+// followers, for applications that serve it themselves. Run serves it on
+// ReplicationAddr. If RequireAuth is set, calls must be signed like API requests.
+// See Follower.
+func (a *Application) ReplicationServer() *grpc.Server {
+	srv := grpc.NewServer(grpc.StreamInterceptor(a.authenticatedStream))
+	replication.RegisterReplicationServer(srv, &replicationServer{app: a})
+	return srv
+}
+
+// authenticatedStream is a gRPC interceptor that verifies the signature of a call
+// to the replication service, like authenticated. The signature is carried in the
+// call's metadata, under the names of the headers that carry it in API requests,
+// and covers the POST method, the call's full method name and its content type.
+func (a *Application) authenticatedStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !a.RequireAuth {
+		return handler(srv, ss)
+	}
+	r, err := http.NewRequest(http.MethodPost, info.FullMethod, http.NoBody)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	md, _ := metadata.FromIncomingContext(ss.Context())
+	for _, name := range []string{"Authorization", "dragonchain", "timestamp", "Content-Type"} {
+		if v := md.Get(name); len(v) > 0 {
+			r.Header.Set(name, v[0])
+		}
+	}
+	if err := a.verifySignature(r); err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return handler(srv, ss)
+}
+
+// replicationServer implements the replication service for an Application.
+type replicationServer struct {
+	replication.UnimplementedReplicationServer
+	app *Application
+}
+
+// Stream streams the ledger and contract heaps to a follower. It begins with a
+// snapshot of every contract heap, followed by the transactions appended after the
+// one whose ID is req.After, or the whole ledger if it is empty. From then on, every
+// transaction and heap change is sent as it happens. If req.After is not in the
+// ledger, the follower has diverged and the call fails. Followers that can't keep up
+// are disconnected.
+func (s *replicationServer) Stream(req *replication.StreamRequest, stream replication.Replication_StreamServer) error {
+	a := s.app
+	if req.After != "" {
+		if _, err := a.Ledger.Find(req.After); err == ErrTransactionNotExist {
+			return status.Error(codes.FailedPrecondition, ErrReplicationDiverged.Error())
+		} else if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	// Changes are buffered from before the snapshot is taken, so that none are
+	// missed. Those already covered by the snapshot are applied again, which is
+	// harmless, and transactions already sent are skipped.
+	rep := &replica{
+		connected: time.Now().UTC(),
+		records:   make(chan *replication.Record, replicationBuffer),
+		done:      make(chan struct{}),
+	}
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		rep.addr = p.Addr.String()
+	}
+	unsubscribe := a.Subscribe(rep.replicate, EventTransaction, EventHeapWrite, EventHeapDelete)
+	defer unsubscribe()
+	a.addReplica(rep)
+	defer a.removeReplica(rep)
+
+	sent, err := a.sendSnapshot(stream, rep, req.After)
+	if err != nil {
+		a.log().Error("failed to send replication snapshot", logging.F("follower", rep.addr), logging.Err(err))
+		return err
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var rec *replication.Record
+		select {
+		case rec = <-rep.records:
+			if t := rec.GetTransaction(); t != nil {
+				if sent[t.Id] {
+					continue
+				}
+				rep.sent(t.Id)
+			}
+		case <-keepAlive.C:
+			rec = &replication.Record{Record: &replication.Record_Ping{Ping: &replication.Ping{}}}
+		case <-rep.done:
+			return status.Error(codes.Unavailable, "follower disconnected")
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+		if err := stream.Send(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// sendSnapshot sends the contract heaps on stream, followed by the transactions
+// appended after the one with the ID after. It returns the IDs of the transactions
+// it sent.
+func (a *Application) sendSnapshot(stream replication.Replication_StreamServer, rep *replica, after string) (map[string]bool, error) {
+	if err := stream.Send(&replication.Record{Record: &replication.Record_ResetHeaps{ResetHeaps: &replication.Reset{}}}); err != nil {
+		return nil, err
+	}
+	buckets, err := a.Heap.Buckets()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range buckets {
+		if isReservedBucket(b) {
+			continue
+		}
+		// The bucket is read in batches, so that a slow follower doesn't hold a read
+		// transaction open. See ExportSCHeap.
+		start := ""
+		for {
+			batch, err := a.exportBatch(b, start)
+			if err != nil && err != ErrHeapNotExist {
+				return nil, err
+			}
+			for _, e := range batch {
+				v, err := e.value()
+				if err != nil {
+					return nil, err
+				}
+				if err := stream.Send(heapWriteRecord(b, e.Key, v)); err != nil {
+					return nil, err
+				}
+			}
+			if len(batch) < exportBatchSize {
+				break
+			}
+			start = batch[len(batch)-1].Key + "\x00"
+		}
+	}
+
+	var txns []*Transaction
+	found := after == ""
+	err = a.Ledger.Iterate(func(t *Transaction) bool {
+		if found {
+			txns = append(txns, t)
+		} else if t.ID == after {
+			found = true
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sent := make(map[string]bool, len(txns))
+	for _, t := range txns {
+		if err := stream.Send(transactionRecord(t)); err != nil {
+			return nil, err
+		}
+		sent[t.ID] = true
+		rep.sent(t.ID)
+	}
+	return sent, nil
+}
+
+func heapWriteRecord(bucket, key string, value []byte) *replication.Record {
+	return &replication.Record{Record: &replication.Record_HeapWrite{
+		HeapWrite: &replication.HeapWrite{Bucket: bucket, Key: key, Value: value},
+	}}
+}
+
+func transactionRecord(t *Transaction) *replication.Record {
+	return &replication.Record{Record: &replication.Record_Transaction{Transaction: transactionToProto(t)}}
+}
+
+// transactionToProto converts t to its replication message.
+func transactionToProto(t *Transaction) *replication.Transaction {
+	return &replication.Transaction{
+		Id:              t.ID,
+		Type:            t.Type,
+		InvokerContract: t.InvokerContract,
+		Status:          string(t.Status),
+		Content:         t.Content,
+		Timestamp:       timestamppb.New(t.Timestamp),
+		PrevHash:        t.PrevHash,
+		Hash:            t.Hash,
+		HashVersion:     int64(t.HashVersion),
+		InvocationChain: t.InvocationChain,
+		Payload:         t.Payload,
+		Signer:          t.Signer,
+		Attempts:        int64(t.Attempts),
+		LastError:       t.LastError,
+		OutputRef:       t.OutputRef,
+		OutputSize:      t.OutputSize,
+	}
+}
+
+// transactionFromProto converts a replication message to a Transaction.
+func transactionFromProto(t *replication.Transaction) *Transaction {
+	return &Transaction{
+		ID:              t.Id,
+		Type:            t.Type,
+		InvokerContract: t.InvokerContract,
+		Status:          TransactionStatus(t.Status),
+		Content:         t.Content,
+		Timestamp:       t.Timestamp.AsTime(),
+		PrevHash:        t.PrevHash,
+		Hash:            t.Hash,
+		HashVersion:     int(t.HashVersion),
+		InvocationChain: t.InvocationChain,
+		Payload:         t.Payload,
+		Signer:          t.Signer,
+		Attempts:        int(t.Attempts),
+		LastError:       t.LastError,
+		OutputRef:       t.OutputRef,
+		OutputSize:      t.OutputSize,
+	}
+}
+
+func (a *Application) addReplica(r *replica) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	if a.replicas == nil {
+		a.replicas = make(map[*replica]struct{})
+	}
+	a.replicas[r] = struct{}{}
+}
+
+func (a *Application) removeReplica(r *replica) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	delete(a.replicas, r)
+}
+
+// GetReplication returns an HTTP handler function that responds with the
+// application's ReplicationStatus.
+func (a *Application) GetReplication() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, a.ReplicationStatus())
+	}
+}
+
+// ReplicationStatus returns the role of the application and the state of its
+// replication.
+func (a *Application) ReplicationStatus() *ReplicationStatus {
+	status := &ReplicationStatus{Role: RolePrimary, Followers: []ReplicaStatus{}}
+	a.replMu.Lock()
+	if a.following() {
+		status.Role = RoleFollower
+		status.Primary = a.Follow.Primary
+		status.Connected = a.replConnected
+		status.LastTransaction = a.replLastTxn
+		status.Error = a.replErr
+	}
+	a.replMu.Unlock()
+
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	for rep := range a.replicas {
+		rep.mu.Lock()
+		status.Followers = append(status.Followers, ReplicaStatus{Addr: rep.addr, Connected: rep.connected, LastTransaction: rep.lastTxn})
+		rep.mu.Unlock()
+	}
+	return status
+}
+
+// Promote returns an HTTP handler function that promotes a follower to a primary.
+// It stops replicating, starts executing transactions and accepting writes, and
+// responds with the new ReplicationStatus. The promotion is stored in the Heap, so
+// the application remains a primary when it restarts.
+func (a *Application) Promote() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := a.promote(); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, a.ReplicationStatus())
+	}
+}
+
+func (a *Application) promote() error {
+	a.replMu.Lock()
+	if !a.following() {
+		a.replMu.Unlock()
+		return ErrNotFollower
+	}
+	a.promoted = true
+	a.replMu.Unlock()
+	a.stopFollowing()
+	stamp := time.Now().UTC().Format(time.RFC3339Nano)
+	if err := a.Heap.Put(replicationBucket, promotedKey, []byte(stamp)); err != nil {
+		return fmt.Errorf("failed to store promotion: %s", err)
+	}
+	a.log().Info("promoted to primary", logging.F("primary", a.Follow.Primary))
+	a.startPrimary()
+	return nil
+}
+
+// following reports whether the application is a follower that hasn't been
+// promoted. a.replMu must be held.
+func (a *Application) following() bool {
+	return a.Follow != nil && !a.promoted
+}
+
+// readOnly is middleware that rejects every request but reads, and promotion, while
+// the application is a follower.
+func (a *Application) readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.replMu.Lock()
+		following := a.following()
+		a.replMu.Unlock()
+		if following && r.Method != http.MethodGet && r.Method != http.MethodHead && r.URL.Path != "/replication/promote" {
+			writeError(w, http.StatusForbidden, ErrCodeReadOnly, "this node is a read-only follower of "+a.Follow.Primary)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startFollowing starts replicating the primary if the application is a follower
+// that hasn't been promoted, and reports whether it did.
+func (a *Application) startFollowing() bool {
+	if a.Follow == nil {
+		return false
+	}
+	if _, err := a.Heap.Get(replicationBucket, promotedKey); err == nil {
+		a.replMu.Lock()
+		a.promoted = true
+		a.replMu.Unlock()
+		return false
+	}
+	a.replMu.Lock()
+	defer a.replMu.Unlock()
+	if !a.following() || a.replCancel != nil {
+		return a.following()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.replCancel = cancel
+	a.replStopped = make(chan struct{})
+	go a.follow(ctx, a.replStopped)
+	return true
+}
+
+// stopFollowing stops replicating the primary, and waits for the record being
+// applied, if any.
+func (a *Application) stopFollowing() {
+	a.replMu.Lock()
+	cancel, stopped := a.replCancel, a.replStopped
+	a.replCancel = nil
+	a.replMu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-stopped
+}
+
+// follow replicates the primary, reconnecting with backoff whenever the stream
+// ends, until ctx is cancelled.
+func (a *Application) follow(ctx context.Context, stopped chan struct{}) {
+	defer close(stopped)
+	backoff := initialReplicationBackoff
+	for {
+		err := a.replicate(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		a.replMu.Lock()
+		connected := a.replConnected
+		a.replConnected = false
+		a.replErr = err.Error()
+		a.replMu.Unlock()
+		a.log().Error("lost connection to primary", logging.F("primary", a.Follow.Primary), logging.Err(err))
+		if connected {
+			backoff = initialReplicationBackoff
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		if backoff *= 2; backoff > maxReplicationBackoff {
+			backoff = maxReplicationBackoff
+		}
+	}
+}
+
+// replicate calls the primary's replication service, from the latest transaction
+// in the ledger, and applies the records it streams until the stream ends.
+func (a *Application) replicate(ctx context.Context) error {
+	var last *Transaction
+	err := a.Ledger.Iterate(func(t *Transaction) bool {
+		last = t
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read ledger: %s", err)
+	}
+	opts := a.Follow.DialOptions
+	if opts == nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(a.Follow.Primary, opts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if creds := a.Follow.Credentials; creds.AuthID != "" {
+		timestamp := time.Now().UTC().Format(dragonchainTimestampLayout)
+		sig := SignRequest(creds.AuthKey, http.MethodPost, replication.Replication_Stream_FullMethodName, creds.DragonChainID, timestamp, "application/grpc", nil)
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			"authorization", hmacScheme+" "+creds.AuthID+":"+sig,
+			"dragonchain", creds.DragonChainID,
+			"timestamp", timestamp,
+		)
+	}
+	req := &replication.StreamRequest{}
+	if last != nil {
+		req.After = last.ID
+	}
+	stream, err := replication.NewReplicationClient(conn).Stream(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rec, err := stream.Recv()
+		if err == io.EOF {
+			return errors.New("primary closed the stream")
+		} else if err != nil {
+			if status.Code(err) == codes.FailedPrecondition {
+				return ErrReplicationDiverged
+			}
+			return err
+		}
+		// The follower is connected once the primary starts streaming.
+		if rec.GetResetHeaps() != nil {
+			a.replMu.Lock()
+			a.replConnected = true
+			a.replErr = ""
+			a.replMu.Unlock()
+			a.log().Info("replicating primary", logging.F("primary", a.Follow.Primary))
+		}
+		if err := a.applyRecord(rec, &last); err != nil {
+			return err
+		}
+	}
+}
+
+// applyRecord applies a record of the primary's replication stream. last is the
+// latest transaction in the ledger, which is updated as transactions are appended.
+// Applied changes are published on the event bus, so that the follower's stream
+// clients and its own followers see them.
+func (a *Application) applyRecord(rec *replication.Record, last **Transaction) error {
+	switch r := rec.Record.(type) {
+	case *replication.Record_ResetHeaps:
+		buckets, err := a.Heap.Buckets()
+		if err != nil {
+			return err
+		}
+		for _, b := range buckets {
+			if isReservedBucket(b) {
+				continue
+			}
+			if err := a.Heap.DeleteBucket(b); err != nil && err != ErrHeapNotExist {
+				return err
+			}
+			a.heapDeleted(b, "")
+		}
+	case *replication.Record_HeapWrite:
+		w := r.HeapWrite
+		if err := a.Heap.Put(w.Bucket, w.Key, w.Value); err != nil {
+			return err
+		}
+		a.heapWritten(w.Bucket, HeapPut{Bucket: w.Bucket, Key: w.Key, Value: w.Value})
+	case *replication.Record_HeapDelete:
+		d := r.HeapDelete
+		var err error
+		if d.Key == "" {
+			err = a.Heap.DeleteBucket(d.Bucket)
+		} else {
+			err = a.Heap.Delete(d.Bucket, d.Key)
+		}
+		if err != nil && err != ErrHeapNotExist {
+			return err
+		}
+		a.heapDeleted(d.Bucket, d.Key)
+	case *replication.Record_Transaction:
+		if r.Transaction == nil {
+			return errors.New("transaction record without a transaction")
+		}
+		t := transactionFromProto(r.Transaction)
+		prevHash := ""
+		if *last != nil {
+			prevHash = (*last).Hash
+		}
+		if t.PrevHash != prevHash {
+			return fmt.Errorf("transaction %s does not follow %s: %s", t.ID, prevHash, ErrReplicationDiverged)
+		}
+		hash := t.Hash
+		if err := a.Ledger.Append(t); err != nil {
+			return fmt.Errorf("failed to append transaction %s: %s", t.ID, err)
+		}
+		if t.Hash != hash {
+			return fmt.Errorf("transaction %s hashed to %s rather than %s", t.ID, t.Hash, hash)
+		}
+		*last = t
+		a.replMu.Lock()
+		a.replLastTxn = t.ID
+		a.replMu.Unlock()
+		a.bus().Publish(&Event{Type: EventTransaction, Contract: t.Type, Transaction: t})
+	case *replication.Record_Ping:
+	default:
+		return fmt.Errorf("unknown replication record %T", rec.Record)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTransactionProtoRoundTrip(t *testing.T) {
+	want := &Transaction{
+		ID:              "id",
+		Type:            "contract",
+		InvokerContract: "invoker",
+		Status:          TransactionStatusSuccess,
+		Content:         []byte("content"),
+		Timestamp:       time.Date(2026, 10, 17, 12, 0, 0, 123456789, time.UTC),
+		PrevHash:        "prev",
+		Hash:            "hash",
+		HashVersion:     1,
+		InvocationChain: []string{"first", "second"},
+		Payload:         []byte("payload"),
+		Signer:          "signer",
+		Attempts:        2,
+		LastError:       "error",
+		OutputRef:       "ref",
+		OutputSize:      42,
+	}
+	got := transactionFromProto(transactionToProto(want))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+// newTestReplicationNode returns an application whose heap and ledger are backed
+// by a temporary BoltDB file.
+func newTestReplicationNode(t *testing.T, entries map[string]string) *Application {
+	heap := newTestBoltDBHeap(t, entries)
+	return &Application{Heap: heap, Ledger: &BoltDBLedger{Heap: heap}}
+}
+
+func TestReplicationStream(t *testing.T) {
+	primary := newTestReplicationNode(t, map[string]string{"key": "value"})
+	first := NewTransaction([]byte("first"))
+	if err := primary.Ledger.Append(first); err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := primary.ReplicationServer()
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	follower := newTestReplicationNode(t, nil)
+	follower.Follow = &Follower{Primary: lis.Addr().String()}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- follower.replicate(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// waitFor waits for the follower to append the transaction with the ID.
+	waitFor := func(want *Transaction) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			got, err := follower.Ledger.Find(want.ID)
+			if err == nil {
+				if got.Hash != want.Hash || !bytes.Equal(got.Content, want.Content) {
+					t.Fatalf("replicated transaction = %+v, want %+v", got, want)
+				}
+				return
+			}
+			select {
+			case err := <-done:
+				t.Fatalf("replication stopped: %v", err)
+			default:
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("transaction %s was not replicated: %s", want.ID, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor(first)
+	if v, err := follower.Heap.Get("bucket", "key"); err != nil || string(v) != "value" {
+		t.Errorf("replicated heap value = %q, %v, want %q", v, err, "value")
+	}
+
+	second := NewTransaction([]byte("second"))
+	if err := primary.Ledger.Append(second); err != nil {
+		t.Fatal(err)
+	}
+	primary.bus().Publish(&Event{Type: EventTransaction, Transaction: second})
+	waitFor(second)
+}
@@ -0,0 +1,369 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// DefaultRetentionInterval is how often the ledger is pruned when its
+// RetentionPolicy doesn't say.
+const DefaultRetentionInterval = time.Hour
+
+// maxPruneRuns is how many of the latest pruning runs are remembered.
+const maxPruneRuns = 20
+
+// Pruning triggers, as recorded in a PruneRun.
+const (
+	PruneTriggerSchedule = "schedule"
+	PruneTriggerAPI      = "api"
+)
+
+// ErrPruneUnsupported is returned when pruning a ledger that doesn't implement
+// backend.Pruner.
+var ErrPruneUnsupported = errors.New("the ledger does not support pruning")
+
+// RetentionPolicy determines which transactions are pruned from the ledger. The
+// latest transaction is never pruned, since the next one appended links to it.
+type RetentionPolicy struct {
+	// MaxAge prunes the transactions older than it. If zero, transactions are
+	// not pruned for their age.
+	MaxAge time.Duration
+	// MaxEntries prunes the oldest transactions beyond the latest MaxEntries. If
+	// zero, transactions are not pruned for their number.
+	MaxEntries int
+	// Interval is how often the ledger is pruned in the background. If zero,
+	// DefaultRetentionInterval is used. If negative, the ledger is only pruned
+	// through the API.
+	Interval time.Duration
+	// Archiver, if set, stores the transactions each run prunes before they are
+	// removed. If they can't be archived, nothing is pruned.
+	Archiver Archiver
+}
+
+// PruneRun describes a run of pruning.
+type PruneRun struct {
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+	// Trigger is PruneTriggerSchedule or PruneTriggerAPI.
+	Trigger string `json:"trigger"`
+	// DryRun is set if the run only reported what it would prune.
+	DryRun bool `json:"dry_run,omitempty"`
+	// Pruned is how many transactions were pruned, or would have been.
+	Pruned int `json:"pruned"`
+	// FirstID and LastID are the first and last transactions pruned, and
+	// Through is the timestamp of the last.
+	FirstID string    `json:"first_id,omitempty"`
+	LastID  string    `json:"last_id,omitempty"`
+	Through time.Time `json:"through"`
+	// Archive is where the pruned transactions were archived.
+	Archive string `json:"archive,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PruneStatus describes how the ledger has been pruned.
+type PruneStatus struct {
+	// MaxAge, MaxEntries and Interval are the application's RetentionPolicy.
+	MaxAge     string `json:"max_age,omitempty"`
+	MaxEntries int    `json:"max_entries,omitempty"`
+	Interval   string `json:"interval,omitempty"`
+	Archive    bool   `json:"archive"`
+	// Pruned is how many transactions have been pruned in all, and AnchorHash
+	// the hash of the last one, which the oldest remaining transaction links to.
+	Pruned     int    `json:"pruned"`
+	AnchorHash string `json:"anchor_hash,omitempty"`
+	// Runs are the latest runs since the application started, newest first.
+	Runs []PruneRun `json:"runs"`
+}
+
+// PruneLedger returns an HTTP handler function that prunes the ledger right away
+// and responds with the PruneRun. The optional max_age and max_entries query
+// parameters override the RetentionPolicy, and dry_run=true reports what would be
+// pruned without pruning or archiving anything. It is an administrative route.
+func (a *Application) PruneLedger() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var policy RetentionPolicy
+		if a.Retention != nil {
+			policy = *a.Retention
+		}
+		q := r.URL.Query()
+		if v := q.Get("max_age"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "max_age must be a non-negative duration")
+				return
+			}
+			policy.MaxAge = d
+		}
+		n, err := queryInt(r, "max_entries", policy.MaxEntries)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "max_entries must be a non-negative integer")
+			return
+		}
+		policy.MaxEntries = n
+		dryRun, _ := strconv.ParseBool(q.Get("dry_run"))
+		run, err := a.pruneLedger(r.Context(), &policy, PruneTriggerAPI, dryRun)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, run)
+	}
+}
+
+// GetPruneStatus returns an HTTP handler function that responds with the ledger's
+// PruneStatus. It is an administrative route.
+func (a *Application) GetPruneStatus() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := a.Ledger.(backend.Pruner)
+		if !ok {
+			writeErrorFrom(w, ErrPruneUnsupported)
+			return
+		}
+		hash, count, err := p.Pruned()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		status := PruneStatus{Pruned: count, AnchorHash: hash, Runs: []PruneRun{}}
+		if policy := a.Retention; policy != nil {
+			if policy.MaxAge > 0 {
+				status.MaxAge = policy.MaxAge.String()
+			}
+			status.MaxEntries = policy.MaxEntries
+			if policy.Interval >= 0 {
+				status.Interval = a.retentionInterval().String()
+			}
+			status.Archive = policy.Archiver != nil
+		}
+		a.pruneRunsMu.Lock()
+		for i := len(a.pruneRuns) - 1; i >= 0; i-- {
+			status.Runs = append(status.Runs, a.pruneRuns[i])
+		}
+		a.pruneRunsMu.Unlock()
+		writeJSONResponse(w, status)
+	}
+}
+
+// pruneLedger prunes the transactions policy selects, after archiving them if the
+// policy has an Archiver, and records the run. Only one run happens at a time. An
+// error is returned, and nothing is pruned, if the ledger can't be pruned or read.
+// A run that fails to archive or prune is recorded, and returned, with its Error
+// set.
+func (a *Application) pruneLedger(ctx context.Context, policy *RetentionPolicy, trigger string, dryRun bool) (*PruneRun, error) {
+	p, ok := a.Ledger.(backend.Pruner)
+	if !ok {
+		return nil, ErrPruneUnsupported
+	}
+	a.pruneMu.Lock()
+	defer a.pruneMu.Unlock()
+	run := &PruneRun{Started: time.Now().UTC(), Trigger: trigger, DryRun: dryRun}
+	n, err := a.pruneCount(policy)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		err = a.prune(ctx, p, policy, n, run)
+	}
+	run.Duration = time.Since(run.Started).String()
+	if err != nil {
+		run.Error = err.Error()
+		a.log().Error("failed to prune ledger", logging.F("trigger", trigger), logging.Err(err))
+	} else if run.Pruned > 0 && !dryRun {
+		a.log().Info("ledger pruned", logging.F("trigger", trigger), logging.F("pruned", run.Pruned),
+			logging.F("through", run.LastID), logging.F("archive", run.Archive))
+	}
+	a.pruneRunsMu.Lock()
+	a.pruneRuns = append(a.pruneRuns, *run)
+	if len(a.pruneRuns) > maxPruneRuns {
+		a.pruneRuns = a.pruneRuns[len(a.pruneRuns)-maxPruneRuns:]
+	}
+	a.pruneRunsMu.Unlock()
+	return run, nil
+}
+
+// pruneCount returns how many of the oldest transactions policy prunes: those
+// older than MaxAge, or beyond the latest MaxEntries, whichever is more, but never
+// the latest transaction. Transactions are pruned in ledger order, so a transaction
+// older than MaxAge that follows a newer one is kept.
+func (a *Application) pruneCount(policy *RetentionPolicy) (int, error) {
+	if policy.MaxAge <= 0 && policy.MaxEntries <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-policy.MaxAge)
+	var total, expired int
+	err := a.Ledger.Iterate(func(t *Transaction) bool {
+		if policy.MaxAge > 0 && expired == total && t.Timestamp.Before(cutoff) {
+			expired++
+		}
+		total++
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	n := expired
+	if policy.MaxEntries > 0 && total-policy.MaxEntries > n {
+		n = total - policy.MaxEntries
+	}
+	if n >= total {
+		n = total - 1
+	}
+	return n, nil
+}
+
+// prune archives the first n transactions in the ledger, if policy has an Archiver,
+// and then prunes them, filling in run. Nothing is changed if run is a dry run.
+func (a *Application) prune(ctx context.Context, p backend.Pruner, policy *RetentionPolicy, n int, run *PruneRun) error {
+	// The archive is written to a temporary file first, rather than streamed to
+	// the Archiver, so that the ledger isn't held open while it is uploaded.
+	var (
+		archive *os.File
+		zw      *gzip.Writer
+		enc     *json.Encoder
+	)
+	if policy.Archiver != nil && !run.DryRun {
+		var err error
+		if archive, err = ioutil.TempFile("", "hatchery-prune-"); err != nil {
+			return err
+		}
+		defer os.Remove(archive.Name())
+		defer archive.Close()
+		zw = gzip.NewWriter(archive)
+		enc = json.NewEncoder(zw)
+	}
+	var (
+		last *Transaction
+		werr error
+	)
+	err := a.Ledger.Iterate(func(t *Transaction) bool {
+		if run.FirstID == "" {
+			run.FirstID = t.ID
+		}
+		if enc != nil {
+			if werr = enc.Encode(&LedgerEntry{Transaction: t, Content: t.Content}); werr != nil {
+				return false
+			}
+		}
+		last = t
+		run.Pruned++
+		return run.Pruned < n
+	})
+	if err == nil {
+		err = werr
+	}
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the transactions to prune: %s", err)
+	}
+	run.LastID, run.Through = last.ID, last.Timestamp
+	if run.DryRun {
+		return nil
+	}
+	if archive != nil {
+		if _, err := archive.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("ledger-%s-%s.jsonl.gz", last.Timestamp.UTC().Format("20060102T150405Z"), last.ID)
+		if a.DragonChainID != "" {
+			name = a.DragonChainID + "-" + name
+		}
+		if run.Archive, err = policy.Archiver.Archive(ctx, name, archive); err != nil {
+			run.Pruned = 0
+			return fmt.Errorf("failed to archive the transactions to prune: %s", err)
+		}
+	}
+	pruned, err := p.Prune(last.ID)
+	run.Pruned = pruned
+	return err
+}
+
+// retentionInterval returns how often the ledger is pruned in the background.
+func (a *Application) retentionInterval() time.Duration {
+	if a.Retention == nil || a.Retention.Interval == 0 {
+		return DefaultRetentionInterval
+	}
+	return a.Retention.Interval
+}
+
+// startRetention begins pruning the ledger every retention interval. It does nothing
+// if the application has no RetentionPolicy or its Interval is negative. In a
+// cluster, only the leader prunes.
+func (a *Application) startRetention() {
+	if a.Retention == nil || a.Retention.Interval < 0 {
+		return
+	}
+	a.retentionOnce.Do(func() {
+		a.retentionMu.Lock()
+		a.retentionDone = make(chan struct{})
+		a.retentionMu.Unlock()
+		go a.pruneLedgerPeriodically(a.retentionDone)
+	})
+}
+
+// stopRetention stops pruning the ledger. A run that is underway finishes first.
+func (a *Application) stopRetention() {
+	a.retentionMu.Lock()
+	if a.retentionDone != nil {
+		select {
+		case <-a.retentionDone:
+		default:
+			close(a.retentionDone)
+		}
+	}
+	a.retentionMu.Unlock()
+	a.pruneMu.Lock()
+	a.pruneMu.Unlock()
+}
+
+func (a *Application) pruneLedgerPeriodically(done chan struct{}) {
+	ticker := time.NewTicker(a.retentionInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !a.leading() {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+			if _, err := a.pruneLedger(ctx, a.Retention, PruneTriggerSchedule, false); err != nil {
+				a.log().Error("failed to prune ledger", logging.Err(err))
+			}
+			cancel()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,349 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/summerplaygames/hatchery/internal/app/docker"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/process"
+	"github.com/summerplaygames/hatchery/internal/app/wasm"
+)
+
+// Built-in contract runtimes.
+const (
+	// RuntimeDocker executes contracts in Docker containers. It is the default.
+	RuntimeDocker = "docker"
+	// RuntimeExec executes contracts as local processes, without Docker. The
+	// manifest's Cmd is the path of the executable and Image is unused.
+	RuntimeExec = "exec"
+	// RuntimeWASM executes contracts as WebAssembly modules with wazero, without
+	// Docker. The manifest's Cmd is the path of the module's .wasm file and Image
+	// is unused.
+	RuntimeWASM = "wasm"
+)
+
+// Runtime executes smart contracts of a particular kind. The runtime used for a
+// contract is selected by the Runtime field of its manifest. Additional runtimes
+// can be added with RegisterRuntime.
+type Runtime interface {
+	// Prepare readies the runtime to execute the contract described by the
+	// manifest, for example by pulling its image. It may record details, such
+	// as ImageDigest, in the manifest. It is called when a contract is stored,
+	// with the manifest's Auth in plaintext.
+	Prepare(manifest *ContractManifest) error
+	// Contract returns a Contract that executes the contract described by the
+	// manifest with the given environment and logger.
+	Contract(manifest *ContractManifest, env map[string]string, logger logging.Logger) (Contract, error)
+	// Remove releases any resources Prepare acquired for the contract, such
+	// as its image.
+	Remove(manifest *ContractManifest) error
+}
+
+// Policies for images whose tag has drifted from the digest pinned in a manifest.
+const (
+	// DigestPolicyRepin points the image's tag back at the pinned digest before
+	// executing the contract.
+	DigestPolicyRepin = "repin"
+	// DigestPolicyRefuse fails executions of the contract until it is stored
+	// again.
+	DigestPolicyRefuse = "refuse"
+)
+
+// Verifier is implemented by Runtimes that can check, before each execution,
+// that a contract still runs what was prepared when it was stored.
+type Verifier interface {
+	// Verify applies the manifest's DigestPolicy if its image no longer matches
+	// ImageDigest. It is called with the manifest's Auth in plaintext.
+	Verify(manifest *ContractManifest, logger logging.Logger) error
+}
+
+// ImageDriftError is returned instead of executing a contract whose image no
+// longer matches its pinned digest, when its DigestPolicy is DigestPolicyRefuse.
+type ImageDriftError struct {
+	Contract string
+	Image    string
+	Pinned   string
+	Current  string
+}
+
+func (e *ImageDriftError) Error() string {
+	return fmt.Sprintf("image %s of contract %s no longer matches its pinned digest %s", e.Image, e.Contract, e.Pinned)
+}
+
+var (
+	runtimesMu sync.RWMutex
+	runtimes   = map[string]Runtime{
+		RuntimeDocker: dockerRuntime{},
+		RuntimeExec:   execRuntime{},
+		RuntimeWASM:   wasmRuntime{},
+	}
+)
+
+// RegisterRuntime makes a Runtime available under the given name. It panics if
+// a runtime with the same name is already registered.
+func RegisterRuntime(name string, r Runtime) {
+	runtimesMu.Lock()
+	defer runtimesMu.Unlock()
+	if _, ok := runtimes[name]; ok {
+		panic("hatchery: runtime " + name + " is already registered")
+	}
+	runtimes[name] = r
+}
+
+// LookupRuntime returns the Runtime registered under the given name. An empty
+// name selects RuntimeDocker. ErrRuntimeNotExist is returned if no such runtime
+// is registered.
+func LookupRuntime(name string) (Runtime, error) {
+	if name == "" {
+		name = RuntimeDocker
+	}
+	runtimesMu.RLock()
+	defer runtimesMu.RUnlock()
+	r, ok := runtimes[name]
+	if !ok {
+		return nil, ErrRuntimeNotExist
+	}
+	return r, nil
+}
+
+// dockerRuntime executes contracts in Docker containers.
+type dockerRuntime struct{}
+
+func (dockerRuntime) Prepare(manifest *ContractManifest) error {
+	if manifest.Source != "" {
+		tag := docker.BuildTag(manifest.Type, manifest.Version)
+		if err := docker.BuildImage(manifest.Source, manifest.Dockerfile, tag); err != nil {
+			return err
+		}
+		digest, err := docker.ImageDigest(tag)
+		if err != nil {
+			return fmt.Errorf("failed to inspect image: %s", err)
+		}
+		manifest.Image, manifest.ImageDigest = tag, digest
+		return nil
+	}
+	var auth *docker.Auth
+	if manifest.Auth != "" {
+		var err error
+		if auth, err = docker.ParseAuth(manifest.Auth, manifest.Image); err != nil {
+			return fmt.Errorf("invalid registry auth: %s", err)
+		}
+	}
+	if err := docker.PullImage(manifest.Image, auth); err != nil {
+		if _, ok := err.(*docker.PullError); ok {
+			return err
+		}
+		return fmt.Errorf("failed to pull image: %s", err)
+	}
+	digest, err := docker.ImageDigest(manifest.Image)
+	if err != nil {
+		return fmt.Errorf("failed to inspect image: %s", err)
+	}
+	manifest.ImageDigest = digest
+	return nil
+}
+
+func (dockerRuntime) CheckAvailable(ctx context.Context, manifest *ContractManifest) error {
+	if manifest.Source != "" {
+		// The image is built when the contract is stored.
+		return nil
+	}
+	var auth *docker.Auth
+	if manifest.Auth != "" {
+		var err error
+		if auth, err = docker.ParseAuth(manifest.Auth, manifest.Image); err != nil {
+			return fmt.Errorf("invalid registry auth: %s", err)
+		}
+	}
+	return docker.CheckImage(ctx, manifest.Image, auth)
+}
+
+func (dockerRuntime) ValidateManifest(manifest *ContractManifest) []Violation {
+	var violations []Violation
+	switch {
+	case manifest.Source != "":
+		// Image is replaced by the tag of the built image.
+		if _, _, err := docker.ParseSource(manifest.Source); err != nil {
+			violations = append(violations, Violation{Field: "Source", Message: err.Error()})
+		}
+		if err := docker.ValidateDockerfile(manifest.Dockerfile); manifest.Dockerfile != "" && err != nil {
+			violations = append(violations, Violation{Field: "Dockerfile", Message: err.Error()})
+		}
+	case manifest.Image == "":
+		violations = append(violations, Violation{Field: "Image", Message: "is required"})
+	default:
+		if err := docker.ValidateImage(manifest.Image); err != nil {
+			violations = append(violations, Violation{Field: "Image", Message: err.Error()})
+		}
+	}
+	if manifest.Source == "" && manifest.Dockerfile != "" {
+		violations = append(violations, Violation{Field: "Dockerfile", Message: "requires Source"})
+	}
+	if manifest.Network == "" && len(manifest.NetworkAllow) > 0 {
+		violations = append(violations, Violation{Field: "NetworkAllow", Message: fmt.Sprintf("requires the %q network policy", docker.NetworkAllowlist)})
+	} else if manifest.Network != "" {
+		if err := docker.ValidateNetwork(manifest.Network, manifest.NetworkAllow); err != nil {
+			violations = append(violations, Violation{Field: "Network", Message: err.Error()})
+		}
+	}
+	if manifest.WarmPool > 0 && manifest.Network == docker.NetworkAllowlist {
+		// The allowlist proxy lives only as long as a single execution.
+		violations = append(violations, Violation{Field: "WarmPool", Message: fmt.Sprintf("can't be used with the %q network policy", docker.NetworkAllowlist)})
+	}
+	return violations
+}
+
+func (dockerRuntime) Verify(manifest *ContractManifest, logger logging.Logger) error {
+	if manifest.DigestPolicy == "" || manifest.ImageDigest == "" {
+		return nil
+	}
+	// An image that is missing locally has drifted as well, since running the
+	// contract would pull whatever the tag refers to now.
+	current, err := docker.ImageDigest(manifest.Image)
+	if err == nil && current == manifest.ImageDigest {
+		return nil
+	}
+	if manifest.DigestPolicy != DigestPolicyRepin {
+		return &ImageDriftError{Contract: manifest.Type, Image: manifest.Image, Pinned: manifest.ImageDigest, Current: current}
+	}
+	if _, err := docker.ImageDigest(manifest.ImageDigest); err != nil {
+		// Images built locally are pinned by ID and can't be pulled again.
+		if !strings.Contains(manifest.ImageDigest, "@") {
+			return fmt.Errorf("pinned image %s is no longer available", manifest.ImageDigest)
+		}
+		var auth *docker.Auth
+		if manifest.Auth != "" {
+			if auth, err = docker.ParseAuth(manifest.Auth, manifest.Image); err != nil {
+				return fmt.Errorf("invalid registry auth: %s", err)
+			}
+		}
+		if err := docker.PullImage(manifest.ImageDigest, auth); err != nil {
+			return fmt.Errorf("failed to pull pinned image: %s", err)
+		}
+	}
+	if err := docker.TagImage(manifest.ImageDigest, manifest.Image); err != nil {
+		return fmt.Errorf("failed to repin image: %s", err)
+	}
+	logger.Info("image drifted from its pinned digest and was repinned",
+		logging.F("image", manifest.Image), logging.F("pinned", manifest.ImageDigest), logging.F("drifted", current))
+	return nil
+}
+
+func (dockerRuntime) Contract(manifest *ContractManifest, env map[string]string, logger logging.Logger) (Contract, error) {
+	timeout, err := manifest.Timeout()
+	if err != nil {
+		return nil, err
+	}
+	return &docker.Contract{
+		Name:       manifest.Type,
+		Env:        env,
+		Image:      manifest.Image,
+		Command:    manifest.Cmd,
+		Args:       manifest.Args,
+		Timeout:    timeout,
+		Logger:     logger,
+		Network:    manifest.Network,
+		AllowHosts: manifest.NetworkAllow,
+		Warm:       manifest.WarmPool,
+		MaxUses:    manifest.WarmMaxUses,
+	}, nil
+}
+
+func (dockerRuntime) Remove(manifest *ContractManifest) error {
+	if err := docker.RemoveImage(manifest.Image); err != nil {
+		return fmt.Errorf("failed to remove image: %s", err)
+	}
+	return nil
+}
+
+// execRuntime executes contracts as local processes.
+type execRuntime struct{}
+
+func (execRuntime) Prepare(manifest *ContractManifest) error {
+	if _, err := exec.LookPath(manifest.Cmd); err != nil {
+		return fmt.Errorf("invalid executable: %s", err)
+	}
+	return nil
+}
+
+func (execRuntime) ValidateManifest(manifest *ContractManifest) []Violation {
+	if manifest.Cmd == "" {
+		return []Violation{{Field: "Cmd", Message: "is required"}}
+	}
+	return nil
+}
+
+func (execRuntime) Contract(manifest *ContractManifest, env map[string]string, logger logging.Logger) (Contract, error) {
+	timeout, err := manifest.Timeout()
+	if err != nil {
+		return nil, err
+	}
+	return &process.Contract{
+		Name:    manifest.Type,
+		Env:     env,
+		Path:    manifest.Cmd,
+		Args:    manifest.Args,
+		Timeout: timeout,
+		Logger:  logger,
+	}, nil
+}
+
+func (execRuntime) Remove(manifest *ContractManifest) error {
+	return nil
+}
+
+// wasmRuntime executes contracts as WebAssembly modules.
+type wasmRuntime struct{}
+
+func (wasmRuntime) Prepare(manifest *ContractManifest) error {
+	return wasm.Compile(context.Background(), manifest.Cmd)
+}
+
+func (wasmRuntime) ValidateManifest(manifest *ContractManifest) []Violation {
+	if manifest.Cmd == "" {
+		return []Violation{{Field: "Cmd", Message: "is required"}}
+	}
+	return nil
+}
+
+func (wasmRuntime) Contract(manifest *ContractManifest, env map[string]string, logger logging.Logger) (Contract, error) {
+	timeout, err := manifest.Timeout()
+	if err != nil {
+		return nil, err
+	}
+	return &wasm.Contract{
+		Name:    manifest.Type,
+		Env:     env,
+		Path:    manifest.Cmd,
+		Args:    manifest.Args,
+		Timeout: timeout,
+		Logger:  logger,
+	}, nil
+}
+
+func (wasmRuntime) Remove(manifest *ContractManifest) error {
+	return nil
+}
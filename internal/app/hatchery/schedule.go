@@ -0,0 +1,123 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultScheduleRuns and maxScheduleRuns bound the number of upcoming runs
+	// listed for each cron job by GET /schedule.
+	defaultScheduleRuns = 5
+	maxScheduleRuns     = 100
+)
+
+// CronSchedule describes a contract's cron schedule and its upcoming runs.
+type CronSchedule struct {
+	TxnType string `json:"txn_type"`
+	Cron    string `json:"cron"`
+	Overlap string `json:"overlap,omitempty"`
+	Jitter  string `json:"jitter,omitempty"`
+	// Running reports whether this node runs the contract's cron job. Only the
+	// leader of a cluster does.
+	Running bool `json:"running"`
+	// Next lists the upcoming runs, soonest first. It is shorter than requested
+	// if the schedule stops activating.
+	Next []time.Time `json:"next"`
+	// Error explains why the contract's Cron can't be parsed, in which case it
+	// never runs.
+	Error string `json:"error,omitempty"`
+}
+
+// ScheduleCalendar is the consolidated schedule of every cron and one-shot job.
+type ScheduleCalendar struct {
+	Cron     []CronSchedule `json:"cron"`
+	OneShots []*OneShot     `json:"one_shots"`
+}
+
+// GetSchedule returns an HTTP handler function that responds with the ScheduleCalendar:
+// every contract with a cron schedule, with its next runs, and every pending one-shot
+// schedule, soonest first. The number of runs listed per contract is set by the optional
+// next query parameter, which defaults to defaultScheduleRuns and may not exceed
+// maxScheduleRuns. Runs are computed from each manifest's Cron. When this node runs the
+// contract's cron job, the first run is its pending activation, jitter included; the
+// jitter of later runs is random and therefore left out.
+func (a *Application) GetSchedule() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, err := queryInt(r, "next", defaultScheduleRuns)
+		if err != nil || n <= 0 || n > maxScheduleRuns {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("next must be an integer between 1 and %d", maxScheduleRuns))
+			return
+		}
+		manifests, err := a.Lib.List()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		shots, err := a.oneShots()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		a.ensureCronTab()
+		now := time.Now()
+		cal := ScheduleCalendar{Cron: []CronSchedule{}, OneShots: shots}
+		for _, m := range manifests {
+			if m.Cron == "" {
+				continue
+			}
+			s := CronSchedule{
+				TxnType: m.Type,
+				Cron:    m.Cron,
+				Overlap: m.CronOverlap,
+				Jitter:  m.CronJitter,
+				Next:    []time.Time{},
+			}
+			a.cronMu.Lock()
+			cron, ok := a.cronTab[m.Type]
+			a.cronMu.Unlock()
+			s.Running = ok
+			schedule, err := ParseSchedule(m.Cron)
+			if err != nil {
+				s.Error = err.Error()
+				cal.Cron = append(cal.Cron, s)
+				continue
+			}
+			t := now
+			if ok {
+				if next := cron.Next(); next.After(now) {
+					s.Next = append(s.Next, next.UTC())
+					t = next
+				}
+			}
+			for len(s.Next) < n {
+				if t = schedule.Next(t); t.IsZero() {
+					break
+				}
+				s.Next = append(s.Next, t.UTC())
+			}
+			cal.Cron = append(cal.Cron, s)
+		}
+		writeJSONResponse(w, cal)
+	}
+}
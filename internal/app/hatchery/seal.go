@@ -0,0 +1,151 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// sealedPrefix marks values that have been encrypted by a sealer.
+	sealedPrefix = "sealed:"
+	// nodeKeySize is the size of the node key in bytes, selecting AES-256.
+	nodeKeySize = 32
+)
+
+// loadNodeKey reads the node key from the file at path. If the file doesn't exist,
+// it is created, readable only by the current user, holding a new random key.
+func loadNodeKey(path string) ([]byte, error) {
+	key, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return createNodeKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node key: %s", err)
+	}
+	if len(key) != nodeKeySize {
+		return nil, fmt.Errorf("node key %s must be %d bytes long", path, nodeKeySize)
+	}
+	return key, nil
+}
+
+func createNodeKey(path string) ([]byte, error) {
+	key := make([]byte, nodeKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate node key: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create node key: %s", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		// Another process created the key first.
+		return loadNodeKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node key: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(key); err != nil {
+		return nil, fmt.Errorf("failed to write node key: %s", err)
+	}
+	return key, nil
+}
+
+// nodeKey lazily loads the node key and builds a sealer from it the first time
+// it is needed, so that no key file is created unless something is sealed.
+type nodeKey struct {
+	once sync.Once
+	s    *sealer
+	err  error
+}
+
+// sealer returns the sealer for the node key at path. The path passed on the
+// first call is used for the lifetime of k.
+func (k *nodeKey) sealer(path string) (*sealer, error) {
+	k.once.Do(func() {
+		var key []byte
+		if key, k.err = loadNodeKey(path); k.err != nil {
+			return
+		}
+		k.s, k.err = newSealer(key)
+	})
+	return k.s, k.err
+}
+
+// sealer encrypts values at rest with AES-GCM under the node key.
+type sealer struct {
+	aead cipher.AEAD
+}
+
+func newSealer(key []byte) (*sealer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{aead: aead}, nil
+}
+
+// seal encrypts plaintext and returns it in a printable form that open accepts.
+func (s *sealer) seal(plaintext string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %s", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a value returned by seal. Values that were never sealed are
+// returned as-is.
+func (s *sealer) open(value string) (string, error) {
+	if !isSealed(value) {
+		return value, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil || len(b) < s.aead.NonceSize() {
+		return "", errors.New("malformed sealed value")
+	}
+	nonce, ciphertext := b[:s.aead.NonceSize()], b[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt sealed value; was it sealed with a different node key?")
+	}
+	return string(plaintext), nil
+}
+
+func isSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
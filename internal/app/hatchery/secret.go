@@ -0,0 +1,242 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const secretBucket = reservedBucketPrefix + "secrets"
+
+// ErrSecretNotExist is returned when a requested secret does not exist.
+var ErrSecretNotExist = errors.New("secret does not exist")
+
+// Secret describes a stored secret. Its value is never returned by the API.
+type Secret struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// SecretStore stores named secrets, such as API keys and credentials, that contract
+// manifests reference in their Secrets field.
+type SecretStore interface {
+	// PutSecret stores value under name, replacing any existing value, and returns
+	// a description of the stored secret.
+	PutSecret(name, value string) (*Secret, error)
+	// Secret returns the decrypted value of the named secret. If the secret doesn't
+	// exist, ErrSecretNotExist is returned.
+	Secret(name string) (string, error)
+	// Secrets describes every stored secret, sorted by name.
+	Secrets() ([]Secret, error)
+	// DeleteSecret removes the named secret. If the secret doesn't exist,
+	// ErrSecretNotExist is returned.
+	DeleteSecret(name string) error
+}
+
+// HeapSecretStore is a SecretStore that keeps secrets in a reserved bucket of a Heap,
+// encrypted with AES-GCM under the node key.
+type HeapSecretStore struct {
+	Heap Heap
+	// KeyPath is the file holding the node key. It is created with a new random key
+	// if it doesn't exist.
+	KeyPath string
+
+	key nodeKey
+}
+
+type storedSecret struct {
+	Secret
+	Value string `json:"value"`
+}
+
+// PutSecret encrypts value and stores it under name.
+func (s *HeapSecretStore) PutSecret(name, value string) (*Secret, error) {
+	sealer, err := s.key.sealer(s.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealer.seal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %s", err)
+	}
+	now := time.Now().UTC()
+	stored := storedSecret{Secret: Secret{Name: name, Created: now, Updated: now}, Value: sealed}
+	if existing, err := s.get(name); err == nil {
+		stored.Created = existing.Created
+	} else if err != ErrSecretNotExist {
+		return nil, err
+	}
+	b, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Heap.Put(secretBucket, name, b); err != nil {
+		return nil, err
+	}
+	return &stored.Secret, nil
+}
+
+// Secret returns the decrypted value of the named secret.
+func (s *HeapSecretStore) Secret(name string) (string, error) {
+	stored, err := s.get(name)
+	if err != nil {
+		return "", err
+	}
+	sealer, err := s.key.sealer(s.KeyPath)
+	if err != nil {
+		return "", err
+	}
+	return sealer.open(stored.Value)
+}
+
+// Secrets describes every stored secret, sorted by name.
+func (s *HeapSecretStore) Secrets() ([]Secret, error) {
+	all, err := s.Heap.GetRange(secretBucket, "", "")
+	if err != nil {
+		return nil, err
+	}
+	secrets := make([]Secret, 0, len(all))
+	for _, b := range all {
+		if len(b) == 0 {
+			continue
+		}
+		var stored storedSecret
+		if err := json.Unmarshal(b, &stored); err != nil {
+			return nil, fmt.Errorf("failed to decode secret: %s", err)
+		}
+		secrets = append(secrets, stored.Secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+	return secrets, nil
+}
+
+// DeleteSecret removes the named secret.
+func (s *HeapSecretStore) DeleteSecret(name string) error {
+	if _, err := s.get(name); err != nil {
+		return err
+	}
+	return s.Heap.Delete(secretBucket, name)
+}
+
+func (s *HeapSecretStore) get(name string) (*storedSecret, error) {
+	b, err := s.Heap.Get(secretBucket, name)
+	if err == ErrHeapNotExist || (err == nil && len(b) == 0) {
+		return nil, ErrSecretNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored storedSecret
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode secret: %s", err)
+	}
+	return &stored, nil
+}
+
+type postSecretRequest struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostSecret returns an HTTP handler function that stores a secret, replacing any
+// existing secret with the same name. The response describes the secret without
+// its value.
+func (a *Application) PostSecret() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.Secrets == nil {
+			writeError(w, http.StatusNotFound, ErrCodeNotFound, "no secret store is configured")
+			return
+		}
+		var req postSecretRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid secret: "+err.Error())
+			return
+		}
+		if !validSecretName(req.Name) {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "secret names must be non-empty and contain only letters, digits, '.', '-' and '_'")
+			return
+		}
+		secret, err := a.Secrets.PutSecret(req.Name, req.Value)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSONResponse(w, secret)
+	}
+}
+
+// ListSecrets returns an HTTP handler function that describes every stored secret.
+// Values are omitted.
+func (a *Application) ListSecrets() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.Secrets == nil {
+			writeJSONResponse(w, []Secret{})
+			return
+		}
+		secrets, err := a.Secrets.Secrets()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		writeJSONResponse(w, secrets)
+	}
+}
+
+// DeleteSecret returns an HTTP handler function that removes a secret. Contracts that
+// reference it fail to execute until it is stored again.
+func (a *Application) DeleteSecret() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.Secrets == nil {
+			writeErrorFrom(w, ErrSecretNotExist)
+			return
+		}
+		if err := a.Secrets.DeleteSecret(mux.Vars(r)["name"]); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func validSecretName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"google.golang.org/grpc"
+)
+
+// DefaultShutdownTimeout is how long Run waits for in-flight requests to drain
+// when Application.ShutdownTimeout is not set.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Run serves the Hatchery API on addr until ctx is cancelled or the process receives
+// SIGINT or SIGTERM. Transactions left in the work queue by a previous run are resumed,
+// cron jobs are restarted for every contract with a cron schedule, one-shot schedules
+// are dispatched as they become due, and blocks are bundled every BlockInterval, from
+// before the server starts listening. A follower instead replicates its primary until
+// it is promoted. On the way out, in-flight requests are given up
+// to ShutdownTimeout to complete, all cron jobs are stopped, and the heap is closed if
+// it implements io.Closer. If BaseURL is not set, it is derived from addr. If
+// ReplicationAddr is set, the gRPC replication service is served on it alongside the
+// API. An error is returned if the server fails to listen or does not shut down cleanly.
+func (a *Application) Run(ctx context.Context, addr string) error {
+	if a.BaseURL == "" {
+		a.BaseURL = baseURL(addr)
+	}
+	var replLis net.Listener
+	if a.ReplicationAddr != "" {
+		var err error
+		if replLis, err = net.Listen("tcp", a.ReplicationAddr); err != nil {
+			a.closeHeap()
+			return fmt.Errorf("failed to listen for followers: %s", err)
+		}
+	}
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: a.Handler(),
+	}
+	// Streams never complete on their own, so disconnect them as soon as shutdown
+	// begins rather than waiting out ShutdownTimeout.
+	srv.RegisterOnShutdown(a.closeStreams)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	var replSrv *grpc.Server
+	if replLis != nil {
+		replSrv = a.ReplicationServer()
+		go func() {
+			errCh <- replSrv.Serve(replLis)
+		}()
+	}
+
+	select {
+	case err := <-errCh:
+		srv.Close()
+		if replSrv != nil {
+			replSrv.Stop()
+		}
+		a.Shutdown()
+		a.closeHeap()
+		return fmt.Errorf("server failed: %s", err)
+	case <-ctx.Done():
+	case <-sigCh:
+	}
+
+	timeout := a.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if replSrv != nil {
+		// Followers were disconnected as the API server shut down, so only those
+		// still being sent a snapshot hold up a graceful stop.
+		stopped := make(chan struct{})
+		go func() {
+			replSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			replSrv.Stop()
+		}
+	}
+	a.Shutdown()
+	if cerr := a.closeHeap(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Handler starts the application's background work, like Run, and returns the handler
+// that serves its API, for applications served by a server of the caller's, such as an
+// httptest.Server. The caller is responsible for calling Shutdown once the server has
+// stopped.
+func (a *Application) Handler() http.Handler {
+	a.start()
+	muxer := mux.NewRouter()
+	a.SetupRoutes(muxer)
+	return muxer
+}
+
+// start starts the application's background work, and that of its chains. A
+// follower only replicates its primary until it is promoted.
+func (a *Application) start() {
+	if !a.startFollowing() {
+		a.startPrimary()
+	}
+	if a.Chains != nil {
+		a.Chains.start(a)
+	}
+}
+
+// startPrimary starts the background work of an application that executes
+// transactions, once any contract output left in the shared bucket is migrated. In
+// a cluster, the migration and cron jobs are left to the leader. See lead.
+func (a *Application) startPrimary() {
+	if a.Elector == nil {
+		if err := a.migrateSharedBucket(); err != nil {
+			a.log().Error("failed to migrate contract output out of shared bucket", logging.F("bucket", a.Bucket), logging.Err(err))
+		}
+	}
+	a.startBlocks()
+	a.startWorkQueue()
+	a.startForwarding()
+	if a.Elector == nil {
+		a.recoverCronJobs()
+	}
+	a.startOneShots()
+	a.startGC()
+	a.startRetention()
+	a.startCampaign()
+}
+
+func (a *Application) closeHeap() error {
+	if c, ok := a.Heap.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// baseURL returns the URL for reaching a server listening on addr from the
+// local machine.
+func baseURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,232 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+const (
+	// streamBuffer is how many events may be waiting to be written to a stream client.
+	// Clients that fall further behind are disconnected.
+	streamBuffer = 64
+	// streamKeepAlive is how often an idle stream is sent a comment, so that proxies
+	// don't time out the connection.
+	streamKeepAlive = 15 * time.Second
+)
+
+// StreamEvent is pushed to the clients of GET /stream as Server-Sent Events, with the
+// event's Type as the SSE event name. It is the wire form of an Event.
+type StreamEvent struct {
+	Type    string    `json:"type"`
+	TxnType string    `json:"txn_type"`
+	Time    time.Time `json:"time"`
+	// Transaction is the appended transaction, set for EventTransaction.
+	Transaction *transactionResponse `json:"transaction,omitempty"`
+	// Duration is how long the execution took, set for EventExecutionFinished,
+	// EventExecutionFailed and EventExecutionCancelled.
+	Duration string `json:"duration,omitempty"`
+	// Error describes why an execution failed.
+	Error string `json:"error,omitempty"`
+	// Bucket, Key and Value describe a heap change, set for EventHeapWrite and
+	// EventHeapDelete, with TxnType set to the contract that made the change. An
+	// empty Key means the whole bucket was deleted.
+	Bucket string `json:"bucket,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Value  []byte `json:"value,omitempty"`
+}
+
+type streamClient struct {
+	txnTypes map[string]bool
+	heap     []HeapWatch
+	events   chan *StreamEvent
+}
+
+func (c *streamClient) wants(e *StreamEvent) bool {
+	if e.Type == EventHeapWrite || e.Type == EventHeapDelete {
+		ev := &Event{Type: e.Type, Bucket: e.Bucket, Key: e.Key}
+		for _, w := range c.heap {
+			if w.matches(ev) {
+				return true
+			}
+		}
+		return false
+	}
+	// A client that only watches the heap doesn't want transactions as well.
+	if len(c.heap) > 0 && len(c.txnTypes) == 0 {
+		return false
+	}
+	return len(c.txnTypes) == 0 || c.txnTypes[e.TxnType]
+}
+
+// Stream returns an HTTP handler function that pushes every transaction appended to
+// the ledger, and every contract execution, to the client as Server-Sent Events. The
+// optional txn_type query parameter, which may be repeated or comma separated, limits
+// the events to those transaction types. The optional heap query parameter, which may
+// be repeated, watches a contract's heap: it is a bucket name optionally followed by
+// a slash and a key prefix, and the writes and deletions that match it are streamed
+// too. A client that only gives heap receives only heap changes. Clients that can't
+// keep up are disconnected.
+func (a *Application) Stream() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternal, "streaming is not supported")
+			return
+		}
+		c := &streamClient{
+			txnTypes: make(map[string]bool),
+			events:   make(chan *StreamEvent, streamBuffer),
+		}
+		for _, v := range r.URL.Query()["txn_type"] {
+			for _, t := range strings.Split(v, ",") {
+				if t != "" {
+					c.txnTypes[t] = true
+				}
+			}
+		}
+		for _, v := range r.URL.Query()["heap"] {
+			hw, ok := parseHeapWatch(v)
+			if !ok {
+				writeError(w, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("invalid heap watch %q", v))
+				return
+			}
+			c.heap = append(c.heap, hw)
+		}
+		a.addStream(c)
+		defer a.removeStream(c)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case e, ok := <-c.events:
+				if !ok {
+					return
+				}
+				b, err := json.Marshal(e)
+				if err != nil {
+					a.log().Error("failed to encode stream event", logging.Err(err))
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (a *Application) addStream(c *streamClient) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	if a.streams == nil {
+		a.streams = make(map[*streamClient]struct{})
+	}
+	a.streams[c] = struct{}{}
+}
+
+// removeStream unregisters c and closes its event channel, unless it was already
+// closed by publish or closeStreams.
+func (a *Application) removeStream(c *streamClient) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	if _, ok := a.streams[c]; ok {
+		delete(a.streams, c)
+		close(c.events)
+	}
+}
+
+// publish sends e to every stream client that wants it. Clients whose buffer is
+// full are disconnected rather than holding up the caller.
+func (a *Application) publish(e *StreamEvent) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	for c := range a.streams {
+		if !c.wants(e) {
+			continue
+		}
+		select {
+		case c.events <- e:
+		default:
+			a.log().Info("disconnecting slow stream client")
+			delete(a.streams, c)
+			close(c.events)
+		}
+	}
+}
+
+// closeStreams disconnects every stream client and follower, so that they don't
+// hold up a graceful shutdown of the server.
+func (a *Application) closeStreams() {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	for c := range a.streams {
+		delete(a.streams, c)
+		close(c.events)
+	}
+	for r := range a.replicas {
+		r.disconnect()
+	}
+}
+
+// publishStream pushes a transaction, execution or heap event from the event bus to
+// stream clients.
+func (a *Application) publishStream(e *Event) {
+	se := &StreamEvent{
+		Type:    e.Type,
+		TxnType: e.Contract,
+		Time:    e.Time,
+	}
+	switch e.Type {
+	case EventTransaction:
+		resp := newTransactionResponse(e.Transaction, ContentEncodingBase64)
+		se.Transaction = &resp
+	case EventExecutionFinished, EventExecutionFailed, EventExecutionCancelled:
+		se.Duration = e.Duration.String()
+		if e.Err != nil {
+			se.Error = e.Err.Error()
+		}
+	case EventHeapWrite, EventHeapDelete:
+		if strings.HasPrefix(e.Bucket, reservedBucketPrefix) {
+			return
+		}
+		se.Bucket, se.Key, se.Value = e.Bucket, e.Key, e.Value
+	}
+	a.publish(se)
+}
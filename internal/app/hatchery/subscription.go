@@ -0,0 +1,309 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+)
+
+const (
+	subscriptionBucket   = reservedBucketPrefix + "subscriptions"
+	deliveryBucketPrefix = reservedBucketPrefix + "deliveries_"
+
+	// maxDeliveryAttempts is how many times a callback is attempted before its
+	// delivery is marked as failed.
+	maxDeliveryAttempts = 5
+	// initialDeliveryBackoff is the delay before the first retry of a callback.
+	// It doubles with each subsequent retry.
+	initialDeliveryBackoff = time.Second
+	// deliveryTimeout bounds a single callback attempt.
+	deliveryTimeout = 10 * time.Second
+)
+
+// Delivery statuses.
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// ErrSubscriptionNotExist is returned when a requested subscription does not exist.
+var ErrSubscriptionNotExist = errors.New("subscription does not exist")
+
+// Subscription is a request to be called back whenever a transaction is appended
+// to the ledger.
+type Subscription struct {
+	ID string `json:"id"`
+	// URL receives a POST request for every matching transaction.
+	URL string `json:"url"`
+	// TxnType optionally restricts callbacks to transactions of a single type.
+	TxnType string `json:"txn_type,omitempty"`
+	// Secret is used to sign callbacks. Each callback carries the hex encoded
+	// HMAC-SHA256 of its body in the X-Hatchery-Signature header.
+	Secret  string    `json:"secret"`
+	Created time.Time `json:"created"`
+}
+
+// Delivery tracks the callbacks made to a Subscription for a single transaction.
+type Delivery struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscription_id"`
+	TxnID          string    `json:"txn_id"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	LastAttempt    time.Time `json:"last_attempt"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+type postSubscriptionRequest struct {
+	URL     string `json:"url"`
+	TxnType string `json:"txn_type"`
+}
+
+// PostSubscription returns an HTTP handler function that registers a new Subscription
+// and responds with it, including the secret used to sign its callbacks.
+func (a *Application) PostSubscription() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req postSubscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "invalid subscription: "+err.Error())
+			return
+		}
+		u, err := url.Parse(req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeError(w, http.StatusBadRequest, ErrCodeBadRequest, "url must be an absolute http or https URL")
+			return
+		}
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		sub := &Subscription{
+			ID:      uuid.New().String(),
+			URL:     u.String(),
+			TxnType: req.TxnType,
+			Secret:  hex.EncodeToString(secret),
+			Created: time.Now().UTC(),
+		}
+		if err := a.putJSON(subscriptionBucket, sub.ID, sub); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSONResponse(w, sub)
+	}
+}
+
+// ListSubscriptions returns an HTTP handler function that responds with all subscriptions.
+// Secrets are omitted.
+func (a *Application) ListSubscriptions() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subs, err := a.subscriptions()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		for _, sub := range subs {
+			sub.Secret = ""
+		}
+		writeJSONResponse(w, subs)
+	}
+}
+
+// DeleteSubscription returns an HTTP handler function that removes a subscription.
+func (a *Application) DeleteSubscription() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		if _, err := a.subscription(id); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		if err := a.Heap.Delete(subscriptionBucket, id); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListDeliveries returns an HTTP handler function that responds with the delivery status
+// of every callback made to a subscription, oldest first.
+func (a *Application) ListDeliveries() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		if _, err := a.subscription(id); err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		all, err := a.Heap.GetAll(deliveryBucketPrefix + id)
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		deliveries := make([]*Delivery, 0, len(all))
+		for _, b := range all {
+			var d Delivery
+			if err := json.Unmarshal(b, &d); err != nil {
+				writeErrorFrom(w, err)
+				return
+			}
+			deliveries = append(deliveries, &d)
+		}
+		sort.Slice(deliveries, func(i, j int) bool {
+			return deliveries[i].LastAttempt.Before(deliveries[j].LastAttempt)
+		})
+		writeJSONResponse(w, deliveries)
+	}
+}
+
+func (a *Application) subscription(id string) (*Subscription, error) {
+	b, err := a.Heap.Get(subscriptionBucket, id)
+	if err == ErrHeapNotExist || (err == nil && len(b) == 0) {
+		return nil, ErrSubscriptionNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sub Subscription
+	if err := json.Unmarshal(b, &sub); err != nil {
+		return nil, fmt.Errorf("failed to decode subscription: %s", err)
+	}
+	return &sub, nil
+}
+
+func (a *Application) subscriptions() ([]*Subscription, error) {
+	all, err := a.Heap.GetAll(subscriptionBucket)
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]*Subscription, 0, len(all))
+	for _, b := range all {
+		if len(b) == 0 {
+			continue
+		}
+		var sub Subscription
+		if err := json.Unmarshal(b, &sub); err != nil {
+			return nil, fmt.Errorf("failed to decode subscription: %s", err)
+		}
+		subs = append(subs, &sub)
+	}
+	return subs, nil
+}
+
+// notifySubscribers starts a delivery, in the background, to every subscription
+// that matches the appended transaction of an EventTransaction.
+func (a *Application) notifySubscribers(e *Event) {
+	t := e.Transaction
+	subs, err := a.subscriptions()
+	if err != nil {
+		a.log().Error("failed to load subscriptions", logging.TxnID(t.ID), logging.Err(err))
+		return
+	}
+	body, err := json.Marshal(newTransactionResponse(t, ContentEncodingBase64))
+	if err != nil {
+		a.log().Error("failed to encode callback", logging.TxnID(t.ID), logging.Err(err))
+		return
+	}
+	for _, sub := range subs {
+		if sub.TxnType != "" && sub.TxnType != t.Type {
+			continue
+		}
+		d := &Delivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: sub.ID,
+			TxnID:          t.ID,
+			Status:         DeliveryStatusPending,
+		}
+		go a.deliver(sub, d, body)
+	}
+}
+
+// deliver POSTs body to the subscription's URL, retrying with exponential backoff
+// until it succeeds or maxDeliveryAttempts is reached. The delivery's status is
+// recorded after every attempt.
+func (a *Application) deliver(sub *Subscription, d *Delivery, body []byte) {
+	logger := a.log().With(logging.TxnID(d.TxnID), logging.F("subscription", sub.ID))
+	mac := hmac.New(sha256.New, []byte(sub.Secret))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+	client := &http.Client{Timeout: deliveryTimeout}
+	backoff := initialDeliveryBackoff
+	for {
+		d.Attempts++
+		d.LastAttempt = time.Now().UTC()
+		err := postCallback(client, sub.URL, d.ID, signature, body)
+		switch {
+		case err == nil:
+			d.Status = DeliveryStatusDelivered
+			d.LastError = ""
+		case d.Attempts >= maxDeliveryAttempts:
+			d.Status = DeliveryStatusFailed
+			d.LastError = err.Error()
+		default:
+			d.LastError = err.Error()
+		}
+		if perr := a.putJSON(deliveryBucketPrefix+sub.ID, d.ID, d); perr != nil {
+			logger.Error("failed to record delivery", logging.Err(perr))
+		}
+		if d.Status != DeliveryStatusPending {
+			if d.Status == DeliveryStatusFailed {
+				logger.Error("callback failed", logging.Err(err))
+			}
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func postCallback(client *http.Client, target, deliveryID, signature string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hatchery-Delivery", deliveryID)
+	req.Header.Set("X-Hatchery-Signature", "sha256="+signature)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// ManifestVars are the values that templates in a posted manifest are rendered with.
+type ManifestVars struct {
+	// Environment is the name of the environment the node serves, such as
+	// "staging". See Application.Environment.
+	Environment string
+	// ChainID is the ID of the chain the manifest is stored on.
+	ChainID string
+	// Vars are the node's manifest variables, referenced as {{ .Vars.name }}.
+	Vars map[string]string
+}
+
+// manifestVars returns the values that manifests posted to the application are
+// rendered with.
+func (a *Application) manifestVars() *ManifestVars {
+	return &ManifestVars{Environment: a.Environment, ChainID: a.DragonChainID, Vars: a.ManifestVars}
+}
+
+// renderManifest resolves the templates in the string fields of m that vary between
+// deployments (Image, Source, Cmd, Args, Env, Secrets, Cron, CronPayloadSource and
+// NetworkAllow) with the application's ManifestVars, so that the same manifest can be
+// posted to nodes serving different environments. Templates use the syntax of package
+// text/template, such as "myorg/contract:{{ .Environment }}". Values without a template
+// are left alone. A Violation is returned for each field whose template is invalid or
+// references a variable that isn't set, and the field is left unrendered.
+func (a *Application) renderManifest(m *ContractManifest) []Violation {
+	vars := a.manifestVars()
+	var violations []Violation
+	render := func(field string, s *string) {
+		if !strings.Contains(*s, "{{") {
+			return
+		}
+		out, err := renderTemplate(field, *s, vars)
+		if err != nil {
+			violations = append(violations, Violation{Field: field, Message: err.Error()})
+			return
+		}
+		*s = out
+	}
+	render("Image", &m.Image)
+	render("Source", &m.Source)
+	render("Cmd", &m.Cmd)
+	for i := range m.Args {
+		render(fmt.Sprintf("Args[%d]", i), &m.Args[i])
+	}
+	for _, name := range sortedKeys(m.Env) {
+		v := m.Env[name]
+		render("Env."+name, &v)
+		m.Env[name] = v
+	}
+	for _, name := range sortedKeys(m.Secrets) {
+		v := m.Secrets[name]
+		render("Secrets."+name, &v)
+		m.Secrets[name] = v
+	}
+	render("Cron", &m.Cron)
+	render("CronPayloadSource", &m.CronPayloadSource)
+	for i := range m.NetworkAllow {
+		render(fmt.Sprintf("NetworkAllow[%d]", i), &m.NetworkAllow[i])
+	}
+	return violations
+}
+
+// renderTemplate renders the template text with vars. Referencing a variable that
+// isn't set is an error.
+func renderTemplate(name, text string, vars *ManifestVars) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render template: %s", err)
+	}
+	return buf.String(), nil
+}
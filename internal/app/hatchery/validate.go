@@ -0,0 +1,240 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Violation describes a field of a request that is invalid.
+type Violation struct {
+	// Field is the name of the invalid field, as it appears in the request. Map
+	// entries are named <field>.<key>.
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ManifestValidator is implemented by Runtimes that check the runtime specific
+// fields of a manifest, such as Image, before it is stored.
+type ManifestValidator interface {
+	// ValidateManifest returns a Violation for each runtime specific field of the
+	// manifest that is invalid.
+	ValidateManifest(manifest *ContractManifest) []Violation
+}
+
+// validateManifest renders the templates in a posted manifest, checks it and returns its
+// parsed cron schedule, if any. If the manifest is invalid, a 422 response listing every
+// violation is written and false is returned. See checkManifest.
+func (a *Application) validateManifest(w http.ResponseWriter, m *ContractManifest) (Schedule, bool) {
+	violations, schedule, err := a.checkManifest(m)
+	if err != nil {
+		writeErrorFrom(w, err)
+		return nil, false
+	}
+	if len(violations) > 0 {
+		writeErrorDetails(w, http.StatusUnprocessableEntity, ErrCodeInvalidManifest, "manifest is invalid", map[string]interface{}{
+			"violations": violations,
+		})
+		return nil, false
+	}
+	return schedule, true
+}
+
+// checkManifest renders the templates in m and returns every violation in the rendered
+// manifest, along with its parsed cron schedule, if any. A manifest whose templates
+// can't be rendered is not checked any further. See renderManifest and
+// manifestViolations.
+func (a *Application) checkManifest(m *ContractManifest) ([]Violation, Schedule, error) {
+	if violations := a.renderManifest(m); len(violations) > 0 {
+		return violations, nil, nil
+	}
+	return a.manifestViolations(m)
+}
+
+// manifestViolations returns every violation in m, along with its parsed cron schedule,
+// if any. An error is returned if the referenced secrets could not be looked up.
+func (a *Application) manifestViolations(m *ContractManifest) ([]Violation, Schedule, error) {
+	violations := []Violation{}
+	add := func(field, format string, args ...interface{}) {
+		violations = append(violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	switch {
+	case m.Type == "":
+		add("txn_type", "is required")
+	case isReservedBucket(m.Type):
+		add("txn_type", "must not begin with %s, which is reserved", reservedBucketPrefix)
+	case strings.HasPrefix(m.Type, ".") || strings.ContainsAny(m.Type, `/\`):
+		add("txn_type", "must not begin with '.' or contain path separators")
+	}
+	runtime, err := LookupRuntime(m.Runtime)
+	if err != nil {
+		add("Runtime", "unknown runtime %q", m.Runtime)
+	} else if v, ok := runtime.(ManifestValidator); ok {
+		violations = append(violations, v.ValidateManifest(m)...)
+	}
+	if _, ok := runtime.(dockerRuntime); runtime != nil && !ok && (m.Network != "" || len(m.NetworkAllow) > 0) {
+		add("Network", "is only supported by the %s runtime", RuntimeDocker)
+	}
+	if _, ok := runtime.(dockerRuntime); runtime != nil && !ok && m.WarmPool != 0 {
+		add("WarmPool", "is only supported by the %s runtime", RuntimeDocker)
+	} else if m.WarmPool < 0 {
+		add("WarmPool", "must not be negative")
+	}
+	if m.WarmMaxUses < 0 {
+		add("WarmMaxUses", "must not be negative")
+	}
+	switch m.DigestPolicy {
+	case "":
+	case DigestPolicyRepin, DigestPolicyRefuse:
+		if _, ok := runtime.(Verifier); runtime != nil && !ok {
+			add("DigestPolicy", "is not supported by the %s runtime", m.Runtime)
+		}
+	default:
+		add("DigestPolicy", "must be %q or %q", DigestPolicyRepin, DigestPolicyRefuse)
+	}
+	switch m.HeapEviction {
+	case "", HeapEvictionReject, HeapEvictionLRU:
+	default:
+		add("HeapEviction", "must be %q or %q", HeapEvictionReject, HeapEvictionLRU)
+	}
+	if m.HeapMaxBytes < 0 {
+		add("HeapMaxBytes", "must not be negative")
+	}
+	if m.HeapMaxKeys < 0 {
+		add("HeapMaxKeys", "must not be negative")
+	}
+	if err := validHeapOutput(m.HeapOutput); err != nil {
+		add("HeapOutput", "%s", err)
+	}
+	if m.HeapOutputKey != "" && m.HeapOutput != HeapOutputSingle {
+		add("HeapOutputKey", "requires the %q HeapOutput strategy", HeapOutputSingle)
+	}
+	violations = append(violations, heapSchemaViolations(m.HeapSchemas)...)
+	for _, name := range m.HeapReaders {
+		if name == "" || isReservedBucket(name) {
+			add("HeapReaders", "%q is not a valid contract name", name)
+		}
+	}
+	for _, name := range m.HeapWriters {
+		if name == "" || isReservedBucket(name) {
+			add("HeapWriters", "%q is not a valid contract name", name)
+		}
+	}
+	switch m.ExecutionOrder {
+	case "", ExecutionOrderParallel, ExecutionOrderSerial:
+	default:
+		add("execution_order", "must be %q or %q", ExecutionOrderParallel, ExecutionOrderSerial)
+	}
+	for _, name := range sortedKeys(m.Env) {
+		if !validEnvName(name) {
+			add("Env."+name, "is not a valid environment variable name")
+		} else if reason := a.EnvPolicy.violation(name); reason != "" {
+			add("Env."+name, "%s", reason)
+		}
+		for _, reason := range heapRefViolations(m.Env[name]) {
+			add("Env."+name, "%s", reason)
+		}
+	}
+	for _, name := range sortedKeys(m.Secrets) {
+		if !validEnvName(name) {
+			add("Secrets."+name, "is not a valid environment variable name")
+		} else if reason := a.EnvPolicy.violation(name); reason != "" {
+			add("Secrets."+name, "%s", reason)
+		}
+		secret := m.Secrets[name]
+		if a.Secrets == nil {
+			add("Secrets."+name, "secret %q does not exist", secret)
+			continue
+		}
+		if _, err := a.Secrets.Secret(secret); err == ErrSecretNotExist {
+			add("Secrets."+name, "secret %q does not exist", secret)
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+	if _, err := m.Timeout(); err != nil {
+		add("ExecutionTimeout", "%s", err)
+	}
+	if m.Priority < 0 {
+		add("Priority", "must not be negative")
+	}
+	if p := m.RetryPolicy; p != nil {
+		if p.MaxAttempts < 0 {
+			add("RetryPolicy.MaxAttempts", "must not be negative")
+		}
+		if _, err := p.BackoffDuration(); err != nil {
+			add("RetryPolicy.Backoff", "%s", err)
+		}
+		for _, code := range p.RetryableExitCodes {
+			if code < 1 || code > 255 {
+				add("RetryPolicy.RetryableExitCodes", "%d is not a failing exit status", code)
+			}
+		}
+	}
+	var schedule Schedule
+	if m.Cron != "" {
+		if schedule, err = ParseSchedule(m.Cron); err != nil {
+			add("Cron", "%s", err)
+		}
+	}
+	switch OverlapPolicy(m.CronOverlap) {
+	case "", OverlapConcurrent, OverlapSkip, OverlapQueue:
+	default:
+		add("CronOverlap", "must be %q, %q or %q", OverlapConcurrent, OverlapSkip, OverlapQueue)
+	}
+	if _, err := m.Jitter(); err != nil {
+		add("CronJitter", "%s", err)
+	}
+	if m.CronPayloadSource != "" {
+		if _, _, err := parsePayloadSource(m.CronPayloadSource); err != nil {
+			add("CronPayloadSource", "%s", err)
+		}
+	}
+	return violations, schedule, nil
+}
+
+// validEnvName reports whether name is a portable environment variable name: a
+// letter or underscore followed by letters, digits and underscores.
+func validEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
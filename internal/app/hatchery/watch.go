@@ -0,0 +1,71 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"strings"
+)
+
+// HeapWatch selects the heap changes a watcher is interested in: the writes and
+// deletions in Bucket whose key starts with Prefix. An empty Prefix matches every
+// key in the bucket.
+type HeapWatch struct {
+	Bucket string
+	Prefix string
+}
+
+// parseHeapWatch parses the value of a heap query parameter, a bucket name optionally
+// followed by a slash and a key prefix, such as "scores/player-".
+func parseHeapWatch(s string) (HeapWatch, bool) {
+	bucket, prefix := s, ""
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		bucket, prefix = s[:i], s[i+1:]
+	}
+	if bucket == "" || strings.HasPrefix(bucket, reservedBucketPrefix) {
+		return HeapWatch{}, false
+	}
+	return HeapWatch{Bucket: bucket, Prefix: prefix}, true
+}
+
+// matches reports whether the heap event e is selected by w. The deletion of a
+// whole bucket matches every prefix in it.
+func (w HeapWatch) matches(e *Event) bool {
+	if e.Bucket != w.Bucket {
+		return false
+	}
+	if e.Type == EventHeapDelete && e.Key == "" {
+		return true
+	}
+	return strings.HasPrefix(e.Key, w.Prefix)
+}
+
+// Watch calls f with every EventHeapWrite and EventHeapDelete in bucket whose key
+// starts with prefix, until unsubscribe is called. Deleting the whole bucket is
+// reported as an EventHeapDelete with an empty Key. Like the other subscribers of
+// the event bus, f is called synchronously by the writer, so it should hand the
+// event off rather than block.
+func (a *Application) Watch(bucket, prefix string, f func(*Event)) (unsubscribe func()) {
+	w := HeapWatch{Bucket: bucket, Prefix: prefix}
+	return a.bus().Subscribe(func(e *Event) {
+		if w.matches(e) {
+			f(e)
+		}
+	}, EventHeapWrite, EventHeapDelete)
+}
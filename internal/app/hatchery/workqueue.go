@@ -0,0 +1,449 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package hatchery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	workQueueBucket = reservedBucketPrefix + "queue"
+
+	// maxExecutionAttempts is how many times a queued transaction is attempted
+	// before it is marked as failed.
+	maxExecutionAttempts = 3
+	// initialExecutionBackoff is the delay before the first retry of a queued
+	// transaction. It doubles with each subsequent retry.
+	initialExecutionBackoff = time.Second
+)
+
+// Queue item statuses.
+const (
+	QueueStatusPending   = "pending"
+	QueueStatusRunning   = "running"
+	QueueStatusFailed    = "failed"
+	QueueStatusCancelled = "cancelled"
+)
+
+// QueueItem is a posted transaction in the durable work queue. Items are removed
+// from the queue once their transaction has been appended to the ledger. Items that
+// exhaust their attempts remain in the queue with QueueStatusFailed, and items that
+// are cancelled with QueueStatusCancelled. See cancelWork.
+type QueueItem struct {
+	// ID is the ID the transaction is appended to the ledger with.
+	ID          string          `json:"id"`
+	TxnType     string          `json:"txn_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	Created     time.Time       `json:"created"`
+	// InvocationChain is the InvocationChain the transaction is appended with.
+	InvocationChain []string `json:"invocation_chain,omitempty"`
+	// Signer is the ID of the signing key whose signature was verified when the
+	// transaction was posted, if it was signed.
+	Signer string `json:"signer,omitempty"`
+	// TraceParent is the W3C traceparent of the span the transaction was queued in,
+	// which its attempts are traced under.
+	TraceParent string `json:"trace_parent,omitempty"`
+	// Node is the NodeID of the application that queued the item, which is the only
+	// node of a cluster that executes it.
+	Node string `json:"node,omitempty"`
+}
+
+// workResult is the outcome of a queued transaction, sent to the request that
+// posted it.
+type workResult struct {
+	t   *Transaction
+	err error
+}
+
+// ListQueue returns an HTTP handler function that responds with the items in the work
+// queue, oldest first. The optional status query parameter restricts the response to
+// items with that status.
+func (a *Application) ListQueue() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, err := a.queueItems()
+		if err != nil {
+			writeErrorFrom(w, err)
+			return
+		}
+		status := r.URL.Query().Get("status")
+		resp := make([]*QueueItem, 0, len(items))
+		for _, item := range items {
+			if status == "" || item.Status == status {
+				resp = append(resp, item)
+			}
+		}
+		writeJSONResponse(w, resp)
+	}
+}
+
+// enqueue adds a transaction to the work queue and returns a channel that receives
+// its outcome once it has been appended to the ledger or has failed for good. The
+// transaction is given the provided ID, or a new one if id is empty, and signer as
+// its Signer. It is traced as part of the trace in ctx.
+func (a *Application) enqueue(ctx context.Context, id, txnType string, payload []byte, signer string) (<-chan workResult, error) {
+	a.startWorkQueue()
+	if id == "" {
+		id = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	item := &QueueItem{
+		ID:          id,
+		TxnType:     txnType,
+		Payload:     payload,
+		Signer:      signer,
+		Status:      QueueStatusPending,
+		NextAttempt: now,
+		Created:     now,
+		TraceParent: tracing.Parent(ctx),
+	}
+	done := make(chan workResult, 1)
+	a.workMu.Lock()
+	a.workWaiters[item.ID] = done
+	a.workMu.Unlock()
+	if err := a.enqueueItem(item); err != nil {
+		a.workMu.Lock()
+		delete(a.workWaiters, item.ID)
+		a.workMu.Unlock()
+		return nil, err
+	}
+	return done, nil
+}
+
+// enqueueItem adds item to the work queue without waiting for its outcome. The item
+// belongs to this node.
+func (a *Application) enqueueItem(item *QueueItem) error {
+	a.startWorkQueue()
+	item.Node = a.NodeID
+	if err := a.putJSON(workQueueBucket, item.ID, item); err != nil {
+		return err
+	}
+	a.wakeWorkQueue()
+	return nil
+}
+
+// startWorkQueue starts dispatching queued transactions, if it hasn't been started
+// already. Items that were running when the application last stopped are returned
+// to the queue first, so that they are attempted again.
+func (a *Application) startWorkQueue() {
+	a.workOnce.Do(func() {
+		a.workMu.Lock()
+		a.workWake = make(chan struct{}, 1)
+		a.workDone = make(chan struct{})
+		a.workWaiters = make(map[string]chan workResult)
+		a.workCancels = make(map[string]context.CancelFunc)
+		a.workCancelled = make(map[string]bool)
+		a.workMu.Unlock()
+		items, err := a.queueItems()
+		if err != nil {
+			a.log().Error("failed to recover work queue", logging.Err(err))
+		}
+		for _, item := range items {
+			if item.Status != QueueStatusRunning || item.Node != a.NodeID {
+				continue
+			}
+			item.Status = QueueStatusPending
+			if err := a.putJSON(workQueueBucket, item.ID, item); err != nil {
+				a.log().Error("failed to recover queued transaction", logging.TxnID(item.ID), logging.Err(err))
+			}
+		}
+		go a.dispatch()
+	})
+}
+
+// cancelWork cancels the queued transaction with the given ID, if its outcome is still
+// awaited, such as when the client that posted it disconnects. A running attempt is
+// cancelled, which kills its container, and an item that is waiting for an attempt
+// is cancelled when it comes due. Either way, the item is marked as cancelled rather
+// than retried.
+func (a *Application) cancelWork(id string) {
+	a.workMu.Lock()
+	defer a.workMu.Unlock()
+	if _, ok := a.workWaiters[id]; !ok {
+		return
+	}
+	if cancel, ok := a.workCancels[id]; ok {
+		cancel()
+		return
+	}
+	a.workCancelled[id] = true
+}
+
+// stopWorkQueue stops dispatching queued transactions. Executions already in
+// progress are not interrupted.
+func (a *Application) stopWorkQueue() {
+	a.workMu.Lock()
+	defer a.workMu.Unlock()
+	if a.workDone == nil {
+		return
+	}
+	select {
+	case <-a.workDone:
+	default:
+		close(a.workDone)
+	}
+}
+
+func (a *Application) wakeWorkQueue() {
+	select {
+	case a.workWake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch starts a worker for each queued transaction as soon as it is due,
+// until the work queue is stopped.
+func (a *Application) dispatch() {
+	for {
+		next, err := a.dispatchDue()
+		if err != nil {
+			a.log().Error("failed to read work queue", logging.Err(err))
+			next = time.Now().Add(initialExecutionBackoff)
+		}
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+		select {
+		case <-a.workWake:
+		case <-due:
+		case <-a.workDone:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-a.workDone:
+			return
+		default:
+		}
+	}
+}
+
+// dispatchDue starts a worker for every pending item of this node that is due and
+// returns when the next pending item will be due. The zero time is returned if there is none.
+func (a *Application) dispatchDue() (time.Time, error) {
+	items, err := a.queueItems()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var next time.Time
+	now := time.Now()
+	for _, item := range items {
+		if item.Status != QueueStatusPending || item.Node != a.NodeID {
+			continue
+		}
+		if item.NextAttempt.After(now) {
+			if next.IsZero() || item.NextAttempt.Before(next) {
+				next = item.NextAttempt
+			}
+			continue
+		}
+		item.Status = QueueStatusRunning
+		if err := a.putJSON(workQueueBucket, item.ID, item); err != nil {
+			return time.Time{}, err
+		}
+		go a.work(item)
+	}
+	return next, nil
+}
+
+// work attempts a queued transaction. If the attempt fails, the item is scheduled
+// for a retry with exponential backoff, or marked as failed once it has exhausted
+// its attempts or its failure isn't retryable, as determined by the contract's
+// RetryPolicy. See retryable. An item that is cancelled with cancelWork is marked as
+// cancelled instead. Each attempt is traced as a span of the trace the item was
+// queued in.
+func (a *Application) work(item *QueueItem) {
+	logger := a.log().With(logging.Contract(item.TxnType), logging.TxnID(item.ID))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.workMu.Lock()
+	cancelled := a.workCancelled[item.ID]
+	delete(a.workCancelled, item.ID)
+	if !cancelled {
+		a.workCancels[item.ID] = cancel
+		defer func() {
+			a.workMu.Lock()
+			delete(a.workCancels, item.ID)
+			a.workMu.Unlock()
+		}()
+	}
+	a.workMu.Unlock()
+	if cancelled {
+		a.cancelItem(item, context.Canceled)
+		return
+	}
+	item.Attempts++
+	ctx, span := tracing.Start(tracing.WithParent(ctx, item.TraceParent), "transaction",
+		attribute.String("hatchery.contract", item.TxnType),
+		attribute.String("hatchery.txn_id", item.ID),
+		attribute.Int("hatchery.attempt", item.Attempts),
+	)
+	// A previous attempt may have appended the transaction before the application
+	// stopped, in which case it must not be executed again.
+	t, err := a.Ledger.Find(item.ID)
+	if err == ErrTransactionNotExist {
+		t, err = a.transact(ctx, item)
+	}
+	tracing.End(span, err)
+	if err == nil {
+		if err := a.Heap.Delete(workQueueBucket, item.ID); err != nil {
+			logger.Error("failed to dequeue transaction", logging.Err(err))
+		}
+		a.finishWork(item.ID, workResult{t: t})
+		return
+	}
+	if ctx.Err() == context.Canceled {
+		a.cancelItem(item, err)
+		return
+	}
+	item.LastError = err.Error()
+	policy := a.retryPolicy(item.TxnType)
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = maxExecutionAttempts
+	}
+	if !retryable(policy, err) || item.Attempts >= maxAttempts {
+		item.Status = QueueStatusFailed
+		logger.Error("queued transaction failed", logging.F("attempts", item.Attempts), logging.Err(err))
+	} else {
+		backoff, _ := policy.BackoffDuration()
+		if backoff == 0 {
+			backoff = initialExecutionBackoff
+		}
+		item.Status = QueueStatusPending
+		item.NextAttempt = time.Now().UTC().Add(backoff << uint(item.Attempts-1))
+	}
+	if err := a.putJSON(workQueueBucket, item.ID, item); err != nil {
+		logger.Error("failed to update queued transaction", logging.Err(err))
+	}
+	if item.Status == QueueStatusFailed {
+		a.finishWork(item.ID, workResult{err: err})
+		return
+	}
+	a.wakeWorkQueue()
+}
+
+// cancelItem marks the queued item as cancelled, with err as its last error, and
+// sends err to the request waiting on it, if any.
+func (a *Application) cancelItem(item *QueueItem, err error) {
+	item.Status = QueueStatusCancelled
+	item.LastError = err.Error()
+	a.log().Info("queued transaction cancelled", logging.Contract(item.TxnType), logging.TxnID(item.ID))
+	if err := a.putJSON(workQueueBucket, item.ID, item); err != nil {
+		a.log().Error("failed to update queued transaction", logging.Contract(item.TxnType), logging.TxnID(item.ID), logging.Err(err))
+	}
+	a.finishWork(item.ID, workResult{err: err})
+}
+
+// retryPolicy returns the RetryPolicy of the named contract, or the default policy
+// if its manifest doesn't set one or can't be read.
+func (a *Application) retryPolicy(name string) *backend.RetryPolicy {
+	m, err := a.Lib.Manifest(name)
+	if err != nil || m.RetryPolicy == nil {
+		return &backend.RetryPolicy{}
+	}
+	return m.RetryPolicy
+}
+
+// retryable reports whether a queued transaction whose attempt failed with err may be
+// attempted again under policy. Retrying a contract whose circuit breaker is open, or
+// whose image has drifted, would only fail fast again, so neither is retried.
+func retryable(policy *backend.RetryPolicy, err error) bool {
+	switch e := err.(type) {
+	case *CircuitOpenError, *ImageDriftError:
+		return false
+	case *ExecutionError:
+		if outputTooLarge(e) {
+			// The contract would produce the same output again.
+			return false
+		}
+		if exit, ok := e.Err.(interface{ ExitCode() int }); ok {
+			return policy.Retryable(exit.ExitCode())
+		}
+	}
+	return true
+}
+
+// finishWork sends res to the request waiting on the item with the given ID, if any.
+func (a *Application) finishWork(id string, res workResult) {
+	a.workMu.Lock()
+	done, ok := a.workWaiters[id]
+	delete(a.workWaiters, id)
+	a.workMu.Unlock()
+	if ok {
+		done <- res
+	}
+}
+
+// queuedItem returns the item with the given ID, or nil if it is not in the queue.
+func (a *Application) queuedItem(id string) (*QueueItem, error) {
+	b, err := a.Heap.Get(workQueueBucket, id)
+	if err == ErrHeapNotExist || (err == nil && len(b) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var item QueueItem
+	if err := json.Unmarshal(b, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// queueItems returns every item in the work queue, oldest first.
+func (a *Application) queueItems() ([]*QueueItem, error) {
+	all, err := a.Heap.GetRange(workQueueBucket, "", "")
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*QueueItem, 0, len(all))
+	for _, v := range all {
+		if len(v) == 0 {
+			continue
+		}
+		var item QueueItem
+		if err := json.Unmarshal(v, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Created.Before(items[j].Created)
+	})
+	return items, nil
+}
@@ -0,0 +1,199 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package logging provides the structured logger used throughout Hatchery.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+// Log levels, in increasing order of severity.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel returns the Level with the given name. An error is returned if
+// the name is not recognized.
+func ParseLevel(name string) (Level, error) {
+	switch name {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// Field is a key value pair attached to a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns a Field with the given key and value.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Contract returns a Field identifying a smart contract by name.
+func Contract(name string) Field {
+	return F("contract", name)
+}
+
+// TxnID returns a Field identifying a transaction.
+func TxnID(id string) Field {
+	return F("txn_id", id)
+}
+
+// Err returns a Field holding an error message.
+func Err(err error) Field {
+	return F("error", err.Error())
+}
+
+// Logger writes structured log entries.
+type Logger interface {
+	// Debug logs a message that is only useful when diagnosing problems.
+	Debug(msg string, fields ...Field)
+	// Info logs a message about normal operation.
+	Info(msg string, fields ...Field)
+	// Error logs a message about a failure.
+	Error(msg string, fields ...Field)
+	// With returns a Logger that attaches fields to every entry it logs.
+	With(fields ...Field) Logger
+}
+
+// JSONLogger is a Logger that writes each entry as a single line JSON object
+// with "time", "level" and "msg" members, followed by the entry's fields.
+type JSONLogger struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  Level
+	fields []Field
+}
+
+// New returns a JSONLogger that writes entries of at least the given level to w.
+func New(w io.Writer, level Level) *JSONLogger {
+	return &JSONLogger{
+		mu:    &sync.Mutex{},
+		w:     w,
+		level: level,
+	}
+}
+
+var defaultLogger Logger = New(os.Stderr, LevelInfo)
+
+// Default returns the Logger used when none has been configured. It writes
+// entries of level info and above to stderr.
+func Default() Logger {
+	return defaultLogger
+}
+
+// Debug logs msg at LevelDebug.
+func (l *JSONLogger) Debug(msg string, fields ...Field) {
+	l.log(LevelDebug, msg, fields)
+}
+
+// Info logs msg at LevelInfo.
+func (l *JSONLogger) Info(msg string, fields ...Field) {
+	l.log(LevelInfo, msg, fields)
+}
+
+// Error logs msg at LevelError.
+func (l *JSONLogger) Error(msg string, fields ...Field) {
+	l.log(LevelError, msg, fields)
+}
+
+// With returns a JSONLogger that shares l's output and attaches fields to
+// every entry.
+func (l *JSONLogger) With(fields ...Field) Logger {
+	all := make([]Field, 0, len(l.fields)+len(fields))
+	all = append(all, l.fields...)
+	all = append(all, fields...)
+	return &JSONLogger{
+		mu:     l.mu,
+		w:      l.w,
+		level:  l.level,
+		fields: all,
+	}
+}
+
+func (l *JSONLogger) log(level Level, msg string, fields []Field) {
+	if level < l.level {
+		return
+	}
+	entry := make(map[string]interface{}, 3+len(l.fields)+len(fields))
+	for _, f := range l.fields {
+		entry[f.Key] = f.Value
+	}
+	for _, f := range fields {
+		entry[f.Key] = f.Value
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["msg"] = msg
+	b, err := json.Marshal(entry)
+	if err != nil {
+		b, _ = json.Marshal(map[string]interface{}{
+			"time":  entry["time"],
+			"level": LevelError.String(),
+			"msg":   "failed to encode log entry",
+			"error": err.Error(),
+		})
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(b, '\n'))
+}
+
+// Nop returns a Logger that discards everything.
+func Nop() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...Field) {}
+func (nopLogger) Info(string, ...Field)  {}
+func (nopLogger) Error(string, ...Field) {}
+func (n nopLogger) With(...Field) Logger { return n }
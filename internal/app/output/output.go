@@ -0,0 +1,225 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package output captures the output of contract executions within a size limit,
+// so that a contract that writes without end can't exhaust the node's memory.
+package output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Policies for output beyond a Limit.
+const (
+	// PolicyTruncate keeps the first Max bytes of the output, followed by
+	// TruncationMarker.
+	PolicyTruncate = "truncate"
+	// PolicyFail fails the execution with a *TooLargeError.
+	PolicyFail = "fail"
+	// PolicySpill keeps the whole output, holding it in a temporary file rather
+	// than in memory once it exceeds Max, and then streams it into the Store of
+	// the execution's context. See WithSpill.
+	PolicySpill = "spill"
+)
+
+// TruncationMarker follows output truncated under PolicyTruncate.
+const TruncationMarker = "\n[output truncated]\n"
+
+// Limit bounds the output of an execution that is kept in memory.
+type Limit struct {
+	// Max is the number of bytes kept in memory. If zero or less, output is not
+	// limited.
+	Max int64
+	// Policy is PolicyTruncate, PolicyFail or PolicySpill. If empty,
+	// PolicyTruncate is assumed.
+	Policy string
+}
+
+// ValidPolicy reports whether policy is one of the policies, or empty.
+func ValidPolicy(policy string) bool {
+	switch policy {
+	case "", PolicyTruncate, PolicyFail, PolicySpill:
+		return true
+	}
+	return false
+}
+
+// Store stores output spilled under PolicySpill. backend.BlobStore implements it.
+type Store interface {
+	// PutBlob stores everything read from r and returns its address and size.
+	PutBlob(r io.Reader) (address string, size int64, err error)
+}
+
+// Spilled is output that was spilled under PolicySpill and stored in a Store.
+type Spilled struct {
+	// Address is the address the Store returned for the output, and Size is the
+	// output's size in bytes. Address is empty if no output was spilled.
+	Address string
+	Size    int64
+}
+
+type spillKey struct{}
+
+// spillTarget is where the Buffers of an execution store spilled output.
+type spillTarget struct {
+	store   Store
+	spilled *Spilled
+}
+
+// WithSpill returns a copy of ctx under which the Result of a Buffer stores output
+// spilled under PolicySpill in store, and records it in the returned Spilled.
+func WithSpill(ctx context.Context, store Store) (context.Context, *Spilled) {
+	spilled := &Spilled{}
+	return context.WithValue(ctx, spillKey{}, &spillTarget{store: store, spilled: spilled}), spilled
+}
+
+// TooLargeError is returned for output that exceeds its Limit under PolicyFail, or
+// under PolicySpill if there is nowhere to store it.
+type TooLargeError struct {
+	Max int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("output exceeds the limit of %d bytes", e.Max)
+}
+
+// Buffer captures output within a Limit. Writes to it never fail, so that the
+// writer's output is always drained; output beyond the limit is discarded, or
+// written to a temporary file under PolicySpill. Result returns what was captured. A Buffer
+// is not safe for concurrent use.
+type Buffer struct {
+	limit Limit
+	buf   bytes.Buffer
+	size  int64
+	file  *os.File
+	err   error
+}
+
+// NewBuffer returns an empty Buffer that captures output within limit.
+func NewBuffer(limit Limit) *Buffer {
+	return &Buffer{limit: limit}
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.size += int64(n)
+	switch {
+	case b.limit.Max <= 0 || b.size <= b.limit.Max:
+		b.buf.Write(p)
+	case b.limit.Policy == PolicySpill:
+		b.spill(p)
+	default:
+		// Keep the part of p that still fits.
+		if keep := int64(n) - (b.size - b.limit.Max); keep > 0 {
+			b.buf.Write(p[:keep])
+		}
+	}
+	return n, nil
+}
+
+// spill writes p to the spill file, moving the output buffered so far into it first.
+func (b *Buffer) spill(p []byte) {
+	if b.err != nil {
+		return
+	}
+	if b.file == nil {
+		if b.file, b.err = ioutil.TempFile("", "hatchery-output-"); b.err != nil {
+			return
+		}
+		b.write(b.buf.Bytes())
+		b.buf = bytes.Buffer{}
+	}
+	b.write(p)
+}
+
+func (b *Buffer) write(p []byte) {
+	if b.err != nil {
+		return
+	}
+	_, b.err = b.file.Write(p)
+}
+
+// Exceeded reports whether more output was written than the limit keeps in memory.
+func (b *Buffer) Exceeded() bool {
+	return b.limit.Max > 0 && b.size > b.limit.Max
+}
+
+// Bytes returns the output kept in memory, without applying the Limit's policy. It
+// is meant for output, such as stderr, that is only ever truncated.
+func (b *Buffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Result returns the captured output according to the Limit's policy. Output that fits
+// within the limit is returned as is. Otherwise, under PolicyTruncate, its first Max
+// bytes are returned followed by TruncationMarker, under PolicyFail, a *TooLargeError
+// is returned, and under PolicySpill, the spill file is streamed into the Store of
+// ctx and recorded in its Spilled, and no output is returned. See WithSpill. If ctx
+// has no Store, spilled output fails like it does under PolicyFail. The spill file is
+// removed either way.
+func (b *Buffer) Result(ctx context.Context) ([]byte, error) {
+	if !b.Exceeded() {
+		return b.buf.Bytes(), nil
+	}
+	switch b.limit.Policy {
+	case PolicyFail:
+		return nil, &TooLargeError{Max: b.limit.Max}
+	case PolicySpill:
+		return nil, b.store(ctx)
+	}
+	return append(b.buf.Bytes(), TruncationMarker...), nil
+}
+
+// store streams the whole output from the spill file into the Store of ctx, and
+// removes the file.
+func (b *Buffer) store(ctx context.Context) error {
+	defer b.Close()
+	target, ok := ctx.Value(spillKey{}).(*spillTarget)
+	if !ok {
+		return &TooLargeError{Max: b.limit.Max}
+	}
+	if b.file == nil || b.err != nil {
+		return fmt.Errorf("failed to spill output: %s", b.err)
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read spilled output: %s", err)
+	}
+	addr, size, err := target.store.PutBlob(b.file)
+	if err != nil {
+		return fmt.Errorf("failed to store spilled output: %s", err)
+	}
+	*target.spilled = Spilled{Address: addr, Size: size}
+	return nil
+}
+
+// Close removes the spill file of output whose Result was never taken, such as that
+// of an execution that failed. Result removes the file once it has stored it.
+func (b *Buffer) Close() {
+	if b.file != nil {
+		name := b.file.Name()
+		b.file.Close()
+		os.Remove(name)
+		b.file = nil
+	}
+}
@@ -0,0 +1,224 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package process executes smart contracts as local processes, for contracts
+// that are lightweight enough not to need a container.
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/output"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+)
+
+// Contract is a Contract implementation that executes Smart Contracts
+// as local processes.
+type Contract struct {
+	Name string
+	// Env is added to the environment inherited from Hatchery, overriding
+	// variables with the same name.
+	Env map[string]string
+	// Path is the path of the executable. If it contains no path separators,
+	// it is looked up in the PATH.
+	Path string
+	Args []string
+	// Dir is the working directory of the process. If empty, the process runs
+	// in Hatchery's working directory.
+	Dir string
+	// Timeout limits how long a single execution may run. If zero,
+	// executions are not limited.
+	Timeout time.Duration
+	// Logger receives the contract's logs. If nil, logging.Default() is used.
+	Logger logging.Logger
+	// Stdout and Stderr optionally receive a copy of the process's stdout and
+	// stderr as it is written. Writes to them must not fail.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Output limits the stdout captured from an execution. Stderr is truncated at
+	// the same size. If its Max is zero, output is not limited.
+	Output output.Limit
+}
+
+// Result is the outcome of running a contract's process to completion.
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+}
+
+// ExitError is returned by Execute when the contract's process exits with a
+// non-zero status.
+type ExitError struct {
+	Code int
+	// Stderr is everything the contract wrote to stderr.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("contract exited with status %d", e.Code)
+	if s := stderrTail(e.Stderr); s != "" {
+		msg += ": " + s
+	}
+	return msg
+}
+
+// stderrTail returns the end of stderr, trimmed of surrounding whitespace, for
+// inclusion in error messages.
+func stderrTail(stderr []byte) string {
+	const max = 512
+	s := strings.TrimSpace(string(stderr))
+	if len(s) > max {
+		s = "..." + s[len(s)-max:]
+	}
+	return s
+}
+
+// ExitCode returns the exit status of the process.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// SetEnv sets the environment variable key to value for subsequent executions.
+func (c *Contract) SetEnv(key, value string) {
+	if c.Env == nil {
+		c.Env = make(map[string]string)
+	}
+	c.Env[key] = value
+}
+
+// SetOutput sets the writers that receive a copy of the process's stdout and stderr
+// in subsequent executions.
+func (c *Contract) SetOutput(stdout, stderr io.Writer) {
+	c.Stdout, c.Stderr = stdout, stderr
+}
+
+// SetOutputLimit sets the limit of the output captured from subsequent executions.
+func (c *Contract) SetOutputLimit(limit output.Limit) {
+	c.Output = limit
+}
+
+// Execute runs the smart contract's executable. The payload is written to the
+// process's stdin and the process's stdout is returned. An error is returned if
+// the process could not be started or it exits with a non-zero status. The
+// process is killed if ctx is cancelled or Timeout is exceeded. If ctx is
+// cancelled, context.Canceled is returned.
+func (c *Contract) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	res, err := c.Run(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Code: res.ExitCode, Stderr: res.Stderr}
+	}
+	return res.Stdout, nil
+}
+
+// Run runs the smart contract's executable like Execute, but returns the complete
+// Result of the run. A non-zero exit status is not considered an error. The trace
+// context of ctx is passed to the process in the TRACEPARENT and TRACESTATE
+// environment variables, and the transaction it carries, if any, in TXN_ID,
+// TXN_TYPE, TXN_TIMESTAMP and INVOKER. See backend.InvocationEnv. The captured
+// stdout is subject to Output, so a *output.TooLargeError is returned for too much
+// output under output.PolicyFail, and output spilled under output.PolicySpill is
+// stored in the Store of ctx. See output.WithSpill.
+func (c *Contract) Run(ctx context.Context, payload []byte) (*Result, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	logger := c.Logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+	logger = logger.With(logging.Contract(c.Name))
+
+	stdout := output.NewBuffer(c.Output)
+	defer stdout.Close()
+	stderr := output.NewBuffer(output.Limit{Max: c.Output.Max})
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Env = append(os.Environ(), envList(backend.InvocationEnv(ctx, tracing.Env(ctx, c.Env)))...)
+	cmd.Dir = c.Dir
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = tee(stdout, c.Stdout)
+	cmd.Stderr = tee(stderr, c.Stderr)
+
+	logger.Debug("starting process", logging.F("path", c.Path))
+	start := time.Now()
+	err := cmd.Run()
+	if ctx.Err() == context.Canceled {
+		logger.Info("process killed because its execution was cancelled")
+		return nil, context.Canceled
+	}
+	if ctx.Err() == context.DeadlineExceeded && c.Timeout > 0 {
+		logger.Error("process timed out", logging.F("timeout", c.Timeout.String()))
+		return nil, fmt.Errorf("contract timed out after %s", c.Timeout)
+	}
+	res := &Result{Duration: time.Since(start)}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		res.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		logger.Error("process failed", logging.Err(err))
+		return nil, fmt.Errorf("failed to execute contract: %s", err)
+	}
+	if res.Stdout, err = stdout.Result(ctx); err != nil {
+		logger.Error("failed to capture process output", logging.Err(err))
+		return nil, err
+	}
+	res.Stderr, _ = stderr.Result(ctx)
+	fields := []logging.Field{
+		logging.F("exit_code", res.ExitCode),
+		logging.F("duration", res.Duration.String()),
+	}
+	if res.ExitCode != 0 {
+		fields = append(fields, logging.F("stderr", string(res.Stderr)))
+	}
+	logger.Debug("process exited", fields...)
+	return res, nil
+}
+
+// envList converts env to KEY=VALUE pairs, sorted by key.
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// tee returns a writer that writes to buf and, if it is not nil, to w.
+func tee(buf io.Writer, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
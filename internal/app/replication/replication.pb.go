@@ -0,0 +1,695 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: replication.proto
+
+package replication
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StreamRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// After is the ID of the follower's latest transaction.
+	After         string `protobuf:"bytes,1,opt,name=after,proto3" json:"after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamRequest) Reset() {
+	*x = StreamRequest{}
+	mi := &file_replication_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest) ProtoMessage() {}
+
+func (x *StreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
+func (*StreamRequest) Descriptor() ([]byte, []int) {
+	return file_replication_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StreamRequest) GetAfter() string {
+	if x != nil {
+		return x.After
+	}
+	return ""
+}
+
+// Record is a change a follower applies, in the order it is received.
+type Record struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Record:
+	//
+	//	*Record_ResetHeaps
+	//	*Record_HeapWrite
+	//	*Record_HeapDelete
+	//	*Record_Transaction
+	//	*Record_Ping
+	Record        isRecord_Record `protobuf_oneof:"record"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Record) Reset() {
+	*x = Record{}
+	mi := &file_replication_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Record) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Record) ProtoMessage() {}
+
+func (x *Record) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Record.ProtoReflect.Descriptor instead.
+func (*Record) Descriptor() ([]byte, []int) {
+	return file_replication_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Record) GetRecord() isRecord_Record {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *Record) GetResetHeaps() *Reset {
+	if x != nil {
+		if x, ok := x.Record.(*Record_ResetHeaps); ok {
+			return x.ResetHeaps
+		}
+	}
+	return nil
+}
+
+func (x *Record) GetHeapWrite() *HeapWrite {
+	if x != nil {
+		if x, ok := x.Record.(*Record_HeapWrite); ok {
+			return x.HeapWrite
+		}
+	}
+	return nil
+}
+
+func (x *Record) GetHeapDelete() *HeapDelete {
+	if x != nil {
+		if x, ok := x.Record.(*Record_HeapDelete); ok {
+			return x.HeapDelete
+		}
+	}
+	return nil
+}
+
+func (x *Record) GetTransaction() *Transaction {
+	if x != nil {
+		if x, ok := x.Record.(*Record_Transaction); ok {
+			return x.Transaction
+		}
+	}
+	return nil
+}
+
+func (x *Record) GetPing() *Ping {
+	if x != nil {
+		if x, ok := x.Record.(*Record_Ping); ok {
+			return x.Ping
+		}
+	}
+	return nil
+}
+
+type isRecord_Record interface {
+	isRecord_Record()
+}
+
+type Record_ResetHeaps struct {
+	ResetHeaps *Reset `protobuf:"bytes,1,opt,name=reset_heaps,json=resetHeaps,proto3,oneof"`
+}
+
+type Record_HeapWrite struct {
+	HeapWrite *HeapWrite `protobuf:"bytes,2,opt,name=heap_write,json=heapWrite,proto3,oneof"`
+}
+
+type Record_HeapDelete struct {
+	HeapDelete *HeapDelete `protobuf:"bytes,3,opt,name=heap_delete,json=heapDelete,proto3,oneof"`
+}
+
+type Record_Transaction struct {
+	Transaction *Transaction `protobuf:"bytes,4,opt,name=transaction,proto3,oneof"`
+}
+
+type Record_Ping struct {
+	Ping *Ping `protobuf:"bytes,5,opt,name=ping,proto3,oneof"`
+}
+
+func (*Record_ResetHeaps) isRecord_Record() {}
+
+func (*Record_HeapWrite) isRecord_Record() {}
+
+func (*Record_HeapDelete) isRecord_Record() {}
+
+func (*Record_Transaction) isRecord_Record() {}
+
+func (*Record_Ping) isRecord_Record() {}
+
+// Reset tells the follower to drop its contract heaps, before the primary sends a
+// snapshot of its own.
+type Reset struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reset) Reset() {
+	*x = Reset{}
+	mi := &file_replication_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reset) ProtoMessage() {}
+
+func (x *Reset) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reset.ProtoReflect.Descriptor instead.
+func (*Reset) Descriptor() ([]byte, []int) {
+	return file_replication_proto_rawDescGZIP(), []int{2}
+}
+
+// HeapWrite writes value to key in bucket.
+type HeapWrite struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bucket        string                 `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeapWrite) Reset() {
+	*x = HeapWrite{}
+	mi := &file_replication_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeapWrite) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeapWrite) ProtoMessage() {}
+
+func (x *HeapWrite) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeapWrite.ProtoReflect.Descriptor instead.
+func (*HeapWrite) Descriptor() ([]byte, []int) {
+	return file_replication_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HeapWrite) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *HeapWrite) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HeapWrite) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+// HeapDelete deletes key from bucket, or the whole bucket if key is empty.
+type HeapDelete struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bucket        string                 `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeapDelete) Reset() {
+	*x = HeapDelete{}
+	mi := &file_replication_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeapDelete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeapDelete) ProtoMessage() {}
+
+func (x *HeapDelete) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeapDelete.ProtoReflect.Descriptor instead.
+func (*HeapDelete) Descriptor() ([]byte, []int) {
+	return file_replication_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HeapDelete) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *HeapDelete) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+// Transaction is a transaction to append to the ledger, with every field its hash
+// covers. See backend.Transaction.
+type Transaction struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type            string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	InvokerContract string                 `protobuf:"bytes,3,opt,name=invoker_contract,json=invokerContract,proto3" json:"invoker_contract,omitempty"`
+	Status          string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	Content         []byte                 `protobuf:"bytes,5,opt,name=content,proto3" json:"content,omitempty"`
+	Timestamp       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	PrevHash        string                 `protobuf:"bytes,7,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	Hash            string                 `protobuf:"bytes,8,opt,name=hash,proto3" json:"hash,omitempty"`
+	HashVersion     int64                  `protobuf:"varint,9,opt,name=hash_version,json=hashVersion,proto3" json:"hash_version,omitempty"`
+	InvocationChain []string               `protobuf:"bytes,10,rep,name=invocation_chain,json=invocationChain,proto3" json:"invocation_chain,omitempty"`
+	Payload         []byte                 `protobuf:"bytes,11,opt,name=payload,proto3" json:"payload,omitempty"`
+	Signer          string                 `protobuf:"bytes,12,opt,name=signer,proto3" json:"signer,omitempty"`
+	Attempts        int64                  `protobuf:"varint,13,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	LastError       string                 `protobuf:"bytes,14,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	OutputRef       string                 `protobuf:"bytes,15,opt,name=output_ref,json=outputRef,proto3" json:"output_ref,omitempty"`
+	OutputSize      int64                  `protobuf:"varint,16,opt,name=output_size,json=outputSize,proto3" json:"output_size,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_replication_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_replication_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Transaction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Transaction) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Transaction) GetInvokerContract() string {
+	if x != nil {
+		return x.InvokerContract
+	}
+	return ""
+}
+
+func (x *Transaction) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Transaction) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *Transaction) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Transaction) GetPrevHash() string {
+	if x != nil {
+		return x.PrevHash
+	}
+	return ""
+}
+
+func (x *Transaction) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Transaction) GetHashVersion() int64 {
+	if x != nil {
+		return x.HashVersion
+	}
+	return 0
+}
+
+func (x *Transaction) GetInvocationChain() []string {
+	if x != nil {
+		return x.InvocationChain
+	}
+	return nil
+}
+
+func (x *Transaction) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Transaction) GetSigner() string {
+	if x != nil {
+		return x.Signer
+	}
+	return ""
+}
+
+func (x *Transaction) GetAttempts() int64 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *Transaction) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *Transaction) GetOutputRef() string {
+	if x != nil {
+		return x.OutputRef
+	}
+	return ""
+}
+
+func (x *Transaction) GetOutputSize() int64 {
+	if x != nil {
+		return x.OutputSize
+	}
+	return 0
+}
+
+// Ping is sent when the stream is idle, so that proxies don't time out the
+// connection.
+type Ping struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ping) Reset() {
+	*x = Ping{}
+	mi := &file_replication_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ping) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ping) ProtoMessage() {}
+
+func (x *Ping) ProtoReflect() protoreflect.Message {
+	mi := &file_replication_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ping.ProtoReflect.Descriptor instead.
+func (*Ping) Descriptor() ([]byte, []int) {
+	return file_replication_proto_rawDescGZIP(), []int{6}
+}
+
+var File_replication_proto protoreflect.FileDescriptor
+
+const file_replication_proto_rawDesc = "" +
+	"\n" +
+	"\x11replication.proto\x12\x14hatchery.replication\x1a\x1fgoogle/protobuf/timestamp.proto\"%\n" +
+	"\rStreamRequest\x12\x14\n" +
+	"\x05after\x18\x01 \x01(\tR\x05after\"\xd2\x02\n" +
+	"\x06Record\x12>\n" +
+	"\vreset_heaps\x18\x01 \x01(\v2\x1b.hatchery.replication.ResetH\x00R\n" +
+	"resetHeaps\x12@\n" +
+	"\n" +
+	"heap_write\x18\x02 \x01(\v2\x1f.hatchery.replication.HeapWriteH\x00R\theapWrite\x12C\n" +
+	"\vheap_delete\x18\x03 \x01(\v2 .hatchery.replication.HeapDeleteH\x00R\n" +
+	"heapDelete\x12E\n" +
+	"\vtransaction\x18\x04 \x01(\v2!.hatchery.replication.TransactionH\x00R\vtransaction\x120\n" +
+	"\x04ping\x18\x05 \x01(\v2\x1a.hatchery.replication.PingH\x00R\x04pingB\b\n" +
+	"\x06record\"\a\n" +
+	"\x05Reset\"K\n" +
+	"\tHeapWrite\x12\x16\n" +
+	"\x06bucket\x18\x01 \x01(\tR\x06bucket\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\"6\n" +
+	"\n" +
+	"HeapDelete\x12\x16\n" +
+	"\x06bucket\x18\x01 \x01(\tR\x06bucket\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"\xf4\x03\n" +
+	"\vTransaction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12)\n" +
+	"\x10invoker_contract\x18\x03 \x01(\tR\x0finvokerContract\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x18\n" +
+	"\acontent\x18\x05 \x01(\fR\acontent\x128\n" +
+	"\ttimestamp\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1b\n" +
+	"\tprev_hash\x18\a \x01(\tR\bprevHash\x12\x12\n" +
+	"\x04hash\x18\b \x01(\tR\x04hash\x12!\n" +
+	"\fhash_version\x18\t \x01(\x03R\vhashVersion\x12)\n" +
+	"\x10invocation_chain\x18\n" +
+	" \x03(\tR\x0finvocationChain\x12\x18\n" +
+	"\apayload\x18\v \x01(\fR\apayload\x12\x16\n" +
+	"\x06signer\x18\f \x01(\tR\x06signer\x12\x1a\n" +
+	"\battempts\x18\r \x01(\x03R\battempts\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x0e \x01(\tR\tlastError\x12\x1d\n" +
+	"\n" +
+	"output_ref\x18\x0f \x01(\tR\toutputRef\x12\x1f\n" +
+	"\voutput_size\x18\x10 \x01(\x03R\n" +
+	"outputSize\"\x06\n" +
+	"\x04Ping2\\\n" +
+	"\vReplication\x12M\n" +
+	"\x06Stream\x12#.hatchery.replication.StreamRequest\x1a\x1c.hatchery.replication.Record0\x01B>Z<github.com/summerplaygames/hatchery/internal/app/replicationb\x06proto3"
+
+var (
+	file_replication_proto_rawDescOnce sync.Once
+	file_replication_proto_rawDescData []byte
+)
+
+func file_replication_proto_rawDescGZIP() []byte {
+	file_replication_proto_rawDescOnce.Do(func() {
+		file_replication_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_replication_proto_rawDesc), len(file_replication_proto_rawDesc)))
+	})
+	return file_replication_proto_rawDescData
+}
+
+var file_replication_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_replication_proto_goTypes = []any{
+	(*StreamRequest)(nil),         // 0: hatchery.replication.StreamRequest
+	(*Record)(nil),                // 1: hatchery.replication.Record
+	(*Reset)(nil),                 // 2: hatchery.replication.Reset
+	(*HeapWrite)(nil),             // 3: hatchery.replication.HeapWrite
+	(*HeapDelete)(nil),            // 4: hatchery.replication.HeapDelete
+	(*Transaction)(nil),           // 5: hatchery.replication.Transaction
+	(*Ping)(nil),                  // 6: hatchery.replication.Ping
+	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
+}
+var file_replication_proto_depIdxs = []int32{
+	2, // 0: hatchery.replication.Record.reset_heaps:type_name -> hatchery.replication.Reset
+	3, // 1: hatchery.replication.Record.heap_write:type_name -> hatchery.replication.HeapWrite
+	4, // 2: hatchery.replication.Record.heap_delete:type_name -> hatchery.replication.HeapDelete
+	5, // 3: hatchery.replication.Record.transaction:type_name -> hatchery.replication.Transaction
+	6, // 4: hatchery.replication.Record.ping:type_name -> hatchery.replication.Ping
+	7, // 5: hatchery.replication.Transaction.timestamp:type_name -> google.protobuf.Timestamp
+	0, // 6: hatchery.replication.Replication.Stream:input_type -> hatchery.replication.StreamRequest
+	1, // 7: hatchery.replication.Replication.Stream:output_type -> hatchery.replication.Record
+	7, // [7:8] is the sub-list for method output_type
+	6, // [6:7] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_replication_proto_init() }
+func file_replication_proto_init() {
+	if File_replication_proto != nil {
+		return
+	}
+	file_replication_proto_msgTypes[1].OneofWrappers = []any{
+		(*Record_ResetHeaps)(nil),
+		(*Record_HeapWrite)(nil),
+		(*Record_HeapDelete)(nil),
+		(*Record_Transaction)(nil),
+		(*Record_Ping)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_replication_proto_rawDesc), len(file_replication_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_replication_proto_goTypes,
+		DependencyIndexes: file_replication_proto_depIdxs,
+		MessageInfos:      file_replication_proto_msgTypes,
+	}.Build()
+	File_replication_proto = out.File
+	file_replication_proto_goTypes = nil
+	file_replication_proto_depIdxs = nil
+}
@@ -0,0 +1,181 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package tracing instruments Hatchery with OpenTelemetry spans and propagates
+// trace context across HTTP requests, the work queue and contract executions.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Environment variables that carry the trace context into contracts, following
+// the W3C Trace Context format. A contract that calls back into the Hatchery API
+// should send TRACEPARENT as the traceparent header, so that its requests join
+// the trace of the transaction that executed it.
+const (
+	TraceParentEnv = "TRACEPARENT"
+	TraceStateEnv  = "TRACESTATE"
+)
+
+// DefaultServiceName is the service name spans are exported with when Settings
+// does not specify one.
+const DefaultServiceName = "hatchery"
+
+const instrumentationName = "github.com/summerplaygames/hatchery"
+
+// propagator propagates trace context whether or not spans are exported, so that
+// traces started by clients survive a Hatchery that doesn't export its own spans.
+var propagator = propagation.TraceContext{}
+
+// Settings configures the export of spans.
+type Settings struct {
+	// Endpoint is the URL of the OTLP/HTTP collector spans are exported to, such
+	// as "http://localhost:4318". If empty, spans are not exported, but trace
+	// context is still propagated.
+	Endpoint string
+	// ServiceName identifies Hatchery in exported spans. If empty,
+	// DefaultServiceName is used.
+	ServiceName string
+	// SampleRatio is the fraction of traces started by Hatchery that are sampled,
+	// between 0 and 1. Traces started by a client follow the client's sampling
+	// decision. If zero, every trace is sampled.
+	SampleRatio float64
+}
+
+// Configure starts exporting spans as described by s, and returns a function that
+// flushes and stops the export. An error is returned if the settings are invalid
+// or the exporter could not be created.
+func Configure(ctx context.Context, s Settings) (func(context.Context) error, error) {
+	if s.SampleRatio < 0 || s.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid trace sample ratio %v: must be between 0 and 1", s.SampleRatio)
+	}
+	if s.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(s.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter for %s: %s", s.Endpoint, err)
+	}
+	name := s.ServiceName
+	if name == "" {
+		name = DefaultServiceName
+	}
+	sampler := sdktrace.AlwaysSample()
+	if s.SampleRatio > 0 {
+		sampler = sdktrace.TraceIDRatioBased(s.SampleRatio)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", name))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	return tp.Shutdown, nil
+}
+
+// Start starts a span with the given name and attributes as a child of the span
+// in ctx, if any, and returns a context carrying the new span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer starts the span of an HTTP request handled by Hatchery, as a child
+// of the trace context in the request's headers, if any. A request whose context
+// already carries a span, because it is being served by a nested router, is traced
+// as a child of that span instead.
+func StartServer(r *http.Request, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := r.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+	}
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// End ends span, marking it as failed with err if err is not nil. Cancellations
+// are recorded, but do not fail the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !errors.Is(err, context.Canceled) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// Inject adds the trace context of ctx to the headers of an outgoing request.
+func Inject(ctx context.Context, h http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// Parent returns the W3C traceparent of the span in ctx, or an empty string if
+// ctx carries no valid span. It is persisted with work that outlives ctx, such as
+// queued transactions, so that the work can rejoin the trace through WithParent.
+func Parent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// WithParent returns a copy of ctx carrying the remote span identified by the W3C
+// traceparent parent. If parent is empty or invalid, ctx is returned unchanged.
+func WithParent(ctx context.Context, parent string) context.Context {
+	if parent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": parent})
+}
+
+// Env returns env with TRACEPARENT and TRACESTATE set to the trace context of ctx,
+// so that a contract's requests can join the trace it is executed in. env itself is
+// not modified. If ctx carries no valid span, env is returned as is.
+func Env(ctx context.Context, env map[string]string) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	parent := carrier.Get("traceparent")
+	if parent == "" {
+		return env
+	}
+	out := make(map[string]string, len(env)+2)
+	for k, v := range env {
+		out[k] = v
+	}
+	out[TraceParentEnv] = parent
+	if state := carrier.Get("tracestate"); state != "" {
+		out[TraceStateEnv] = state
+	}
+	return out
+}
@@ -0,0 +1,273 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package wasm executes smart contracts as WebAssembly modules with wazero, for
+// lightweight contracts that need neither Docker nor a native executable.
+// Modules are run as WASI commands: the payload is their stdin and their stdout is
+// the contract's output. They have no access to the filesystem or the network.
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/internal/app/output"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// cache holds the compiled code of modules, keyed by their contents, so that a
+// module is only compiled once however many times it is executed.
+var cache = wazero.NewCompilationCache()
+
+// Contract is a Contract implementation that executes Smart Contracts
+// as WebAssembly modules.
+type Contract struct {
+	Name string
+	// Env is the environment of the module. Unlike a process, the module
+	// doesn't inherit Hatchery's environment.
+	Env map[string]string
+	// Path is the path of the module's .wasm file. It is read for every
+	// execution, so a replaced module is picked up by the next one.
+	Path string
+	Args []string
+	// Timeout limits how long a single execution may run. If zero,
+	// executions are not limited.
+	Timeout time.Duration
+	// Logger receives the contract's logs. If nil, logging.Default() is used.
+	Logger logging.Logger
+	// Stdout and Stderr optionally receive a copy of the module's stdout and
+	// stderr as it is written. Writes to them must not fail.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Output limits the stdout captured from an execution. Stderr is truncated at
+	// the same size. If its Max is zero, output is not limited.
+	Output output.Limit
+}
+
+// Result is the outcome of running a contract's module to completion.
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+}
+
+// ExitError is returned by Execute when the contract's module exits with a
+// non-zero status.
+type ExitError struct {
+	Code int
+	// Stderr is everything the contract wrote to stderr.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("contract exited with status %d", e.Code)
+	if s := stderrTail(e.Stderr); s != "" {
+		msg += ": " + s
+	}
+	return msg
+}
+
+// stderrTail returns the end of stderr, trimmed of surrounding whitespace, for
+// inclusion in error messages.
+func stderrTail(stderr []byte) string {
+	const max = 512
+	s := strings.TrimSpace(string(stderr))
+	if len(s) > max {
+		s = "..." + s[len(s)-max:]
+	}
+	return s
+}
+
+// ExitCode returns the exit status of the module.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Compile reads and compiles the module at path, so that an invalid module is
+// reported before it is executed.
+func Compile(ctx context.Context, path string) error {
+	bin, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read module: %s", err)
+	}
+	r := newRuntime(ctx)
+	defer r.Close(ctx)
+	if _, err := r.CompileModule(ctx, bin); err != nil {
+		return fmt.Errorf("invalid module: %s", err)
+	}
+	return nil
+}
+
+// newRuntime returns a wazero runtime that shares the compilation cache and aborts
+// its modules when their context is done.
+func newRuntime(ctx context.Context) wazero.Runtime {
+	config := wazero.NewRuntimeConfig().WithCompilationCache(cache).WithCloseOnContextDone(true)
+	return wazero.NewRuntimeWithConfig(ctx, config)
+}
+
+// SetEnv sets the environment variable key to value for subsequent executions.
+func (c *Contract) SetEnv(key, value string) {
+	if c.Env == nil {
+		c.Env = make(map[string]string)
+	}
+	c.Env[key] = value
+}
+
+// SetOutput sets the writers that receive a copy of the module's stdout and stderr
+// in subsequent executions.
+func (c *Contract) SetOutput(stdout, stderr io.Writer) {
+	c.Stdout, c.Stderr = stdout, stderr
+}
+
+// SetOutputLimit sets the limit of the output captured from subsequent executions.
+func (c *Contract) SetOutputLimit(limit output.Limit) {
+	c.Output = limit
+}
+
+// Execute runs the smart contract's module. The payload is written to the module's
+// stdin and the module's stdout is returned. An error is returned if the module
+// could not be instantiated or it exits with a non-zero status. The module is
+// aborted if ctx is cancelled or Timeout is exceeded. If ctx is cancelled,
+// context.Canceled is returned.
+func (c *Contract) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	res, err := c.Run(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, &ExitError{Code: res.ExitCode, Stderr: res.Stderr}
+	}
+	return res.Stdout, nil
+}
+
+// Run runs the smart contract's module like Execute, but returns the complete
+// Result of the run. A non-zero exit status is not considered an error. The trace
+// context of ctx is passed to the module in the TRACEPARENT and TRACESTATE
+// environment variables, and the transaction it carries, if any, in TXN_ID,
+// TXN_TYPE, TXN_TIMESTAMP and INVOKER. See backend.InvocationEnv. The captured
+// stdout is subject to Output, so a *output.TooLargeError is returned for too much
+// output under output.PolicyFail, and output spilled under output.PolicySpill is
+// stored in the Store of ctx. See output.WithSpill.
+func (c *Contract) Run(ctx context.Context, payload []byte) (*Result, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	logger := c.Logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+	logger = logger.With(logging.Contract(c.Name))
+
+	bin, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		logger.Error("failed to read module", logging.Err(err))
+		return nil, fmt.Errorf("failed to read module: %s", err)
+	}
+	r := newRuntime(ctx)
+	defer r.Close(context.Background())
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		return nil, fmt.Errorf("failed to instantiate WASI: %s", err)
+	}
+	compiled, err := r.CompileModule(ctx, bin)
+	if err != nil {
+		logger.Error("failed to compile module", logging.Err(err))
+		return nil, fmt.Errorf("invalid module: %s", err)
+	}
+
+	stdout := output.NewBuffer(c.Output)
+	defer stdout.Close()
+	stderr := output.NewBuffer(output.Limit{Max: c.Output.Max})
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(append([]string{c.Name}, c.Args...)...).
+		WithStdin(bytes.NewReader(payload)).
+		WithStdout(tee(stdout, c.Stdout)).
+		WithStderr(tee(stderr, c.Stderr)).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	env := backend.InvocationEnv(ctx, tracing.Env(ctx, c.Env))
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		config = config.WithEnv(k, env[k])
+	}
+
+	logger.Debug("starting module", logging.F("path", c.Path))
+	start := time.Now()
+	mod, err := r.InstantiateModule(ctx, compiled, config)
+	if mod != nil {
+		mod.Close(context.Background())
+	}
+	if ctx.Err() == context.Canceled {
+		logger.Info("module aborted because its execution was cancelled")
+		return nil, context.Canceled
+	}
+	if ctx.Err() == context.DeadlineExceeded && c.Timeout > 0 {
+		logger.Error("module timed out", logging.F("timeout", c.Timeout.String()))
+		return nil, fmt.Errorf("contract timed out after %s", c.Timeout)
+	}
+	res := &Result{Duration: time.Since(start)}
+	if exitErr, ok := err.(*sys.ExitError); ok {
+		res.ExitCode = int(exitErr.ExitCode())
+	} else if err != nil {
+		logger.Error("module failed", logging.Err(err))
+		return nil, fmt.Errorf("failed to execute contract: %s", err)
+	}
+	if res.Stdout, err = stdout.Result(ctx); err != nil {
+		logger.Error("failed to capture module output", logging.Err(err))
+		return nil, err
+	}
+	res.Stderr, _ = stderr.Result(ctx)
+	fields := []logging.Field{
+		logging.F("exit_code", res.ExitCode),
+		logging.F("duration", res.Duration.String()),
+	}
+	if res.ExitCode != 0 {
+		fields = append(fields, logging.F("stderr", string(res.Stderr)))
+	}
+	logger.Debug("module exited", fields...)
+	return res, nil
+}
+
+// tee returns a writer that writes to buf and, if it is not nil, to w.
+func tee(buf io.Writer, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
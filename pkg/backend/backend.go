@@ -0,0 +1,548 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package backend defines the interfaces of the stores behind a Hatchery node:
+// the Heap, the Ledger and the contract Library. Implementations outside of
+// Hatchery register themselves by name with RegisterHeap, RegisterLedger or
+// RegisterLibrary and are then selected through the node's configuration.
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ExecutionOrderParallel signifies parrellel execution of smart contracts.
+	ExecutionOrderParallel = "parallel"
+	// ExecutionOrderSerial signifies serial execution of smart contracts.
+	ExecutionOrderSerial = "serial"
+)
+
+var (
+	// ErrContractNotExist is returned when a request contract does not exist.
+	ErrContractNotExist = errors.New("contract does not exist")
+	// ErrHeapNotExist is returned when a requested heap key does not exist.
+	ErrHeapNotExist = errors.New("heap value doesn't exist for key")
+	// ErrTransactionNotExist is returned when a requested transaction does not exist.
+	ErrTransactionNotExist = errors.New("transaction does not exist")
+	// ErrVersionNotExist is returned when a requested contract version does not exist.
+	ErrVersionNotExist = errors.New("contract version does not exist")
+	// ErrPruneLatest is returned when pruning would remove the latest transaction
+	// in a ledger, which the next transaction appended must link to.
+	ErrPruneLatest = errors.New("the latest transaction can't be pruned")
+)
+
+// ExecutionOrder determines how multiple instances of the same contract are executed.
+type ExecutionOrder string
+
+// TransactionStatus describes the outcome of a transaction.
+type TransactionStatus string
+
+const (
+	// TransactionStatusPending signifies a transaction that has not been processed yet.
+	TransactionStatusPending TransactionStatus = "pending"
+	// TransactionStatusSuccess signifies a transaction that was processed successfully.
+	TransactionStatusSuccess TransactionStatus = "success"
+	// TransactionStatusFailure signifies a posted transaction that failed for good and
+	// was never appended to the ledger.
+	TransactionStatusFailure TransactionStatus = "failure"
+)
+
+// Transaction is a single, atomic operation on the ledger.
+type Transaction struct {
+	// The transaction's unique ID.
+	ID string
+	// Type is the transaction type. For the output of a smart contract, this
+	// is the name of the contract.
+	Type string `json:"txn_type"`
+	// InvokerContract is the name of the smart contract whose output is the
+	// content of the transaction. It is empty for regular transactions, whose
+	// content is the posted payload.
+	InvokerContract string
+	// Status is the outcome of the transaction.
+	Status TransactionStatus
+	// The content that is stored along with the transaction. This could
+	// be the output of a smart contract or simply the payload of a
+	// posted transaction.
+	Content []byte `json:"-"`
+	// Timestamp is the time at which the transaction was created.
+	Timestamp time.Time
+	// PrevHash is the Hash of the transaction that precedes this one in
+	// the ledger. It is empty for the genesis transaction.
+	PrevHash string
+	// Hash is the hex encoded SHA-256 hash of the transaction, computed
+	// when the transaction is appended to the ledger. See ComputeHash.
+	Hash string
+	// HashVersion is the version of ComputeHash that Hash was computed with.
+	// It is zero for transactions appended before the version was recorded.
+	HashVersion int `json:",omitempty"`
+	// InvocationChain holds the IDs of the transactions whose contracts invoked
+	// this one, starting with the transaction that began the chain. It is empty
+	// for transactions that were posted directly.
+	InvocationChain []string `json:",omitempty"`
+	// Payload is the payload the smart contract was executed with, kept so that
+	// the execution can be replayed. It is empty for regular transactions, whose
+	// Content is their payload.
+	Payload []byte `json:",omitempty"`
+	// Signer is the ID of the signing key whose signature was verified when the
+	// transaction was posted. It is empty for unsigned transactions.
+	Signer string `json:",omitempty"`
+	// Attempts is how many times the smart contract was executed before the
+	// transaction was appended, and LastError is the error of the last attempt
+	// that failed, if any did. Attempts is zero for regular transactions.
+	Attempts  int    `json:",omitempty"`
+	LastError string `json:",omitempty"`
+	// ContentRef and PayloadRef are the content addresses of Content and Payload
+	// while they are stored as blobs by a ledger. They are never set on the
+	// transactions a Ledger returns. See SplitBlobs.
+	ContentRef string `json:"-"`
+	PayloadRef string `json:"-"`
+	// OutputRef is the BlobAddress of the output of a contract that was spilled
+	// into the ledger's BlobStore rather than kept in Content, which is empty then,
+	// and OutputSize is the output's size in bytes. Unlike ContentRef, it is kept
+	// on the transactions a Ledger returns, and the output is read with
+	// BlobStore.OpenBlob.
+	OutputRef  string `json:",omitempty"`
+	OutputSize int64  `json:",omitempty"`
+}
+
+// NewTransaction returns a new Transaction instance with the provided
+// content. A unique ID is generated for the transaction.
+func NewTransaction(content []byte) *Transaction {
+	id := uuid.New()
+	return &Transaction{
+		ID:        id.String(),
+		Content:   content,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// Contract is a smart contract that can be executed.
+type Contract interface {
+	// Execute executes the smart contract. The provided payload
+	// is passed into the contract's stdin and the contract's stdout
+	// is returned. An error is returned if the contract could not be
+	// executed. If ctx is cancelled before the contract finishes, the
+	// execution is aborted and an error is returned.
+	Execute(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// ContractManifest contains information about a smart contract. It is used
+// by a Library to track posted contracts for later execution.
+type ContractManifest struct {
+	// Type is the transaction type. For smart contracts, this
+	// will be the name of the contract.
+	Type string `json:"txn_type"`
+	// Runtime is the name of the runtime that executes the contract, such as
+	// "docker", "exec" or "wasm". If empty, "docker" is assumed.
+	Runtime string
+	// Image is the Docker image that contains the contract code to be
+	// executed. It should be in the format <dockerhub id>/<image name>:<image version>.
+	// The docker container will be pulled down from DockerHub and the container will be
+	// executed via `docker run`.
+	Image string
+	// Source optionally builds the contract's image from a git repository instead
+	// of pulling Image, as <repository URL>[#<branch, tag or commit>]. The image
+	// is built when the contract is stored and tagged with the contract's name
+	// and version, which replaces Image.
+	Source string `json:",omitempty"`
+	// Dockerfile is the path, in the repository named by Source, of the
+	// Dockerfile to build. The repository is the build context. If empty,
+	// "Dockerfile" is built.
+	Dockerfile string `json:",omitempty"`
+	// Cmd is the command to execute in the smart contract's docker container.
+	// For the "exec" runtime, it is the path of the executable, and for the
+	// "wasm" runtime, the path of the WebAssembly module.
+	Cmd string
+	// Args are optional additional application arguments that are passed in to the docker
+	// container after the command.
+	Args []string
+	// ExecutionOrder stipulates how multiple instances of the same smart contract are
+	// executed. Valid values are ExecutionOrderParallel and ExecutionOrderSerial. If
+	// empty, ExecutionOrderParallel is assumed.
+	ExecutionOrder ExecutionOrder `json:"execution_order"`
+	// Priority weights the contract's share of the node's execution slots when
+	// more executions are waiting than the node may run at once: waiting
+	// contracts take turns in proportion to their priorities, so a contract of
+	// priority 3 is admitted three times as often as one of priority 1. If zero,
+	// a priority of 1 is assumed.
+	Priority int `json:",omitempty"`
+	// Env is an optional set of environment variables to pass into the contract at runtime.
+	Env map[string]string
+	// Secrets maps environment variable names to the names of secrets in the
+	// node's secret store. Each secret is decrypted and passed into the contract
+	// when it is launched, taking precedence over Env. Secret values are never
+	// stored in the manifest.
+	Secrets map[string]string
+	// Cron is an optional schedule for recurring execution. It may be a standard five-field
+	// cron expression (e.g. "0 */5 * * *"), a descriptor such as "@hourly" or "@every 5m",
+	// or a plain duration such as "30s".
+	Cron string
+	// CronOverlap determines what happens when Cron activates while the previous
+	// scheduled execution is still running: "concurrent" executes anyway, "skip"
+	// skips the activation and "queue" executes once the previous execution
+	// finishes, with at most one execution waiting. If empty, "concurrent" is assumed.
+	CronOverlap string
+	// CronJitter is an optional upper bound on a random delay added to each
+	// activation of Cron, specified as a duration such as "10s", so that contracts
+	// on the same schedule don't all execute at once.
+	CronJitter string
+	// CronPayload is an optional JSON value passed to the contract as the payload
+	// of each scheduled execution.
+	CronPayload json.RawMessage `json:",omitempty"`
+	// CronPayloadSource optionally reads the payload of each scheduled execution
+	// from the heap when Cron activates, as a reference of the form
+	// "heap:<bucket>/<key>". CronPayload is passed instead if the key doesn't exist.
+	CronPayloadSource string
+	// ExecutionTimeout is an optional limit on how long a single execution of the
+	// contract may run, specified as a duration such as "30s". Executions that exceed
+	// it are killed. If empty, executions are not limited.
+	ExecutionTimeout string
+	// DigestPolicy determines what happens when Image no longer refers to the
+	// ImageDigest recorded when the contract was stored, for example because its
+	// tag was pushed again: "repin" points the tag back at the recorded digest and
+	// "refuse" fails the execution. If empty, the digest is not verified.
+	DigestPolicy string
+	// Network controls the network access of the contract's container: "none"
+	// gives it none, "bridge" attaches it to Docker's default bridge network, "host"
+	// shares the host's network and "allowlist" only lets it make HTTP and HTTPS
+	// requests, through a proxy, to the hosts in NetworkAllow. If empty, the node's
+	// default policy, which is "none" unless configured otherwise, is used.
+	Network string
+	// NetworkAllow lists the hosts a contract whose Network is "allowlist" may
+	// reach. An entry beginning with "*." matches every subdomain.
+	NetworkAllow []string
+	// WarmPool is how many idle containers of the contract to keep running between
+	// executions, so that executions don't wait for a container to start. The
+	// contract must serve executions with Hatchery's warm protocol: see
+	// docker.WarmEnv. If zero, every execution runs in a new container.
+	WarmPool int `json:",omitempty"`
+	// WarmMaxUses is how many executions a warm container serves before it is
+	// replaced with a new one. If zero, warm containers are reused indefinitely.
+	WarmMaxUses int `json:",omitempty"`
+	// HeapMaxBytes and HeapMaxKeys override the node's default quota of the
+	// contract's heap bucket: the total size, in bytes, of its keys and values,
+	// and the number of its keys. If zero, the node's default applies.
+	HeapMaxBytes int64
+	HeapMaxKeys  int
+	// HeapEviction determines what happens to a write that would exceed the
+	// heap quota: "reject" fails the write, and "lru" evicts the least recently
+	// read or written keys to make room for it. If empty, "reject" is assumed.
+	HeapEviction string
+	// HeapOutput is how the contract's output is stored in the heap: "top_level"
+	// stores each member of a JSON object under its own key, "flatten" stores
+	// each leaf of nested objects under its dotted path, such as "a.b.c",
+	// "single" stores the whole output under HeapOutputKey, and "envelope" stores
+	// the members of the output's "heap" object, like DragonChain. If empty,
+	// "top_level" is assumed.
+	HeapOutput string `json:",omitempty"`
+	// HeapOutputKey is the key the "single" HeapOutput strategy stores output
+	// under. If empty, "output" is used.
+	HeapOutputKey string `json:",omitempty"`
+	// HeapReaders and HeapWriters list the other contracts that may read and
+	// write the contract's heap bucket, with their own heap tokens or through
+	// heap references in their manifests. Writers may also read. "*" lists every
+	// contract. The contract itself always has full access to its bucket.
+	HeapReaders []string `json:",omitempty"`
+	HeapWriters []string `json:",omitempty"`
+	// HeapSchemas optionally type the values of the contract's heap bucket by key
+	// prefix. Writes are validated against the schema of their key, and reads
+	// are served with its content type.
+	HeapSchemas []HeapSchema `json:",omitempty"`
+	// RetryPolicy optionally determines how failed executions of the contract's
+	// posted transactions are retried. If nil, they are attempted three times, a
+	// second apart and then two.
+	RetryPolicy *RetryPolicy `json:",omitempty"`
+	// Auth is an optional registry credential that is used when pulling the container image.
+	// This is used when your container image is private. It has the form
+	// <username>:<password or access token>, optionally base64 encoded. Libraries store it
+	// encrypted and it is never returned by the API.
+	Auth string
+	// Version is the version of the contract, assigned by the Library each time the
+	// contract is stored. The first version of a contract is 1.
+	Version int
+	// ImageDigest is the digest of Image at the time the version was stored, as
+	// recorded by the Library.
+	ImageDigest string
+}
+
+// HeapSchema types the values of the heap keys that begin with Prefix. If the
+// prefixes of several schemas match a key, the longest one applies.
+type HeapSchema struct {
+	// Prefix selects the keys the schema applies to. An empty prefix matches
+	// every key.
+	Prefix string
+	// ContentType is the media type of the values, such as "application/json" or
+	// "image/png", which heap reads are served with. If empty, it is
+	// "application/json" when Schema is set, and otherwise guessed from each value.
+	ContentType string `json:",omitempty"`
+	// Schema is an optional JSON Schema the values must satisfy. Values of keys
+	// with a schema must be JSON.
+	Schema json.RawMessage `json:",omitempty"`
+}
+
+// RetryPolicy determines how the failed executions of a posted transaction are
+// retried.
+type RetryPolicy struct {
+	// MaxAttempts is how many times a transaction is executed, including the first
+	// attempt, before it fails for good. If zero, it is attempted three times.
+	MaxAttempts int `json:",omitempty"`
+	// Backoff is how long to wait before the first retry, specified as a duration
+	// such as "5s". The wait doubles with each subsequent retry. If empty, the
+	// first retry waits a second.
+	Backoff string `json:",omitempty"`
+	// RetryableExitCodes, if set, restricts retries to executions that exit with
+	// one of these statuses. Executions that fail without exiting, for example
+	// because their container couldn't be started or they timed out, are always
+	// retried.
+	RetryableExitCodes []int `json:",omitempty"`
+}
+
+// BackoffDuration returns the parsed Backoff of the policy. Zero is returned if no
+// backoff is set. An error is returned if Backoff is not a valid, non-negative
+// duration.
+func (p *RetryPolicy) BackoffDuration() (time.Duration, error) {
+	if p.Backoff == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(p.Backoff)
+	if err != nil {
+		return 0, fmt.Errorf("invalid retry backoff: %s", err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid retry backoff: %s is negative", d)
+	}
+	return d, nil
+}
+
+// Retryable reports whether an execution that exited with the given status may
+// be retried under the policy.
+func (p *RetryPolicy) Retryable(code int) bool {
+	if len(p.RetryableExitCodes) == 0 {
+		return true
+	}
+	for _, c := range p.RetryableExitCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ContractVersion describes a stored version of a smart contract.
+type ContractVersion struct {
+	Version     int       `json:"version"`
+	Image       string    `json:"image"`
+	ImageDigest string    `json:"image_digest"`
+	Created     time.Time `json:"created"`
+}
+
+// Timeout returns the parsed ExecutionTimeout of the manifest. Zero is returned
+// if no timeout is set. An error is returned if ExecutionTimeout is not a valid,
+// non-negative duration.
+func (m *ContractManifest) Timeout() (time.Duration, error) {
+	if m.ExecutionTimeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(m.ExecutionTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid execution timeout: %s", err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid execution timeout: %s is negative", d)
+	}
+	return d, nil
+}
+
+// Jitter returns the parsed CronJitter of the manifest. Zero is returned if no
+// jitter is set. An error is returned if CronJitter is not a valid, non-negative
+// duration.
+func (m *ContractManifest) Jitter() (time.Duration, error) {
+	if m.CronJitter == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(m.CronJitter)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cron jitter: %s", err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid cron jitter: %s is negative", d)
+	}
+	return d, nil
+}
+
+// Redacted returns a copy of the manifest with secrets, such as Auth, removed so
+// that it is safe to return to API clients.
+func (m ContractManifest) Redacted() ContractManifest {
+	m.Auth = ""
+	return m
+}
+
+// Library is a collection of smart contracts.
+type Library interface {
+	// Get returns the smart contract with the provided name.
+	// If the contract doesn't exist in the library, ErrContractNotExist
+	// is returned. Otherwise, an error is returned if something went wrong
+	// when retrieving the contract.
+	Get(name string) (Contract, error)
+	// GetVersion returns the given version of the smart contract with the
+	// provided name. If the contract doesn't exist in the library,
+	// ErrContractNotExist is returned. If it exists but has no such version,
+	// ErrVersionNotExist is returned.
+	GetVersion(name string, version int) (Contract, error)
+	// Contract returns a Contract that executes the contract described by the
+	// provided manifest, in the same environment as the library's stored contracts,
+	// without storing it.
+	Contract(manifest *ContractManifest) (Contract, error)
+	// Manifest returns the ContractManifest of the smart contract with the
+	// provided name. If the contract doesn't exist in the library,
+	// ErrContractNotExist is returned.
+	Manifest(name string) (*ContractManifest, error)
+	// List returns the manifests of all contracts in the library. An error
+	// is returned if the manifests could not be retrieved.
+	List() ([]ContractManifest, error)
+	// Versions returns the version history of the smart contract with the
+	// provided name, oldest first. If the contract doesn't exist in the library,
+	// ErrContractNotExist is returned.
+	Versions(name string) ([]ContractVersion, error)
+	// Put stores a contract in the library, described by the provided
+	// ContractManifest. If the contract already exists, the manifest is stored
+	// as its next version. An error is returned if the contract could not be
+	// stored.
+	Put(req *ContractManifest) error
+	// Update stores the manifest as the next version of an existing contract.
+	// If the contract doesn't exist in the library, ErrContractNotExist is
+	// returned. Otherwise, an error is returned if the contract could not be
+	// stored.
+	Update(req *ContractManifest) error
+	// Delete removes the contract with the provided name from the library.
+	// If the contract doesn't exist in the library, ErrContractNotExist is
+	// returned. Otherwise, an error is returned if the contract could not be
+	// removed.
+	Delete(name string) error
+}
+
+// Heap is a generic key-value store that can contracts can write to to persist
+// data across multiple contract executions.
+type Heap interface {
+	// Put inserts a key value pair in the heap. The bucket parameter is used
+	// to segregate kvps into logical groups. This is useful when running multiple
+	// instances of Hatchery using the same backing datastore.
+	//
+	// An error is returned if the kvp could not be stored.
+	Put(bucket, key string, value []byte) error
+	// CompareAndSwap atomically stores new under key in a bucket if the key
+	// currently holds old, and reports whether it did. A nil old swaps only if
+	// the key doesn't exist. An error is returned if the value could not be
+	// compared or stored.
+	CompareAndSwap(bucket, key string, old, new []byte) (bool, error)
+	// Get retrieves a value with the provided key from the Heap. An error is
+	// returned if the value for the key cannot be retrieved.
+	Get(bucket string, key string) ([]byte, error)
+	// GetAll returns all kvps for a bucket. An error is returned if the kvps
+	// could not be retrieved.
+	GetAll(bucket string) (map[string][]byte, error)
+	// Keys returns the keys in a bucket that begin with prefix, in ascending
+	// order. An empty prefix matches every key. An error is returned if the
+	// keys could not be retrieved.
+	Keys(bucket, prefix string) ([]string, error)
+	// GetRange returns the kvps in a bucket whose keys fall in the range
+	// [start, end). An empty end leaves the range unbounded above. An error is
+	// returned if the kvps could not be retrieved.
+	GetRange(bucket, start, end string) (map[string][]byte, error)
+	// Delete removes the kvp with the provided key from a bucket. ErrHeapNotExist
+	// is returned if there is no such kvp. Otherwise, an error is returned if the
+	// kvp could not be removed.
+	Delete(bucket, key string) error
+	// DeleteBucket removes a bucket and every kvp in it. ErrHeapNotExist is
+	// returned if the bucket doesn't exist. Otherwise, an error is returned if the
+	// bucket could not be removed.
+	DeleteBucket(bucket string) error
+	// Buckets returns the names of every bucket in the heap, including those
+	// reserved for Hatchery's internal use, in ascending order. An error is
+	// returned if the names could not be retrieved.
+	Buckets() ([]string, error)
+}
+
+// Ledger is a transaction log that mimics the "blockchain."
+type Ledger interface {
+	// Head returns the first transaction in the ledger. This is
+	// known as the "genesis" transcation. If the ledger is empty,
+	// nil is returned instead. An error is returned if the ledger
+	// could not be read.
+	Head() (*Transaction, error)
+	// Find searches the ledger for a transaction with the given ID and returns it.
+	// If no transaction with the provided ID exists in the log, ErrTransactionNotExist
+	// is returned.
+	Find(id string) (*Transaction, error)
+	// Append adds Transactions to the end of the ledger, in order, linking each
+	// to the current tail by setting its PrevHash and Hash. The transactions are
+	// appended atomically: if any of them could not be stored, an error is
+	// returned and none of them are appended.
+	Append(ts ...*Transaction) error
+	// Iterate calls fn for each transaction in the ledger, in the order they were
+	// appended. Iteration stops early if fn returns false.
+	Iterate(fn func(t *Transaction) bool) error
+	// Verify walks the ledger from the genesis transaction and checks that each
+	// transaction's hash is intact and links to its predecessor. A *BrokenLinkError
+	// describing the first broken link is returned if the chain has been tampered
+	// with. Otherwise, an error is returned only if the ledger could not be read.
+	Verify() error
+}
+
+// Pruner is implemented by Ledgers whose oldest transactions can be removed, so
+// that the ledger doesn't grow forever.
+type Pruner interface {
+	Ledger
+	// Prune removes every transaction up to and including the one with the
+	// given ID, and returns how many were removed. The ledger remembers the hash
+	// of the last transaction removed, so that Verify checks that the remaining
+	// chain links to it. ErrTransactionNotExist is returned if no such
+	// transaction exists, and ErrPruneLatest if it is the latest transaction.
+	Prune(throughID string) (int, error)
+	// Pruned returns the hash of the last transaction removed by Prune, and how
+	// many transactions have been removed in all. If the ledger has never been
+	// pruned, the hash is empty.
+	Pruned() (hash string, count int, err error)
+}
+
+// HeapPut is a single write of a value to a Heap.
+type HeapPut struct {
+	Bucket string
+	Key    string
+	Value  []byte
+}
+
+// HeapAppender is implemented by Ledgers that are stored in the same database as
+// the Heap, so that a contract's output and the transaction that records it can
+// be committed together.
+type HeapAppender interface {
+	Ledger
+	// AppendWithHeap writes puts to the heap and appends ts to the ledger in a
+	// single database transaction: either all of them take effect or none do. The
+	// buckets of puts are namespaced like those of the Heap the Ledger was
+	// created with.
+	AppendWithHeap(puts []HeapPut, ts ...*Transaction) error
+}
@@ -0,0 +1,214 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// MinBlobSize is the size, in bytes, from which SplitBlobs moves the Content
+// or Payload of a transaction into a blob. Smaller values are cheaper to store
+// inline than to reference.
+const MinBlobSize = 512
+
+// BlobChunkSize is the size of the chunks WriteChunks splits a streamed blob into,
+// so that no more than a chunk of it is held in memory at once.
+const BlobChunkSize = 1 << 20
+
+// ErrBlobNotExist is returned by BlobStore.OpenBlob if no blob has the address.
+var ErrBlobNotExist = errors.New("blob does not exist")
+
+// BlobStore is implemented by Ledgers that can store content too large to hold in
+// memory, such as the output of a contract spilled under an output limit, which a
+// transaction then references with OutputRef. A blob is kept as long as a transaction
+// references it, and for a grace period after it is stored, so that it isn't removed
+// before the transaction that references it has been appended.
+type BlobStore interface {
+	// PutBlob stores everything read from r as a blob and returns its BlobAddress
+	// and size, without holding more than BlobChunkSize bytes of r in memory.
+	PutBlob(r io.Reader) (address string, size int64, err error)
+	// OpenBlob returns a reader of the blob at address, which checks the blob
+	// against its address as it is read. ErrBlobNotExist is returned if there is
+	// no such blob.
+	OpenBlob(address string) (io.ReadCloser, error)
+}
+
+// BlobIntegrityError is returned when a blob restored to a transaction doesn't
+// match its content address, because it was corrupted or is missing.
+type BlobIntegrityError struct {
+	// ID is the ID of the transaction the blob belongs to. It is empty for blobs
+	// read from a BlobStore.
+	ID string
+	// Address is the content address the transaction references.
+	Address string
+}
+
+func (e *BlobIntegrityError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("blob %s does not match its address", e.Address)
+	}
+	return fmt.Sprintf("blob %s of transaction %s does not match its address", e.Address, e.ID)
+}
+
+// BlobAddress returns the content address of b: its hex encoded SHA-256 hash.
+func BlobAddress(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// SplitBlobs prepares t to be stored by a ledger that keeps large content in a
+// content-addressable blob store, so that content that is appended repeatedly is
+// only stored once. It returns a copy of t whose Content and Payload, if they
+// are at least MinBlobSize bytes, are replaced by their BlobAddress in
+// ContentRef and PayloadRef, along with the blobs to store, keyed by address.
+// Ledgers should link t before splitting it, since its Hash covers the content.
+func SplitBlobs(t *Transaction) (*Transaction, map[string][]byte) {
+	stored := *t
+	blobs := make(map[string][]byte)
+	if len(t.Content) >= MinBlobSize {
+		stored.ContentRef = BlobAddress(t.Content)
+		stored.Content = nil
+		blobs[stored.ContentRef] = t.Content
+	}
+	if len(t.Payload) >= MinBlobSize {
+		stored.PayloadRef = BlobAddress(t.Payload)
+		stored.Payload = nil
+		blobs[stored.PayloadRef] = t.Payload
+	}
+	return &stored, blobs
+}
+
+// JoinBlobs restores the Content and Payload of a transaction stored with
+// SplitBlobs, reading the blobs it references with get, and checks them. See
+// CheckBlobs.
+func JoinBlobs(t *Transaction, get func(address string) ([]byte, error)) error {
+	var err error
+	if t.ContentRef != "" {
+		if t.Content, err = get(t.ContentRef); err != nil {
+			return err
+		}
+	}
+	if t.PayloadRef != "" {
+		if t.Payload, err = get(t.PayloadRef); err != nil {
+			return err
+		}
+	}
+	return CheckBlobs(t)
+}
+
+// CheckBlobs returns a *BlobIntegrityError if the Content or Payload restored to
+// a transaction stored with SplitBlobs doesn't match its reference. Otherwise, it
+// clears the references.
+func CheckBlobs(t *Transaction) error {
+	if t.ContentRef != "" && BlobAddress(t.Content) != t.ContentRef {
+		return &BlobIntegrityError{ID: t.ID, Address: t.ContentRef}
+	}
+	if t.PayloadRef != "" && BlobAddress(t.Payload) != t.PayloadRef {
+		return &BlobIntegrityError{ID: t.ID, Address: t.PayloadRef}
+	}
+	t.ContentRef, t.PayloadRef = "", ""
+	return nil
+}
+
+// WriteChunks reads r to its end in chunks of BlobChunkSize bytes, and calls put with
+// the BlobAddress of each chunk and its bytes, which are only valid until put returns.
+// Empty input is written as a single empty chunk. It returns the BlobAddress and size
+// of everything read, along with the addresses of its chunks in order. BlobStores
+// built on a store of small values use it to store a blob as content-addressed
+// chunks, and NewChunkReader to read it back.
+func WriteChunks(r io.Reader, put func(address string, chunk []byte) error) (string, []string, int64, error) {
+	var (
+		whole  = sha256.New()
+		buf    = make([]byte, BlobChunkSize)
+		chunks []string
+		size   int64
+	)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", nil, 0, err
+		}
+		if n > 0 || len(chunks) == 0 {
+			chunk := buf[:n]
+			whole.Write(chunk)
+			addr := BlobAddress(chunk)
+			if e := put(addr, chunk); e != nil {
+				return "", nil, 0, e
+			}
+			chunks = append(chunks, addr)
+			size += int64(n)
+		}
+		if err != nil {
+			return hex.EncodeToString(whole.Sum(nil)), chunks, size, nil
+		}
+	}
+}
+
+// NewChunkReader returns a reader of the blob at address that was stored as the
+// given chunks with WriteChunks, reading each chunk with get as it is needed. Each
+// chunk is checked against its address, and the whole blob once it has been read,
+// so a corrupted or missing chunk fails the read with a *BlobIntegrityError.
+func NewChunkReader(address string, chunks []string, get func(address string) ([]byte, error)) io.Reader {
+	return &chunkReader{address: address, chunks: chunks, get: get, whole: sha256.New()}
+}
+
+type chunkReader struct {
+	address string
+	chunks  []string
+	get     func(address string) ([]byte, error)
+	whole   hash.Hash
+	chunk   []byte
+	err     error
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 && r.err == nil {
+		if len(r.chunks) == 0 {
+			r.err = io.EOF
+			if hex.EncodeToString(r.whole.Sum(nil)) != r.address {
+				r.err = &BlobIntegrityError{Address: r.address}
+			}
+			break
+		}
+		addr := r.chunks[0]
+		r.chunks = r.chunks[1:]
+		chunk, err := r.get(addr)
+		switch {
+		case err != nil:
+			r.err = err
+		case BlobAddress(chunk) != addr:
+			r.err = &BlobIntegrityError{Address: r.address}
+		default:
+			r.whole.Write(chunk)
+			r.chunk = chunk
+		}
+	}
+	if len(r.chunk) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
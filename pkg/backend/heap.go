@@ -0,0 +1,85 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package backend
+
+// HeapIterator is implemented by Heaps that can stream the kvps of a bucket in key
+// order without loading the whole bucket into memory.
+type HeapIterator interface {
+	Heap
+	// Iterate calls fn with each kvp in a bucket whose key is at least start, in
+	// ascending byte order of keys, until fn returns false or the bucket is
+	// exhausted. If the bucket doesn't exist, fn is never called. value is only
+	// valid until fn returns, and fn must not modify the heap. An error is
+	// returned if the kvps could not be read.
+	Iterate(bucket, start string, fn func(key string, value []byte) bool) error
+}
+
+// IterateHeap calls fn with each kvp in a bucket of h whose key is at least start,
+// in ascending byte order of keys, until fn returns false, as described by
+// HeapIterator.Iterate. Heaps that don't implement HeapIterator are read one value
+// at a time after listing the bucket's keys. Keys deleted after they were listed
+// are skipped.
+func IterateHeap(h Heap, bucket, start string, fn func(key string, value []byte) bool) error {
+	if it, ok := h.(HeapIterator); ok {
+		return it.Iterate(bucket, start, fn)
+	}
+	keys, err := h.Keys(bucket, "")
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if k < start {
+			continue
+		}
+		v, err := h.Get(bucket, k)
+		if err == ErrHeapNotExist {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(k, v) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// PageHeap returns the kvps of a bucket of h in ascending byte order of keys,
+// skipping the first offset and returning at most limit of them, or all of the
+// rest if limit is not positive. If the bucket doesn't exist, an empty slice is
+// returned.
+func PageHeap(h Heap, bucket string, offset, limit int) ([]HeapPut, error) {
+	page := []HeapPut{}
+	i := 0
+	err := IterateHeap(h, bucket, "", func(key string, value []byte) bool {
+		if i++; i <= offset {
+			return true
+		}
+		v := make([]byte, len(value))
+		copy(v, value)
+		page = append(page, HeapPut{Bucket: bucket, Key: key, Value: v})
+		return limit <= 0 || len(page) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
@@ -0,0 +1,86 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package backend
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Environment variables that describe the transaction a contract is executed for,
+// as DragonChain passes them to its contracts.
+const (
+	// TxnIDEnv holds the ID of the transaction. It is not set for scheduled
+	// executions, which have no transaction.
+	TxnIDEnv = "TXN_ID"
+	// TxnTypeEnv holds the transaction type.
+	TxnTypeEnv = "TXN_TYPE"
+	// TxnTimestampEnv holds the transaction's timestamp, in seconds since the
+	// Unix epoch.
+	TxnTimestampEnv = "TXN_TIMESTAMP"
+	// InvokerEnv holds the name of the contract whose output invoked the
+	// transaction. It is empty for transactions that were posted directly.
+	InvokerEnv = "INVOKER"
+)
+
+// Invocation describes the transaction a contract is executed for.
+type Invocation struct {
+	TxnID     string
+	TxnType   string
+	Timestamp time.Time
+	Invoker   string
+}
+
+type invocationKey struct{}
+
+// WithInvocation returns a copy of ctx that carries inv, so that the Contract it is
+// passed to can make inv available to the contract's code. See InvocationEnv.
+func WithInvocation(ctx context.Context, inv Invocation) context.Context {
+	return context.WithValue(ctx, invocationKey{}, inv)
+}
+
+// InvocationFrom returns the Invocation carried by ctx, if any.
+func InvocationFrom(ctx context.Context) (Invocation, bool) {
+	inv, ok := ctx.Value(invocationKey{}).(Invocation)
+	return inv, ok
+}
+
+// InvocationEnv returns env with TXN_ID, TXN_TYPE, TXN_TIMESTAMP and INVOKER set
+// from the Invocation carried by ctx, overriding any variables of the same name.
+// env itself is not modified. If ctx carries no Invocation, env is returned as is.
+func InvocationEnv(ctx context.Context, env map[string]string) map[string]string {
+	inv, ok := InvocationFrom(ctx)
+	if !ok {
+		return env
+	}
+	out := make(map[string]string, len(env)+4)
+	for k, v := range env {
+		out[k] = v
+	}
+	out[TxnIDEnv] = inv.TxnID
+	out[TxnTypeEnv] = inv.TxnType
+	out[TxnTimestampEnv] = ""
+	if !inv.Timestamp.IsZero() {
+		out[TxnTimestampEnv] = strconv.FormatInt(inv.Timestamp.Unix(), 10)
+	}
+	out[InvokerEnv] = inv.Invoker
+	return out
+}
@@ -0,0 +1,138 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// BrokenLinkError is returned by Ledger.Verify when the chain of transaction
+// hashes is broken.
+type BrokenLinkError struct {
+	// Index is the zero-based position of the offending transaction in the ledger.
+	Index int
+	// ID is the ID of the offending transaction.
+	ID string
+	// Reason describes how the link is broken.
+	Reason string
+}
+
+func (e *BrokenLinkError) Error() string {
+	return fmt.Sprintf("broken link at transaction %d (%s): %s", e.Index, e.ID, e.Reason)
+}
+
+// HashVersion is the version of ComputeHash that Link hashes transactions with.
+// Version 1 also covers the Type, InvokerContract and Status of a transaction.
+const HashVersion = 1
+
+// ComputeHash returns the hex encoded SHA-256 hash of the transaction. The hash
+// covers the transaction's PrevHash, Content, Timestamp, Payload and Signer, so
+// altering any of them, or reordering the ledger, invalidates the chain. An empty
+// Payload or Signer is left out, so regular and unsigned transactions hash the
+// same as they always have. From HashVersion 1 on, the hash also covers the
+// version itself, Type, InvokerContract and Status, each prefixed with its length,
+// and the OutputRef and OutputSize of spilled output, if there is any. Transactions
+// with a HashVersion of zero hash the same as they always have.
+func (t *Transaction) ComputeHash() string {
+	h := sha256.New()
+	h.Write([]byte(t.PrevHash))
+	h.Write(t.Content)
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(t.Timestamp.UnixNano()))
+	h.Write(ts[:])
+	if len(t.Payload) > 0 {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(t.Payload)))
+		h.Write(n[:])
+		h.Write(t.Payload)
+	}
+	if t.Signer != "" {
+		h.Write([]byte(t.Signer))
+	}
+	if t.HashVersion > 0 {
+		var v [8]byte
+		binary.BigEndian.PutUint64(v[:], uint64(t.HashVersion))
+		h.Write(v[:])
+		for _, field := range []string{t.Type, t.InvokerContract, string(t.Status)} {
+			var n [8]byte
+			binary.BigEndian.PutUint64(n[:], uint64(len(field)))
+			h.Write(n[:])
+			h.Write([]byte(field))
+		}
+		if t.OutputRef != "" {
+			var n [8]byte
+			binary.BigEndian.PutUint64(n[:], uint64(len(t.OutputRef)))
+			h.Write(n[:])
+			h.Write([]byte(t.OutputRef))
+			binary.BigEndian.PutUint64(n[:], uint64(t.OutputSize))
+			h.Write(n[:])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Link sets the PrevHash and Hash of t so that it follows prev in the ledger,
+// hashing it with the current HashVersion. If prev is nil, t is the genesis
+// transaction. Ledgers call it from Append.
+func (t *Transaction) Link(prev *Transaction) {
+	t.PrevHash = ""
+	if prev != nil {
+		t.PrevHash = prev.Hash
+	}
+	t.HashVersion = HashVersion
+	t.Hash = t.ComputeHash()
+}
+
+// VerifyChain implements Ledger.Verify on top of Ledger.Iterate.
+func VerifyChain(iterate func(fn func(t *Transaction) bool) error) error {
+	return VerifyChainFrom("", iterate)
+}
+
+// VerifyChainFrom implements Ledger.Verify for a ledger that has been pruned: the
+// first transaction must link to anchor, the hash of the last transaction Prune
+// removed, rather than being a genesis transaction. See Pruner.
+func VerifyChainFrom(anchor string, iterate func(fn func(t *Transaction) bool) error) error {
+	var (
+		broken *BrokenLinkError
+		prev   = anchor
+		i      int
+	)
+	err := iterate(func(t *Transaction) bool {
+		switch {
+		case t.PrevHash != prev:
+			broken = &BrokenLinkError{Index: i, ID: t.ID, Reason: "previous hash does not match"}
+		case t.Hash != t.ComputeHash():
+			broken = &BrokenLinkError{Index: i, ID: t.ID, Reason: "hash does not match content"}
+		}
+		prev = t.Hash
+		i++
+		return broken == nil
+	})
+	if err != nil {
+		return err
+	}
+	if broken != nil {
+		return broken
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package backend
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Options configures a backend created from the registry.
+type Options struct {
+	// Settings holds the backend specific settings from the options of the
+	// backend's section of the configuration.
+	Settings map[string]string
+	// Namespace is empty for the node's root chain. Virtual chains create their
+	// own Ledger, Library and Elector with a Namespace that identifies the chain,
+	// and backends that share storage between chains must keep their data apart.
+	Namespace string
+	// Heap is the node's heap, or the chain's heap if Namespace is set. It is nil
+	// when a Heap is being created.
+	Heap Heap
+}
+
+// HeapFactory creates a Heap from Options.
+type HeapFactory func(opts Options) (Heap, error)
+
+// LedgerFactory creates a Ledger from Options.
+type LedgerFactory func(opts Options) (Ledger, error)
+
+// LibraryFactory creates a Library from Options.
+type LibraryFactory func(opts Options) (Library, error)
+
+// ElectorFactory creates an Elector from Options.
+type ElectorFactory func(opts Options) (Elector, error)
+
+var (
+	mu        sync.RWMutex
+	heaps     = make(map[string]HeapFactory)
+	ledgers   = make(map[string]LedgerFactory)
+	libraries = make(map[string]LibraryFactory)
+	electors  = make(map[string]ElectorFactory)
+)
+
+// RegisterHeap makes a Heap implementation available under the given name. It
+// is intended to be called from the init function of the implementation's
+// package. RegisterHeap panics if name is already registered.
+func RegisterHeap(name string, f HeapFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := heaps[name]; ok {
+		panic(fmt.Sprintf("backend: heap %q registered twice", name))
+	}
+	heaps[name] = f
+}
+
+// RegisterLedger makes a Ledger implementation available under the given name.
+// It panics if name is already registered. See RegisterHeap.
+func RegisterLedger(name string, f LedgerFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := ledgers[name]; ok {
+		panic(fmt.Sprintf("backend: ledger %q registered twice", name))
+	}
+	ledgers[name] = f
+}
+
+// RegisterLibrary makes a Library implementation available under the given name.
+// It panics if name is already registered. See RegisterHeap.
+func RegisterLibrary(name string, f LibraryFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := libraries[name]; ok {
+		panic(fmt.Sprintf("backend: library %q registered twice", name))
+	}
+	libraries[name] = f
+}
+
+// RegisterElector makes an Elector implementation available under the given
+// name. It panics if name is already registered. See RegisterHeap.
+func RegisterElector(name string, f ElectorFactory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := electors[name]; ok {
+		panic(fmt.Sprintf("backend: elector %q registered twice", name))
+	}
+	electors[name] = f
+}
+
+// LookupHeap returns the HeapFactory registered under name, if any.
+func LookupHeap(name string) (HeapFactory, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := heaps[name]
+	return f, ok
+}
+
+// LookupLedger returns the LedgerFactory registered under name, if any.
+func LookupLedger(name string) (LedgerFactory, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := ledgers[name]
+	return f, ok
+}
+
+// LookupLibrary returns the LibraryFactory registered under name, if any.
+func LookupLibrary(name string) (LibraryFactory, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := libraries[name]
+	return f, ok
+}
+
+// LookupElector returns the ElectorFactory registered under name, if any.
+func LookupElector(name string) (ElectorFactory, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := electors[name]
+	return f, ok
+}
@@ -0,0 +1,675 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package client is a Go client for the Hatchery API. It is intended for test
+// suites that drive a local Hatchery instance.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// hmacScheme is the Authorization scheme used to sign requests.
+	hmacScheme = "DC1-HMAC-SHA256"
+	// timestampLayout is the layout of the timestamp header, matching the
+	// DragonChain SDKs.
+	timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 100 * time.Millisecond
+)
+
+// Transaction is a transaction on the Hatchery ledger.
+type Transaction struct {
+	ID              string
+	Type            string `json:"txn_type"`
+	InvokerContract string
+	Status          string
+	Timestamp       time.Time
+	PrevHash        string
+	Hash            string
+	// Signer is the ID of the signing key the transaction was signed with, if
+	// any.
+	Signer string `json:",omitempty"`
+	// Attempts is how many times the smart contract was executed before the
+	// transaction was appended, and LastError the error of the last attempt that
+	// failed, if any did.
+	Attempts  int    `json:",omitempty"`
+	LastError string `json:",omitempty"`
+	// Content is the transaction's payload, or the output of the smart contract
+	// that handled it.
+	Content []byte
+}
+
+// TransactionPage is a page of transactions from the ledger, in the order they
+// were appended.
+type TransactionPage struct {
+	Transactions []Transaction
+	Offset       int
+	Limit        int
+	// Total is the number of transactions on the ledger.
+	Total int
+}
+
+// ContractManifest describes a smart contract to post to Hatchery. See the
+// Hatchery documentation for the meaning of each field.
+type ContractManifest struct {
+	Type              string `json:"txn_type"`
+	Runtime           string `json:",omitempty"`
+	Image             string
+	Source            string `json:",omitempty"`
+	Dockerfile        string `json:",omitempty"`
+	Cmd               string
+	Args              []string          `json:",omitempty"`
+	ExecutionOrder    string            `json:"execution_order,omitempty"`
+	Priority          int               `json:",omitempty"`
+	Env               map[string]string `json:",omitempty"`
+	Cron              string            `json:",omitempty"`
+	CronOverlap       string            `json:",omitempty"`
+	CronJitter        string            `json:",omitempty"`
+	CronPayload       json.RawMessage   `json:",omitempty"`
+	CronPayloadSource string            `json:",omitempty"`
+	ExecutionTimeout  string            `json:",omitempty"`
+	DigestPolicy      string            `json:",omitempty"`
+	Network           string            `json:",omitempty"`
+	NetworkAllow      []string          `json:",omitempty"`
+	Auth              string            `json:",omitempty"`
+	Secrets           map[string]string `json:",omitempty"`
+	HeapOutput        string            `json:",omitempty"`
+	HeapOutputKey     string            `json:",omitempty"`
+	HeapReaders       []string          `json:",omitempty"`
+	HeapWriters       []string          `json:",omitempty"`
+	HeapSchemas       []HeapSchema      `json:",omitempty"`
+	RetryPolicy       *RetryPolicy      `json:",omitempty"`
+}
+
+// HeapSchema types the values of a contract's heap keys that begin with Prefix.
+type HeapSchema struct {
+	Prefix      string
+	ContentType string          `json:",omitempty"`
+	Schema      json.RawMessage `json:",omitempty"`
+}
+
+// RetryPolicy determines how the failed executions of a contract's posted
+// transactions are retried.
+type RetryPolicy struct {
+	MaxAttempts        int    `json:",omitempty"`
+	Backoff            string `json:",omitempty"`
+	RetryableExitCodes []int  `json:",omitempty"`
+}
+
+// HeapEntry is a key value pair of an exported heap. Values that are valid JSON
+// are held in Value, and any other value in Base64.
+type HeapEntry struct {
+	Key    string          `json:"key"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Base64 []byte          `json:"base64,omitempty"`
+}
+
+// Error is returned when Hatchery responds with an unsuccessful status code.
+type Error struct {
+	StatusCode int
+	// Code identifies the kind of error, such as "contract_not_found". It is
+	// empty if the response had no error envelope.
+	Code string
+	// Message describes the error.
+	Message string
+	// Details holds additional information about some errors.
+	Details json.RawMessage
+	// Body is the raw body of the response.
+	Body string
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = strings.TrimSpace(e.Body)
+	}
+	if msg == "" {
+		return fmt.Sprintf("hatchery: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("hatchery: %d %s: %s", e.StatusCode, e.Code, msg)
+	}
+	return fmt.Sprintf("hatchery: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), msg)
+}
+
+// Client is a Hatchery API client. Its zero value is not usable; BaseURL must
+// be set. A Client is safe for concurrent use.
+type Client struct {
+	// BaseURL is the URL Hatchery is served at, such as "http://localhost:8080".
+	BaseURL string
+	// AuthKeyID and AuthKey are the API key used to sign requests. If AuthKeyID
+	// is empty, requests are not signed.
+	AuthKeyID string
+	AuthKey   string
+	// DragonChainID is sent with signed requests, and must match the ID
+	// Hatchery is configured with, if any.
+	DragonChainID string
+	// SigningKeyID and SigningKey are the registered ed25519 key that posted
+	// transactions are signed with. If SigningKeyID is empty, transactions are
+	// not signed.
+	SigningKeyID string
+	SigningKey   ed25519.PrivateKey
+	// HTTPClient is used to make requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+	// MaxRetries is how many times a request is retried after a connection
+	// error or a 502, 503 or 504 response. If zero, requests are retried 3
+	// times. If negative, requests are never retried.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry. It doubles with each
+	// subsequent retry. If zero, 100ms is used.
+	RetryBackoff time.Duration
+}
+
+// PostTransaction posts a transaction of the given type to the ledger. The
+// payload is JSON encoded; a json.RawMessage or []byte of JSON is sent as-is.
+// If the transaction type is a smart contract, Hatchery executes it before
+// the transaction is returned. The transaction is signed if SigningKeyID is set.
+func (c *Client) PostTransaction(ctx context.Context, txnType string, payload interface{}) (*Transaction, error) {
+	req, err := c.transactionRequest(txnType, payload)
+	if err != nil {
+		return nil, err
+	}
+	var t Transaction
+	if err := c.do(ctx, http.MethodPost, "/transaction", req, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// PostTransactionAsync is like PostTransaction, but returns the ID of the transaction
+// as soon as it is queued, without waiting for its contract to execute. Its outcome
+// can be polled for with TransactionStatus.
+func (c *Client) PostTransactionAsync(ctx context.Context, txnType string, payload interface{}) (string, error) {
+	req, err := c.transactionRequest(txnType, payload)
+	if err != nil {
+		return "", err
+	}
+	var status TransactionStatus
+	if err := c.do(ctx, http.MethodPost, "/transaction?async=true", req, &status); err != nil {
+		return "", err
+	}
+	return status.ID, nil
+}
+
+// Statuses of a TransactionStatus.
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// TransactionStatus is the state of a posted transaction. Transaction is set once
+// Status is StatusSuccess, and Error once it is StatusFailure.
+type TransactionStatus struct {
+	ID          string       `json:"id"`
+	Status      string       `json:"status"`
+	TxnType     string       `json:"txn_type"`
+	Attempts    int          `json:"attempts"`
+	Error       string       `json:"error"`
+	Transaction *Transaction `json:"transaction"`
+}
+
+// TransactionStatus returns the status of the posted transaction with the given ID.
+func (c *Client) TransactionStatus(ctx context.Context, id string) (*TransactionStatus, error) {
+	var status TransactionStatus
+	if err := c.do(ctx, http.MethodGet, "/transaction/"+url.PathEscape(id)+"/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// transactionRequest returns the body of a request posting a transaction, signed if
+// SigningKeyID is set.
+func (c *Client) transactionRequest(txnType string, payload interface{}) (interface{}, error) {
+	var raw json.RawMessage
+	switch p := payload.(type) {
+	case json.RawMessage:
+		raw = p
+	case []byte:
+		raw = p
+	default:
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode payload: %s", err)
+		}
+		raw = b
+	}
+	req := struct {
+		Type      string `json:"txn_type"`
+		Payload   json.RawMessage
+		Signer    string `json:"signer,omitempty"`
+		Signature string `json:"signature,omitempty"`
+	}{Type: txnType, Payload: raw}
+	if c.SigningKeyID != "" {
+		sig, err := SignTransaction(c.SigningKey, txnType, raw)
+		if err != nil {
+			return nil, err
+		}
+		req.Signer, req.Signature = c.SigningKeyID, sig
+	}
+	return req, nil
+}
+
+// PostContract posts a smart contract to Hatchery. If the contract already
+// exists, a new version of it is created.
+func (c *Client) PostContract(ctx context.Context, manifest *ContractManifest) error {
+	return c.do(ctx, http.MethodPost, "/contract", manifest, nil)
+}
+
+// ValidateContract checks a smart contract manifest, and that its image can be
+// pulled, without posting it. A manifest with errors is reported in the LintReport
+// rather than as an error.
+func (c *Client) ValidateContract(ctx context.Context, manifest *ContractManifest) (*LintReport, error) {
+	var report LintReport
+	if err := c.do(ctx, http.MethodPost, "/contract/validate", manifest, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// TestResult is the outcome of executing a contract with TestContract. Stdout and
+// Stderr are returned as text.
+type TestResult struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	Duration string `json:"duration"`
+}
+
+// TestContract executes the manifest's contract with payload without posting the
+// contract, writing its output to the heap or appending to the ledger. A non-zero
+// exit code is reported in the TestResult rather than as an error.
+func (c *Client) TestContract(ctx context.Context, manifest *ContractManifest, payload json.RawMessage) (*TestResult, error) {
+	req := struct {
+		Manifest *ContractManifest `json:"manifest"`
+		Payload  json.RawMessage   `json:"payload"`
+	}{manifest, payload}
+	var res TestResult
+	if err := c.do(ctx, http.MethodPost, "/contract/test", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// RegisterSigningKey registers an ed25519 public key with Hatchery under id, so
+// that transactions signed with its private key can be posted.
+func (c *Client) RegisterSigningKey(ctx context.Context, id string, key ed25519.PublicKey) error {
+	req := struct {
+		ID        string `json:"id"`
+		PublicKey []byte `json:"public_key"`
+	}{id, key}
+	return c.do(ctx, http.MethodPost, "/keys", req, nil)
+}
+
+// ListTransactions returns up to limit transactions from the ledger, starting at
+// offset. If limit is zero, Hatchery's default page size is used.
+func (c *Client) ListTransactions(ctx context.Context, offset, limit int) (*TransactionPage, error) {
+	q := url.Values{}
+	q.Set("offset", strconv.Itoa(offset))
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var page TransactionPage
+	if err := c.do(ctx, http.MethodGet, "/transactions?"+q.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ListContracts returns the manifests of every smart contract. Secrets are
+// redacted.
+func (c *Client) ListContracts(ctx context.Context) ([]ContractManifest, error) {
+	var manifests []ContractManifest
+	if err := c.do(ctx, http.MethodGet, "/contract", nil, &manifests); err != nil {
+		return nil, err
+	}
+	return manifests, nil
+}
+
+// DeleteContract deletes the named smart contract and stops its cron job, if
+// it has one.
+func (c *Client) DeleteContract(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/contract/"+url.PathEscape(name), nil, nil)
+}
+
+// ListHeap returns the keys in the heap of the named smart contract that begin
+// with prefix, sorted in ascending order.
+func (c *Client) ListHeap(ctx context.Context, scName, prefix string) ([]string, error) {
+	path := "/list/" + url.PathEscape(scName)
+	if prefix != "" {
+		path += "/" + url.PathEscape(prefix)
+	}
+	var keys []string
+	if err := c.do(ctx, http.MethodGet, path, nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetHeap returns the value stored under key in the heap of the named smart
+// contract. Values written by contracts are raw JSON.
+func (c *Client) GetHeap(ctx context.Context, scName, key string) ([]byte, error) {
+	var v rawBody
+	path := "/get/" + url.PathEscape(scName) + "/" + url.PathEscape(key) + "?format=raw"
+	if err := c.do(ctx, http.MethodGet, path, nil, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CompareAndSwapHeap stores new under key in the heap of the named smart
+// contract if the key holds old, as returned by GetHeap, or if old is nil, if
+// the key doesn't exist. It reports whether the value was stored; if it
+// wasn't, the key should be read again before retrying.
+func (c *Client) CompareAndSwapHeap(ctx context.Context, scName, key string, old, new json.RawMessage) (bool, error) {
+	body := struct {
+		Old json.RawMessage `json:"old,omitempty"`
+		New json.RawMessage `json:"new"`
+	}{old, new}
+	path := "/heap/" + url.PathEscape(scName) + "/" + url.PathEscape(key) + "/cas"
+	err := c.do(ctx, http.MethodPost, path, body, nil)
+	if e, ok := err.(*Error); ok && e.StatusCode == http.StatusConflict {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ExportHeap returns every key value pair in the heap of the named smart
+// contract, in ascending key order.
+func (c *Client) ExportHeap(ctx context.Context, scName string) ([]HeapEntry, error) {
+	var entries []HeapEntry
+	if err := c.do(ctx, http.MethodGet, "/heap/"+url.PathEscape(scName)+"/export", nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ImportHeap writes entries, such as those returned by ExportHeap, to the heap of
+// the named smart contract. If replace is true, the heap is emptied first. It
+// returns the number of entries written.
+func (c *Client) ImportHeap(ctx context.Context, scName string, entries []HeapEntry, replace bool) (int, error) {
+	path := "/heap/" + url.PathEscape(scName) + "/import"
+	if replace {
+		path += "?replace=true"
+	}
+	if entries == nil {
+		entries = []HeapEntry{}
+	}
+	var resp struct {
+		Imported int `json:"imported"`
+	}
+	if err := c.do(ctx, http.MethodPost, path, entries, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Imported, nil
+}
+
+// ExportLedger returns every transaction in the ledger, in the order they were
+// appended and with the hashes that link them, as JSON Lines that ImportLedger
+// accepts.
+func (c *Client) ExportLedger(ctx context.Context) ([]byte, error) {
+	var v rawBody
+	if err := c.do(ctx, http.MethodGet, "/ledger/export", nil, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ImportLedger appends the transactions of export, as returned by ExportLedger, to
+// the ledger. Transactions already in the ledger are skipped, and the rest must
+// continue from its latest transaction. It returns the number of transactions
+// appended and skipped.
+func (c *Client) ImportLedger(ctx context.Context, export []byte) (imported, skipped int, err error) {
+	var resp struct {
+		Imported int `json:"imported"`
+		Skipped  int `json:"skipped"`
+	}
+	if err := c.doBody(ctx, http.MethodPost, "/ledger/import", "application/x-ndjson", export, &resp); err != nil {
+		return 0, 0, err
+	}
+	return resp.Imported, resp.Skipped, nil
+}
+
+// Violation is a problem with a single field of a contract manifest.
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// LintReport is the outcome of checking a manifest with ValidateContract. Errors
+// would prevent it from being posted, and warnings point out settings that are
+// allowed but probably not intended.
+type LintReport struct {
+	Valid    bool        `json:"valid"`
+	Errors   []Violation `json:"errors"`
+	Warnings []Violation `json:"warnings"`
+}
+
+// BundleResult is the outcome of registering one manifest of a contract bundle.
+// Error is set if the manifest was not registered, along with Violations if it was
+// invalid.
+type BundleResult struct {
+	File       string      `json:"file"`
+	Contract   string      `json:"contract,omitempty"`
+	Version    int         `json:"version,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// PostContractBundle registers every contract manifest in bundle, a tar, gzipped tar
+// or zip archive of JSON manifests, and returns a result for each. Manifests that fail
+// don't prevent the others from being registered, so the results should be checked
+// even if the error is nil.
+func (c *Client) PostContractBundle(ctx context.Context, bundle []byte) ([]BundleResult, error) {
+	var results []BundleResult
+	if err := c.doBody(ctx, http.MethodPost, "/contract/bundle", bundleContentType(bundle), bundle, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func bundleContentType(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, []byte("PK")):
+		return "application/zip"
+	case bytes.HasPrefix(b, []byte{0x1f, 0x8b}):
+		return "application/gzip"
+	}
+	return "application/x-tar"
+}
+
+// rawBody may be passed to do to receive the response body undecoded.
+type rawBody []byte
+
+// do sends a request with the JSON encoding of in as its body, if in is not
+// nil, and decodes the JSON response into out, if out is not nil. The request
+// is retried according to MaxRetries and RetryBackoff.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request: %s", err)
+		}
+	}
+	return c.doBody(ctx, method, path, "application/json", body, out)
+}
+
+// doBody is like do, but sends body, of the given content type, as-is.
+func (c *Client) doBody(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	retries := c.MaxRetries
+	if retries == 0 {
+		retries = defaultMaxRetries
+	}
+	backoff := c.RetryBackoff
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, contentType, body)
+		if err == nil && !retryable(resp.StatusCode) {
+			return decodeResponse(resp, out)
+		}
+		if attempt >= retries {
+			if err != nil {
+				return err
+			}
+			return decodeResponse(resp, out)
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(backoff << uint(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// send makes a single, signed attempt at a request.
+func (c *Client) send(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, strings.TrimRight(c.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.AuthKeyID != "" {
+		timestamp := time.Now().UTC().Format(timestampLayout)
+		sig := SignRequest(c.AuthKey, method, req.URL.RequestURI(), c.DragonChainID, timestamp, req.Header.Get("Content-Type"), body)
+		req.Header.Set("Authorization", hmacScheme+" "+c.AuthKeyID+":"+sig)
+		req.Header.Set("dragonchain", c.DragonChainID)
+		req.Header.Set("timestamp", timestamp)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return hc.Do(req)
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		e := &Error{StatusCode: resp.StatusCode, Body: string(b)}
+		var envelope struct {
+			Error struct {
+				Code    string          `json:"code"`
+				Message string          `json:"message"`
+				Details json.RawMessage `json:"details"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(b, &envelope) == nil {
+			e.Code = envelope.Error.Code
+			e.Message = envelope.Error.Message
+			e.Details = envelope.Error.Details
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	if raw, ok := out.(*rawBody); ok {
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %s", err)
+		}
+		*raw = b
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %s", err)
+	}
+	return nil
+}
+
+// SignRequest returns the base64 encoded HMAC-SHA256 signature of a request, as
+// expected in the Authorization header by DragonChain and Hatchery.
+func SignRequest(secret, method, uri, dragonchainID, timestamp, contentType string, body []byte) string {
+	contentHash := sha256.Sum256(body)
+	msg := strings.Join([]string{
+		strings.ToUpper(method),
+		uri,
+		dragonchainID,
+		timestamp,
+		contentType,
+		base64.StdEncoding.EncodeToString(contentHash[:]),
+	}, "\n")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(msg))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TransactionMessage returns the message that is signed to sign a transaction:
+// the transaction type and the compacted JSON payload, separated by a newline.
+// An error is returned if the payload is not valid JSON.
+func TransactionMessage(txnType string, payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(txnType)
+	buf.WriteByte('\n')
+	if len(payload) > 0 {
+		if err := json.Compact(&buf, payload); err != nil {
+			return nil, fmt.Errorf("invalid payload: %s", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// SignTransaction returns the base64 encoded ed25519 signature of a transaction,
+// as expected in the signature field of posted transactions by Hatchery.
+func SignTransaction(key ed25519.PrivateKey, txnType string, payload []byte) (string, error) {
+	msg, err := TransactionMessage(txnType, payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, msg)), nil
+}
@@ -0,0 +1,357 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package hatcherytest runs Hatchery in memory, so that contract pipelines can be
+// unit tested without Docker. A Harness serves the Hatchery API from an
+// httptest.Server, keeps its heap and ledger in memory, and executes contracts with
+// Go functions in place of containers:
+//
+//	func TestScorer(t *testing.T) {
+//		h := hatcherytest.New(t)
+//		h.Deploy("scorer", func(ctx context.Context, payload []byte, env map[string]string) ([]byte, error) {
+//			return []byte(`{"score": 3}`), nil
+//		})
+//		h.Post("scorer", map[string]int{"points": 3})
+//		h.AssertHeap("scorer", "score", 3)
+//		h.AssertLedgerLen(1)
+//	}
+//
+// Contracts are executed like any other: their output is stored in the heap, they
+// may write to the heap API at HATCHERY_URL with their HEAP_TOKEN, and their
+// transactions are appended to the ledger.
+package hatcherytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/summerplaygames/hatchery/internal/app/hatchery"
+	"github.com/summerplaygames/hatchery/internal/app/logging"
+	"github.com/summerplaygames/hatchery/pkg/backend"
+	"github.com/summerplaygames/hatchery/pkg/client"
+)
+
+// Runtime is the name of the runtime that executes the contracts deployed to a
+// Harness.
+const Runtime = "hatcherytest"
+
+// Func implements a contract. It is called with the payload of each execution and the
+// contract's environment, which includes its manifest's Env and the variables Hatchery
+// passes to every contract, such as HATCHERY_URL, HEAP_TOKEN and TXN_ID, and returns
+// the contract's output. An error fails the execution; if it has an ExitCode() int
+// method, the execution fails as if the contract exited with that status.
+type Func func(ctx context.Context, payload []byte, env map[string]string) ([]byte, error)
+
+var (
+	funcsMu sync.RWMutex
+	funcs   = make(map[string]Func)
+)
+
+func init() {
+	hatchery.RegisterRuntime(Runtime, funcRuntime{})
+}
+
+// Harness is a Hatchery node for a single test. It is stopped when the test finishes.
+type Harness struct {
+	// Client is a client of the node's API.
+	Client *client.Client
+	// URL is the base URL of the node's API.
+	URL string
+	// Heap and Ledger hold the node's state, for tests that inspect it directly.
+	Heap   backend.Heap
+	Ledger backend.Ledger
+
+	t  testing.TB
+	id string
+}
+
+// New starts a Harness for the test t, with an empty heap, ledger and contract
+// library. The test fails at once if the harness can't be started.
+func New(t testing.TB) *Harness {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "hatcherytest-")
+	if err != nil {
+		t.Fatalf("hatcherytest: failed to create contract library: %s", err)
+	}
+	log := &testLog{t: t}
+	logger := logging.New(log, logging.LevelError)
+	heap, ledger := hatchery.NewMemHeap(), hatchery.NewMemLedger()
+	keyPath := filepath.Join(dir, ".key")
+	secrets := &hatchery.HeapSecretStore{Heap: heap, KeyPath: keyPath}
+	app := &hatchery.Application{
+		Heap:    heap,
+		Ledger:  ledger,
+		Secrets: secrets,
+		Lib: &hatchery.FSLibrary{
+			BasePath: dir,
+			Logger:   logger,
+			KeyPath:  keyPath,
+			Secrets:  secrets,
+		},
+		Logger: logger,
+	}
+	// The listener is reserved first, so that BaseURL is set before Handler starts
+	// the background work that reads it.
+	srv := httptest.NewUnstartedServer(nil)
+	app.BaseURL = "http://" + srv.Listener.Addr().String()
+	srv.Config.Handler = app.Handler()
+	srv.Start()
+	h := &Harness{
+		Client: &client.Client{BaseURL: srv.URL},
+		URL:    srv.URL,
+		Heap:   heap,
+		Ledger: ledger,
+		t:      t,
+		id:     uuid.New().String(),
+	}
+	t.Cleanup(func() {
+		srv.Close()
+		app.Shutdown()
+		log.close()
+		funcsMu.Lock()
+		for image := range funcs {
+			if strings.HasPrefix(image, h.id+"/") {
+				delete(funcs, image)
+			}
+		}
+		funcsMu.Unlock()
+		os.RemoveAll(dir)
+	})
+	return h
+}
+
+// Deploy posts a contract named name that executes fn.
+func (h *Harness) Deploy(name string, fn Func) {
+	h.t.Helper()
+	h.DeployManifest(&client.ContractManifest{Type: name}, fn)
+}
+
+// DeployManifest posts the contract described by m, which executes fn. Its Runtime and
+// Image are replaced with those of the harness. Unless m sets a RetryPolicy, failed
+// executions are not retried, so that failures are reported at once. The test fails at
+// once if the contract is rejected.
+func (h *Harness) DeployManifest(m *client.ContractManifest, fn Func) {
+	h.t.Helper()
+	manifest := *m
+	manifest.Runtime = Runtime
+	manifest.Image = h.id + "/" + manifest.Type
+	if manifest.RetryPolicy == nil {
+		manifest.RetryPolicy = &client.RetryPolicy{MaxAttempts: 1}
+	}
+	funcsMu.Lock()
+	funcs[manifest.Image] = fn
+	funcsMu.Unlock()
+	if err := h.Client.PostContract(context.Background(), &manifest); err != nil {
+		h.t.Fatalf("hatcherytest: failed to deploy %s: %s", manifest.Type, err)
+	}
+}
+
+// Post posts a transaction of the given type with payload, encoded like
+// client.PostTransaction encodes it, and returns it once it has been appended to the
+// ledger. The test fails at once if the transaction fails.
+func (h *Harness) Post(txnType string, payload interface{}) *client.Transaction {
+	h.t.Helper()
+	t, err := h.TryPost(txnType, payload)
+	if err != nil {
+		h.t.Fatalf("hatcherytest: transaction %s failed: %s", txnType, err)
+	}
+	return t
+}
+
+// TryPost is like Post, but returns the error of a transaction that fails, such as a
+// *client.Error whose Code is "execution_failed", for tests of failures.
+func (h *Harness) TryPost(txnType string, payload interface{}) (*client.Transaction, error) {
+	return h.Client.PostTransaction(context.Background(), txnType, payload)
+}
+
+// HeapValue returns the value of key in the heap bucket, and whether it exists.
+func (h *Harness) HeapValue(bucket, key string) ([]byte, bool) {
+	h.t.Helper()
+	v, err := h.Heap.Get(bucket, key)
+	if err == backend.ErrHeapNotExist {
+		return nil, false
+	}
+	if err != nil {
+		h.t.Fatalf("hatcherytest: failed to read heap key %s/%s: %s", bucket, key, err)
+	}
+	return v, true
+}
+
+// AssertHeap reports an error if key in the heap bucket doesn't hold the JSON encoding of
+// want. Values are compared as JSON, so formatting and the order of object members
+// don't matter. A json.RawMessage want is compared as is.
+func (h *Harness) AssertHeap(bucket, key string, want interface{}) {
+	h.t.Helper()
+	v, ok := h.HeapValue(bucket, key)
+	if !ok {
+		h.t.Errorf("heap key %s/%s doesn't exist; want %s", bucket, key, encode(want))
+		return
+	}
+	var got, wanted interface{}
+	if err := json.Unmarshal(v, &got); err != nil {
+		h.t.Errorf("heap key %s/%s holds %q, which isn't JSON; want %s", bucket, key, v, encode(want))
+		return
+	}
+	if err := json.Unmarshal(encode(want), &wanted); err != nil {
+		h.t.Fatalf("hatcherytest: invalid expected value of %s/%s: %s", bucket, key, err)
+	}
+	if !reflect.DeepEqual(got, wanted) {
+		h.t.Errorf("heap key %s/%s holds %s; want %s", bucket, key, v, encode(want))
+	}
+}
+
+// AssertNoHeap reports an error if key exists in the heap bucket.
+func (h *Harness) AssertNoHeap(bucket, key string) {
+	h.t.Helper()
+	if v, ok := h.HeapValue(bucket, key); ok {
+		h.t.Errorf("heap key %s/%s holds %s; want no value", bucket, key, v)
+	}
+}
+
+// Transactions returns every transaction on the ledger, in the order they were
+// appended.
+func (h *Harness) Transactions() []*backend.Transaction {
+	h.t.Helper()
+	var ts []*backend.Transaction
+	err := h.Ledger.Iterate(func(t *backend.Transaction) bool {
+		ts = append(ts, t)
+		return true
+	})
+	if err != nil {
+		h.t.Fatalf("hatcherytest: failed to read ledger: %s", err)
+	}
+	return ts
+}
+
+// AssertLedgerLen reports an error if the ledger doesn't hold n transactions.
+func (h *Harness) AssertLedgerLen(n int) {
+	h.t.Helper()
+	if got := len(h.Transactions()); got != n {
+		h.t.Errorf("ledger holds %d transactions; want %d", got, n)
+	}
+}
+
+// encode returns the JSON encoding of v, or v itself if it is a json.RawMessage.
+func encode(v interface{}) []byte {
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []byte(fmt.Sprintf("%v", v))
+	}
+	return b
+}
+
+// funcRuntime executes the contracts deployed to a Harness with their Funcs, which
+// are looked up by the Image of their manifests.
+type funcRuntime struct{}
+
+func (funcRuntime) Prepare(manifest *backend.ContractManifest) error {
+	_, err := lookupFunc(manifest)
+	return err
+}
+
+func (funcRuntime) Contract(manifest *backend.ContractManifest, env map[string]string, logger logging.Logger) (backend.Contract, error) {
+	fn, err := lookupFunc(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &funcContract{fn: fn, env: env}, nil
+}
+
+func (funcRuntime) Remove(manifest *backend.ContractManifest) error {
+	return nil
+}
+
+func lookupFunc(manifest *backend.ContractManifest) (Func, error) {
+	funcsMu.RLock()
+	defer funcsMu.RUnlock()
+	fn, ok := funcs[manifest.Image]
+	if !ok {
+		return nil, fmt.Errorf("contract %s was not deployed with hatcherytest", manifest.Type)
+	}
+	return fn, nil
+}
+
+// funcContract is a contract executed by a Func.
+type funcContract struct {
+	fn  Func
+	mu  sync.Mutex
+	env map[string]string
+}
+
+// SetEnv sets the environment variable key to value for subsequent executions.
+func (c *funcContract) SetEnv(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.env == nil {
+		c.env = make(map[string]string)
+	}
+	c.env[key] = value
+}
+
+// Execute calls the contract's Func with a copy of its environment, which includes the
+// transaction carried by ctx. If ctx is cancelled, context.Canceled is returned, like
+// the other runtimes.
+func (c *funcContract) Execute(ctx context.Context, payload []byte) ([]byte, error) {
+	c.mu.Lock()
+	env := make(map[string]string, len(c.env))
+	for k, v := range c.env {
+		env[k] = v
+	}
+	c.mu.Unlock()
+	out, err := c.fn(ctx, payload, backend.InvocationEnv(ctx, env))
+	if ctx.Err() == context.Canceled {
+		return nil, context.Canceled
+	}
+	return out, err
+}
+
+// testLog writes the logs of a Harness to the test log, until the test finishes.
+type testLog struct {
+	mu     sync.Mutex
+	t      testing.TB
+	closed bool
+}
+
+func (l *testLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func (l *testLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+}
@@ -0,0 +1,106 @@
+//  Created on Sat Oct 17 2026
+//
+//  The MIT License (MIT)
+//  Copyright (c) 2019 SummerPlay LLC
+//
+//  Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+//  and associated documentation files (the "Software"), to deal in the Software without restriction,
+//  including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+//  and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+//  subject to the following conditions:
+//
+//  The above copyright notice and this permission notice shall be included in all copies or substantial
+//  portions of the Software.
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED
+//  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL
+//  THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+//  TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package server runs a Hatchery node. Programs that provide their own Heap,
+// Ledger or Library implementations import them for their side effect of
+// registering with package backend, and then call Main:
+//
+//	package main
+//
+//	import (
+//		_ "example.com/hatchery-dynamodb"
+//		"github.com/summerplaygames/hatchery/pkg/server"
+//	)
+//
+//	func main() {
+//		server.Main()
+//	}
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/summerplaygames/hatchery/internal/app/config"
+	"github.com/summerplaygames/hatchery/internal/app/hatchery"
+	"github.com/summerplaygames/hatchery/internal/app/tracing"
+)
+
+// Main loads the configuration named by the -config flag, builds the node from
+// it and serves the API until the process is interrupted. Spans are exported as
+// configured by the tracing section, and flushed before Main returns. It exits
+// the process if anything goes wrong.
+func Main() {
+	configPath := flag.String("config", "", "path to a YAML or JSON config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	stopTracing, err := tracing.Configure(context.Background(), tracing.Settings{
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	app, err := hatchery.NewApplicationFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if cfg.RequireAuth {
+		if err := ensureAPIKey(app); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	err = app.Run(context.Background(), cfg.Addr)
+	if serr := stopTracing(context.Background()); serr != nil {
+		fmt.Fprintln(os.Stderr, "failed to flush spans:", serr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// ensureAPIKey generates and prints an API key if none exist yet, so that the
+// first client has a way in.
+func ensureAPIKey(app *hatchery.Application) error {
+	keys, err := app.APIKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return nil
+	}
+	key, err := app.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Generated API key\n  ID:  %s\n  Key: %s\n", key.ID, key.Key)
+	return nil
+}